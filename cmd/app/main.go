@@ -4,6 +4,8 @@ import (
 	"catalyst.api/config"
 	"catalyst.api/internal/application"
 	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/domain/user"
+	"catalyst.api/internal/domain/user/data"
 	"catalyst.api/internal/routes"
 )
 
@@ -17,12 +19,16 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	authentication.NewAuthentication(cfg)
 	app, err := application.NewApplication(cfg)
 	if err != nil {
 		panic(err)
 	}
 	defer app.Database.Close()
-	routes.SetupRoutes(app.Gin, app.Database, app.Repositories, app.Middlewares, app.Logger)
+	exportWorker := user.NewDataExportWorker(app.Repositories.UserRepository, user.NewDataExporter(app.Repositories.UserRepository, data.New(app.Database), app.Logger), app.Logger)
+	routes.SetupRoutes(app.Gin, app.Database, app.Repositories, app.Authentication, app.Middlewares, app.Mailer, exportWorker, app.Cursors, cfg.UserConfig, app.Logger)
+	go exportWorker.Run()
+	go authentication.NewExpiredTokenSweeper(app.Repositories.AuthenticationRepository, app.Logger).Run()
+	go user.NewUserPurger(app.Repositories.UserRepository, app.Logger).Run()
+	go user.NewAdminBootstrap(app.Repositories.UserRepository, cfg.UserConfig.BootstrapAdminEmail, app.Logger).Run()
 	app.Start()
 }
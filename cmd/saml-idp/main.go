@@ -0,0 +1,47 @@
+// Command saml-idp runs the stand-in SAML identity provider for trying SAML
+// sign-in locally. It signs in whoever fills in its form, with no password, so
+// it must never be exposed beyond a developer's machine.
+//
+// Generate its key with `make saml-idp-key`, run it with `make saml-idp`, then
+// create a SAML connection whose idpMetadata is the XML served at
+// http://localhost:8085/metadata. The email, firstName and lastName attributes
+// it sends match the default attribute mapping.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"catalyst.api/internal/samlidp"
+)
+
+func main() {
+	address := flag.String("addr", ":8085", "address to listen on")
+	baseURL := flag.String("base-url", "http://localhost:8085", "URL the browser reaches the identity provider at")
+	keyPath := flag.String("key", "keys/saml-idp.pem", "PEM encoded RSA private key")
+	certificatePath := flag.String("cert", "keys/saml-idp.crt", "PEM encoded certificate for the key")
+	serviceProviderMetadataURL := flag.String("sp-metadata", "", "service provider metadata URL, if the entity ID is not one")
+	flag.Parse()
+
+	keyPair, err := tls.LoadX509KeyPair(*certificatePath, *keyPath)
+	if err != nil {
+		log.Fatalf("load key: %v", err)
+	}
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		log.Fatalf("parse certificate: %v", err)
+	}
+
+	logger := log.New(os.Stdout, "saml-idp ", log.LstdFlags)
+	identityProvider, err := samlidp.New(*baseURL, keyPair.PrivateKey, certificate, *serviceProviderMetadataURL, logger)
+	if err != nil {
+		log.Fatalf("parse base URL: %v", err)
+	}
+
+	logger.Printf("metadata at %s/metadata", *baseURL)
+	log.Fatal(http.ListenAndServe(*address, identityProvider))
+}
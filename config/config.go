@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,16 +14,127 @@ import (
 type Config struct {
 	HttpConfig           HttpConfig
 	AuthenticationConfig AuthenticationConfig
+	MailerConfig         MailerConfig
+	PaginationConfig     PaginationConfig
+	UserConfig           UserConfig
 }
 
 type HttpConfig struct {
 	Port         string
 	IsProduction bool
 	Timeout      int
+	// PublicURL is where clients reach the API, used for links the API hands out.
+	PublicURL string
+	// FrontendURL is the web app's base URL. Sign-in redirects and emailed
+	// links point there, and it is the only origin CORS allows.
+	FrontendURL string
 }
 type AuthenticationConfig struct {
-	GithubClientID     string
-	GithubClientSecret string
+	Providers           []OAuthProviderConfig
+	JWTIssuer           string
+	JWTSigningKey       JWTKeyConfig
+	JWTVerificationKeys []JWTKeyConfig
+	// ProviderTokenKey encrypts the OAuth tokens providers issue at sign-in.
+	// ProviderTokenPreviousKeys are only used to read tokens stored before a
+	// rotation, which are re-encrypted with ProviderTokenKey as they are read.
+	ProviderTokenKey          EncryptionKeyConfig
+	ProviderTokenPreviousKeys []EncryptionKeyConfig
+	// SessionKeys sign and encrypt the session gothic keeps OAuth state in. The
+	// first pair is used for new sessions; the rest only to read sessions from
+	// before a rotation.
+	SessionKeys []SessionKeyConfig
+	// SessionStore is "cookie" to keep the session in the cookie itself, or
+	// "postgres" to keep it in the database so it is shared between instances.
+	SessionStore string
+	Admission    AdmissionConfig
+	SAML         SAMLConfig
+}
+
+// SAMLConfig points at the PEM encoded RSA key and certificate the API uses as
+// a SAML service provider. They are published in every workspace's metadata so
+// identity providers can encrypt assertions and check signed requests. Both
+// may be left empty, in which case requests go unsigned and encrypted
+// assertions are refused.
+type SAMLConfig struct {
+	KeyPath         string
+	CertificatePath string
+}
+
+// AdmissionConfig decides who may sign in. A user is let in if they match any
+// of the GitHub organizations, GitHub teams (written org/team-slug) or email
+// domains, or hold an invitation. With none of these set anyone may sign in,
+// and InviteOnly additionally requires an invitation to create an account.
+// GitHub checks need the read:org scope on the GitHub provider.
+type AdmissionConfig struct {
+	GitHubOrganizations []string
+	GitHubTeams         []string
+	EmailDomains        []string
+	InviteOnly          bool
+	GitHubAPIURL        string
+}
+
+// MailerConfig points at the SMTP server used for outgoing mail. Username and
+// Password may be left empty for servers that do not require authentication,
+// such as the local mailpit container. Transport is "smtp", or "memory" to keep
+// mail in memory instead of sending it.
+type MailerConfig struct {
+	Transport   string
+	SMTPHost    string
+	SMTPPort    int
+	Username    string
+	Password    string
+	FromAddress string
+}
+
+// PaginationConfig holds the base64 encoded key list cursors are signed with,
+// at least 32 bytes long. When CursorKey is empty a random key is used, so
+// cursors stop working when the API restarts and are not shared between
+// instances.
+type PaginationConfig struct {
+	CursorKey string
+}
+
+// UserConfig holds account settings. DeletionGracePeriod is how long a
+// deleted account can still be restored before everything the user owns is
+// purged. BootstrapAdminEmail, when set, makes the user with that email an
+// admin while there are no admins yet.
+type UserConfig struct {
+	DeletionGracePeriod time.Duration
+	BootstrapAdminEmail string
+}
+
+// OAuthProviderConfig configures one login provider. Type is one of github,
+// gitlab, google, microsoft or oidc and defaults to Name, so several providers
+// of the same type can be enabled under different names.
+type OAuthProviderConfig struct {
+	Name            string
+	Type            string
+	DisplayName     string
+	ClientID        string
+	ClientSecret    string
+	Scopes          []string
+	CallbackBaseURL string
+	DiscoveryURL    string
+}
+
+// JWTKeyConfig points at a PEM encoded RSA or Ed25519 key. The signing key must
+// be a private key; verification keys may be public or private keys.
+type JWTKeyConfig struct {
+	KeyID string
+	Path  string
+}
+
+// EncryptionKeyConfig is a base64 encoded 256-bit AES key.
+type EncryptionKeyConfig struct {
+	KeyID string
+	Key   string
+}
+
+// SessionKeyConfig is a base64 encoded hash key, used to sign, and an optional
+// base64 encoded AES block key, used to encrypt.
+type SessionKeyConfig struct {
+	HashKey  string
+	BlockKey string
 }
 
 func LoadConfig() (*Config, error) {
@@ -34,22 +147,122 @@ func LoadConfig() (*Config, error) {
 	port := getEnvVariable("PORT", ":8080")
 	isProduction := getEnvAsBool("ISPRODUCTION", false)
 	timeout := getEnvVariableAsInt("TIMEOUT", 20)
-	githubClientID := getEnvVariable("GITHUB_CLIENT_ID", "no client id")
-	githubClientSecret := getEnvVariable("GITHUB_CLIENT_SECRET", "no client secret")
+	publicURL := strings.TrimSuffix(getEnvVariable("PUBLIC_URL", "http://localhost:42069"), "/")
+	frontendURL := strings.TrimSuffix(getEnvVariable("FRONTEND_URL", "http://localhost:4200"), "/")
+	providers := getOAuthProviderConfigs()
+	jwtIssuer := getEnvVariable("JWT_ISSUER", "catalyst.api")
+	jwtSigningKeyID := getEnvVariable("JWT_SIGNING_KEY_ID", "")
+	jwtSigningKeyPath := getEnvVariable("JWT_SIGNING_KEY_FILE", "")
+	jwtVerificationKeys, err := getEnvAsJWTKeyConfigs("JWT_VERIFICATION_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	providerTokenKeyID := getEnvVariable("PROVIDER_TOKEN_KEY_ID", "")
+	providerTokenKey := getEnvVariable("PROVIDER_TOKEN_KEY", "")
+	providerTokenPreviousKeys, err := getEnvAsEncryptionKeyConfigs("PROVIDER_TOKEN_PREVIOUS_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	sessionKeys, err := getEnvAsSessionKeyConfigs("SESSION_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	sessionStore := getEnvVariable("SESSION_STORE", "cookie")
+	admissionGitHubOrganizations := getEnvAsList("ADMISSION_GITHUB_ORGS", nil)
+	admissionGitHubTeams := getEnvAsList("ADMISSION_GITHUB_TEAMS", nil)
+	admissionEmailDomains := getEnvAsList("ADMISSION_EMAIL_DOMAINS", nil)
+	admissionInviteOnly := getEnvAsBool("ADMISSION_INVITE_ONLY", false)
+	githubAPIURL := strings.TrimSuffix(getEnvVariable("GITHUB_API_URL", "https://api.github.com"), "/")
+	samlKeyPath := getEnvVariable("SAML_SP_KEY_FILE", "")
+	samlCertificatePath := getEnvVariable("SAML_SP_CERTIFICATE_FILE", "")
+	mailTransport := getEnvVariable("MAIL_TRANSPORT", "smtp")
+	smtpHost := getEnvVariable("SMTP_HOST", "localhost")
+	smtpPort := getEnvVariableAsInt("SMTP_PORT", 1025)
+	smtpUsername := getEnvVariable("SMTP_USERNAME", "")
+	smtpPassword := getEnvVariable("SMTP_PASSWORD", "")
+	mailFrom := getEnvVariable("MAIL_FROM", "catalyst <no-reply@catalyst.local>")
+	cursorKey := getEnvVariable("CURSOR_SIGNING_KEY", "")
+	deletionGracePeriod := getEnvAsDuration("USER_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	bootstrapAdminEmail := strings.TrimSpace(getEnvVariable("BOOTSTRAP_ADMIN_EMAIL", ""))
 
 	return &Config{
 		HttpConfig: HttpConfig{
 			Port:         port,
 			IsProduction: isProduction,
 			Timeout:      timeout,
+			PublicURL:    publicURL,
+			FrontendURL:  frontendURL,
 		},
 		AuthenticationConfig: AuthenticationConfig{
-			GithubClientID:     githubClientID,
-			GithubClientSecret: githubClientSecret,
+			Providers: providers,
+			JWTIssuer: jwtIssuer,
+			JWTSigningKey: JWTKeyConfig{
+				KeyID: jwtSigningKeyID,
+				Path:  jwtSigningKeyPath,
+			},
+			JWTVerificationKeys: jwtVerificationKeys,
+			ProviderTokenKey: EncryptionKeyConfig{
+				KeyID: providerTokenKeyID,
+				Key:   providerTokenKey,
+			},
+			ProviderTokenPreviousKeys: providerTokenPreviousKeys,
+			SessionKeys:               sessionKeys,
+			SessionStore:              sessionStore,
+			Admission: AdmissionConfig{
+				GitHubOrganizations: admissionGitHubOrganizations,
+				GitHubTeams:         admissionGitHubTeams,
+				EmailDomains:        admissionEmailDomains,
+				InviteOnly:          admissionInviteOnly,
+				GitHubAPIURL:        githubAPIURL,
+			},
+			SAML: SAMLConfig{
+				KeyPath:         samlKeyPath,
+				CertificatePath: samlCertificatePath,
+			},
+		},
+		MailerConfig: MailerConfig{
+			Transport:   mailTransport,
+			SMTPHost:    smtpHost,
+			SMTPPort:    smtpPort,
+			Username:    smtpUsername,
+			Password:    smtpPassword,
+			FromAddress: mailFrom,
+		},
+		PaginationConfig: PaginationConfig{
+			CursorKey: cursorKey,
+		},
+		UserConfig: UserConfig{
+			DeletionGracePeriod: deletionGracePeriod,
+			BootstrapAdminEmail: bootstrapAdminEmail,
 		},
 	}, nil
 }
 
+// getOAuthProviderConfigs reads AUTH_PROVIDERS and the <NAME>_* variables for
+// each provider listed, e.g. GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET.
+func getOAuthProviderConfigs() []OAuthProviderConfig {
+	defaultCallbackBaseURL := getEnvVariable("AUTH_CALLBACK_BASE_URL", "http://localhost:42069")
+
+	providers := []OAuthProviderConfig{}
+	for _, name := range getEnvAsList("AUTH_PROVIDERS", []string{"github"}) {
+		name = strings.ToLower(name)
+		prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providerType := getEnvVariable(prefix+"TYPE", name)
+
+		providers = append(providers, OAuthProviderConfig{
+			Name:            name,
+			Type:            providerType,
+			DisplayName:     getEnvVariable(prefix+"DISPLAY_NAME", ""),
+			ClientID:        getEnvVariable(prefix+"CLIENT_ID", "no client id"),
+			ClientSecret:    getEnvVariable(prefix+"CLIENT_SECRET", "no client secret"),
+			Scopes:          getEnvAsList(prefix+"SCOPES", nil),
+			CallbackBaseURL: getEnvVariable(prefix+"CALLBACK_BASE_URL", defaultCallbackBaseURL),
+			DiscoveryURL:    getEnvVariable(prefix+"DISCOVERY_URL", ""),
+		})
+	}
+	return providers
+}
+
 // Helper functions
 func getEnvVariable(key string, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -75,3 +288,68 @@ func getEnvAsBool(name string, defaultVal bool) bool {
 	}
 	return defaultVal
 }
+
+// getEnvAsDuration reads a duration such as 720h. Negative durations are
+// ignored like malformed ones.
+func getEnvAsDuration(name string, defaultVal time.Duration) time.Duration {
+	if valStr, exists := os.LookupEnv(name); exists {
+		if val, err := time.ParseDuration(valStr); err == nil && val >= 0 {
+			return val
+		}
+	}
+	return defaultVal
+}
+
+func getEnvAsList(name string, defaultVal []string) []string {
+	if valStr, exists := os.LookupEnv(name); exists {
+		values := []string{}
+		for _, value := range strings.Split(valStr, ",") {
+			value = strings.TrimSpace(value)
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+	return defaultVal
+}
+
+// getEnvAsJWTKeyConfigs reads a comma separated list of kid=path pairs.
+func getEnvAsJWTKeyConfigs(name string) ([]JWTKeyConfig, error) {
+	keyConfigs := []JWTKeyConfig{}
+	for _, pair := range getEnvAsList(name, nil) {
+		keyID, path, found := strings.Cut(pair, "=")
+		if !found || keyID == "" || path == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, expected kid=path", name, pair)
+		}
+		keyConfigs = append(keyConfigs, JWTKeyConfig{KeyID: keyID, Path: path})
+	}
+	return keyConfigs, nil
+}
+
+// getEnvAsEncryptionKeyConfigs reads a comma separated list of kid=base64key pairs.
+func getEnvAsEncryptionKeyConfigs(name string) ([]EncryptionKeyConfig, error) {
+	keyConfigs := []EncryptionKeyConfig{}
+	for _, pair := range getEnvAsList(name, nil) {
+		keyID, key, found := strings.Cut(pair, "=")
+		if !found || keyID == "" || key == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, expected kid=key", name, pair)
+		}
+		keyConfigs = append(keyConfigs, EncryptionKeyConfig{KeyID: keyID, Key: key})
+	}
+	return keyConfigs, nil
+}
+
+// getEnvAsSessionKeyConfigs reads a comma separated list of hashkey:blockkey
+// pairs, where the block key may be left out.
+func getEnvAsSessionKeyConfigs(name string) ([]SessionKeyConfig, error) {
+	keyConfigs := []SessionKeyConfig{}
+	for _, pair := range getEnvAsList(name, nil) {
+		hashKey, blockKey, _ := strings.Cut(pair, ":")
+		if hashKey == "" {
+			return nil, fmt.Errorf("invalid %s entry, expected hashkey:blockkey", name)
+		}
+		keyConfigs = append(keyConfigs, SessionKeyConfig{HashKey: hashKey, BlockKey: blockKey})
+	}
+	return keyConfigs, nil
+}
@@ -7,8 +7,11 @@ import (
 	"time"
 
 	"catalyst.api/config"
+	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/common/pagination"
 	"catalyst.api/internal/database"
 	"catalyst.api/internal/domain"
+	"catalyst.api/internal/mailer"
 	"catalyst.api/internal/middleware"
 	"catalyst.api/migrations"
 
@@ -19,13 +22,16 @@ import (
 )
 
 type Application struct {
-	Logger       *log.Logger
-	Gin          *gin.Engine
-	Config       *config.Config
-	Server       *http.Server
-	Database     *pgxpool.Pool
-	Repositories *domain.Repositories
-	Middlewares  *middleware.Middlewares
+	Logger         *log.Logger
+	Gin            *gin.Engine
+	Config         *config.Config
+	Server         *http.Server
+	Database       *pgxpool.Pool
+	Repositories   *domain.Repositories
+	Authentication *authentication.Authentication
+	Middlewares    *middleware.Middlewares
+	Mailer         mailer.Mailer
+	Cursors        *pagination.CursorCodec
 }
 
 func NewApplication(cfg *config.Config) (*Application, error) {
@@ -52,14 +58,29 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 	engine := gin.New()
 	engine.Use(gin.Logger(), gin.Recovery())
 	engine.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:4200"}, // Allow frontend URL (Angular app)
+		AllowOrigins:     []string{cfg.HttpConfig.FrontendURL}, // Allow frontend URL (Angular app)
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Requested-With"},
+		ExposeHeaders:    []string{"Link"},
 		AllowCredentials: true,
 	}))
 
+	appMailer, err := mailer.NewMailer(cfg.MailerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	cursors, err := pagination.NewCursorCodec(cfg.PaginationConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	repositories := domain.RegisterRepositories(pool)
-	middlewares := middleware.RegisterMiddlewares(repositories)
+	auth, err := authentication.NewAuthentication(cfg, repositories.AuthenticationRepository)
+	if err != nil {
+		return nil, err
+	}
+	middlewares := middleware.RegisterMiddlewares(repositories, auth)
 	server := &http.Server{
 		Addr:         cfg.HttpConfig.Port,
 		IdleTimeout:  time.Minute,
@@ -68,13 +89,16 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 		Handler:      engine,
 	}
 	app := &Application{
-		Logger:       logger,
-		Gin:          engine,
-		Config:       cfg,
-		Server:       server,
-		Database:     pool,
-		Repositories: repositories,
-		Middlewares:  middlewares,
+		Logger:         logger,
+		Gin:            engine,
+		Config:         cfg,
+		Server:         server,
+		Database:       pool,
+		Repositories:   repositories,
+		Authentication: auth,
+		Middlewares:    middlewares,
+		Mailer:         appMailer,
+		Cursors:        cursors,
 	}
 
 	return app, nil
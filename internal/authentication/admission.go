@@ -0,0 +1,284 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"catalyst.api/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/markbates/goth"
+)
+
+// Codes sent to the front-end when the admission rules turn a user away.
+const (
+	AdmissionInvitationRequired    = "invitation_required"
+	AdmissionOrganizationRequired  = "organization_membership_required"
+	AdmissionEmailDomainNotAllowed = "email_domain_not_allowed"
+	AdmissionEmailNotVerified      = "email_not_verified"
+)
+
+const admissionDefaultDeniedMessage = "You are not allowed to sign in."
+
+var admissionErrorMessages = map[string]string{
+	AdmissionInvitationRequired:    "An invitation is needed to create an account.",
+	AdmissionOrganizationRequired:  "Sign in with a GitHub account that belongs to an allowed organization or team.",
+	AdmissionEmailDomainNotAllowed: "Sign in with an email address from an allowed domain.",
+	AdmissionEmailNotVerified:      "Sign in with a magic link or a provider that confirms your email address.",
+}
+
+// AdmissionError is returned when the admission rules turn a user away. Code
+// is one of the Admission constants.
+type AdmissionError struct {
+	Code string
+}
+
+func (err *AdmissionError) Error() string {
+	message, ok := admissionErrorMessages[err.Code]
+	if !ok {
+		return admissionDefaultDeniedMessage
+	}
+	return message
+}
+
+type GitHubTeam struct {
+	Organization string
+	Slug         string
+}
+
+// AdmissionRules is the parsed form of config.AdmissionConfig.
+type AdmissionRules struct {
+	GitHubOrganizations []string
+	GitHubTeams         []GitHubTeam
+	EmailDomains        []string
+	InviteOnly          bool
+	GitHubAPIURL        string
+}
+
+func LoadAdmissionRules(cfg *config.Config) (*AdmissionRules, error) {
+	admissionConfig := cfg.AuthenticationConfig.Admission
+	rules := &AdmissionRules{
+		GitHubOrganizations: admissionConfig.GitHubOrganizations,
+		InviteOnly:          admissionConfig.InviteOnly,
+		GitHubAPIURL:        admissionConfig.GitHubAPIURL,
+	}
+
+	for _, team := range admissionConfig.GitHubTeams {
+		organization, slug, found := strings.Cut(team, "/")
+		if !found || organization == "" || slug == "" {
+			return nil, fmt.Errorf("invalid GitHub team %q, expected org/team-slug", team)
+		}
+		rules.GitHubTeams = append(rules.GitHubTeams, GitHubTeam{Organization: organization, Slug: slug})
+	}
+	for _, domain := range admissionConfig.EmailDomains {
+		rules.EmailDomains = append(rules.EmailDomains, strings.ToLower(strings.TrimPrefix(domain, "@")))
+	}
+	return rules, nil
+}
+
+// IsOpen reports whether anyone may sign in.
+func (rules *AdmissionRules) IsOpen() bool {
+	return !rules.InviteOnly && !rules.hasAllowList()
+}
+
+func (rules *AdmissionRules) hasGitHubRules() bool {
+	return len(rules.GitHubOrganizations) > 0 || len(rules.GitHubTeams) > 0
+}
+
+func (rules *AdmissionRules) hasAllowList() bool {
+	return rules.hasGitHubRules() || len(rules.EmailDomains) > 0
+}
+
+func (rules *AdmissionRules) allowsEmailDomain(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	return slices.Contains(rules.EmailDomains, strings.ToLower(email[at+1:]))
+}
+
+// AdmissionCandidate describes someone signing in in the terms the rules need.
+type AdmissionCandidate struct {
+	Email string
+	// EmailVerified is true when a provider vouches for Email. Invitations and
+	// email domains only count towards a new account if it is. For GitHub
+	// sign-ins the policy asks GitHub instead.
+	EmailVerified bool
+	// Existing is true when the user already has an account.
+	Existing bool
+	// GitHubAccessToken and GitHubLogin are set when signing in with GitHub.
+	GitHubAccessToken string
+	GitHubLogin       string
+}
+
+// ProviderCandidate describes a user signing in with an OAuth provider. Some
+// providers let users set an email address they have not confirmed, so the
+// email only counts as verified if the provider says so.
+func (policy *AdmissionPolicy) ProviderCandidate(gothUser goth.User, existing bool) AdmissionCandidate {
+	providerType := policy.providerTypes[gothUser.Provider]
+	candidate := AdmissionCandidate{
+		Email:         gothUser.Email,
+		EmailVerified: gothUser.Email != "" && providerEmailVerified(providerType, gothUser.RawData),
+		Existing:      existing,
+	}
+	if providerType == "github" {
+		candidate.GitHubAccessToken = gothUser.AccessToken
+		candidate.GitHubLogin = gothUser.NickName
+	}
+	return candidate
+}
+
+// providerEmailVerified reads whether the provider verified the user's email
+// from its user info. Google's userinfo endpoint calls the claim
+// verified_email, OpenID Connect calls it email_verified. GitLab and Microsoft
+// do not vouch for the email at all.
+func providerEmailVerified(providerType string, rawData map[string]interface{}) bool {
+	var claim interface{}
+	switch providerType {
+	case "google":
+		claim = rawData["verified_email"]
+		if claim == nil {
+			claim = rawData["email_verified"]
+		}
+	case "oidc":
+		claim = rawData["email_verified"]
+	}
+
+	// some identity providers send the claim as a string
+	switch verified := claim.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+// AdmissionPolicy applies the admission rules at sign-in. A user is let in if
+// they hold an invitation, or if they match any of the allowed GitHub
+// organizations, teams or email domains. Invite-only mode stops anyone
+// without an invitation from creating an account.
+type AdmissionPolicy struct {
+	repository    AuthenticationRepository
+	github        *GitHubMembershipClient
+	rules         *AdmissionRules
+	providerTypes map[string]string
+}
+
+func NewAdmissionPolicy(authenticationRepo AuthenticationRepository, rules *AdmissionRules, providerTypes map[string]string) *AdmissionPolicy {
+	return newAdmissionPolicy(authenticationRepo, NewGitHubMembershipClient(rules.GitHubAPIURL, nil), rules, providerTypes)
+}
+
+func newAdmissionPolicy(authenticationRepo AuthenticationRepository, github *GitHubMembershipClient, rules *AdmissionRules, providerTypes map[string]string) *AdmissionPolicy {
+	return &AdmissionPolicy{
+		repository:    authenticationRepo,
+		github:        github,
+		rules:         rules,
+		providerTypes: providerTypes,
+	}
+}
+
+// Admit returns an *AdmissionError if the candidate may not sign in. Other
+// errors mean the rules could not be checked.
+func (policy *AdmissionPolicy) Admit(ctx context.Context, candidate AdmissionCandidate) error {
+	if policy.rules.IsOpen() {
+		return nil
+	}
+
+	emailVerified, err := policy.isEmailVerified(ctx, candidate)
+	if err != nil {
+		return err
+	}
+	// an existing account already got past the rules with this email
+	emailTrusted := emailVerified || candidate.Existing
+	if emailTrusted && candidate.Email != "" {
+		invited, err := policy.repository.HasInvitation(ctx, candidate.Email)
+		if err != nil {
+			return err
+		}
+		if invited {
+			return nil
+		}
+	}
+
+	if policy.rules.InviteOnly && !candidate.Existing {
+		return &AdmissionError{Code: AdmissionInvitationRequired}
+	}
+	if !policy.rules.hasAllowList() {
+		return nil
+	}
+
+	domainAllowed := policy.rules.allowsEmailDomain(candidate.Email)
+	if emailTrusted && domainAllowed {
+		return nil
+	}
+
+	member, err := policy.isGitHubMember(ctx, candidate)
+	if err != nil {
+		return err
+	}
+	if member {
+		return nil
+	}
+
+	switch {
+	case domainAllowed:
+		return &AdmissionError{Code: AdmissionEmailNotVerified}
+	case policy.rules.hasGitHubRules():
+		return &AdmissionError{Code: AdmissionOrganizationRequired}
+	default:
+		return &AdmissionError{Code: AdmissionEmailDomainNotAllowed}
+	}
+}
+
+func (policy *AdmissionPolicy) isEmailVerified(ctx context.Context, candidate AdmissionCandidate) (bool, error) {
+	if candidate.EmailVerified || candidate.Email == "" || candidate.GitHubAccessToken == "" {
+		return candidate.EmailVerified, nil
+	}
+	return policy.github.IsVerifiedPrimaryEmail(ctx, candidate.GitHubAccessToken, candidate.Email)
+}
+
+func (policy *AdmissionPolicy) isGitHubMember(ctx context.Context, candidate AdmissionCandidate) (bool, error) {
+	if candidate.GitHubAccessToken == "" {
+		return false, nil
+	}
+
+	for _, organization := range policy.rules.GitHubOrganizations {
+		member, err := policy.github.IsOrganizationMember(ctx, candidate.GitHubAccessToken, organization)
+		if err != nil || member {
+			return member, err
+		}
+	}
+	if candidate.GitHubLogin == "" {
+		return false, nil
+	}
+	for _, team := range policy.rules.GitHubTeams {
+		member, err := policy.github.IsTeamMember(ctx, candidate.GitHubAccessToken, team.Organization, team.Slug, candidate.GitHubLogin)
+		if err != nil || member {
+			return member, err
+		}
+	}
+	return false, nil
+}
+
+// admitCandidate applies the admission rules for a JSON endpoint and answers
+// the request if the candidate is turned away.
+func admitCandidate(ctx *gin.Context, admission *AdmissionPolicy, logger *log.Logger, candidate AdmissionCandidate) bool {
+	err := admission.Admit(ctx.Request.Context(), candidate)
+	var admissionErr *AdmissionError
+	if errors.As(err, &admissionErr) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": admissionErr.Error(), "code": admissionErr.Code})
+		return false
+	}
+	if err != nil {
+		logger.Printf("ERROR: admissionPolicyAdmit: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return false
+	}
+	return true
+}
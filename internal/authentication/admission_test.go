@@ -0,0 +1,213 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"catalyst.api/config"
+
+	"github.com/markbates/goth"
+)
+
+// The bodies GitHub answers membership requests with.
+const (
+	activeMembership  = `{"state":"active"}`
+	pendingMembership = `{"state":"pending"}`
+)
+
+// newGitHubStandIn answers GitHub API requests from responses, keyed by access
+// token and request path. A response of "error" fails the request, and paths
+// that are not listed answer 404 as GitHub does for organizations the user
+// cannot see.
+func newGitHubStandIn(t *testing.T, responses map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		accessToken := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+		response, ok := responses[accessToken+" "+request.URL.Path]
+		switch {
+		case !ok:
+			http.NotFound(writer, request)
+		case response == "error":
+			http.Error(writer, "server error", http.StatusInternalServerError)
+		default:
+			writer.Header().Set("Content-Type", "application/json")
+			writer.Write([]byte(response))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAdmissionPolicyAdmit(t *testing.T) {
+	github := newGitHubStandIn(t, map[string]string{
+		"member-token /user/memberships/orgs/acme":                     activeMembership,
+		"pending-token /user/memberships/orgs/acme":                    pendingMembership,
+		"team-token /orgs/acme/teams/platform/memberships/ada":         activeMembership,
+		"pending-team-token /orgs/acme/teams/platform/memberships/ada": pendingMembership,
+		"verified-token /user/emails":                                  `[{"email":"ada@acme.com","primary":true,"verified":true}]`,
+		"unverified-token /user/emails":                                `[{"email":"ada@acme.com","primary":true,"verified":false}]`,
+		"secondary-token /user/emails":                                 `[{"email":"ada@example.org","primary":true,"verified":true},{"email":"ada@acme.com","primary":false,"verified":true}]`,
+	})
+
+	repository := newFakeAuthenticationRepository()
+	repository.invitations["invited@example.org"] = true
+
+	open := &AdmissionRules{}
+	inviteOnly := &AdmissionRules{InviteOnly: true}
+	domains := &AdmissionRules{EmailDomains: []string{"acme.com"}}
+	organizations := &AdmissionRules{GitHubOrganizations: []string{"acme"}}
+	teams := &AdmissionRules{GitHubTeams: []GitHubTeam{{Organization: "acme", Slug: "platform"}}}
+	inviteOnlyOrganizations := &AdmissionRules{InviteOnly: true, GitHubOrganizations: []string{"acme"}}
+
+	tests := []struct {
+		name      string
+		rules     *AdmissionRules
+		candidate AdmissionCandidate
+		// code is the expected AdmissionError code, empty if admitted
+		code string
+	}{
+		{"open", open, AdmissionCandidate{Email: "ada@example.org"}, ""},
+		{"invite only, invited", inviteOnly, AdmissionCandidate{Email: "Invited@example.org", EmailVerified: true}, ""},
+		{"invite only, invited but unverified", inviteOnly, AdmissionCandidate{Email: "invited@example.org"}, AdmissionInvitationRequired},
+		{"invite only, not invited", inviteOnly, AdmissionCandidate{Email: "ada@example.org", EmailVerified: true}, AdmissionInvitationRequired},
+		{"invite only, existing account", inviteOnly, AdmissionCandidate{Email: "ada@example.org", Existing: true}, ""},
+		{"domain, verified", domains, AdmissionCandidate{Email: "ada@ACME.com", EmailVerified: true}, ""},
+		{"domain, existing account", domains, AdmissionCandidate{Email: "ada@acme.com", Existing: true}, ""},
+		{"domain, unverified", domains, AdmissionCandidate{Email: "ada@acme.com"}, AdmissionEmailNotVerified},
+		{"domain, other domain", domains, AdmissionCandidate{Email: "ada@example.org", EmailVerified: true}, AdmissionEmailDomainNotAllowed},
+		{"domain, invited from other domain", domains, AdmissionCandidate{Email: "invited@example.org", EmailVerified: true}, ""},
+		{"domain, GitHub verified primary email", domains, AdmissionCandidate{Email: "Ada@acme.com", GitHubAccessToken: "verified-token"}, ""},
+		{"domain, GitHub unverified email", domains, AdmissionCandidate{Email: "ada@acme.com", GitHubAccessToken: "unverified-token"}, AdmissionEmailNotVerified},
+		{"domain, GitHub secondary email", domains, AdmissionCandidate{Email: "ada@acme.com", GitHubAccessToken: "secondary-token"}, AdmissionEmailNotVerified},
+		{"domain, GitHub emails hidden", domains, AdmissionCandidate{Email: "ada@acme.com", GitHubAccessToken: "other-token"}, AdmissionEmailNotVerified},
+		{"organization, active member", organizations, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "member-token"}, ""},
+		{"organization, pending member", organizations, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "pending-token"}, AdmissionOrganizationRequired},
+		{"organization, not a member", organizations, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "other-token"}, AdmissionOrganizationRequired},
+		{"organization, not signed in with GitHub", organizations, AdmissionCandidate{Email: "ada@example.org", EmailVerified: true}, AdmissionOrganizationRequired},
+		{"team, active member", teams, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "team-token", GitHubLogin: "ada"}, ""},
+		{"team, pending member", teams, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "pending-team-token", GitHubLogin: "ada"}, AdmissionOrganizationRequired},
+		{"team, no login", teams, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "team-token"}, AdmissionOrganizationRequired},
+		{"team, other login", teams, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "team-token", GitHubLogin: "grace"}, AdmissionOrganizationRequired},
+		{"invite only with organization, new member", inviteOnlyOrganizations, AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "member-token"}, AdmissionInvitationRequired},
+		{"invite only with organization, existing member", inviteOnlyOrganizations, AdmissionCandidate{Email: "ada@example.org", Existing: true, GitHubAccessToken: "member-token"}, ""},
+		{"invite only with organization, existing non-member", inviteOnlyOrganizations, AdmissionCandidate{Email: "ada@example.org", Existing: true, GitHubAccessToken: "other-token"}, AdmissionOrganizationRequired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := newAdmissionPolicy(repository, NewGitHubMembershipClient(github.URL, github.Client()), test.rules, nil)
+
+			err := policy.Admit(context.Background(), test.candidate)
+			if test.code == "" {
+				if err != nil {
+					t.Errorf("Admit = %v, want the candidate admitted", err)
+				}
+				return
+			}
+			var admissionErr *AdmissionError
+			if !errors.As(err, &admissionErr) || admissionErr.Code != test.code {
+				t.Errorf("Admit = %v, want %s", err, test.code)
+			}
+		})
+	}
+}
+
+func TestAdmissionPolicyReportsGitHubFailures(t *testing.T) {
+	github := newGitHubStandIn(t, map[string]string{
+		"broken-token /user/memberships/orgs/acme": "error",
+		"broken-token /user/emails":                "error",
+	})
+	rules := &AdmissionRules{GitHubOrganizations: []string{"acme"}}
+	policy := newAdmissionPolicy(newFakeAuthenticationRepository(), NewGitHubMembershipClient(github.URL, github.Client()), rules, nil)
+
+	err := policy.Admit(context.Background(), AdmissionCandidate{Email: "ada@example.org", GitHubAccessToken: "broken-token"})
+	var admissionErr *AdmissionError
+	if err == nil || errors.As(err, &admissionErr) {
+		t.Errorf("Admit = %v, want an error that is not an AdmissionError", err)
+	}
+}
+
+func TestProviderAdmissionCandidateEmailVerified(t *testing.T) {
+	providerTypes := map[string]string{"github": "github", "gitlab": "gitlab", "google": "google", "microsoft": "microsoft", "oidc": "oidc"}
+	policy := newAdmissionPolicy(newFakeAuthenticationRepository(), NewGitHubMembershipClient("http://github.invalid", nil), &AdmissionRules{}, providerTypes)
+
+	tests := []struct {
+		name     string
+		gothUser goth.User
+		verified bool
+	}{
+		{"google, verified", goth.User{Provider: "google", Email: "ada@acme.com", RawData: map[string]interface{}{"verified_email": true}}, true},
+		{"google, unverified", goth.User{Provider: "google", Email: "ada@acme.com", RawData: map[string]interface{}{"verified_email": false}}, false},
+		{"oidc, verified", goth.User{Provider: "oidc", Email: "ada@acme.com", RawData: map[string]interface{}{"email_verified": true}}, true},
+		{"oidc, verified as a string", goth.User{Provider: "oidc", Email: "ada@acme.com", RawData: map[string]interface{}{"email_verified": "true"}}, true},
+		{"oidc, unverified", goth.User{Provider: "oidc", Email: "ada@acme.com", RawData: map[string]interface{}{"email_verified": false}}, false},
+		{"oidc, no claim", goth.User{Provider: "oidc", Email: "ada@acme.com", RawData: map[string]interface{}{}}, false},
+		{"oidc, no email", goth.User{Provider: "oidc", RawData: map[string]interface{}{"email_verified": true}}, false},
+		{"microsoft", goth.User{Provider: "microsoft", Email: "ada@acme.com", RawData: map[string]interface{}{"email_verified": true}}, false},
+		{"gitlab", goth.User{Provider: "gitlab", Email: "ada@acme.com"}, false},
+		{"github, asked at admission", goth.User{Provider: "github", Email: "ada@acme.com", AccessToken: "token"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			candidate := policy.ProviderCandidate(test.gothUser, false)
+			if candidate.EmailVerified != test.verified {
+				t.Errorf("EmailVerified = %v, want %v", candidate.EmailVerified, test.verified)
+			}
+		})
+	}
+}
+
+// An account whose provider lets anyone type in an email address must not get
+// in through the domain or invitation rules with it.
+func TestAdmissionPolicyRejectsUnverifiedProviderEmail(t *testing.T) {
+	repository := newFakeAuthenticationRepository()
+	repository.invitations["invited@acme.com"] = true
+	gothUser := goth.User{Provider: "microsoft", Email: "invited@acme.com"}
+
+	tests := []struct {
+		name  string
+		rules *AdmissionRules
+		code  string
+	}{
+		{"email domain", &AdmissionRules{EmailDomains: []string{"acme.com"}}, AdmissionEmailNotVerified},
+		{"invitation", &AdmissionRules{InviteOnly: true}, AdmissionInvitationRequired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := newAdmissionPolicy(repository, NewGitHubMembershipClient("http://github.invalid", nil), test.rules, map[string]string{"microsoft": "microsoft"})
+			err := policy.Admit(context.Background(), policy.ProviderCandidate(gothUser, false))
+			var admissionErr *AdmissionError
+			if !errors.As(err, &admissionErr) || admissionErr.Code != test.code {
+				t.Errorf("Admit = %v, want %s", err, test.code)
+			}
+		})
+	}
+}
+
+func TestLoadAdmissionRules(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.AuthenticationConfig.Admission = config.AdmissionConfig{
+		GitHubTeams:  []string{"acme/platform"},
+		EmailDomains: []string{"@ACME.com", "example.org"},
+	}
+	rules, err := LoadAdmissionRules(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules.GitHubTeams) != 1 || rules.GitHubTeams[0] != (GitHubTeam{Organization: "acme", Slug: "platform"}) {
+		t.Errorf("GitHubTeams = %+v, want acme/platform", rules.GitHubTeams)
+	}
+	if strings.Join(rules.EmailDomains, ",") != "acme.com,example.org" {
+		t.Errorf("EmailDomains = %v, want acme.com and example.org", rules.EmailDomains)
+	}
+
+	for _, team := range []string{"acme", "acme/", "/platform"} {
+		cfg.AuthenticationConfig.Admission.GitHubTeams = []string{team}
+		if _, err := LoadAdmissionRules(cfg); err == nil {
+			t.Errorf("LoadAdmissionRules accepted team %q", team)
+		}
+	}
+}
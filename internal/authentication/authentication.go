@@ -1,35 +1,83 @@
 package authentication
 
 import (
-	"net/http"
-
 	"catalyst.api/config"
 
-	"github.com/gorilla/sessions"
-	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
-	"github.com/markbates/goth/providers/github"
 )
 
 const (
-	// TODO change this key
-	key          = "randomString"
-	MaxAge       = 86400 * 30
-	IsProduction = false
+	MaxAge = 86400 * 30
 )
 
-func NewAuthentication(cfg *config.Config) {
-	store := sessions.NewCookieStore([]byte(key))
-	store.MaxAge(MaxAge)
+// Authentication holds what the authentication handlers and middleware share.
+// It is loaded from config once by NewAuthentication and handed to
+// RegisterRoutes and the middleware.
+type Authentication struct {
+	KeySet *KeySet
+	// ProviderTokens is shared by everything that calls a provider as the
+	// user.
+	ProviderTokens *ProviderTokenSource
+	AdmissionRules *AdmissionRules
+	// SAMLKeyPair is nil when no service provider key is configured.
+	SAMLKeyPair *SAMLKeyPair
+	Cookies     *Cookies
+	// EnabledProviders are the configured login providers, in config order.
+	EnabledProviders []EnabledProvider
+	// ProviderTypes maps each configured provider name to its type.
+	ProviderTypes map[string]string
+	// PublicURL is the API's own base URL, used for links the API hands out.
+	PublicURL string
+	// FrontendURL is the web app's base URL, where sign-in redirects and
+	// emailed links point.
+	FrontendURL string
+}
 
-	store.Options.Path = "/"
-	store.Options.HttpOnly = true
-	store.Options.Secure = IsProduction
-	store.Options.SameSite = http.SameSiteLaxMode
+// NewAuthentication loads the keys and rules the authentication handlers use.
+// It also configures gothic's session store and the goth providers, which goth
+// keeps globally.
+func NewAuthentication(cfg *config.Config, authenticationRepo AuthenticationRepository) (*Authentication, error) {
+	keySet, err := LoadKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	providerTokenKeyring, err := LoadProviderTokenKeyring(cfg)
+	if err != nil {
+		return nil, err
+	}
+	admissionRules, err := LoadAdmissionRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	samlKeyPair, err := LoadSAMLKeyPair(cfg)
+	if err != nil {
+		return nil, err
+	}
 
+	sessionKeyPairs, err := LoadSessionKeyPairs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	store, err := newGothicSessionStore(cfg, authenticationRepo, sessionKeyPairs)
+	if err != nil {
+		return nil, err
+	}
 	gothic.Store = store
 
-	goth.UseProviders(
-		github.New(cfg.AuthenticationConfig.GithubClientID, cfg.AuthenticationConfig.GithubClientSecret, "http://localhost:42069/auth/github/callback", "user", "repo"),
-	)
+	enabledProviders, providerTypes, err := registerProviders(cfg.AuthenticationConfig.Providers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authentication{
+		KeySet:           keySet,
+		ProviderTokens:   NewProviderTokenSource(authenticationRepo, providerTokenKeyring),
+		AdmissionRules:   admissionRules,
+		SAMLKeyPair:      samlKeyPair,
+		Cookies:          NewCookies(keySet, cfg.HttpConfig.IsProduction),
+		EnabledProviders: enabledProviders,
+		ProviderTypes:    providerTypes,
+		PublicURL:        cfg.HttpConfig.PublicURL,
+		FrontendURL:      cfg.HttpConfig.FrontendURL,
+	}, nil
 }
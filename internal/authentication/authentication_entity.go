@@ -1,6 +1,7 @@
 package authentication
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,14 +13,39 @@ type AuthUser struct {
 	FirstName    string
 	LastName     string
 	MobileNumber *string
+	Roles        []string
+	// DeactivatedAt is set while the account is deactivated, which blocks
+	// sign-in and every token the user holds without deleting anything.
+	DeactivatedAt *time.Time
+	// DeletionScheduledAt is set while the account waits to be erased. It is
+	// locked like a deactivated account until then.
+	DeletionScheduledAt *time.Time
 }
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 var AnonymousUser = &AuthUser{}
 
 func (usr *AuthUser) IsAnonymous() bool {
 	return usr == AnonymousUser
 }
 
+func (usr *AuthUser) HasRole(role string) bool {
+	return slices.Contains(usr.Roles, role)
+}
+
+func (usr *AuthUser) IsAdmin() bool {
+	return usr.HasRole(RoleAdmin)
+}
+
+// IsDeactivated also holds for an account pending deletion.
+func (usr *AuthUser) IsDeactivated() bool {
+	return usr.DeactivatedAt != nil || usr.DeletionScheduledAt != nil
+}
+
 func Create(email string, firstName string, lastName string) (*AuthUser, error) {
 	user := AuthUser{
 		Email:     email,
@@ -1,6 +1,7 @@
 package authentication
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -10,11 +11,19 @@ import (
 
 type AuthenticationMiddleware struct {
 	AuthenticationRepository AuthenticationRepository
+	RevocationStore          RevocationStore
+	KeySet                   *KeySet
 }
 
 type contextKey string
 
-const AuthUserContextKey = contextKey("authUser")
+const (
+	AuthUserContextKey            = contextKey("authUser")
+	AccessTokenClaimsContextKey   = contextKey("accessTokenClaims")
+	ActorContextKey               = contextKey("actor")
+	SCIMTokenContextKey           = contextKey("scimToken")
+	PersonalAccessTokenContextKey = contextKey("personalAccessToken")
+)
 
 func SetAuthUser(context *gin.Context, authUser *AuthUser) {
 	context.Set(string(AuthUserContextKey), authUser)
@@ -34,6 +43,77 @@ func GetAuthUser(context *gin.Context) *AuthUser {
 	return authUser
 }
 
+func SetAccessTokenClaims(context *gin.Context, claims *AccessTokenClaims) {
+	context.Set(string(AccessTokenClaimsContextKey), claims)
+}
+
+// GetAccessTokenClaims returns the verified claims of the request's bearer token,
+// or nil for anonymous requests.
+func GetAccessTokenClaims(context *gin.Context) *AccessTokenClaims {
+	value, exists := context.Get(string(AccessTokenClaimsContextKey))
+	if !exists {
+		return nil
+	}
+	claims, ok := value.(*AccessTokenClaims)
+	if !ok {
+		panic("invalid access token claims type in context")
+	}
+	return claims
+}
+
+func SetActor(context *gin.Context, actor *AuthUser) {
+	context.Set(string(ActorContextKey), actor)
+}
+
+// GetActor returns the admin acting as the request's auth user, or nil unless
+// the request is made with an impersonation token.
+func GetActor(context *gin.Context) *AuthUser {
+	value, exists := context.Get(string(ActorContextKey))
+	if !exists {
+		return nil
+	}
+	actor, ok := value.(*AuthUser)
+	if !ok {
+		panic("invalid actor type in context")
+	}
+	return actor
+}
+
+func SetPersonalAccessToken(context *gin.Context, personalAccessToken *PersonalAccessToken) {
+	context.Set(string(PersonalAccessTokenContextKey), personalAccessToken)
+}
+
+// GetPersonalAccessToken returns the personal access token the request was
+// made with, or nil.
+func GetPersonalAccessToken(context *gin.Context) *PersonalAccessToken {
+	value, exists := context.Get(string(PersonalAccessTokenContextKey))
+	if !exists {
+		return nil
+	}
+	personalAccessToken, ok := value.(*PersonalAccessToken)
+	if !ok {
+		panic("invalid personal access token type in context")
+	}
+	return personalAccessToken
+}
+
+func SetSCIMToken(context *gin.Context, scimToken *SCIMToken) {
+	context.Set(string(SCIMTokenContextKey), scimToken)
+}
+
+// GetSCIMToken returns the SCIM token the request was made with, or nil.
+func GetSCIMToken(context *gin.Context) *SCIMToken {
+	value, exists := context.Get(string(SCIMTokenContextKey))
+	if !exists {
+		return nil
+	}
+	scimToken, ok := value.(*SCIMToken)
+	if !ok {
+		panic("invalid SCIM token type in context")
+	}
+	return scimToken
+}
+
 func (authenticationMiddleware *AuthenticationMiddleware) Authenticate() gin.HandlerFunc {
 	return func(context *gin.Context) {
 		context.Header("Vary", "Authorization")
@@ -52,28 +132,165 @@ func (authenticationMiddleware *AuthenticationMiddleware) Authenticate() gin.Han
 		}
 
 		tokenString := headerParts[1]
-		claims, err := VerifyJWTToken(tokenString)
+		if IsPersonalAccessToken(tokenString) {
+			authenticationMiddleware.authenticatePersonalAccessToken(context, tokenString)
+			return
+		}
+		if IsSCIMToken(tokenString) {
+			authenticationMiddleware.authenticateSCIMToken(context, tokenString)
+			return
+		}
+
+		claims, err := authenticationMiddleware.KeySet.VerifyJWTToken(tokenString)
 		if err != nil {
 			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
 
-		authUserID, err := uuid.Parse(claims["sub"].(string))
+		revoked, err := authenticationMiddleware.RevocationStore.IsRevoked(context.Request.Context(), claims)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		if revoked {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+			return
+		}
+
+		// mfa tokens do not sign the user in; they only reach RequireMFAChallenge routes
+		if claims.IsMFAChallenge() {
+			SetAuthUser(context, AnonymousUser)
+			SetAccessTokenClaims(context, claims)
+			context.Next()
+			return
+		}
+
+		authUserID, err := claims.UserID()
 		if err != nil {
 			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
 			return
 		}
 
 		authUser, err := authenticationMiddleware.AuthenticationRepository.FindAuthUserByID(context.Request.Context(), authUserID)
-		if err != nil {
+		if err != nil || authUser == nil {
 			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unable to find auth user"})
 			return
 		}
+		if authUser.IsDeactivated() {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrAccountDeactivated.Error()})
+			return
+		}
+		if claims.IsImpersonation() {
+			authenticationMiddleware.authenticateImpersonation(context, claims, authUser)
+			return
+		}
+		// last seen is informational, so a failed write does not fail the request
+		if sessionID := claims.Session(); sessionID != uuid.Nil {
+			_ = authenticationMiddleware.AuthenticationRepository.TouchSession(context.Request.Context(), sessionID)
+		}
+
 		SetAuthUser(context, authUser)
+		SetAccessTokenClaims(context, claims)
 		context.Next()
 	}
 }
 
+func (authenticationMiddleware *AuthenticationMiddleware) authenticatePersonalAccessToken(context *gin.Context, tokenString string) {
+	personalAccessToken, err := authenticationMiddleware.AuthenticationRepository.FindPersonalAccessTokenByHash(context.Request.Context(), HashPersonalAccessToken(tokenString))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if personalAccessToken == nil || personalAccessToken.IsRevoked() || personalAccessToken.IsExpired() {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	authUser, err := authenticationMiddleware.AuthenticationRepository.FindAuthUserByID(context.Request.Context(), personalAccessToken.UserID)
+	if err != nil || authUser == nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unable to find auth user"})
+		return
+	}
+	if authUser.IsDeactivated() {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrAccountDeactivated.Error()})
+		return
+	}
+
+	// last used is informational, so a failed write does not fail the request
+	_ = authenticationMiddleware.AuthenticationRepository.TouchPersonalAccessToken(context.Request.Context(), personalAccessToken.ID)
+
+	SetAuthUser(context, authUser)
+	SetAccessTokenClaims(context, personalAccessToken.Claims(authUser))
+	SetPersonalAccessToken(context, personalAccessToken)
+	context.Next()
+}
+
+// authenticateSCIMToken lets an identity provider's SCIM client through as
+// an anonymous user. The token only opens the RequireSCIMToken routes.
+func (authenticationMiddleware *AuthenticationMiddleware) authenticateSCIMToken(context *gin.Context, tokenString string) {
+	scimToken, err := authenticationMiddleware.AuthenticationRepository.FindSCIMTokenByHash(context.Request.Context(), HashSCIMToken(tokenString))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if scimToken == nil || scimToken.IsRevoked() {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	// last used is informational, so a failed write does not fail the request
+	_ = authenticationMiddleware.AuthenticationRepository.TouchSCIMToken(context.Request.Context(), scimToken.ID)
+
+	SetAuthUser(context, AnonymousUser)
+	SetSCIMToken(context, scimToken)
+	context.Next()
+}
+
+// authenticateImpersonation signs the request in as the impersonated user while
+// the admin behind the token still is one, and records it in the
+// impersonation's audit log. Nothing is done as the user without a record.
+func (authenticationMiddleware *AuthenticationMiddleware) authenticateImpersonation(context *gin.Context, claims *AccessTokenClaims, authUser *AuthUser) {
+	actorID, err := claims.ActorID()
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid actor in token"})
+		return
+	}
+	impersonationID, err := uuid.Parse(claims.ID)
+	if err != nil || authUser == nil {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	actor, err := authenticationMiddleware.AuthenticationRepository.FindAuthUserByID(context.Request.Context(), actorID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if actor == nil || !actor.IsAdmin() {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "impersonation is no longer allowed"})
+		return
+	}
+
+	request := &ImpersonationRequest{
+		ImpersonationID: impersonationID,
+		Method:          context.Request.Method,
+		Path:            truncateRunes(context.Request.URL.RequestURI(), maxImpersonationPathLength),
+		IPAddress:       context.ClientIP(),
+	}
+	requestID, err := authenticationMiddleware.AuthenticationRepository.CreateImpersonationRequest(context.Request.Context(), request)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	SetAuthUser(context, authUser)
+	SetActor(context, actor)
+	SetAccessTokenClaims(context, claims)
+	context.Next()
+
+	_ = authenticationMiddleware.AuthenticationRepository.RecordImpersonationResponse(context.Request.Context(), requestID, context.Writer.Status())
+}
+
 func (authenticationMiddleware *AuthenticationMiddleware) RequireAuthUser() gin.HandlerFunc {
 	return func(context *gin.Context) {
 		user := GetAuthUser(context)
@@ -87,3 +304,85 @@ func (authenticationMiddleware *AuthenticationMiddleware) RequireAuthUser() gin.
 		context.Next()
 	}
 }
+
+// RequireMFAChallenge only lets through the short-lived token handed out while
+// a sign-in waits for its second factor.
+func (authenticationMiddleware *AuthenticationMiddleware) RequireMFAChallenge() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		claims := GetAccessTokenClaims(context)
+
+		if claims == nil || !claims.IsMFAChallenge() {
+			context.JSON(http.StatusUnauthorized, gin.H{"error": "an mfa token is required to access this route"})
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
+
+// RequireScopes rejects requests whose access token does not carry every one of scopes.
+func (authenticationMiddleware *AuthenticationMiddleware) RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		claims := GetAccessTokenClaims(context)
+
+		if claims == nil {
+			context.JSON(http.StatusUnauthorized, gin.H{"error": "you must be logged in to access this route"})
+			context.Abort()
+			return
+		}
+
+		if !claims.HasScopes(scopes...) {
+			context.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, JoinScopes(scopes)))
+			context.JSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
+
+// RequireSCIMToken only lets through requests made with a SCIM token.
+func (authenticationMiddleware *AuthenticationMiddleware) RequireSCIMToken() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if GetSCIMToken(context) == nil {
+			context.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			context.JSON(http.StatusUnauthorized, gin.H{"error": "a SCIM token is required to access this route"})
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
+
+// RejectImpersonation keeps admins acting as another user away from routes
+// that cannot be undone or that change how the user signs in.
+func (authenticationMiddleware *AuthenticationMiddleware) RejectImpersonation() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		claims := GetAccessTokenClaims(context)
+
+		if claims != nil && claims.IsImpersonation() {
+			context.JSON(http.StatusForbidden, gin.H{"error": "this route is not available while impersonating"})
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
+
+// RejectPersonalAccessTokens keeps scripts away from routes that hand out a
+// signed-in session, which a personal access token must never turn into.
+func (authenticationMiddleware *AuthenticationMiddleware) RejectPersonalAccessTokens() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if GetPersonalAccessToken(context) != nil {
+			context.JSON(http.StatusForbidden, gin.H{"error": "this route is not available to personal access tokens"})
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
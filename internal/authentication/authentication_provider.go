@@ -6,35 +6,73 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/markbates/goth/gothic"
 )
 
 type ProviderHandler struct {
-	logger *log.Logger
+	cookies     *Cookies
+	providers   []EnabledProvider
+	frontendURL string
+	logger      *log.Logger
 }
 
-func NewProviderHandler(logger *log.Logger) *ProviderHandler {
+func NewProviderHandler(cookies *Cookies, providers []EnabledProvider, frontendURL string, logger *log.Logger) *ProviderHandler {
 	return &ProviderHandler{
-		logger: logger,
+		cookies:     cookies,
+		providers:   providers,
+		frontendURL: frontendURL,
+		logger:      logger,
 	}
 }
 
 // @Summary Start OAuth login
-// @Description Begins the OAuth flow with the selected provider or redirects if already authenticated.
+// @Description Begins the OAuth flow with the selected provider or redirects if already authenticated. To sign in, the front-end sends a PKCE S256 code challenge; the callback then redirects back with a one-time code to exchange at /auth/token.
 // @Tags auth
 // @Param provider path string true "OAuth Provider"
+// @Param code_challenge query string false "PKCE code challenge, required to sign in"
+// @Param code_challenge_method query string false "Must be S256"
 // @Produce json
 // @Success 302 {string} string "Redirect to provider login page or front-end"
+// @Failure 400 {object} map[string]string "Invalid code challenge"
+// @Failure 500 {object} map[string]string "Internal server error"
 // @Router /auth/{provider} [get]
 func (handler ProviderHandler) GetProvider(ctx *gin.Context) {
 	provider := ctx.Param("provider")
 
+	codeChallenge := ctx.Query("code_challenge")
+	if codeChallenge != "" {
+		if !ValidCodeChallenge(codeChallenge, ctx.Query("code_challenge_method")) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code challenge, expected S256"})
+			return
+		}
+
+		intent := NewSignInIntent(SignInIntentSignIn, provider, uuid.Nil)
+		intent.CodeChallenge = codeChallenge
+		err := handler.cookies.setSignInIntent(ctx, intent)
+		if err != nil {
+			handler.logger.Printf("ERROR: setSignInIntent: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+
 	newCtx := context.WithValue(ctx.Request.Context(), "provider", provider)
 	ctx.Request = ctx.Request.WithContext(newCtx)
 	_, err := gothic.CompleteUserAuth(ctx.Writer, ctx.Request)
 	if err != nil {
 		gothic.BeginAuthHandler(ctx.Writer, ctx.Request)
 	} else {
-		ctx.Redirect(http.StatusTemporaryRedirect, "http://localhost:4200")
+		ctx.Redirect(http.StatusTemporaryRedirect, handler.frontendURL)
 	}
 }
+
+// @Summary List login providers
+// @Description Lists the login providers enabled in config so the front-end can show a button for each.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]EnabledProvider "Enabled providers"
+// @Router /auth/providers [get]
+func (handler ProviderHandler) ListProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"providers": handler.providers})
+}
@@ -3,20 +3,119 @@ package authentication
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"catalyst.api/internal/authentication/data"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/markbates/goth"
 )
 
 type AuthenticationRepository interface {
-	FindUserIDByProvider(ctx context.Context, providerUserID string) (uuid.UUID, error)
+	FindUserIDByProvider(ctx context.Context, provider string, providerUserID string) (uuid.UUID, error)
 	FindAuthUserByID(ctx context.Context, id uuid.UUID) (*AuthUser, error)
 	RegisterAuthUser(ctx context.Context, gothUser goth.User) (uuid.UUID, error)
+	ListAuthProviders(ctx context.Context, userID uuid.UUID) ([]*AuthProvider, error)
+	LinkAuthProvider(ctx context.Context, userID uuid.UUID, gothUser goth.User) (uuid.UUID, error)
+	UnlinkAuthProvider(ctx context.Context, userID uuid.UUID, authProviderID uuid.UUID) error
+	CreateRefreshToken(ctx context.Context, refreshToken *RefreshToken) (uuid.UUID, error)
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, current *RefreshToken, next *RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error
+	RevokeToken(ctx context.Context, jti uuid.UUID, userID uuid.UUID, expiresAt time.Time) error
+	FindTokenRevocationState(ctx context.Context, jti uuid.UUID, sessionID uuid.UUID, userID uuid.UUID) (*TokenRevocationState, error)
+	RevokeAllTokensForUser(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	RegisterPasswordAuthUser(ctx context.Context, authUser *AuthUser, passwordHash string) (uuid.UUID, error)
+	FindPasswordCredentials(ctx context.Context, email string) (*PasswordCredentials, error)
+	FindPasswordHash(ctx context.Context, userID uuid.UUID) (string, error)
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	CreatePasswordResetToken(ctx context.Context, resetToken *PasswordResetToken) (uuid.UUID, error)
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (uuid.UUID, error)
+	CreatePersonalAccessToken(ctx context.Context, personalAccessToken *PersonalAccessToken) (uuid.UUID, error)
+	FindPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (*PersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]*PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, userID uuid.UUID, personalAccessTokenID uuid.UUID) error
+	RevokePersonalAccessTokensForUser(ctx context.Context, userID uuid.UUID) error
+	TouchPersonalAccessToken(ctx context.Context, personalAccessTokenID uuid.UUID) error
+	CreateDeviceAuthorization(ctx context.Context, deviceAuthorization *DeviceAuthorization) (uuid.UUID, error)
+	FindDeviceAuthorizationByDeviceCode(ctx context.Context, deviceCodeHash string) (*DeviceAuthorization, error)
+	FindDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*DeviceAuthorization, error)
+	RecordDeviceAuthorizationPoll(ctx context.Context, deviceAuthorizationID uuid.UUID, interval time.Duration) error
+	ApproveDeviceAuthorization(ctx context.Context, deviceAuthorizationID uuid.UUID, userID uuid.UUID) error
+	ConsumeDeviceAuthorization(ctx context.Context, deviceAuthorizationID uuid.UUID) (uuid.UUID, error)
+	CreateAuthorizationCode(ctx context.Context, authorizationCode *AuthorizationCode) (uuid.UUID, error)
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*AuthorizationCode, error)
+	CreateSession(ctx context.Context, session *Session) (uuid.UUID, error)
+	FindActiveSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (*Session, error)
+	ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error)
+	RevokeSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keepSessionID uuid.UUID) error
+	RevokeSessionsForUser(ctx context.Context, userID uuid.UUID) error
+	TouchSession(ctx context.Context, sessionID uuid.UUID) error
+	StartTOTPEnrolment(ctx context.Context, userID uuid.UUID, secret string) error
+	FindTOTPCredential(ctx context.Context, userID uuid.UUID) (*TOTPCredential, error)
+	ConfirmTOTPCredential(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+	CreateMFAChallenge(ctx context.Context, challenge *MFAChallenge) error
+	FindMFAChallenge(ctx context.Context, sessionID uuid.UUID) (*MFAChallenge, error)
+	RecordFailedMFAAttempt(ctx context.Context, sessionID uuid.UUID) (int, error)
+	CompleteMFAChallenge(ctx context.Context, sessionID uuid.UUID) error
+	FindAuthUserProviderID(ctx context.Context, provider string, providerUserID string) (uuid.UUID, error)
+	FindProviderToken(ctx context.Context, authUserProviderID uuid.UUID) (*EncryptedProviderToken, error)
+	FindProviderTokenForUser(ctx context.Context, userID uuid.UUID, provider string) (*EncryptedProviderToken, error)
+	SaveProviderToken(ctx context.Context, providerToken *EncryptedProviderToken) error
+	UpdateProviderToken(ctx context.Context, authUserProviderID uuid.UUID, update func(*EncryptedProviderToken) (*EncryptedProviderToken, error)) error
+	FindGothicSession(ctx context.Context, id string) (string, error)
+	SaveGothicSession(ctx context.Context, id string, encodedValues string, expiresAt time.Time) error
+	DeleteGothicSession(ctx context.Context, id string) error
+	DeleteExpiredTokens(ctx context.Context) (map[string]int64, error)
+	CreateInvitation(ctx context.Context, invitation *Invitation) (uuid.UUID, error)
+	ListInvitations(ctx context.Context) ([]*Invitation, error)
+	RevokeInvitation(ctx context.Context, invitationID uuid.UUID) error
+	HasInvitation(ctx context.Context, email string) (bool, error)
+	AcceptInvitation(ctx context.Context, email string) error
+	CreateMagicLink(ctx context.Context, magicLink *MagicLink) (uuid.UUID, error)
+	ConsumeMagicLink(ctx context.Context, magicLinkID uuid.UUID) (string, error)
+	RegisterEmailAuthUser(ctx context.Context, authUser *AuthUser) (uuid.UUID, error)
+	CreateImpersonation(ctx context.Context, impersonation *Impersonation) (uuid.UUID, error)
+	FindImpersonation(ctx context.Context, impersonationID uuid.UUID) (*Impersonation, error)
+	ListImpersonations(ctx context.Context, limit int) ([]*Impersonation, error)
+	EndImpersonation(ctx context.Context, impersonationID uuid.UUID) (*Impersonation, error)
+	CreateImpersonationRequest(ctx context.Context, request *ImpersonationRequest) (uuid.UUID, error)
+	RecordImpersonationResponse(ctx context.Context, requestID uuid.UUID, statusCode int) error
+	ListImpersonationRequests(ctx context.Context, impersonationID uuid.UUID) ([]*ImpersonationRequest, error)
+	CreateSAMLConnection(ctx context.Context, connection *SAMLConnection) (uuid.UUID, error)
+	FindSAMLConnection(ctx context.Context, connectionID uuid.UUID) (*SAMLConnection, error)
+	FindSAMLConnectionByWorkspace(ctx context.Context, workspace string) (*SAMLConnection, error)
+	ListSAMLConnections(ctx context.Context) ([]*SAMLConnection, error)
+	UpdateSAMLConnection(ctx context.Context, connection *SAMLConnection) error
+	DeleteSAMLConnection(ctx context.Context, connectionID uuid.UUID) error
+	CreateSAMLRequest(ctx context.Context, request *SAMLRequest) (uuid.UUID, error)
+	ConsumeSAMLRequest(ctx context.Context, requestID uuid.UUID, connectionID uuid.UUID) (*SAMLRequest, error)
+	FindUnclaimedProvisionedUserID(ctx context.Context, email string) (uuid.UUID, error)
+	CreateSCIMToken(ctx context.Context, scimToken *SCIMToken) (uuid.UUID, error)
+	FindSCIMTokenByHash(ctx context.Context, tokenHash string) (*SCIMToken, error)
+	ListSCIMTokens(ctx context.Context) ([]*SCIMToken, error)
+	RevokeSCIMToken(ctx context.Context, scimTokenID uuid.UUID) error
+	TouchSCIMToken(ctx context.Context, scimTokenID uuid.UUID) error
 }
 
+// uniqueViolationCode is the Postgres SQLSTATE for a unique constraint violation.
+const uniqueViolationCode = "23505"
+
+// providerIdentityConstraint keeps a provider identity on one account.
+const providerIdentityConstraint = "auth_user_providers_provider_identity_key"
+
 type AuthenticationSqlRepository struct {
 	queries *data.Queries
 	db      *pgxpool.Pool
@@ -30,10 +129,15 @@ func NewAuthenticationSqlRepository(pool *pgxpool.Pool) *AuthenticationSqlReposi
 	}
 }
 
-func (repository *AuthenticationSqlRepository) FindUserIDByProvider(ctx context.Context, providerUserID string) (uuid.UUID, error) {
-	id, err := repository.queries.FindUserIDByProvider(ctx, providerUserID)
+// FindUserIDByProvider returns uuid.Nil when no user has signed in with this identity.
+func (repository *AuthenticationSqlRepository) FindUserIDByProvider(ctx context.Context, provider string, providerUserID string) (uuid.UUID, error) {
+	providerParams := data.FindUserIDByProviderParams{
+		Provider:       provider,
+		ProviderUserID: providerUserID,
+	}
+	id, err := repository.queries.FindUserIDByProvider(ctx, providerParams)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return uuid.Nil, nil
 	}
 	if err != nil {
@@ -45,24 +149,34 @@ func (repository *AuthenticationSqlRepository) FindUserIDByProvider(ctx context.
 func (repository *AuthenticationSqlRepository) FindAuthUserByID(ctx context.Context, id uuid.UUID) (*AuthUser, error) {
 	authUserRow, err := repository.queries.FindAuthUserByID(ctx, id)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	roles, err := repository.queries.ListRolesByUserID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	authUser := &AuthUser{
-		ID:           authUserRow.ID,
-		Email:        authUserRow.Email,
-		FirstName:    authUserRow.FirstName,
-		LastName:     authUserRow.LastName,
-		MobileNumber: authUserRow.MobileNumber,
+		ID:                  authUserRow.ID,
+		Email:               authUserRow.Email,
+		FirstName:           authUserRow.FirstName,
+		LastName:            authUserRow.LastName,
+		MobileNumber:        authUserRow.MobileNumber,
+		Roles:               roles,
+		DeactivatedAt:       timePointer(authUserRow.DeactivatedAt),
+		DeletionScheduledAt: timePointer(authUserRow.DeletionScheduledAt),
 	}
 
 	return authUser, nil
 }
 
+// RegisterAuthUser returns ErrEmailTaken if another account already uses the
+// provider's email, whichever way that account signs in.
 func (repository *AuthenticationSqlRepository) RegisterAuthUser(ctx context.Context, gothUser goth.User) (uuid.UUID, error) {
 	authUserParams := data.CreateAuthUserParams{
 		Email:          gothUser.Email,
@@ -73,9 +187,1445 @@ func (repository *AuthenticationSqlRepository) RegisterAuthUser(ctx context.Cont
 	}
 
 	registeredID, err := repository.queries.CreateAuthUser(ctx, authUserParams)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName != providerIdentityConstraint {
+		return uuid.Nil, ErrEmailTaken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return registeredID, nil
+}
+
+func (repository *AuthenticationSqlRepository) ListAuthProviders(ctx context.Context, userID uuid.UUID) ([]*AuthProvider, error) {
+	providerRows, err := repository.queries.ListAuthUserProvidersByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	authProviders := make([]*AuthProvider, 0, len(providerRows))
+	for _, providerRow := range providerRows {
+		authProviders = append(authProviders, &AuthProvider{
+			ID:             providerRow.ID,
+			UserID:         providerRow.UserID,
+			Provider:       providerRow.Provider,
+			ProviderUserID: providerRow.ProviderUserID,
+			CreatedAt:      providerRow.CreatedAt.Time,
+		})
+	}
+	return authProviders, nil
+}
+
+func (repository *AuthenticationSqlRepository) LinkAuthProvider(ctx context.Context, userID uuid.UUID, gothUser goth.User) (uuid.UUID, error) {
+	providerParams := data.CreateAuthUserProviderParams{
+		UserID:         userID,
+		Provider:       gothUser.Provider,
+		ProviderUserID: gothUser.UserID,
+	}
+	return repository.queries.CreateAuthUserProvider(ctx, providerParams)
+}
+
+// UnlinkAuthProvider refuses to remove the user's last identity and returns
+// ErrLastSignInMethod instead.
+func (repository *AuthenticationSqlRepository) UnlinkAuthProvider(ctx context.Context, userID uuid.UUID, authProviderID uuid.UUID) error {
+	unlinkParams := data.DeleteAuthUserProviderParams{
+		ID:     authProviderID,
+		UserID: userID,
+	}
+	result, err := repository.queries.DeleteAuthUserProvider(ctx, unlinkParams)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrLastSignInMethod
+	}
+	return nil
+}
+
+func (repository *AuthenticationSqlRepository) CreateRefreshToken(ctx context.Context, refreshToken *RefreshToken) (uuid.UUID, error) {
+	return repository.queries.CreateRefreshToken(ctx, createRefreshTokenParams(refreshToken))
+}
+
+func (repository *AuthenticationSqlRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	refreshTokenRow, err := repository.queries.FindRefreshTokenByHash(ctx, tokenHash)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := &RefreshToken{
+		ID:        refreshTokenRow.ID,
+		UserID:    refreshTokenRow.UserID,
+		FamilyID:  refreshTokenRow.FamilyID,
+		SessionID: uuid.UUID(refreshTokenRow.SessionID.Bytes),
+		TokenHash: refreshTokenRow.TokenHash,
+		ExpiresAt: refreshTokenRow.ExpiresAt.Time,
+		UsedAt:    timePointer(refreshTokenRow.UsedAt),
+		RevokedAt: timePointer(refreshTokenRow.RevokedAt),
+		CreatedAt: refreshTokenRow.CreatedAt.Time,
+	}
+
+	return refreshToken, nil
+}
+
+// RotateRefreshToken marks current as used and stores next in a single
+// transaction. ErrRefreshTokenReused is returned if current was already spent,
+// which covers two clients racing with the same token.
+func (repository *AuthenticationSqlRepository) RotateRefreshToken(ctx context.Context, current *RefreshToken, next *RefreshToken) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := repository.queries.WithTx(tx)
+	result, err := queries.MarkRefreshTokenUsed(ctx, current.ID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	next.ID, err = queries.CreateRefreshToken(ctx, createRefreshTokenParams(next))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (repository *AuthenticationSqlRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := repository.queries.RevokeRefreshTokenFamily(ctx, familyID)
+	return err
+}
+
+func (repository *AuthenticationSqlRepository) RevokeRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := repository.queries.RevokeRefreshTokensForUser(ctx, userID)
+	return err
+}
+
+func (repository *AuthenticationSqlRepository) RevokeToken(ctx context.Context, jti uuid.UUID, userID uuid.UUID, expiresAt time.Time) error {
+	revokeTokenParams := data.RevokeTokenParams{
+		Jti:       jti,
+		UserID:    userID,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}
+	return repository.queries.RevokeToken(ctx, revokeTokenParams)
+}
+
+func (repository *AuthenticationSqlRepository) FindTokenRevocationState(ctx context.Context, jti uuid.UUID, sessionID uuid.UUID, userID uuid.UUID) (*TokenRevocationState, error) {
+	stateParams := data.FindTokenRevocationStateParams{
+		Jti:       jti,
+		SessionID: sessionID,
+		UserID:    userID,
+	}
+	stateRow, err := repository.queries.FindTokenRevocationState(ctx, stateParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &TokenRevocationState{UserDeleted: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &TokenRevocationState{
+		TokenRevoked:    stateRow.TokenRevoked,
+		SessionRevoked:  stateRow.SessionRevoked,
+		TokensRevokedAt: timePointer(stateRow.TokensRevokedAt),
+	}
+	return state, nil
+}
+
+func (repository *AuthenticationSqlRepository) RevokeAllTokensForUser(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	tokensRevokedAt, err := repository.queries.RevokeAllTokensForUser(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tokensRevokedAt.Time, nil
+}
+
+// RegisterPasswordAuthUser returns ErrEmailTaken if the email is already in use,
+// whichever way that account signs in.
+func (repository *AuthenticationSqlRepository) RegisterPasswordAuthUser(ctx context.Context, authUser *AuthUser, passwordHash string) (uuid.UUID, error) {
+	authUserParams := data.CreatePasswordAuthUserParams{
+		Email:        authUser.Email,
+		FirstName:    authUser.FirstName,
+		LastName:     authUser.LastName,
+		PasswordHash: &passwordHash,
+	}
+
+	registeredID, err := repository.queries.CreatePasswordAuthUser(ctx, authUserParams)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return uuid.Nil, ErrEmailTaken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return registeredID, nil
+}
+
+// RegisterEmailAuthUser creates an account with no password or provider, for
+// users who sign in with a magic link.
+func (repository *AuthenticationSqlRepository) RegisterEmailAuthUser(ctx context.Context, authUser *AuthUser) (uuid.UUID, error) {
+	authUserParams := data.CreatePasswordAuthUserParams{
+		Email:     authUser.Email,
+		FirstName: authUser.FirstName,
+		LastName:  authUser.LastName,
+	}
+
+	registeredID, err := repository.queries.CreatePasswordAuthUser(ctx, authUserParams)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return uuid.Nil, ErrEmailTaken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return registeredID, nil
+}
+
+// FindPasswordCredentials matches the email case-insensitively and returns nil
+// when no account uses it.
+func (repository *AuthenticationSqlRepository) FindPasswordCredentials(ctx context.Context, email string) (*PasswordCredentials, error) {
+	credentialsRow, err := repository.queries.FindPasswordCredentialsByEmail(ctx, email)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := &PasswordCredentials{
+		UserID: credentialsRow.ID,
+	}
+	if credentialsRow.PasswordHash != nil {
+		credentials.PasswordHash = *credentialsRow.PasswordHash
+	}
+	return credentials, nil
+}
+
+// FindPasswordHash returns an empty string when the user has no password set.
+func (repository *AuthenticationSqlRepository) FindPasswordHash(ctx context.Context, userID uuid.UUID) (string, error) {
+	passwordHash, err := repository.queries.FindPasswordHashByUserID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if passwordHash == nil {
+		return "", nil
+	}
+	return *passwordHash, nil
+}
+
+func (repository *AuthenticationSqlRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	updateParams := data.UpdatePasswordHashParams{
+		PasswordHash: &passwordHash,
+		ID:           userID,
+	}
+	_, err := repository.queries.UpdatePasswordHash(ctx, updateParams)
+	return err
+}
+
+func (repository *AuthenticationSqlRepository) CreatePasswordResetToken(ctx context.Context, resetToken *PasswordResetToken) (uuid.UUID, error) {
+	resetTokenParams := data.CreatePasswordResetTokenParams{
+		UserID:    resetToken.UserID,
+		TokenHash: resetToken.TokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: resetToken.ExpiresAt, Valid: true},
+	}
+	return repository.queries.CreatePasswordResetToken(ctx, resetTokenParams)
+}
+
+// ResetPassword spends the reset token and sets the new password hash in a
+// single transaction. ErrInvalidPasswordResetToken is returned if the token is
+// unknown, expired or already used.
+func (repository *AuthenticationSqlRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (uuid.UUID, error) {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := repository.queries.WithTx(tx)
+	userID, err := queries.ConsumePasswordResetToken(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrInvalidPasswordResetToken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	updateParams := data.UpdatePasswordHashParams{
+		PasswordHash: &passwordHash,
+		ID:           userID,
+	}
+	_, err = queries.UpdatePasswordHash(ctx, updateParams)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, tx.Commit(ctx)
+}
+
+func (repository *AuthenticationSqlRepository) CreatePersonalAccessToken(ctx context.Context, personalAccessToken *PersonalAccessToken) (uuid.UUID, error) {
+	personalAccessTokenParams := data.CreatePersonalAccessTokenParams{
+		UserID:    personalAccessToken.UserID,
+		Name:      personalAccessToken.Name,
+		TokenHash: personalAccessToken.TokenHash,
+		TokenHint: personalAccessToken.TokenHint,
+		Scopes:    JoinScopes(personalAccessToken.Scopes),
+		ExpiresAt: pgtype.Timestamptz{Time: personalAccessToken.ExpiresAt, Valid: true},
+	}
+	return repository.queries.CreatePersonalAccessToken(ctx, personalAccessTokenParams)
+}
+
+func (repository *AuthenticationSqlRepository) FindPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (*PersonalAccessToken, error) {
+	personalAccessTokenRow, err := repository.queries.FindPersonalAccessTokenByHash(ctx, tokenHash)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return personalAccessTokenFromRow(personalAccessTokenRow), nil
+}
+
+// ListPersonalAccessTokens returns the user's tokens that have not been revoked,
+// newest first. Expired tokens are included so the user can clean them up.
+func (repository *AuthenticationSqlRepository) ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]*PersonalAccessToken, error) {
+	personalAccessTokenRows, err := repository.queries.ListPersonalAccessTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	personalAccessTokens := make([]*PersonalAccessToken, 0, len(personalAccessTokenRows))
+	for _, personalAccessTokenRow := range personalAccessTokenRows {
+		personalAccessTokens = append(personalAccessTokens, personalAccessTokenFromRow(personalAccessTokenRow))
+	}
+	return personalAccessTokens, nil
+}
+
+// RevokePersonalAccessToken returns ErrPersonalAccessTokenNotFound unless the
+// token belongs to userID and is still active.
+func (repository *AuthenticationSqlRepository) RevokePersonalAccessToken(ctx context.Context, userID uuid.UUID, personalAccessTokenID uuid.UUID) error {
+	revokeParams := data.RevokePersonalAccessTokenParams{
+		ID:     personalAccessTokenID,
+		UserID: userID,
+	}
+	result, err := repository.queries.RevokePersonalAccessToken(ctx, revokeParams)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
+
+func (repository *AuthenticationSqlRepository) RevokePersonalAccessTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	return repository.queries.RevokePersonalAccessTokensForUser(ctx, userID)
+}
+
+// TouchPersonalAccessToken records that the token was used. Writes are skipped
+// if it was already recorded within the last minute.
+func (repository *AuthenticationSqlRepository) TouchPersonalAccessToken(ctx context.Context, personalAccessTokenID uuid.UUID) error {
+	return repository.queries.TouchPersonalAccessToken(ctx, personalAccessTokenID)
+}
+
+func personalAccessTokenFromRow(personalAccessTokenRow data.PersonalAccessToken) *PersonalAccessToken {
+	return &PersonalAccessToken{
+		ID:         personalAccessTokenRow.ID,
+		UserID:     personalAccessTokenRow.UserID,
+		Name:       personalAccessTokenRow.Name,
+		TokenHash:  personalAccessTokenRow.TokenHash,
+		TokenHint:  personalAccessTokenRow.TokenHint,
+		Scopes:     ParseScopes(personalAccessTokenRow.Scopes),
+		ExpiresAt:  personalAccessTokenRow.ExpiresAt.Time,
+		LastUsedAt: timePointer(personalAccessTokenRow.LastUsedAt),
+		RevokedAt:  timePointer(personalAccessTokenRow.RevokedAt),
+		CreatedAt:  personalAccessTokenRow.CreatedAt.Time,
+	}
+}
+
+// CreateDeviceAuthorization returns ErrUserCodeTaken if the generated user code
+// is already in use, so the caller can generate another.
+func (repository *AuthenticationSqlRepository) CreateDeviceAuthorization(ctx context.Context, deviceAuthorization *DeviceAuthorization) (uuid.UUID, error) {
+	deviceAuthorizationParams := data.CreateDeviceAuthorizationParams{
+		DeviceCodeHash:  deviceAuthorization.DeviceCodeHash,
+		UserCode:        deviceAuthorization.UserCode,
+		ClientID:        deviceAuthorization.ClientID,
+		IntervalSeconds: int32(deviceAuthorization.Interval.Seconds()),
+		ExpiresAt:       pgtype.Timestamptz{Time: deviceAuthorization.ExpiresAt, Valid: true},
+	}
+
+	deviceAuthorizationID, err := repository.queries.CreateDeviceAuthorization(ctx, deviceAuthorizationParams)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return uuid.Nil, ErrUserCodeTaken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return deviceAuthorizationID, nil
+}
+
+func (repository *AuthenticationSqlRepository) FindDeviceAuthorizationByDeviceCode(ctx context.Context, deviceCodeHash string) (*DeviceAuthorization, error) {
+	deviceAuthorizationRow, err := repository.queries.FindDeviceAuthorizationByDeviceCodeHash(ctx, deviceCodeHash)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deviceAuthorizationFromRow(deviceAuthorizationRow), nil
+}
+
+func (repository *AuthenticationSqlRepository) FindDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*DeviceAuthorization, error) {
+	deviceAuthorizationRow, err := repository.queries.FindDeviceAuthorizationByUserCode(ctx, userCode)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deviceAuthorizationFromRow(deviceAuthorizationRow), nil
+}
+
+// RecordDeviceAuthorizationPoll stores the poll time and the interval the device
+// must wait before polling again.
+func (repository *AuthenticationSqlRepository) RecordDeviceAuthorizationPoll(ctx context.Context, deviceAuthorizationID uuid.UUID, interval time.Duration) error {
+	pollParams := data.UpdateDeviceAuthorizationPollParams{
+		ID:              deviceAuthorizationID,
+		IntervalSeconds: int32(interval.Seconds()),
+	}
+	return repository.queries.UpdateDeviceAuthorizationPoll(ctx, pollParams)
+}
+
+// ApproveDeviceAuthorization returns ErrDeviceAuthorizationNotFound unless the
+// authorization is still pending.
+func (repository *AuthenticationSqlRepository) ApproveDeviceAuthorization(ctx context.Context, deviceAuthorizationID uuid.UUID, userID uuid.UUID) error {
+	approveParams := data.ApproveDeviceAuthorizationParams{
+		ID:     deviceAuthorizationID,
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+	}
+	result, err := repository.queries.ApproveDeviceAuthorization(ctx, approveParams)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrDeviceAuthorizationNotFound
+	}
+	return nil
+}
+
+// ConsumeDeviceAuthorization marks an approved authorization as used and
+// returns the approving user. It returns ErrDeviceAuthorizationNotFound if the
+// authorization is not approved, including when it was already consumed.
+func (repository *AuthenticationSqlRepository) ConsumeDeviceAuthorization(ctx context.Context, deviceAuthorizationID uuid.UUID) (uuid.UUID, error) {
+	userID, err := repository.queries.ConsumeDeviceAuthorization(ctx, deviceAuthorizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrDeviceAuthorizationNotFound
+	}
 	if err != nil {
 		return uuid.Nil, err
 	}
+	return uuid.UUID(userID.Bytes), nil
+}
+
+func deviceAuthorizationFromRow(deviceAuthorizationRow data.DeviceAuthorization) *DeviceAuthorization {
+	return &DeviceAuthorization{
+		ID:             deviceAuthorizationRow.ID,
+		DeviceCodeHash: deviceAuthorizationRow.DeviceCodeHash,
+		UserCode:       deviceAuthorizationRow.UserCode,
+		ClientID:       deviceAuthorizationRow.ClientID,
+		Status:         deviceAuthorizationRow.Status,
+		UserID:         uuid.UUID(deviceAuthorizationRow.UserID.Bytes),
+		Interval:       time.Duration(deviceAuthorizationRow.IntervalSeconds) * time.Second,
+		LastPolledAt:   timePointer(deviceAuthorizationRow.LastPolledAt),
+		ExpiresAt:      deviceAuthorizationRow.ExpiresAt.Time,
+		CreatedAt:      deviceAuthorizationRow.CreatedAt.Time,
+	}
+}
+
+func (repository *AuthenticationSqlRepository) CreateAuthorizationCode(ctx context.Context, authorizationCode *AuthorizationCode) (uuid.UUID, error) {
+	authorizationCodeParams := data.CreateAuthorizationCodeParams{
+		UserID:        authorizationCode.UserID,
+		CodeHash:      authorizationCode.CodeHash,
+		CodeChallenge: authorizationCode.CodeChallenge,
+		ExpiresAt:     pgtype.Timestamptz{Time: authorizationCode.ExpiresAt, Valid: true},
+		SessionID:     nullableUUID(authorizationCode.SessionID),
+	}
+	return repository.queries.CreateAuthorizationCode(ctx, authorizationCodeParams)
+}
+
+// ConsumeAuthorizationCode spends the code and returns it. Unknown, expired and
+// already used codes return ErrInvalidAuthorizationCode.
+func (repository *AuthenticationSqlRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*AuthorizationCode, error) {
+	authorizationCodeRow, err := repository.queries.ConsumeAuthorizationCode(ctx, codeHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAuthorizationCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationCode := &AuthorizationCode{
+		UserID:        authorizationCodeRow.UserID,
+		SessionID:     uuid.UUID(authorizationCodeRow.SessionID.Bytes),
+		CodeHash:      codeHash,
+		CodeChallenge: authorizationCodeRow.CodeChallenge,
+	}
+	return authorizationCode, nil
+}
+
+func (repository *AuthenticationSqlRepository) CreateSession(ctx context.Context, session *Session) (uuid.UUID, error) {
+	sessionParams := data.CreateSessionParams{
+		UserID:    session.UserID,
+		Provider:  session.Provider,
+		UserAgent: session.UserAgent,
+		IpAddress: session.IPAddress,
+	}
+	return repository.queries.CreateSession(ctx, sessionParams)
+}
+
+// FindActiveSession returns nil unless the session belongs to userID and has
+// not been revoked.
+func (repository *AuthenticationSqlRepository) FindActiveSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (*Session, error) {
+	findParams := data.FindActiveSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	}
+	sessionRow, err := repository.queries.FindActiveSession(ctx, findParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ID:         sessionRow.ID,
+		UserID:     sessionRow.UserID,
+		Provider:   sessionRow.Provider,
+		UserAgent:  sessionRow.UserAgent,
+		IPAddress:  sessionRow.IpAddress,
+		CreatedAt:  sessionRow.CreatedAt.Time,
+		LastSeenAt: sessionRow.LastSeenAt.Time,
+		RevokedAt:  timePointer(sessionRow.RevokedAt),
+	}, nil
+}
+
+// ListActiveSessions returns the user's sessions that have not been revoked,
+// most recently seen first.
+func (repository *AuthenticationSqlRepository) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	sessionRows, err := repository.queries.ListActiveSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(sessionRows))
+	for _, sessionRow := range sessionRows {
+		sessions = append(sessions, &Session{
+			ID:         sessionRow.ID,
+			UserID:     sessionRow.UserID,
+			Provider:   sessionRow.Provider,
+			UserAgent:  sessionRow.UserAgent,
+			IPAddress:  sessionRow.IpAddress,
+			CreatedAt:  sessionRow.CreatedAt.Time,
+			LastSeenAt: sessionRow.LastSeenAt.Time,
+			RevokedAt:  timePointer(sessionRow.RevokedAt),
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes the session and its refresh tokens in a single
+// transaction. ErrSessionNotFound is returned unless the session belongs to
+// userID and is still active.
+func (repository *AuthenticationSqlRepository) RevokeSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := repository.queries.WithTx(tx)
+	revokeParams := data.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	}
+	result, err := queries.RevokeSession(ctx, revokeParams)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	_, err = queries.RevokeRefreshTokensForSession(ctx, nullableUUID(sessionID))
+	if err != nil {
+		return err
+	}
 
-	return registeredID, err
+	return tx.Commit(ctx)
+}
+
+// RevokeOtherSessions revokes every session of the user except keepSessionID,
+// along with their refresh tokens. Pass uuid.Nil to revoke them all.
+func (repository *AuthenticationSqlRepository) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keepSessionID uuid.UUID) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := repository.queries.WithTx(tx)
+	sessionParams := data.RevokeOtherSessionsForUserParams{
+		UserID: userID,
+		ID:     keepSessionID,
+	}
+	err = queries.RevokeOtherSessionsForUser(ctx, sessionParams)
+	if err != nil {
+		return err
+	}
+
+	refreshTokenParams := data.RevokeRefreshTokensForOtherSessionsParams{
+		UserID:    userID,
+		SessionID: nullableUUID(keepSessionID),
+	}
+	err = queries.RevokeRefreshTokensForOtherSessions(ctx, refreshTokenParams)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (repository *AuthenticationSqlRepository) RevokeSessionsForUser(ctx context.Context, userID uuid.UUID) error {
+	return repository.queries.RevokeSessionsForUser(ctx, userID)
+}
+
+// TouchSession records that the session was used. Writes are skipped if it was
+// already recorded within the last minute.
+func (repository *AuthenticationSqlRepository) TouchSession(ctx context.Context, sessionID uuid.UUID) error {
+	return repository.queries.TouchSession(ctx, sessionID)
+}
+
+// StartTOTPEnrolment stores a new, unconfirmed secret for the user, replacing
+// any earlier enrolment they did not finish. ErrTOTPAlreadyEnabled is returned
+// if they already have a confirmed authenticator app.
+func (repository *AuthenticationSqlRepository) StartTOTPEnrolment(ctx context.Context, userID uuid.UUID, secret string) error {
+	enrolmentParams := data.StartTOTPEnrolmentParams{
+		UserID: userID,
+		Secret: secret,
+	}
+	result, err := repository.queries.StartTOTPEnrolment(ctx, enrolmentParams)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+func (repository *AuthenticationSqlRepository) FindTOTPCredential(ctx context.Context, userID uuid.UUID) (*TOTPCredential, error) {
+	credentialRow, err := repository.queries.FindTOTPCredentialByUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &TOTPCredential{
+		UserID:       credentialRow.UserID,
+		Secret:       credentialRow.Secret,
+		ConfirmedAt:  timePointer(credentialRow.ConfirmedAt),
+		LastUsedStep: credentialRow.LastUsedStep,
+		CreatedAt:    credentialRow.CreatedAt.Time,
+	}
+	return credential, nil
+}
+
+// ConfirmTOTPCredential turns on the pending authenticator app and replaces the
+// user's recovery codes in a single transaction. ErrTOTPNotPending is returned
+// if there is no unconfirmed enrolment.
+func (repository *AuthenticationSqlRepository) ConfirmTOTPCredential(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := repository.queries.WithTx(tx)
+	confirmParams := data.ConfirmTOTPCredentialParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	}
+	result, err := queries.ConfirmTOTPCredential(ctx, confirmParams)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTOTPNotPending
+	}
+
+	err = replaceRecoveryCodes(ctx, queries, userID, recoveryCodeHashes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep records that a code for step was used. It reports false if that
+// step or a later one has already been used, meaning the code is a replay.
+func (repository *AuthenticationSqlRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	stepParams := data.UseTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	}
+	result, err := repository.queries.UseTOTPStep(ctx, stepParams)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// DisableTOTP removes the user's authenticator app and recovery codes.
+func (repository *AuthenticationSqlRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := repository.queries.WithTx(tx)
+	err = queries.DeleteTOTPCredential(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = queries.DeleteRecoveryCodesForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (repository *AuthenticationSqlRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = replaceRecoveryCodes(ctx, repository.queries.WithTx(tx), userID, recoveryCodeHashes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseRecoveryCode spends one of the user's recovery codes. It reports false if
+// the code is not theirs or has already been used.
+func (repository *AuthenticationSqlRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	recoveryCodeParams := data.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+	}
+	result, err := repository.queries.UseRecoveryCode(ctx, recoveryCodeParams)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (repository *AuthenticationSqlRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	count, err := repository.queries.CountUnusedRecoveryCodes(ctx, userID)
+	return int(count), err
+}
+
+func (repository *AuthenticationSqlRepository) CreateMFAChallenge(ctx context.Context, challenge *MFAChallenge) error {
+	challengeParams := data.CreateMFAChallengeParams{
+		SessionID: challenge.SessionID,
+		UserID:    challenge.UserID,
+		ExpiresAt: pgtype.Timestamptz{Time: challenge.ExpiresAt, Valid: true},
+	}
+	return repository.queries.CreateMFAChallenge(ctx, challengeParams)
+}
+
+func (repository *AuthenticationSqlRepository) FindMFAChallenge(ctx context.Context, sessionID uuid.UUID) (*MFAChallenge, error) {
+	challengeRow, err := repository.queries.FindMFAChallengeBySessionID(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &MFAChallenge{
+		SessionID:      challengeRow.SessionID,
+		UserID:         challengeRow.UserID,
+		FailedAttempts: int(challengeRow.FailedAttempts),
+		ExpiresAt:      challengeRow.ExpiresAt.Time,
+		CreatedAt:      challengeRow.CreatedAt.Time,
+	}
+	return challenge, nil
+}
+
+// RecordFailedMFAAttempt counts a wrong code against the challenge and returns
+// the number of wrong codes so far.
+func (repository *AuthenticationSqlRepository) RecordFailedMFAAttempt(ctx context.Context, sessionID uuid.UUID) (int, error) {
+	failedAttempts, err := repository.queries.RecordFailedMFAAttempt(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrMFAChallengeNotFound
+	}
+	return int(failedAttempts), err
+}
+
+// CompleteMFAChallenge releases the session for token issuance.
+// ErrMFAChallengeNotFound is returned if another request completed it first.
+func (repository *AuthenticationSqlRepository) CompleteMFAChallenge(ctx context.Context, sessionID uuid.UUID) error {
+	result, err := repository.queries.CompleteMFAChallenge(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMFAChallengeNotFound
+	}
+	return nil
+}
+
+// FindAuthUserProviderID returns the auth_user_providers row for a provider
+// identity, or uuid.Nil if it is not linked to anyone.
+func (repository *AuthenticationSqlRepository) FindAuthUserProviderID(ctx context.Context, provider string, providerUserID string) (uuid.UUID, error) {
+	providerParams := data.FindAuthUserProviderIDParams{
+		Provider:       provider,
+		ProviderUserID: providerUserID,
+	}
+	authUserProviderID, err := repository.queries.FindAuthUserProviderID(ctx, providerParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return authUserProviderID, err
+}
+
+func (repository *AuthenticationSqlRepository) FindProviderToken(ctx context.Context, authUserProviderID uuid.UUID) (*EncryptedProviderToken, error) {
+	providerTokenRow, err := repository.queries.FindProviderTokenByAuthUserProviderID(ctx, authUserProviderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encryptedProviderTokenFromRow(providerTokenRow), nil
+}
+
+// FindProviderTokenForUser returns the token for the user's identity at
+// provider. If they linked several, the most recently signed in one wins.
+func (repository *AuthenticationSqlRepository) FindProviderTokenForUser(ctx context.Context, userID uuid.UUID, provider string) (*EncryptedProviderToken, error) {
+	providerTokenParams := data.FindProviderTokenByUserIDParams{
+		UserID:   userID,
+		Provider: provider,
+	}
+	providerTokenRow, err := repository.queries.FindProviderTokenByUserID(ctx, providerTokenParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encryptedProviderTokenFromRow(providerTokenRow), nil
+}
+
+func (repository *AuthenticationSqlRepository) SaveProviderToken(ctx context.Context, providerToken *EncryptedProviderToken) error {
+	return repository.queries.UpsertProviderToken(ctx, upsertProviderTokenParams(providerToken))
+}
+
+// UpdateProviderToken locks the identity's token, hands it to update and
+// stores the token update returns, all in one transaction, so two requests
+// refreshing the same token take turns. A nil token from update leaves the row
+// as it was. It returns ErrProviderTokenNotFound if there is no token.
+func (repository *AuthenticationSqlRepository) UpdateProviderToken(ctx context.Context, authUserProviderID uuid.UUID, update func(*EncryptedProviderToken) (*EncryptedProviderToken, error)) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	providerTokenRow, err := queries.LockProviderToken(ctx, authUserProviderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProviderTokenNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	updated, err := update(encryptedProviderTokenFromRow(providerTokenRow))
+	if err != nil {
+		return err
+	}
+	if updated != nil {
+		err = queries.UpsertProviderToken(ctx, upsertProviderTokenParams(updated))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func upsertProviderTokenParams(providerToken *EncryptedProviderToken) data.UpsertProviderTokenParams {
+	providerTokenParams := data.UpsertProviderTokenParams{
+		AuthUserProviderID: providerToken.AuthUserProviderID,
+		KeyID:              providerToken.KeyID,
+		WrappedKey:         providerToken.WrappedKey,
+		AccessToken:        providerToken.AccessToken,
+		RefreshToken:       providerToken.RefreshToken,
+	}
+	if providerToken.ExpiresAt != nil {
+		providerTokenParams.ExpiresAt = pgtype.Timestamptz{Time: *providerToken.ExpiresAt, Valid: true}
+	}
+	return providerTokenParams
+}
+
+func encryptedProviderTokenFromRow(providerTokenRow data.ProviderToken) *EncryptedProviderToken {
+	return &EncryptedProviderToken{
+		AuthUserProviderID: providerTokenRow.AuthUserProviderID,
+		KeyID:              providerTokenRow.KeyID,
+		WrappedKey:         providerTokenRow.WrappedKey,
+		AccessToken:        providerTokenRow.AccessToken,
+		RefreshToken:       providerTokenRow.RefreshToken,
+		ExpiresAt:          timePointer(providerTokenRow.ExpiresAt),
+	}
+}
+
+// FindGothicSession returns the encoded values of an unexpired gothic session,
+// or an empty string if there is none.
+func (repository *AuthenticationSqlRepository) FindGothicSession(ctx context.Context, id string) (string, error) {
+	gothicSessionRow, err := repository.queries.FindGothicSession(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return gothicSessionRow.Data, nil
+}
+
+func (repository *AuthenticationSqlRepository) SaveGothicSession(ctx context.Context, id string, encodedValues string, expiresAt time.Time) error {
+	gothicSessionParams := data.UpsertGothicSessionParams{
+		ID:        id,
+		Data:      encodedValues,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}
+	return repository.queries.UpsertGothicSession(ctx, gothicSessionParams)
+}
+
+func (repository *AuthenticationSqlRepository) DeleteGothicSession(ctx context.Context, id string) error {
+	return repository.queries.DeleteGothicSession(ctx, id)
+}
+
+// expiredTokenSteps delete the rows of tokens that can no longer be used once
+// expired, and gothic sessions of sign-ins that were never finished. mfa_challenges are left alone: a session without a challenge counts
+// as having passed MFA, so they go with their session instead.
+var expiredTokenSteps = []struct {
+	table  string
+	delete func(*data.Queries, context.Context) (pgconn.CommandTag, error)
+}{
+	{"revoked_tokens", (*data.Queries).DeleteExpiredRevokedTokens},
+	{"refresh_tokens", (*data.Queries).DeleteExpiredRefreshTokens},
+	{"authorization_codes", (*data.Queries).DeleteExpiredAuthorizationCodes},
+	{"device_authorizations", (*data.Queries).DeleteExpiredDeviceAuthorizations},
+	{"magic_links", (*data.Queries).DeleteExpiredMagicLinks},
+	{"saml_requests", (*data.Queries).DeleteExpiredSAMLRequests},
+	{"password_reset_tokens", (*data.Queries).DeleteExpiredPasswordResetTokens},
+	{"gothic_sessions", (*data.Queries).DeleteExpiredGothicSessions},
+}
+
+// DeleteExpiredTokens returns how many rows it deleted from each table. Every
+// table is swept on its own, so a failure leaves the ones before it swept.
+func (repository *AuthenticationSqlRepository) DeleteExpiredTokens(ctx context.Context) (map[string]int64, error) {
+	deletedRows := map[string]int64{}
+	for _, step := range expiredTokenSteps {
+		result, err := step.delete(repository.queries, ctx)
+		if err != nil {
+			return deletedRows, fmt.Errorf("delete expired %s: %w", step.table, err)
+		}
+		deletedRows[step.table] = result.RowsAffected()
+	}
+	return deletedRows, nil
+}
+
+// CreateInvitation returns ErrInvitationExists if the email already has an
+// invitation that has not been revoked.
+func (repository *AuthenticationSqlRepository) CreateInvitation(ctx context.Context, invitation *Invitation) (uuid.UUID, error) {
+	invitationParams := data.CreateInvitationParams{
+		Email:     invitation.Email,
+		InvitedBy: nullableUUID(invitation.InvitedBy),
+		ExpiresAt: pgtype.Timestamptz{Time: invitation.ExpiresAt, Valid: true},
+	}
+
+	invitationID, err := repository.queries.CreateInvitation(ctx, invitationParams)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return uuid.Nil, ErrInvitationExists
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return invitationID, nil
+}
+
+// ListInvitations returns every invitation that has not been revoked, newest
+// first, including accepted and expired ones.
+func (repository *AuthenticationSqlRepository) ListInvitations(ctx context.Context) ([]*Invitation, error) {
+	invitationRows, err := repository.queries.ListInvitations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := make([]*Invitation, 0, len(invitationRows))
+	for _, invitationRow := range invitationRows {
+		invitations = append(invitations, &Invitation{
+			ID:         invitationRow.ID,
+			Email:      invitationRow.Email,
+			InvitedBy:  invitationRow.InvitedBy.Bytes,
+			ExpiresAt:  invitationRow.ExpiresAt.Time,
+			AcceptedAt: timePointer(invitationRow.AcceptedAt),
+			CreatedAt:  invitationRow.CreatedAt.Time,
+		})
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation returns ErrInvitationNotFound unless the invitation exists
+// and has not already been revoked.
+func (repository *AuthenticationSqlRepository) RevokeInvitation(ctx context.Context, invitationID uuid.UUID) error {
+	result, err := repository.queries.RevokeInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// HasInvitation reports whether the email holds an invitation that has been
+// accepted or can still be.
+func (repository *AuthenticationSqlRepository) HasInvitation(ctx context.Context, email string) (bool, error) {
+	return repository.queries.HasInvitation(ctx, email)
+}
+
+func (repository *AuthenticationSqlRepository) AcceptInvitation(ctx context.Context, email string) error {
+	return repository.queries.AcceptInvitation(ctx, email)
+}
+
+func (repository *AuthenticationSqlRepository) CreateMagicLink(ctx context.Context, magicLink *MagicLink) (uuid.UUID, error) {
+	magicLinkParams := data.CreateMagicLinkParams{
+		Email:     magicLink.Email,
+		ExpiresAt: pgtype.Timestamptz{Time: magicLink.ExpiresAt, Valid: true},
+	}
+	return repository.queries.CreateMagicLink(ctx, magicLinkParams)
+}
+
+// ConsumeMagicLink marks the link used and returns the email it was sent to.
+// It returns ErrInvalidMagicLink if the link was already used or has expired.
+func (repository *AuthenticationSqlRepository) ConsumeMagicLink(ctx context.Context, magicLinkID uuid.UUID) (string, error) {
+	email, err := repository.queries.ConsumeMagicLink(ctx, magicLinkID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidMagicLink
+	}
+	if err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+func (repository *AuthenticationSqlRepository) CreateImpersonation(ctx context.Context, impersonation *Impersonation) (uuid.UUID, error) {
+	impersonationParams := data.CreateImpersonationParams{
+		ActorID:    nullableUUID(impersonation.ActorID),
+		ActorEmail: impersonation.ActorEmail,
+		UserID:     impersonation.UserID,
+		Reason:     impersonation.Reason,
+		ExpiresAt:  pgtype.Timestamptz{Time: impersonation.ExpiresAt, Valid: true},
+	}
+	return repository.queries.CreateImpersonation(ctx, impersonationParams)
+}
+
+func (repository *AuthenticationSqlRepository) FindImpersonation(ctx context.Context, impersonationID uuid.UUID) (*Impersonation, error) {
+	impersonationRow, err := repository.queries.FindImpersonationByID(ctx, impersonationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return impersonationFromRow(impersonationRow), nil
+}
+
+// ListImpersonations returns the most recent impersonations, newest first.
+func (repository *AuthenticationSqlRepository) ListImpersonations(ctx context.Context, limit int) ([]*Impersonation, error) {
+	impersonationRows, err := repository.queries.ListImpersonations(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	impersonations := make([]*Impersonation, 0, len(impersonationRows))
+	for _, impersonationRow := range impersonationRows {
+		impersonations = append(impersonations, impersonationFromRow(impersonationRow))
+	}
+	return impersonations, nil
+}
+
+// EndImpersonation returns ErrImpersonationNotFound unless the impersonation
+// exists and has not already been ended.
+func (repository *AuthenticationSqlRepository) EndImpersonation(ctx context.Context, impersonationID uuid.UUID) (*Impersonation, error) {
+	impersonationRow, err := repository.queries.EndImpersonation(ctx, impersonationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImpersonationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return impersonationFromRow(impersonationRow), nil
+}
+
+func (repository *AuthenticationSqlRepository) CreateImpersonationRequest(ctx context.Context, request *ImpersonationRequest) (uuid.UUID, error) {
+	requestParams := data.CreateImpersonationRequestParams{
+		ImpersonationID: request.ImpersonationID,
+		Method:          request.Method,
+		Path:            request.Path,
+		IpAddress:       request.IPAddress,
+	}
+	return repository.queries.CreateImpersonationRequest(ctx, requestParams)
+}
+
+func (repository *AuthenticationSqlRepository) RecordImpersonationResponse(ctx context.Context, requestID uuid.UUID, statusCode int) error {
+	statusCode32 := int32(statusCode)
+	responseParams := data.RecordImpersonationResponseParams{
+		ID:         requestID,
+		StatusCode: &statusCode32,
+	}
+	return repository.queries.RecordImpersonationResponse(ctx, responseParams)
+}
+
+// ListImpersonationRequests returns the impersonation's audit log, oldest first.
+func (repository *AuthenticationSqlRepository) ListImpersonationRequests(ctx context.Context, impersonationID uuid.UUID) ([]*ImpersonationRequest, error) {
+	requestRows, err := repository.queries.ListImpersonationRequests(ctx, impersonationID)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]*ImpersonationRequest, 0, len(requestRows))
+	for _, requestRow := range requestRows {
+		request := &ImpersonationRequest{
+			ID:              requestRow.ID,
+			ImpersonationID: requestRow.ImpersonationID,
+			Method:          requestRow.Method,
+			Path:            requestRow.Path,
+			IPAddress:       requestRow.IpAddress,
+			CreatedAt:       requestRow.CreatedAt.Time,
+		}
+		if requestRow.StatusCode != nil {
+			statusCode := int(*requestRow.StatusCode)
+			request.StatusCode = &statusCode
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+func impersonationFromRow(impersonationRow data.Impersonation) *Impersonation {
+	return &Impersonation{
+		ID:         impersonationRow.ID,
+		ActorID:    impersonationRow.ActorID.Bytes,
+		ActorEmail: impersonationRow.ActorEmail,
+		UserID:     impersonationRow.UserID,
+		Reason:     impersonationRow.Reason,
+		ExpiresAt:  impersonationRow.ExpiresAt.Time,
+		EndedAt:    timePointer(impersonationRow.EndedAt),
+		CreatedAt:  impersonationRow.CreatedAt.Time,
+	}
+}
+
+// CreateSAMLConnection returns ErrSAMLConnectionExists if the workspace already
+// has a connection.
+func (repository *AuthenticationSqlRepository) CreateSAMLConnection(ctx context.Context, connection *SAMLConnection) (uuid.UUID, error) {
+	connectionParams := data.CreateSAMLConnectionParams{
+		Workspace:          connection.Workspace,
+		EntityID:           connection.EntityID,
+		IdpEntityID:        connection.IdPEntityID,
+		IdpMetadata:        connection.IdPMetadata,
+		EmailAttribute:     connection.AttributeMapping.Email,
+		FirstNameAttribute: connection.AttributeMapping.FirstName,
+		LastNameAttribute:  connection.AttributeMapping.LastName,
+		Enabled:            connection.Enabled,
+	}
+
+	connectionID, err := repository.queries.CreateSAMLConnection(ctx, connectionParams)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return uuid.Nil, ErrSAMLConnectionExists
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return connectionID, nil
+}
+
+func (repository *AuthenticationSqlRepository) FindSAMLConnection(ctx context.Context, connectionID uuid.UUID) (*SAMLConnection, error) {
+	connectionRow, err := repository.queries.FindSAMLConnectionByID(ctx, connectionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return samlConnectionFromRow(connectionRow), nil
+}
+
+func (repository *AuthenticationSqlRepository) FindSAMLConnectionByWorkspace(ctx context.Context, workspace string) (*SAMLConnection, error) {
+	connectionRow, err := repository.queries.FindSAMLConnectionByWorkspace(ctx, workspace)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return samlConnectionFromRow(connectionRow), nil
+}
+
+func (repository *AuthenticationSqlRepository) ListSAMLConnections(ctx context.Context) ([]*SAMLConnection, error) {
+	connectionRows, err := repository.queries.ListSAMLConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	connections := make([]*SAMLConnection, 0, len(connectionRows))
+	for _, connectionRow := range connectionRows {
+		connections = append(connections, samlConnectionFromRow(connectionRow))
+	}
+	return connections, nil
+}
+
+// UpdateSAMLConnection saves everything but the workspace. It returns
+// ErrSAMLConnectionNotFound if the connection does not exist.
+func (repository *AuthenticationSqlRepository) UpdateSAMLConnection(ctx context.Context, connection *SAMLConnection) error {
+	connectionParams := data.UpdateSAMLConnectionParams{
+		ID:                 connection.ID,
+		EntityID:           connection.EntityID,
+		IdpEntityID:        connection.IdPEntityID,
+		IdpMetadata:        connection.IdPMetadata,
+		EmailAttribute:     connection.AttributeMapping.Email,
+		FirstNameAttribute: connection.AttributeMapping.FirstName,
+		LastNameAttribute:  connection.AttributeMapping.LastName,
+		Enabled:            connection.Enabled,
+	}
+
+	result, err := repository.queries.UpdateSAMLConnection(ctx, connectionParams)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSAMLConnectionNotFound
+	}
+	return nil
+}
+
+// DeleteSAMLConnection returns ErrSAMLConnectionNotFound if the connection does
+// not exist. Identities created through the connection are kept.
+func (repository *AuthenticationSqlRepository) DeleteSAMLConnection(ctx context.Context, connectionID uuid.UUID) error {
+	result, err := repository.queries.DeleteSAMLConnection(ctx, connectionID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSAMLConnectionNotFound
+	}
+	return nil
+}
+
+func (repository *AuthenticationSqlRepository) CreateSAMLRequest(ctx context.Context, request *SAMLRequest) (uuid.UUID, error) {
+	requestParams := data.CreateSAMLRequestParams{
+		ConnectionID:  request.ConnectionID,
+		RequestID:     request.RequestID,
+		CodeChallenge: request.CodeChallenge,
+		ExpiresAt:     pgtype.Timestamptz{Time: request.ExpiresAt, Valid: true},
+	}
+	return repository.queries.CreateSAMLRequest(ctx, requestParams)
+}
+
+// ConsumeSAMLRequest marks the request answered. It returns
+// ErrInvalidSAMLRequest if the request was not sent for this connection, was
+// already answered or has expired.
+func (repository *AuthenticationSqlRepository) ConsumeSAMLRequest(ctx context.Context, requestID uuid.UUID, connectionID uuid.UUID) (*SAMLRequest, error) {
+	requestParams := data.ConsumeSAMLRequestParams{
+		ID:           requestID,
+		ConnectionID: connectionID,
+	}
+
+	requestRow, err := repository.queries.ConsumeSAMLRequest(ctx, requestParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidSAMLRequest
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SAMLRequest{
+		ID:            requestID,
+		ConnectionID:  connectionID,
+		RequestID:     requestRow.RequestID,
+		CodeChallenge: requestRow.CodeChallenge,
+	}, nil
+}
+
+func samlConnectionFromRow(connectionRow data.SamlConnection) *SAMLConnection {
+	return &SAMLConnection{
+		ID:          connectionRow.ID,
+		Workspace:   connectionRow.Workspace,
+		EntityID:    connectionRow.EntityID,
+		IdPEntityID: connectionRow.IdpEntityID,
+		IdPMetadata: connectionRow.IdpMetadata,
+		AttributeMapping: SAMLAttributeMapping{
+			Email:     connectionRow.EmailAttribute,
+			FirstName: connectionRow.FirstNameAttribute,
+			LastName:  connectionRow.LastNameAttribute,
+		},
+		Enabled:   connectionRow.Enabled,
+		CreatedAt: connectionRow.CreatedAt.Time,
+		UpdatedAt: connectionRow.UpdatedAt.Time,
+	}
+}
+
+func replaceRecoveryCodes(ctx context.Context, queries *data.Queries, userID uuid.UUID, recoveryCodeHashes []string) error {
+	err := queries.DeleteRecoveryCodesForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		recoveryCodeParams := data.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: codeHash,
+		}
+		err = queries.CreateRecoveryCode(ctx, recoveryCodeParams)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createRefreshTokenParams(refreshToken *RefreshToken) data.CreateRefreshTokenParams {
+	return data.CreateRefreshTokenParams{
+		UserID:    refreshToken.UserID,
+		FamilyID:  refreshToken.FamilyID,
+		TokenHash: refreshToken.TokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: refreshToken.ExpiresAt, Valid: true},
+		SessionID: nullableUUID(refreshToken.SessionID),
+	}
+}
+
+func timePointer(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+// nullableUUID stores uuid.Nil as NULL.
+func nullableUUID(id uuid.UUID) pgtype.UUID {
+	return pgtype.UUID{Bytes: id, Valid: id != uuid.Nil}
+}
+
+// FindUnclaimedProvisionedUserID returns the account provisioned over SCIM
+// with this email, matched case-insensitively, as long as nobody has signed in
+// to it yet. It returns uuid.Nil otherwise.
+func (repository *AuthenticationSqlRepository) FindUnclaimedProvisionedUserID(ctx context.Context, email string) (uuid.UUID, error) {
+	id, err := repository.queries.FindUnclaimedProvisionedUserID(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+func (repository *AuthenticationSqlRepository) CreateSCIMToken(ctx context.Context, scimToken *SCIMToken) (uuid.UUID, error) {
+	scimTokenParams := data.CreateSCIMTokenParams{
+		Name:      scimToken.Name,
+		TokenHash: scimToken.TokenHash,
+		TokenHint: scimToken.TokenHint,
+		CreatedBy: nullableUUID(scimToken.CreatedBy),
+	}
+	return repository.queries.CreateSCIMToken(ctx, scimTokenParams)
+}
+
+func (repository *AuthenticationSqlRepository) FindSCIMTokenByHash(ctx context.Context, tokenHash string) (*SCIMToken, error) {
+	scimTokenRow, err := repository.queries.FindSCIMTokenByHash(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scimTokenFromRow(scimTokenRow), nil
+}
+
+// ListSCIMTokens returns the tokens that have not been revoked, newest first.
+func (repository *AuthenticationSqlRepository) ListSCIMTokens(ctx context.Context) ([]*SCIMToken, error) {
+	scimTokenRows, err := repository.queries.ListSCIMTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scimTokens := make([]*SCIMToken, 0, len(scimTokenRows))
+	for _, scimTokenRow := range scimTokenRows {
+		scimTokens = append(scimTokens, scimTokenFromRow(scimTokenRow))
+	}
+	return scimTokens, nil
+}
+
+// RevokeSCIMToken returns ErrSCIMTokenNotFound unless the token is still active.
+func (repository *AuthenticationSqlRepository) RevokeSCIMToken(ctx context.Context, scimTokenID uuid.UUID) error {
+	result, err := repository.queries.RevokeSCIMToken(ctx, scimTokenID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSCIMTokenNotFound
+	}
+	return nil
+}
+
+// TouchSCIMToken records that the token was used. Writes are skipped if it was
+// already recorded within the last minute.
+func (repository *AuthenticationSqlRepository) TouchSCIMToken(ctx context.Context, scimTokenID uuid.UUID) error {
+	return repository.queries.TouchSCIMToken(ctx, scimTokenID)
+}
+
+func scimTokenFromRow(scimTokenRow data.ScimToken) *SCIMToken {
+	return &SCIMToken{
+		ID:         scimTokenRow.ID,
+		Name:       scimTokenRow.Name,
+		TokenHash:  scimTokenRow.TokenHash,
+		TokenHint:  scimTokenRow.TokenHint,
+		CreatedBy:  scimTokenRow.CreatedBy.Bytes,
+		LastUsedAt: timePointer(scimTokenRow.LastUsedAt),
+		RevokedAt:  timePointer(scimTokenRow.RevokedAt),
+		CreatedAt:  scimTokenRow.CreatedAt.Time,
+	}
 }
@@ -3,18 +3,128 @@ package authentication
 import (
 	"log"
 
+	"catalyst.api/internal/mailer"
+
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(router *gin.Engine, authenticationRepo AuthenticationRepository, logger *log.Logger) {
+func RegisterRoutes(router *gin.Engine, auth *Authentication, authenticationRepo AuthenticationRepository, authMiddleware AuthenticationMiddleware, mailer mailer.Mailer, logger *log.Logger) {
+	tokenIssuer := NewTokenIssuer(authenticationRepo, auth.KeySet)
+	admission := NewAdmissionPolicy(authenticationRepo, auth.AdmissionRules, auth.ProviderTypes)
+	cookies := auth.Cookies
+
 	// Set up handlers
-	signInHandler := NewSignInHandler(authenticationRepo, logger)
-	logoutHandler := NewLogoutHandler(logger)
-	providerHandler := NewProviderHandler(logger)
+	signInHandler := NewSignInHandler(authenticationRepo, tokenIssuer, auth.ProviderTokens, admission, cookies, auth.FrontendURL, logger)
+	logoutHandler := NewLogoutHandler(authenticationRepo, tokenIssuer, authMiddleware.RevocationStore, cookies, logger)
+	providerHandler := NewProviderHandler(cookies, auth.EnabledProviders, auth.FrontendURL, logger)
+	refreshHandler := NewRefreshHandler(tokenIssuer, cookies, logger)
+	tokenExchangeHandler := NewTokenExchangeHandler(authenticationRepo, tokenIssuer, cookies, logger)
+	jwksHandler := NewJWKSHandler(auth.KeySet, logger)
+	identityHandler := NewIdentityHandler(authenticationRepo, cookies, logger)
+	mfaHandler := NewMFAHandler(authenticationRepo, tokenIssuer, authMiddleware.RevocationStore, cookies, logger)
+	sessionHandler := NewSessionHandler(authenticationRepo, authMiddleware.RevocationStore, cookies, logger)
+	deviceAuthorizationHandler := NewDeviceAuthorizationHandler(authenticationRepo, tokenIssuer, cookies, auth.EnabledProviders, auth.PublicURL, logger)
+	personalAccessTokenHandler := NewPersonalAccessTokenHandler(authenticationRepo, logger)
+	passwordHandler := NewPasswordHandler(authenticationRepo, tokenIssuer, authMiddleware.RevocationStore, mailer, admission, cookies, auth.FrontendURL, logger)
+	invitationHandler := NewInvitationHandler(authenticationRepo, mailer, auth.FrontendURL, logger)
+	magicLinkHandler := NewMagicLinkHandler(authenticationRepo, tokenIssuer, admission, mailer, auth.KeySet, cookies, auth.FrontendURL, logger)
+	impersonationHandler := NewImpersonationHandler(authenticationRepo, authMiddleware.RevocationStore, auth.KeySet, logger)
+	samlHandler := NewSAMLHandler(authenticationRepo, tokenIssuer, admission, auth.PublicURL, auth.SAMLKeyPair, auth.FrontendURL, logger)
+	samlConnectionHandler := NewSAMLConnectionHandler(authenticationRepo, auth.PublicURL, logger)
+	scimTokenHandler := NewSCIMTokenHandler(authenticationRepo, logger)
 
 	// Set up routes
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	authRoutes := router.Group("/auth")
+	authRoutes.GET("/providers", providerHandler.ListProviders)
 	authRoutes.GET("/:provider/callback", signInHandler.SignInCallback)
-	authRoutes.GET("/logout/:provider", logoutHandler.Logout)
 	authRoutes.GET("/:provider", providerHandler.GetProvider)
+	authRoutes.POST("/token", tokenExchangeHandler.ExchangeCode)
+	authRoutes.POST("/refresh", refreshHandler.Refresh)
+	authRoutes.POST("/logout/:provider", logoutHandler.Logout)
+	authRoutes.POST("/logout/all", authMiddleware.RequireAuthUser(), authMiddleware.RejectImpersonation(), logoutHandler.LogoutEverywhere)
+	authRoutes.POST("/register", passwordHandler.Register)
+	authRoutes.POST("/login", passwordHandler.Login)
+	authRoutes.PUT("/password", authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AuthScope), authMiddleware.RejectImpersonation(), authMiddleware.RejectPersonalAccessTokens(), passwordHandler.ChangePassword)
+	authRoutes.POST("/password/forgot", passwordHandler.ForgotPassword)
+	authRoutes.POST("/password/reset", passwordHandler.ResetPassword)
+	authRoutes.POST("/magic-link", magicLinkHandler.SendMagicLink)
+	authRoutes.POST("/magic-link/verify", magicLinkHandler.VerifyMagicLink)
+	authRoutes.GET("/device", deviceAuthorizationHandler.VerificationPage)
+	authRoutes.POST("/device/code", deviceAuthorizationHandler.DeviceCode)
+	authRoutes.POST("/device/verify", deviceAuthorizationHandler.Verify)
+	authRoutes.POST("/device/confirm", deviceAuthorizationHandler.Confirm)
+	authRoutes.POST("/device/token", deviceAuthorizationHandler.Token)
+	authRoutes.GET("/saml/:workspace", samlHandler.SignIn)
+	authRoutes.GET("/saml/:workspace/metadata", samlHandler.Metadata)
+	authRoutes.POST("/saml/:workspace/acs", samlHandler.AssertionConsumerService)
+
+	identityRoutes := authRoutes.Group("/identities")
+	identityRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RejectImpersonation())
+	{
+		identityRoutes.GET("", identityHandler.ListIdentities)
+		identityRoutes.POST("/:provider/link", identityHandler.LinkIdentity)
+		identityRoutes.DELETE("/:id", identityHandler.UnlinkIdentity)
+	}
+
+	authRoutes.POST("/mfa/verify", authMiddleware.RequireMFAChallenge(), mfaHandler.Verify)
+	mfaRoutes := authRoutes.Group("/mfa")
+	mfaRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AuthScope))
+	{
+		mfaRoutes.GET("", mfaHandler.GetStatus)
+		mfaRoutes.POST("/totp", mfaHandler.EnrolTOTP)
+		mfaRoutes.POST("/totp/confirm", mfaHandler.ConfirmTOTP)
+		mfaRoutes.DELETE("/totp", mfaHandler.DisableTOTP)
+		mfaRoutes.POST("/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	}
+
+	sessionRoutes := authRoutes.Group("/sessions")
+	sessionRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AuthScope))
+	{
+		sessionRoutes.GET("", sessionHandler.ListSessions)
+		sessionRoutes.DELETE("/:id", sessionHandler.RevokeSession)
+	}
+
+	tokenRoutes := authRoutes.Group("/tokens")
+	tokenRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AuthScope))
+	{
+		tokenRoutes.GET("", personalAccessTokenHandler.ListPersonalAccessTokens)
+		tokenRoutes.POST("", personalAccessTokenHandler.CreatePersonalAccessToken)
+		tokenRoutes.DELETE("/:id", personalAccessTokenHandler.RevokePersonalAccessToken)
+	}
+
+	invitationRoutes := authRoutes.Group("/invitations")
+	invitationRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AdminScope))
+	{
+		invitationRoutes.GET("", invitationHandler.ListInvitations)
+		invitationRoutes.POST("", invitationHandler.CreateInvitation)
+		invitationRoutes.DELETE("/:id", invitationHandler.RevokeInvitation)
+	}
+
+	impersonationRoutes := authRoutes.Group("/impersonations")
+	impersonationRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AdminScope))
+	{
+		impersonationRoutes.GET("", impersonationHandler.ListImpersonations)
+		impersonationRoutes.POST("", impersonationHandler.CreateImpersonation)
+		impersonationRoutes.GET("/:id/requests", impersonationHandler.ListImpersonationRequests)
+		impersonationRoutes.DELETE("/:id", impersonationHandler.EndImpersonation)
+	}
+
+	samlConnectionRoutes := authRoutes.Group("/saml/connections")
+	samlConnectionRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AdminScope))
+	{
+		samlConnectionRoutes.GET("", samlConnectionHandler.ListSAMLConnections)
+		samlConnectionRoutes.POST("", samlConnectionHandler.CreateSAMLConnection)
+		samlConnectionRoutes.PUT("/:id", samlConnectionHandler.UpdateSAMLConnection)
+		samlConnectionRoutes.DELETE("/:id", samlConnectionHandler.DeleteSAMLConnection)
+	}
+
+	scimTokenRoutes := authRoutes.Group("/scim/tokens")
+	scimTokenRoutes.Use(authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AdminScope))
+	{
+		scimTokenRoutes.GET("", scimTokenHandler.ListSCIMTokens)
+		scimTokenRoutes.POST("", scimTokenHandler.CreateSCIMToken)
+		scimTokenRoutes.DELETE("/:id", scimTokenHandler.RevokeSCIMToken)
+	}
 }
@@ -1,53 +1,211 @@
 package authentication
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+const (
+	AccessTokenTimeToLive  = 2 * time.Hour
+	RefreshTokenTimeToLive = 30 * 24 * time.Hour
+)
+
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+
+// RefreshToken is a single link in a rotation family. Only the hash of the
+// token is stored; the plain value is handed to the client once.
 type RefreshToken struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	ID        uuid.UUID
 	UserID    uuid.UUID
-	Token     string
+	FamilyID  uuid.UUID
+	SessionID uuid.UUID
+	TokenHash string
 	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
 	CreatedAt time.Time
 }
 
-// swap this string for something from env
-var jwtSecret = []byte("secret-key")
+// NewRefreshToken creates the next token in a family and returns it along with
+// the plain token value. Pass uuid.Nil to start a new family.
+func NewRefreshToken(userID uuid.UUID, familyID uuid.UUID, sessionID uuid.UUID, timeToLive time.Duration) (*RefreshToken, string, error) {
+	plainToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if familyID == uuid.Nil {
+		familyID = uuid.New()
+	}
+
+	refreshToken := &RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		SessionID: sessionID,
+		TokenHash: HashRefreshToken(plainToken),
+		ExpiresAt: time.Now().Add(timeToLive),
+	}
+	return refreshToken, plainToken, nil
+}
+
+func HashRefreshToken(plainToken string) string {
+	return hashOpaqueToken(plainToken)
+}
+
+func (refreshToken *RefreshToken) IsExpired() bool {
+	return time.Now().After(refreshToken.ExpiresAt)
+}
+
+// IsSpent reports whether the token has already been rotated or revoked.
+// Presenting a spent token means it has been replayed.
+func (refreshToken *RefreshToken) IsSpent() bool {
+	return refreshToken.UsedAt != nil || refreshToken.RevokedAt != nil
+}
+
+// generateOpaqueToken returns 256 random bits for tokens that are stored hashed.
+func generateOpaqueToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+func hashOpaqueToken(plainToken string) string {
+	hash := sha256.Sum256([]byte(plainToken))
+	return hex.EncodeToString(hash[:])
+}
+
+// AccessTokenClaims are the claims carried by the access tokens from GenerateJWT.
+type AccessTokenClaims struct {
+	Email     string       `json:"email"`
+	Scopes    string       `json:"scopes"`
+	SessionID string       `json:"sid,omitempty"`
+	Actor     *ActorClaims `json:"act,omitempty"`
+	// IssuedAtMicro repeats iat in microseconds, the precision of the
+	// revocation cut-off. iat alone cannot tell a token issued just before a
+	// cut-off from one issued just after it.
+	IssuedAtMicro int64 `json:"iat_us,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ActorClaims name the admin acting as the token's subject, in the form of the
+// RFC 8693 act claim.
+type ActorClaims struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+func (claims *AccessTokenClaims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(claims.Subject)
+}
+
+// Session returns the session the token was issued for, or uuid.Nil for tokens
+// that are not tied to one, such as personal access tokens.
+func (claims *AccessTokenClaims) Session() uuid.UUID {
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return uuid.Nil
+	}
+	return sessionID
+}
+
+// IssuedBefore reports whether the token was issued before cutoff. A token
+// with only the whole-second iat counts as issued before any cut-off in the
+// same second.
+func (claims *AccessTokenClaims) IssuedBefore(cutoff time.Time) bool {
+	if claims.IssuedAtMicro != 0 {
+		return time.UnixMicro(claims.IssuedAtMicro).Before(cutoff)
+	}
+	if claims.IssuedAt == nil {
+		return false
+	}
+	return claims.IssuedAt.Time.Before(cutoff.Truncate(time.Second).Add(time.Second))
+}
+
+func (claims *AccessTokenClaims) HasScopes(required ...string) bool {
+	return HasScopes(ParseScopes(claims.Scopes), required...)
+}
+
+// IsImpersonation reports whether an admin is acting as the token's subject.
+func (claims *AccessTokenClaims) IsImpersonation() bool {
+	return claims.Actor != nil
+}
+
+// ActorID returns the admin acting as the subject of an impersonation token.
+func (claims *AccessTokenClaims) ActorID() (uuid.UUID, error) {
+	if claims.Actor == nil {
+		return uuid.Nil, errors.New("token has no actor")
+	}
+	return uuid.Parse(claims.Actor.Subject)
+}
+
+// IsMFAChallenge reports whether the token only lets its holder submit a
+// second factor, rather than act as the user.
+func (claims *AccessTokenClaims) IsMFAChallenge() bool {
+	return claims.HasScopes(MFAScope)
+}
+
+func (keySet *KeySet) GenerateJWT(userID uuid.UUID, email string, scopes string, sessionID uuid.UUID, timeToLive time.Duration) (string, error) {
+	claims := newAccessTokenClaims(keySet.issuer, uuid.New(), userID, email, scopes, time.Now().Add(timeToLive))
+	if sessionID != uuid.Nil {
+		claims.SessionID = sessionID.String()
+	}
+
+	tokenString, err := keySet.sign(claims, accessTokenType)
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
 
-func GenerateJWT(userID uuid.UUID, email string, scopes string, timeToLive time.Duration) (string, error) {
-	claims := jwt.MapClaims{
-		"sub":    userID,
-		"email":  email,
-		"exp":    time.Now().Add(timeToLive).Unix(),
-		"scopes": scopes,
+// GenerateImpersonationJWT issues a token for the impersonated user that names
+// the admin in its act claim. Its ID is the impersonation's, so ending the
+// impersonation revokes it. It has no session and cannot be refreshed.
+func (keySet *KeySet) GenerateImpersonationJWT(impersonation *Impersonation, user *AuthUser, actor *AuthUser) (string, error) {
+	claims := newAccessTokenClaims(keySet.issuer, impersonation.ID, user.ID, user.Email, JoinScopes(ImpersonationScopes(user)), impersonation.ExpiresAt)
+	claims.Actor = &ActorClaims{
+		Subject: actor.ID.String(),
+		Email:   actor.Email,
 	}
 
-	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	tokenString, err := keySet.sign(claims, accessTokenType)
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func VerifyJWTToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// check algorithm
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+func newAccessTokenClaims(issuer string, tokenID uuid.UUID, userID uuid.UUID, email string, scopes string, expiresAt time.Time) AccessTokenClaims {
+	now := time.Now()
+	return AccessTokenClaims{
+		Email:         email,
+		Scopes:        scopes,
+		IssuedAtMicro: now.UnixMicro(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID.String(),
+			Issuer:    issuer,
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+}
+
+func (keySet *KeySet) VerifyJWTToken(tokenString string) (*AccessTokenClaims, error) {
+	token, err := keySet.parse(tokenString, &AccessTokenClaims{}, accessTokenType)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*AccessTokenClaims); ok && token.Valid {
 		return claims, nil
 	}
 	return nil, errors.New("invalid token")
@@ -0,0 +1,74 @@
+package authentication
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuthorizationCodeTimeToLive = time.Minute
+	CodeChallengeMethodS256     = "S256"
+)
+
+var ErrInvalidAuthorizationCode = errors.New("invalid authorization code")
+
+// codeChallengePattern matches a base64url SHA-256 digest, and
+// codeVerifierPattern the verifier described in RFC 7636 section 4.1.
+var (
+	codeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+	codeVerifierPattern  = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+)
+
+// AuthorizationCode is handed to the front-end after an OAuth sign-in in place
+// of the access token. It can be exchanged once, shortly after, by whoever
+// holds the PKCE verifier for CodeChallenge. Only the hash of the code is stored.
+type AuthorizationCode struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	SessionID     uuid.UUID
+	CodeHash      string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+func NewAuthorizationCode(userID uuid.UUID, sessionID uuid.UUID, codeChallenge string) (*AuthorizationCode, string, error) {
+	plainCode, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	authorizationCode := &AuthorizationCode{
+		UserID:        userID,
+		SessionID:     sessionID,
+		CodeHash:      HashAuthorizationCode(plainCode),
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     time.Now().Add(AuthorizationCodeTimeToLive),
+	}
+	return authorizationCode, plainCode, nil
+}
+
+func HashAuthorizationCode(plainCode string) string {
+	return hashOpaqueToken(plainCode)
+}
+
+// ValidCodeChallenge reports whether challenge is an S256 PKCE challenge. The
+// plain method is not supported.
+func ValidCodeChallenge(codeChallenge string, method string) bool {
+	return method == CodeChallengeMethodS256 && codeChallengePattern.MatchString(codeChallenge)
+}
+
+// VerifyCodeVerifier checks verifier against an S256 challenge.
+func VerifyCodeVerifier(codeChallenge string, codeVerifier string) bool {
+	if !codeVerifierPattern.MatchString(codeVerifier) {
+		return false
+	}
+	digest := sha256.Sum256([]byte(codeVerifier))
+	expected := base64.RawURLEncoding.EncodeToString(digest[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
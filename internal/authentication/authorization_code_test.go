@@ -0,0 +1,80 @@
+package authentication
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// The verifier and challenge of the RFC 7636 appendix B example.
+const (
+	rfc7636CodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfc7636CodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestValidCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name          string
+		codeChallenge string
+		method        string
+		valid         bool
+	}{
+		{"S256", rfc7636CodeChallenge, CodeChallengeMethodS256, true},
+		{"plain method", rfc7636CodeChallenge, "plain", false},
+		{"no method", rfc7636CodeChallenge, "", false},
+		{"too short", rfc7636CodeChallenge[:42], CodeChallengeMethodS256, false},
+		{"too long", rfc7636CodeChallenge + "A", CodeChallengeMethodS256, false},
+		{"padded", rfc7636CodeChallenge[:42] + "=", CodeChallengeMethodS256, false},
+		{"standard base64", strings.Replace(rfc7636CodeChallenge, "-", "+", 1), CodeChallengeMethodS256, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := ValidCodeChallenge(test.codeChallenge, test.method); valid != test.valid {
+				t.Errorf("ValidCodeChallenge(%q, %q) = %v, want %v", test.codeChallenge, test.method, valid, test.valid)
+			}
+		})
+	}
+}
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	tests := []struct {
+		name         string
+		codeVerifier string
+		valid        bool
+	}{
+		{"matching verifier", rfc7636CodeVerifier, true},
+		{"other verifier", strings.Repeat("a", 43), false},
+		{"the challenge itself", rfc7636CodeChallenge, false},
+		{"too short", rfc7636CodeVerifier[:42], false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"invalid characters", rfc7636CodeVerifier[:42] + "+", false},
+		{"empty", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := VerifyCodeVerifier(rfc7636CodeChallenge, test.codeVerifier); valid != test.valid {
+				t.Errorf("VerifyCodeVerifier(%q) = %v, want %v", test.codeVerifier, valid, test.valid)
+			}
+		})
+	}
+}
+
+func TestNewAuthorizationCodeStoresOnlyTheHash(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	authorizationCode, plainCode, err := NewAuthorizationCode(userID, sessionID, rfc7636CodeChallenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorizationCode.CodeHash == plainCode {
+		t.Error("CodeHash is the plain code")
+	}
+	if authorizationCode.CodeHash != HashAuthorizationCode(plainCode) {
+		t.Error("CodeHash is not the hash of the plain code")
+	}
+	if authorizationCode.UserID != userID || authorizationCode.SessionID != sessionID || authorizationCode.CodeChallenge != rfc7636CodeChallenge {
+		t.Errorf("NewAuthorizationCode = %+v, want it bound to the user, session and challenge", authorizationCode)
+	}
+}
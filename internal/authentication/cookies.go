@@ -0,0 +1,47 @@
+package authentication
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookies sets the cookies the authentication handlers keep in the browser.
+// They are HTTPS only when secure is set, which follows HttpConfig.IsProduction.
+type Cookies struct {
+	keySet *KeySet
+	secure bool
+}
+
+func NewCookies(keySet *KeySet, secure bool) *Cookies {
+	return &Cookies{
+		keySet: keySet,
+		secure: secure,
+	}
+}
+
+func (cookies *Cookies) setRefreshToken(ctx *gin.Context, plainRefreshToken string, expiresAt time.Time) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    plainRefreshToken,
+		Path:     "/auth",
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   cookies.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (cookies *Cookies) clearRefreshToken(ctx *gin.Context) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    "",
+		Path:     "/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   cookies.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
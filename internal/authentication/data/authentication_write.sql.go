@@ -9,6 +9,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createAuthUser = `-- name: CreateAuthUser :one
@@ -24,6 +25,10 @@ new_provider AS (
 new_user AS (
     INSERT INTO users (id, email, first_name, last_name)
     SELECT id, $1, $2, $3 FROM new_auth_user
+),
+new_role AS (
+    INSERT INTO user_roles (user_id, role)
+    SELECT id, 'user' FROM new_auth_user
 )
 SELECT id FROM new_auth_user
 `
@@ -50,14 +55,27 @@ func (q *Queries) CreateAuthUser(ctx context.Context, arg CreateAuthUserParams)
 }
 
 const findAuthUserByID = `-- name: FindAuthUserByID :one
-SELECT id, email, first_name, last_name, mobile_number, created_at, updated_at 
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.deactivated_at, auth_users.deletion_scheduled_at
 FROM users
-WHERE id = $1
+JOIN auth_users ON auth_users.id = users.id
+WHERE users.id = $1
 `
 
-func (q *Queries) FindAuthUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+type FindAuthUserByIDRow struct {
+	ID                  uuid.UUID
+	Email               string
+	FirstName           string
+	LastName            string
+	MobileNumber        *string
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	DeactivatedAt       pgtype.Timestamptz
+	DeletionScheduledAt pgtype.Timestamptz
+}
+
+func (q *Queries) FindAuthUserByID(ctx context.Context, id uuid.UUID) (FindAuthUserByIDRow, error) {
 	row := q.db.QueryRow(ctx, findAuthUserByID, id)
-	var i User
+	var i FindAuthUserByIDRow
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
@@ -66,18 +84,41 @@ func (q *Queries) FindAuthUserByID(ctx context.Context, id uuid.UUID) (User, err
 		&i.MobileNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeactivatedAt,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
 
+const findUnclaimedProvisionedUserID = `-- name: FindUnclaimedProvisionedUserID :one
+SELECT id
+FROM auth_users
+WHERE lower(email) = lower($1)
+  AND provisioned
+  AND password_hash IS NULL
+  AND NOT EXISTS (SELECT 1 FROM auth_user_providers WHERE auth_user_providers.user_id = auth_users.id)
+`
+
+func (q *Queries) FindUnclaimedProvisionedUserID(ctx context.Context, email string) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, findUnclaimedProvisionedUserID, email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const findUserIDByProvider = `-- name: FindUserIDByProvider :one
 SELECT user_id 
 FROM auth_user_providers 
-WHERE provider_user_id = $1
+WHERE provider = $1 AND provider_user_id = $2
 `
 
-func (q *Queries) FindUserIDByProvider(ctx context.Context, providerUserID string) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, findUserIDByProvider, providerUserID)
+type FindUserIDByProviderParams struct {
+	Provider       string
+	ProviderUserID string
+}
+
+func (q *Queries) FindUserIDByProvider(ctx context.Context, arg FindUserIDByProviderParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, findUserIDByProvider, arg.Provider, arg.ProviderUserID)
 	var user_id uuid.UUID
 	err := row.Scan(&user_id)
 	return user_id, err
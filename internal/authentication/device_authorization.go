@@ -0,0 +1,128 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DeviceCodeGrantType              = "urn:ietf:params:oauth:grant-type:device_code"
+	DeviceAuthorizationTimeToLive    = 15 * time.Minute
+	DeviceAuthorizationPollInterval  = 5 * time.Second
+	DeviceAuthorizationSlowDownDelay = 5 * time.Second
+
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationConsumed = "consumed"
+)
+
+// userCodeAlphabet leaves out vowels and easily confused characters, as
+// suggested in RFC 8628 section 6.1.
+const (
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+var (
+	ErrDeviceAuthorizationNotFound = errors.New("device authorization not found")
+	ErrUserCodeTaken               = errors.New("user code already in use")
+)
+
+// DeviceAuthorization tracks one RFC 8628 device flow. The device polls with
+// the device code, whose hash is stored, while the user approves it in a
+// browser with the short user code.
+type DeviceAuthorization struct {
+	ID             uuid.UUID
+	DeviceCodeHash string
+	UserCode       string
+	ClientID       string
+	Status         string
+	UserID         uuid.UUID
+	Interval       time.Duration
+	LastPolledAt   *time.Time
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+// NewDeviceAuthorization returns the authorization along with the plain device
+// code, which is handed to the device once.
+func NewDeviceAuthorization(clientID string) (*DeviceAuthorization, string, error) {
+	plainDeviceCode, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, "", err
+	}
+
+	deviceAuthorization := &DeviceAuthorization{
+		DeviceCodeHash: HashDeviceCode(plainDeviceCode),
+		UserCode:       userCode,
+		ClientID:       clientID,
+		Status:         DeviceAuthorizationPending,
+		Interval:       DeviceAuthorizationPollInterval,
+		ExpiresAt:      time.Now().Add(DeviceAuthorizationTimeToLive),
+	}
+	return deviceAuthorization, plainDeviceCode, nil
+}
+
+func HashDeviceCode(plainDeviceCode string) string {
+	return hashOpaqueToken(plainDeviceCode)
+}
+
+func generateUserCode() (string, error) {
+	var builder strings.Builder
+	alphabetLength := big.NewInt(int64(len(userCodeAlphabet)))
+	for range userCodeLength {
+		index, err := rand.Int(rand.Reader, alphabetLength)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteByte(userCodeAlphabet[index.Int64()])
+	}
+	return builder.String(), nil
+}
+
+// NormalizeUserCode accepts the code the way people type it: any case, with or
+// without the dash and spaces.
+func NormalizeUserCode(userCode string) string {
+	userCode = strings.ToUpper(userCode)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, userCode)
+}
+
+// FormatUserCode splits the code in half for display, e.g. WDJB-MJHT.
+func FormatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+func (deviceAuthorization *DeviceAuthorization) IsExpired() bool {
+	return time.Now().After(deviceAuthorization.ExpiresAt)
+}
+
+func (deviceAuthorization *DeviceAuthorization) IsPending() bool {
+	return deviceAuthorization.Status == DeviceAuthorizationPending && !deviceAuthorization.IsExpired()
+}
+
+// PolledTooSoon reports whether the device polled again before its interval
+// was up, in which case it should be told to slow down.
+func (deviceAuthorization *DeviceAuthorization) PolledTooSoon() bool {
+	if deviceAuthorization.LastPolledAt == nil {
+		return false
+	}
+	return time.Since(*deviceAuthorization.LastPolledAt) < deviceAuthorization.Interval
+}
@@ -0,0 +1,129 @@
+package authentication
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const deviceAuthorizationCSRFCookieName = "device_csrf"
+
+type deviceVerificationPage struct {
+	UserCode  string
+	CSRFToken string
+	Error     string
+}
+
+// deviceConfirmationPage shows which client asked for the code before the
+// user signs in to approve it, as RFC 8628 section 5.4 recommends, so a code
+// sent by someone else is not approved unseen.
+type deviceConfirmationPage struct {
+	UserCode  string
+	ClientID  string
+	CSRFToken string
+	Providers []EnabledProvider
+	Error     string
+}
+
+type deviceResultPage struct {
+	Title   string
+	Message string
+}
+
+var deviceVerificationTemplate = template.Must(template.New("deviceVerification").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Connect a device</title>
+</head>
+<body>
+<h1>Connect a device</h1>
+<p>Enter the code shown on your device.</p>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="/auth/device/verify">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<label for="user_code">Code</label>
+<input id="user_code" name="user_code" value="{{.UserCode}}" placeholder="XXXX-XXXX" autocomplete="off" autocapitalize="characters" required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+var deviceConfirmationTemplate = template.Must(template.New("deviceConfirmation").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Connect a device</title>
+</head>
+<body>
+<h1>Connect a device</h1>
+<p>A device calling itself <strong>{{.ClientID}}</strong> is asking to sign in to your account with the code <strong>{{.UserCode}}</strong>.</p>
+<p>Only continue if you started this yourself on a device in front of you. If someone sent you this code or link, close this page.</p>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="/auth/device/confirm">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<input type="hidden" name="user_code" value="{{.UserCode}}">
+{{range .Providers}}<button type="submit" name="provider" value="{{.Name}}">Sign in with {{.DisplayName}} to approve</button>
+{{end}}</form>
+<p><a href="/auth/device">This is not my device</a></p>
+</body>
+</html>
+`))
+
+var deviceResultTemplate = template.Must(template.New("deviceResult").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+func renderDeviceVerificationPage(ctx *gin.Context, status int, page deviceVerificationPage) {
+	renderDevicePage(ctx, status, deviceVerificationTemplate, page)
+}
+
+func renderDeviceConfirmationPage(ctx *gin.Context, status int, page deviceConfirmationPage) {
+	renderDevicePage(ctx, status, deviceConfirmationTemplate, page)
+}
+
+func renderDeviceResultPage(ctx *gin.Context, status int, title string, message string) {
+	renderDevicePage(ctx, status, deviceResultTemplate, deviceResultPage{Title: title, Message: message})
+}
+
+func renderDevicePage(ctx *gin.Context, status int, pageTemplate *template.Template, page any) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("X-Frame-Options", "DENY")
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Status(status)
+	_ = pageTemplate.Execute(ctx.Writer, page)
+}
+
+// setDeviceCSRFToken pairs the verification form with this browser, so another
+// site cannot submit a code of its choosing on the user's behalf.
+func (cookies *Cookies) setDeviceCSRFToken(ctx *gin.Context) (string, error) {
+	csrfToken, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     deviceAuthorizationCSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/auth/device",
+		MaxAge:   int(DeviceAuthorizationTimeToLive.Seconds()),
+		HttpOnly: true,
+		Secure:   cookies.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return csrfToken, nil
+}
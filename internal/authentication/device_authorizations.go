@@ -0,0 +1,380 @@
+package authentication
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/markbates/goth"
+)
+
+// maxUserCodeAttempts bounds retries when a generated user code collides with
+// an existing one.
+const maxUserCodeAttempts = 3
+
+// DeviceCodeApiDto is the device authorization response from RFC 8628 section 3.2.
+type DeviceCodeApiDto struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceTokenApiDto is the access token response from RFC 6749 section 5.1.
+type DeviceTokenApiDto struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type DeviceAuthorizationHandler struct {
+	repository  AuthenticationRepository
+	tokenIssuer *TokenIssuer
+	cookies     *Cookies
+	providers   []EnabledProvider
+	publicURL   string
+	logger      *log.Logger
+}
+
+func NewDeviceAuthorizationHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, cookies *Cookies, providers []EnabledProvider, publicURL string, logger *log.Logger) *DeviceAuthorizationHandler {
+	return &DeviceAuthorizationHandler{
+		repository:  authenticationRepo,
+		tokenIssuer: tokenIssuer,
+		cookies:     cookies,
+		providers:   providers,
+		publicURL:   publicURL,
+		logger:      logger,
+	}
+}
+
+// @Summary Start device authorization
+// @Description Starts an RFC 8628 device flow. The device shows the user code and verification URI, then polls /auth/device/token with the device code.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string true "Client identifier, e.g. the CLI name"
+// @Success 200 {object} DeviceCodeApiDto "Device and user codes"
+// @Failure 400 {object} map[string]string "OAuth error"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/device/code [post]
+func (handler *DeviceAuthorizationHandler) DeviceCode(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	clientID := ctx.PostForm("client_id")
+	if clientID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "client_id is required"})
+		return
+	}
+
+	var deviceAuthorization *DeviceAuthorization
+	var plainDeviceCode string
+	var err error
+	for range maxUserCodeAttempts {
+		deviceAuthorization, plainDeviceCode, err = NewDeviceAuthorization(clientID)
+		if err != nil {
+			break
+		}
+		deviceAuthorization.ID, err = handler.repository.CreateDeviceAuthorization(ctx.Request.Context(), deviceAuthorization)
+		if !errors.Is(err, ErrUserCodeTaken) {
+			break
+		}
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateDeviceAuthorization: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	verificationURI := handler.publicURL + "/auth/device"
+	userCode := FormatUserCode(deviceAuthorization.UserCode)
+	ctx.JSON(http.StatusOK, DeviceCodeApiDto{
+		DeviceCode:              plainDeviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(DeviceAuthorizationTimeToLive.Seconds()),
+		Interval:                int(deviceAuthorization.Interval.Seconds()),
+	})
+}
+
+// @Summary Device verification page
+// @Description HTML page where the user enters the code shown on their device.
+// @Tags auth
+// @Produce html
+// @Param user_code query string false "User code to prefill"
+// @Success 200 {string} string "Verification page"
+// @Router /auth/device [get]
+func (handler *DeviceAuthorizationHandler) VerificationPage(ctx *gin.Context) {
+	csrfToken, err := handler.cookies.setDeviceCSRFToken(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: setDeviceCSRFToken: %v", err)
+		renderDeviceResultPage(ctx, http.StatusInternalServerError, "Something went wrong", "Please try again.")
+		return
+	}
+
+	renderDeviceVerificationPage(ctx, http.StatusOK, deviceVerificationPage{
+		UserCode:  ctx.Query("user_code"),
+		CSRFToken: csrfToken,
+	})
+}
+
+// @Summary Submit device user code
+// @Description Checks the user code from the verification page and shows which client asked for it, with the providers to sign in with. Nothing is approved until the user confirms on that page.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param user_code formData string true "User code shown on the device"
+// @Param csrf_token formData string true "Token from the verification page"
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Verification page with an error"
+// @Router /auth/device/verify [post]
+func (handler *DeviceAuthorizationHandler) Verify(ctx *gin.Context) {
+	csrfToken, ok := handler.checkCSRFToken(ctx)
+	if !ok {
+		return
+	}
+
+	userCode := ctx.PostForm("user_code")
+	deviceAuthorization, ok := handler.findPending(ctx, userCode)
+	if !ok {
+		return
+	}
+	if deviceAuthorization == nil {
+		renderDeviceVerificationPage(ctx, http.StatusBadRequest, deviceVerificationPage{
+			UserCode:  userCode,
+			CSRFToken: csrfToken,
+			Error:     "That code is invalid or has expired. Check the code on your device.",
+		})
+		return
+	}
+
+	renderDeviceConfirmationPage(ctx, http.StatusOK, deviceConfirmationPage{
+		UserCode:  FormatUserCode(deviceAuthorization.UserCode),
+		ClientID:  deviceAuthorization.ClientID,
+		CSRFToken: csrfToken,
+		Providers: handler.providers,
+	})
+}
+
+// @Summary Confirm device sign-in
+// @Description Approves the code after the user has seen which client asked for it, by continuing to the chosen provider's login. Signing in there approves the device.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param user_code formData string true "User code shown on the device"
+// @Param provider formData string true "Provider to sign in with"
+// @Param csrf_token formData string true "Token from the verification page"
+// @Success 303 {string} string "Redirect to provider login"
+// @Failure 400 {string} string "Verification or confirmation page with an error"
+// @Router /auth/device/confirm [post]
+func (handler *DeviceAuthorizationHandler) Confirm(ctx *gin.Context) {
+	csrfToken, ok := handler.checkCSRFToken(ctx)
+	if !ok {
+		return
+	}
+
+	userCode := ctx.PostForm("user_code")
+	deviceAuthorization, ok := handler.findPending(ctx, userCode)
+	if !ok {
+		return
+	}
+	if deviceAuthorization == nil {
+		renderDeviceVerificationPage(ctx, http.StatusBadRequest, deviceVerificationPage{
+			UserCode:  userCode,
+			CSRFToken: csrfToken,
+			Error:     "That code is invalid or has expired. Check the code on your device.",
+		})
+		return
+	}
+
+	provider := ctx.PostForm("provider")
+	if _, err := goth.GetProvider(provider); err != nil {
+		renderDeviceConfirmationPage(ctx, http.StatusBadRequest, deviceConfirmationPage{
+			UserCode:  FormatUserCode(deviceAuthorization.UserCode),
+			ClientID:  deviceAuthorization.ClientID,
+			CSRFToken: csrfToken,
+			Providers: handler.providers,
+			Error:     "Choose a provider to sign in with.",
+		})
+		return
+	}
+
+	intent := NewSignInIntent(SignInIntentDeviceAuthorization, provider, uuid.Nil)
+	intent.DeviceAuthorizationID = deviceAuthorization.ID.String()
+	err := handler.cookies.setSignInIntent(ctx, intent)
+	if err != nil {
+		handler.logger.Printf("ERROR: setSignInIntent: %v", err)
+		renderDeviceResultPage(ctx, http.StatusInternalServerError, "Something went wrong", "Please try again.")
+		return
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/auth/"+provider)
+}
+
+// checkCSRFToken compares the form's csrf_token with the cookie set by the
+// verification page, rendering an error page when they differ.
+func (handler *DeviceAuthorizationHandler) checkCSRFToken(ctx *gin.Context) (string, bool) {
+	csrfCookie, err := ctx.Cookie(deviceAuthorizationCSRFCookieName)
+	csrfToken := ctx.PostForm("csrf_token")
+	if err != nil || csrfCookie == "" || subtle.ConstantTimeCompare([]byte(csrfCookie), []byte(csrfToken)) != 1 {
+		renderDeviceResultPage(ctx, http.StatusBadRequest, "Session expired", "Please reload the page and enter your code again.")
+		return "", false
+	}
+	return csrfToken, true
+}
+
+// findPending looks up the pending device authorization for a user code. It
+// returns nil when there is none, and false after rendering an error page.
+func (handler *DeviceAuthorizationHandler) findPending(ctx *gin.Context, userCode string) (*DeviceAuthorization, bool) {
+	deviceAuthorization, err := handler.repository.FindDeviceAuthorizationByUserCode(ctx.Request.Context(), NormalizeUserCode(userCode))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindDeviceAuthorizationByUserCode: %v", err)
+		renderDeviceResultPage(ctx, http.StatusInternalServerError, "Something went wrong", "Please try again.")
+		return nil, false
+	}
+	if deviceAuthorization == nil || !deviceAuthorization.IsPending() {
+		return nil, true
+	}
+	return deviceAuthorization, true
+}
+
+// @Summary Poll for device tokens
+// @Description Token endpoint for the RFC 8628 device flow. Returns authorization_pending until the user approves the device, then the same access and refresh tokens as a browser sign-in, once. Users with an authenticator app get mfa_required with an mfa_token for /auth/mfa/verify instead.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "urn:ietf:params:oauth:grant-type:device_code"
+// @Param device_code formData string true "Device code"
+// @Param client_id formData string false "Client identifier used to request the code"
+// @Success 200 {object} DeviceTokenApiDto "Tokens"
+// @Failure 400 {object} map[string]string "OAuth error, e.g. authorization_pending, slow_down, mfa_required or access_denied for a deactivated account"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/device/token [post]
+func (handler *DeviceAuthorizationHandler) Token(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	if ctx.PostForm("grant_type") != DeviceCodeGrantType {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	plainDeviceCode := ctx.PostForm("device_code")
+	if plainDeviceCode == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "device_code is required"})
+		return
+	}
+
+	deviceAuthorization, err := handler.repository.FindDeviceAuthorizationByDeviceCode(ctx.Request.Context(), HashDeviceCode(plainDeviceCode))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindDeviceAuthorizationByDeviceCode: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	clientID := ctx.PostForm("client_id")
+	if deviceAuthorization == nil || (clientID != "" && clientID != deviceAuthorization.ClientID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		return
+	}
+	if deviceAuthorization.IsExpired() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "expired_token"})
+		return
+	}
+
+	switch deviceAuthorization.Status {
+	case DeviceAuthorizationPending:
+		handler.pending(ctx, deviceAuthorization)
+	case DeviceAuthorizationApproved:
+		handler.issue(ctx, deviceAuthorization)
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+	}
+}
+
+// pending answers a poll before approval, asking devices that poll faster than
+// their interval to slow down as RFC 8628 section 3.5 describes.
+func (handler *DeviceAuthorizationHandler) pending(ctx *gin.Context, deviceAuthorization *DeviceAuthorization) {
+	interval := deviceAuthorization.Interval
+	tooSoon := deviceAuthorization.PolledTooSoon()
+	if tooSoon {
+		interval += DeviceAuthorizationSlowDownDelay
+	}
+
+	err := handler.repository.RecordDeviceAuthorizationPoll(ctx.Request.Context(), deviceAuthorization.ID, interval)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRecordDeviceAuthorizationPoll: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	if tooSoon {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "slow_down", "interval": int(interval.Seconds())})
+		return
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "authorization_pending"})
+}
+
+func (handler *DeviceAuthorizationHandler) issue(ctx *gin.Context, deviceAuthorization *DeviceAuthorization) {
+	userID, err := handler.repository.ConsumeDeviceAuthorization(ctx.Request.Context(), deviceAuthorization.ID)
+	if errors.Is(err, ErrDeviceAuthorizationNotFound) {
+		// another poll with the same device code got there first
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryConsumeDeviceAuthorization: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	sessionID, err := startSession(ctx, handler.tokenIssuer, userID, SessionProviderDevice)
+	if errors.Is(err, ErrAccountDeactivated) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "access_denied", "error_description": ErrAccountDeactivated.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: startSession: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Issue(ctx.Request.Context(), userID, sessionID)
+	if errors.Is(err, ErrMFARequired) {
+		handler.challengeMFA(ctx, userID, sessionID)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssue: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, DeviceTokenApiDto{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(AccessTokenTimeToLive.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// challengeMFA hands the device the token it needs to submit a code from the
+// user's authenticator app, since the device flow has no browser to ask in.
+func (handler *DeviceAuthorizationHandler) challengeMFA(ctx *gin.Context, userID uuid.UUID, sessionID uuid.UUID) {
+	mfaToken, err := handler.tokenIssuer.IssueMFAChallenge(ctx.Request.Context(), userID, sessionID)
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssueMFAChallenge: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	ctx.JSON(http.StatusBadRequest, gin.H{
+		"error":             "mfa_required",
+		"error_description": "submit a code from the authenticator app to /auth/mfa/verify",
+		"mfa_token":         mfaToken,
+		"expires_in":        int(MFAChallengeTimeToLive.Seconds()),
+	})
+}
@@ -0,0 +1,137 @@
+package authentication
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/markbates/goth"
+	"github.com/markbates/goth/providers/github"
+)
+
+const testDeviceCSRFToken = "csrf-token"
+
+type deviceVerificationTest struct {
+	repository          *fakeAuthenticationRepository
+	router              *gin.Engine
+	deviceAuthorization *DeviceAuthorization
+}
+
+func newDeviceVerificationTest(t *testing.T) *deviceVerificationTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	goth.UseProviders(github.New("client", "secret", "http://api.test/auth/github/callback"))
+	t.Cleanup(goth.ClearProviders)
+
+	deviceAuthorization, _, err := NewDeviceAuthorization("living-room-tv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := &deviceVerificationTest{
+		repository:          newFakeAuthenticationRepository(),
+		router:              gin.New(),
+		deviceAuthorization: deviceAuthorization,
+	}
+	test.repository.deviceAuthorizations[deviceAuthorization.UserCode] = deviceAuthorization
+
+	keySet := newTestKeySet(t)
+	providers := []EnabledProvider{{Name: "github", DisplayName: "GitHub", LoginURL: "/auth/github"}}
+	handler := NewDeviceAuthorizationHandler(test.repository, NewTokenIssuer(test.repository, keySet), NewCookies(keySet, false), providers, "http://api.test", log.New(io.Discard, "", 0))
+	test.router.POST("/auth/device/verify", handler.Verify)
+	test.router.POST("/auth/device/confirm", handler.Confirm)
+	return test
+}
+
+func (test *deviceVerificationTest) post(path string, form url.Values) *httptest.ResponseRecorder {
+	form.Set("csrf_token", testDeviceCSRFToken)
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.AddCookie(&http.Cookie{Name: deviceAuthorizationCSRFCookieName, Value: testDeviceCSRFToken})
+	test.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func signInIntentCookie(recorder *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == SignInIntentCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// Entering a code only shows which client asked for it, so a code someone
+// else sent cannot be approved without the user seeing that first.
+func TestVerifyShowsClientBeforeSignIn(t *testing.T) {
+	test := newDeviceVerificationTest(t)
+	userCode := FormatUserCode(test.deviceAuthorization.UserCode)
+
+	recorder := test.post("/auth/device/verify", url.Values{"user_code": {strings.ToLower(userCode)}, "provider": {"github"}})
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("verify = %d %s, want 200", recorder.Code, recorder.Body)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, "living-room-tv") || !strings.Contains(body, userCode) {
+		t.Errorf("confirmation page does not show the client and code:\n%s", body)
+	}
+	if !strings.Contains(body, `action="/auth/device/confirm"`) {
+		t.Errorf("confirmation page does not post to /auth/device/confirm:\n%s", body)
+	}
+	if signInIntentCookie(recorder) != nil {
+		t.Error("verify set a sign-in intent before the user confirmed")
+	}
+}
+
+func TestConfirmContinuesToProviderLogin(t *testing.T) {
+	test := newDeviceVerificationTest(t)
+
+	recorder := test.post("/auth/device/confirm", url.Values{"user_code": {FormatUserCode(test.deviceAuthorization.UserCode)}, "provider": {"github"}})
+
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/auth/github" {
+		t.Fatalf("confirm = %d to %q, want 303 to /auth/github", recorder.Code, recorder.Header().Get("Location"))
+	}
+	if signInIntentCookie(recorder) == nil {
+		t.Error("confirm set no sign-in intent")
+	}
+}
+
+func TestDeviceVerificationRejectsUnusableCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		prepare func(test *deviceVerificationTest)
+	}{
+		{"verify unknown code", "/auth/device/verify", func(test *deviceVerificationTest) {
+			delete(test.repository.deviceAuthorizations, test.deviceAuthorization.UserCode)
+		}},
+		{"verify expired code", "/auth/device/verify", func(test *deviceVerificationTest) {
+			test.deviceAuthorization.ExpiresAt = time.Now().Add(-time.Minute)
+		}},
+		{"confirm approved code", "/auth/device/confirm", func(test *deviceVerificationTest) {
+			test.deviceAuthorization.Status = DeviceAuthorizationApproved
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verification := newDeviceVerificationTest(t)
+			test.prepare(verification)
+
+			recorder := verification.post(test.path, url.Values{"user_code": {verification.deviceAuthorization.UserCode}, "provider": {"github"}})
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s = %d, want 400", test.path, recorder.Code)
+			}
+			if signInIntentCookie(recorder) != nil {
+				t.Errorf("%s set a sign-in intent for an unusable code", test.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package authentication
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const expiredTokenSweepInterval = time.Hour
+
+// ExpiredTokenSweeper deletes revoked token entries, refresh tokens,
+// single-use codes and links and unfinished gothic sessions once they have
+// expired, so those tables do not grow for as long as the service runs. Several instances can run side by
+// side.
+type ExpiredTokenSweeper struct {
+	repository AuthenticationRepository
+	logger     *log.Logger
+}
+
+func NewExpiredTokenSweeper(repository AuthenticationRepository, logger *log.Logger) *ExpiredTokenSweeper {
+	return &ExpiredTokenSweeper{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// Run sweeps expired tokens now and then every hour, for as long as the
+// process runs.
+func (sweeper *ExpiredTokenSweeper) Run() {
+	sweeper.sweep(context.Background())
+
+	ticker := time.NewTicker(expiredTokenSweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		sweeper.sweep(context.Background())
+	}
+}
+
+func (sweeper *ExpiredTokenSweeper) sweep(ctx context.Context) {
+	deletedRows, err := sweeper.repository.DeleteExpiredTokens(ctx)
+	if err != nil {
+		sweeper.logger.Printf("ERROR: repositoryDeleteExpiredTokens: %v", err)
+	}
+	for table, count := range deletedRows {
+		if count > 0 {
+			sweeper.logger.Printf("INFO: deleted %d expired rows from %s", count, table)
+		}
+	}
+}
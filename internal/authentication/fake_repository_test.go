@@ -0,0 +1,339 @@
+package authentication
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/markbates/goth"
+)
+
+// fakeAuthenticationRepository keeps just enough state in memory for the
+// handlers under test. Calling a method it does not override panics on the
+// nil embedded interface, which points at what a new test needs.
+type fakeAuthenticationRepository struct {
+	AuthenticationRepository
+	authUsers          map[uuid.UUID]*AuthUser
+	identities         map[string]uuid.UUID
+	invitations        map[string]bool
+	magicLinks         map[uuid.UUID]*fakeMagicLink
+	sessions           map[uuid.UUID]*Session
+	authorizationCodes []*AuthorizationCode
+	// refreshTokens are keyed by their hash
+	refreshTokens   map[string]*RefreshToken
+	samlConnections map[string]*SAMLConnection
+	samlRequests    map[uuid.UUID]*SAMLRequest
+	// personalAccessTokens are keyed by their hash
+	personalAccessTokens map[string]*PersonalAccessToken
+	passwordHashes       map[uuid.UUID]string
+	tokensRevokedAt      map[uuid.UUID]time.Time
+	// impersonationRequests is the audit log of every impersonation
+	impersonationRequests []*ImpersonationRequest
+	// deviceAuthorizations are keyed by their user code
+	deviceAuthorizations map[string]*DeviceAuthorization
+}
+
+type fakeMagicLink struct {
+	email     string
+	expiresAt time.Time
+	used      bool
+}
+
+func newFakeAuthenticationRepository() *fakeAuthenticationRepository {
+	return &fakeAuthenticationRepository{
+		authUsers:       map[uuid.UUID]*AuthUser{},
+		identities:      map[string]uuid.UUID{},
+		invitations:     map[string]bool{},
+		magicLinks:      map[uuid.UUID]*fakeMagicLink{},
+		sessions:        map[uuid.UUID]*Session{},
+		refreshTokens:   map[string]*RefreshToken{},
+		samlConnections: map[string]*SAMLConnection{},
+		samlRequests:    map[uuid.UUID]*SAMLRequest{},
+
+		personalAccessTokens: map[string]*PersonalAccessToken{},
+		passwordHashes:       map[uuid.UUID]string{},
+		tokensRevokedAt:      map[uuid.UUID]time.Time{},
+		deviceAuthorizations: map[string]*DeviceAuthorization{},
+	}
+}
+
+func (repository *fakeAuthenticationRepository) FindAuthUserByID(ctx context.Context, id uuid.UUID) (*AuthUser, error) {
+	return repository.authUsers[id], nil
+}
+
+func (repository *fakeAuthenticationRepository) FindPasswordCredentials(ctx context.Context, email string) (*PasswordCredentials, error) {
+	for _, authUser := range repository.authUsers {
+		if strings.EqualFold(authUser.Email, email) {
+			return &PasswordCredentials{UserID: authUser.ID}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindUserIDByProvider(ctx context.Context, provider string, providerUserID string) (uuid.UUID, error) {
+	return repository.identities[provider+" "+providerUserID], nil
+}
+
+func (repository *fakeAuthenticationRepository) RegisterAuthUser(ctx context.Context, gothUser goth.User) (uuid.UUID, error) {
+	authUserID, err := repository.RegisterEmailAuthUser(ctx, &AuthUser{Email: gothUser.Email, FirstName: gothUser.FirstName, LastName: gothUser.LastName})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	repository.identities[gothUser.Provider+" "+gothUser.UserID] = authUserID
+	return authUserID, nil
+}
+
+func (repository *fakeAuthenticationRepository) LinkAuthProvider(ctx context.Context, userID uuid.UUID, gothUser goth.User) (uuid.UUID, error) {
+	repository.identities[gothUser.Provider+" "+gothUser.UserID] = userID
+	return uuid.New(), nil
+}
+
+func (repository *fakeAuthenticationRepository) FindUnclaimedProvisionedUserID(ctx context.Context, email string) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+
+func (repository *fakeAuthenticationRepository) RegisterEmailAuthUser(ctx context.Context, authUser *AuthUser) (uuid.UUID, error) {
+	credentials, _ := repository.FindPasswordCredentials(ctx, authUser.Email)
+	if credentials != nil {
+		return uuid.Nil, ErrEmailTaken
+	}
+	registered := *authUser
+	registered.ID = uuid.New()
+	registered.Roles = []string{RoleUser}
+	repository.authUsers[registered.ID] = &registered
+	return registered.ID, nil
+}
+
+func (repository *fakeAuthenticationRepository) HasInvitation(ctx context.Context, email string) (bool, error) {
+	return repository.invitations[strings.ToLower(email)], nil
+}
+
+func (repository *fakeAuthenticationRepository) AcceptInvitation(ctx context.Context, email string) error {
+	delete(repository.invitations, strings.ToLower(email))
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) CreateMagicLink(ctx context.Context, magicLink *MagicLink) (uuid.UUID, error) {
+	magicLinkID := uuid.New()
+	repository.magicLinks[magicLinkID] = &fakeMagicLink{email: magicLink.Email, expiresAt: magicLink.ExpiresAt}
+	return magicLinkID, nil
+}
+
+func (repository *fakeAuthenticationRepository) ConsumeMagicLink(ctx context.Context, magicLinkID uuid.UUID) (string, error) {
+	magicLink, ok := repository.magicLinks[magicLinkID]
+	if !ok || magicLink.used || !magicLink.expiresAt.After(time.Now()) {
+		return "", ErrInvalidMagicLink
+	}
+	magicLink.used = true
+	return magicLink.email, nil
+}
+
+func (repository *fakeAuthenticationRepository) CreateSession(ctx context.Context, session *Session) (uuid.UUID, error) {
+	session.ID = uuid.New()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	repository.sessions[session.ID] = session
+	return session.ID, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindActiveSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (*Session, error) {
+	session, ok := repository.sessions[sessionID]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return nil, nil
+	}
+	return session, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindTOTPCredential(ctx context.Context, userID uuid.UUID) (*TOTPCredential, error) {
+	return nil, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindMFAChallenge(ctx context.Context, sessionID uuid.UUID) (*MFAChallenge, error) {
+	return nil, nil
+}
+
+func (repository *fakeAuthenticationRepository) CreateRefreshToken(ctx context.Context, refreshToken *RefreshToken) (uuid.UUID, error) {
+	refreshToken.ID = uuid.New()
+	stored := *refreshToken
+	repository.refreshTokens[refreshToken.TokenHash] = &stored
+	return refreshToken.ID, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	refreshToken, ok := repository.refreshTokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	found := *refreshToken
+	return &found, nil
+}
+
+func (repository *fakeAuthenticationRepository) RotateRefreshToken(ctx context.Context, current *RefreshToken, next *RefreshToken) error {
+	stored := repository.refreshTokens[current.TokenHash]
+	if stored == nil || stored.IsSpent() {
+		return ErrRefreshTokenReused
+	}
+	usedAt := time.Now()
+	stored.UsedAt = &usedAt
+	_, err := repository.CreateRefreshToken(ctx, next)
+	return err
+}
+
+func (repository *fakeAuthenticationRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	revokedAt := time.Now()
+	for _, refreshToken := range repository.refreshTokens {
+		if refreshToken.FamilyID == familyID && refreshToken.RevokedAt == nil {
+			refreshToken.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) CreateAuthorizationCode(ctx context.Context, authorizationCode *AuthorizationCode) (uuid.UUID, error) {
+	authorizationCode.ID = uuid.New()
+	repository.authorizationCodes = append(repository.authorizationCodes, authorizationCode)
+	return authorizationCode.ID, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindSAMLConnectionByWorkspace(ctx context.Context, workspace string) (*SAMLConnection, error) {
+	return repository.samlConnections[workspace], nil
+}
+
+func (repository *fakeAuthenticationRepository) CreateSAMLRequest(ctx context.Context, request *SAMLRequest) (uuid.UUID, error) {
+	requestID := uuid.New()
+	repository.samlRequests[requestID] = request
+	return requestID, nil
+}
+
+func (repository *fakeAuthenticationRepository) ConsumeSAMLRequest(ctx context.Context, requestID uuid.UUID, connectionID uuid.UUID) (*SAMLRequest, error) {
+	request, ok := repository.samlRequests[requestID]
+	if !ok || request.ConnectionID != connectionID || !request.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidSAMLRequest
+	}
+	delete(repository.samlRequests, requestID)
+	return request, nil
+}
+
+func (repository *fakeAuthenticationRepository) CreatePersonalAccessToken(ctx context.Context, personalAccessToken *PersonalAccessToken) (uuid.UUID, error) {
+	personalAccessToken.ID = uuid.New()
+	personalAccessToken.CreatedAt = time.Now()
+	repository.personalAccessTokens[personalAccessToken.TokenHash] = personalAccessToken
+	return personalAccessToken.ID, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (*PersonalAccessToken, error) {
+	return repository.personalAccessTokens[tokenHash], nil
+}
+
+func (repository *fakeAuthenticationRepository) TouchPersonalAccessToken(ctx context.Context, personalAccessTokenID uuid.UUID) error {
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) FindPasswordHash(ctx context.Context, userID uuid.UUID) (string, error) {
+	return repository.passwordHashes[userID], nil
+}
+
+func (repository *fakeAuthenticationRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	repository.passwordHashes[userID] = passwordHash
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) RevokeRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+// fakeRevocationStore revokes nothing and records which users were signed out.
+type fakeRevocationStore struct {
+	RevocationStore
+	revokedUsers []uuid.UUID
+}
+
+func (store *fakeRevocationStore) IsRevoked(ctx context.Context, claims *AccessTokenClaims) (bool, error) {
+	return false, nil
+}
+
+func (store *fakeRevocationStore) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keepSessionID uuid.UUID) error {
+	store.revokedUsers = append(store.revokedUsers, userID)
+	return nil
+}
+
+func (store *fakeRevocationStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	store.revokedUsers = append(store.revokedUsers, userID)
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) TouchSession(ctx context.Context, sessionID uuid.UUID) error {
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) RevokeAllTokensForUser(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	tokensRevokedAt := time.Now()
+	repository.tokensRevokedAt[userID] = tokensRevokedAt
+	return tokensRevokedAt, nil
+}
+
+func (repository *fakeAuthenticationRepository) FindTokenRevocationState(ctx context.Context, jti uuid.UUID, sessionID uuid.UUID, userID uuid.UUID) (*TokenRevocationState, error) {
+	if _, found := repository.authUsers[userID]; !found {
+		return &TokenRevocationState{UserDeleted: true}, nil
+	}
+	state := &TokenRevocationState{}
+	if session, found := repository.sessions[sessionID]; found {
+		state.SessionRevoked = session.RevokedAt != nil
+	}
+	if tokensRevokedAt, found := repository.tokensRevokedAt[userID]; found {
+		state.TokensRevokedAt = &tokensRevokedAt
+	}
+	return state, nil
+}
+
+func (repository *fakeAuthenticationRepository) RevokeSessionsForUser(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+	for _, session := range repository.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) RevokePersonalAccessToken(ctx context.Context, userID uuid.UUID, personalAccessTokenID uuid.UUID) error {
+	for _, personalAccessToken := range repository.personalAccessTokens {
+		if personalAccessToken.ID == personalAccessTokenID && personalAccessToken.UserID == userID && !personalAccessToken.IsRevoked() {
+			now := time.Now()
+			personalAccessToken.RevokedAt = &now
+			return nil
+		}
+	}
+	return ErrPersonalAccessTokenNotFound
+}
+
+func (repository *fakeAuthenticationRepository) RevokePersonalAccessTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+	for _, personalAccessToken := range repository.personalAccessTokens {
+		if personalAccessToken.UserID == userID && !personalAccessToken.IsRevoked() {
+			personalAccessToken.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) CreateImpersonationRequest(ctx context.Context, request *ImpersonationRequest) (uuid.UUID, error) {
+	request.ID = uuid.New()
+	repository.impersonationRequests = append(repository.impersonationRequests, request)
+	return request.ID, nil
+}
+
+func (repository *fakeAuthenticationRepository) RecordImpersonationResponse(ctx context.Context, requestID uuid.UUID, statusCode int) error {
+	for _, request := range repository.impersonationRequests {
+		if request.ID == requestID {
+			request.StatusCode = &statusCode
+		}
+	}
+	return nil
+}
+
+func (repository *fakeAuthenticationRepository) FindDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*DeviceAuthorization, error) {
+	return repository.deviceAuthorizations[userCode], nil
+}
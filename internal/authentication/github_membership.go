@@ -0,0 +1,114 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const githubMembershipTimeout = 10 * time.Second
+
+// GitHubMembershipClient asks the GitHub API whether a user belongs to an
+// organization or team, using the user's own OAuth token. The base URL is
+// configurable for GitHub Enterprise and for tests.
+type GitHubMembershipClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewGitHubMembershipClient(baseURL string, httpClient *http.Client) *GitHubMembershipClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: githubMembershipTimeout}
+	}
+	return &GitHubMembershipClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+type githubMembership struct {
+	State string `json:"state"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// IsOrganizationMember reports whether the token's user is an active member of
+// the organization. Pending invitations do not count.
+func (client *GitHubMembershipClient) IsOrganizationMember(ctx context.Context, accessToken string, organization string) (bool, error) {
+	path := fmt.Sprintf("/user/memberships/orgs/%s", url.PathEscape(organization))
+	return client.isActiveMember(ctx, accessToken, path)
+}
+
+// IsTeamMember reports whether login is an active member of the team. The
+// token's user must be able to see the team, which members always can.
+func (client *GitHubMembershipClient) IsTeamMember(ctx context.Context, accessToken string, organization string, teamSlug string, login string) (bool, error) {
+	path := fmt.Sprintf("/orgs/%s/teams/%s/memberships/%s", url.PathEscape(organization), url.PathEscape(teamSlug), url.PathEscape(login))
+	return client.isActiveMember(ctx, accessToken, path)
+}
+
+// IsVerifiedPrimaryEmail reports whether email is the token's user's primary
+// email address and GitHub has verified it. The profile email GitHub hands out
+// at sign-in is not necessarily either.
+func (client *GitHubMembershipClient) IsVerifiedPrimaryEmail(ctx context.Context, accessToken string, email string) (bool, error) {
+	var emails []githubEmail
+	found, err := client.get(ctx, accessToken, "/user/emails", &emails)
+	if err != nil || !found {
+		return false, err
+	}
+	for _, githubEmail := range emails {
+		if githubEmail.Primary && githubEmail.Verified && strings.EqualFold(githubEmail.Email, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (client *GitHubMembershipClient) isActiveMember(ctx context.Context, accessToken string, path string) (bool, error) {
+	var membership githubMembership
+	found, err := client.get(ctx, accessToken, path, &membership)
+	if err != nil || !found {
+		return false, err
+	}
+	return membership.State == "active", nil
+}
+
+// get decodes the response to a GET of path into value. It returns false if
+// GitHub does not let the token see the resource.
+func (client *GitHubMembershipClient) get(ctx context.Context, accessToken string, path string, value any) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, client.baseURL+path, nil)
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	// GitHub answers 404 rather than 403 for organizations the user cannot see
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("github request %s: unexpected status %d", path, response.StatusCode)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(value)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
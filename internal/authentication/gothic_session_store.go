@@ -0,0 +1,185 @@
+package authentication
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"catalyst.api/config"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	SessionStoreCookie   = "cookie"
+	SessionStorePostgres = "postgres"
+)
+
+// LoadSessionKeyPairs reads the gothic session keys named in the config, in the
+// hash key, block key order gorilla/sessions expects. Outside of production a
+// missing key is replaced with a throwaway one, so sign-ins in progress do not
+// survive a restart.
+func LoadSessionKeyPairs(cfg *config.Config) ([][]byte, error) {
+	sessionKeys := cfg.AuthenticationConfig.SessionKeys
+	if len(sessionKeys) == 0 {
+		if cfg.HttpConfig.IsProduction {
+			return nil, errors.New("SESSION_KEYS must be set in production")
+		}
+		return [][]byte{securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32)}, nil
+	}
+
+	keyPairs := make([][]byte, 0, len(sessionKeys)*2)
+	for index, sessionKey := range sessionKeys {
+		hashKey, err := base64.StdEncoding.DecodeString(sessionKey.HashKey)
+		if err != nil {
+			return nil, fmt.Errorf("SESSION_KEYS entry %d: hash key: %w", index, err)
+		}
+		if len(hashKey) < 32 {
+			return nil, fmt.Errorf("SESSION_KEYS entry %d: hash key must be at least 32 bytes", index)
+		}
+
+		var blockKey []byte
+		if sessionKey.BlockKey != "" {
+			blockKey, err = base64.StdEncoding.DecodeString(sessionKey.BlockKey)
+			if err != nil {
+				return nil, fmt.Errorf("SESSION_KEYS entry %d: block key: %w", index, err)
+			}
+			if len(blockKey) != 16 && len(blockKey) != 24 && len(blockKey) != 32 {
+				return nil, fmt.Errorf("SESSION_KEYS entry %d: block key must be 16, 24 or 32 bytes", index)
+			}
+		}
+		keyPairs = append(keyPairs, hashKey, blockKey)
+	}
+	return keyPairs, nil
+}
+
+// newGothicSessionStore builds the store named by SESSION_STORE, with cookie
+// flags that follow HttpConfig.IsProduction.
+func newGothicSessionStore(cfg *config.Config, authenticationRepo AuthenticationRepository, keyPairs [][]byte) (sessions.Store, error) {
+	options := &sessions.Options{
+		Path:     "/",
+		MaxAge:   MaxAge,
+		HttpOnly: true,
+		Secure:   cfg.HttpConfig.IsProduction,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	switch cfg.AuthenticationConfig.SessionStore {
+	case "", SessionStoreCookie:
+		store := sessions.NewCookieStore(keyPairs...)
+		store.Options = options
+		store.MaxAge(options.MaxAge)
+		return store, nil
+	case SessionStorePostgres:
+		store := NewPostgresSessionStore(authenticationRepo, options, keyPairs...)
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE %q, expected %s or %s", cfg.AuthenticationConfig.SessionStore, SessionStoreCookie, SessionStorePostgres)
+	}
+}
+
+// PostgresSessionStore keeps gothic sessions in the gothic_sessions table, so a
+// sign-in started on one instance can finish on another, and survives a
+// restart. The cookie only carries the signed session ID.
+type PostgresSessionStore struct {
+	repository AuthenticationRepository
+	codecs     []securecookie.Codec
+	Options    *sessions.Options
+}
+
+func NewPostgresSessionStore(authenticationRepo AuthenticationRepository, options *sessions.Options, keyPairs ...[]byte) *PostgresSessionStore {
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		if secureCookie, ok := codec.(*securecookie.SecureCookie); ok {
+			secureCookie.MaxAge(options.MaxAge)
+			// values are stored in the database, not the cookie, so the cookie size limit does not apply
+			secureCookie.MaxLength(0)
+		}
+	}
+	return &PostgresSessionStore{
+		repository: authenticationRepo,
+		codecs:     codecs,
+		Options:    options,
+	}
+}
+
+func (store *PostgresSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(store, name)
+}
+
+// New returns the session named in the request's cookie, or an empty one if
+// there is no cookie or the session has expired.
+func (store *PostgresSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(store, name)
+	options := *store.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var sessionID string
+	err = securecookie.DecodeMulti(name, cookie.Value, &sessionID, store.codecs...)
+	if err != nil {
+		return session, nil
+	}
+
+	encodedValues, err := store.repository.FindGothicSession(r.Context(), sessionID)
+	if err != nil {
+		return session, err
+	}
+	if encodedValues == "" {
+		return session, nil
+	}
+
+	err = securecookie.DecodeMulti(name, encodedValues, &session.Values, store.codecs...)
+	if err != nil {
+		return session, nil
+	}
+	session.ID = sessionID
+	session.IsNew = false
+	return session, nil
+}
+
+// Save writes the session to the database and its ID to the cookie. A negative
+// MaxAge deletes it.
+func (store *PostgresSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			err := store.repository.DeleteGothicSession(r.Context(), session.ID)
+			if err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+	}
+
+	encodedValues, err := securecookie.EncodeMulti(session.Name(), session.Values, store.codecs...)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(time.Duration(session.Options.MaxAge) * time.Second)
+	err = store.repository.SaveGothicSession(r.Context(), session.ID, encodedValues, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	encodedID, err := securecookie.EncodeMulti(session.Name(), session.ID, store.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encodedID, session.Options))
+	return nil
+}
@@ -0,0 +1,150 @@
+package authentication
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/markbates/goth"
+)
+
+var ErrLastSignInMethod = errors.New("cannot unlink the last sign-in method")
+
+type IdentityApiDto struct {
+	ID             uuid.UUID `json:"id"`
+	Provider       string    `json:"provider"`
+	ProviderUserID string    `json:"providerUserId"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type IdentityHandler struct {
+	repository AuthenticationRepository
+	cookies    *Cookies
+	logger     *log.Logger
+}
+
+func NewIdentityHandler(authenticationRepo AuthenticationRepository, cookies *Cookies, logger *log.Logger) *IdentityHandler {
+	return &IdentityHandler{
+		repository: authenticationRepo,
+		cookies:    cookies,
+		logger:     logger,
+	}
+}
+
+// @Summary List linked identities
+// @Description Lists the login providers linked to the current user.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]IdentityApiDto "Linked identities"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/identities [get]
+func (handler IdentityHandler) ListIdentities(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	authProviders, err := handler.repository.ListAuthProviders(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListAuthProviders: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	identities := make([]IdentityApiDto, 0, len(authProviders))
+	for _, authProvider := range authProviders {
+		identities = append(identities, IdentityApiDto{
+			ID:             authProvider.ID,
+			Provider:       authProvider.Provider,
+			ProviderUserID: authProvider.ProviderUserID,
+			CreatedAt:      authProvider.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// @Summary Start linking an identity
+// @Description Prepares this browser to link another login provider to the current user and returns the URL to navigate to. The provider callback then attaches the identity instead of signing in.
+// @Tags auth
+// @Param provider path string true "OAuth Provider"
+// @Produce json
+// @Success 200 {object} map[string]string "URL to continue linking at"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Provider not enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/identities/{provider}/link [post]
+func (handler IdentityHandler) LinkIdentity(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+	provider := ctx.Param("provider")
+
+	_, err := goth.GetProvider(provider)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	err = handler.cookies.setSignInIntent(ctx, NewSignInIntent(SignInIntentLinkIdentity, provider, authUser.ID))
+	if err != nil {
+		handler.logger.Printf("ERROR: setSignInIntent: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"url": "/auth/" + provider})
+}
+
+// @Summary Unlink an identity
+// @Description Removes a linked login provider from the current user. The last remaining sign-in method cannot be removed.
+// @Tags auth
+// @Param id path string true "Identity ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Identity not found"
+// @Failure 409 {object} map[string]string "Last sign-in method"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/identities/{id} [delete]
+func (handler IdentityHandler) UnlinkIdentity(ctx *gin.Context) {
+	authProviderID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Identity ID"})
+		return
+	}
+	authUser := GetAuthUser(ctx)
+
+	authProviders, err := handler.repository.ListAuthProviders(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListAuthProviders: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	found := false
+	for _, authProvider := range authProviders {
+		if authProvider.ID == authProviderID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	err = handler.repository.UnlinkAuthProvider(ctx.Request.Context(), authUser.ID, authProviderID)
+	if errors.Is(err, ErrLastSignInMethod) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "cannot unlink your last sign-in method"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryUnlinkAuthProvider: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package authentication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ImpersonationTimeToLive = 15 * time.Minute
+	// maxImpersonationPathLength matches the impersonation_requests path column.
+	maxImpersonationPathLength = 2048
+)
+
+var (
+	ErrImpersonationNotFound = errors.New("impersonation not found")
+	ErrImpersonationDenied   = errors.New("admins cannot impersonate themselves or other admins")
+)
+
+// Impersonation is a support admin acting as another user. The admin gets a
+// short-lived token for the user whose ID is the impersonation's, and every
+// request made with it is recorded as an ImpersonationRequest. ActorEmail is
+// kept so the record still names the admin after their account is erased,
+// when ActorID becomes uuid.Nil.
+type Impersonation struct {
+	ID         uuid.UUID
+	ActorID    uuid.UUID
+	ActorEmail string
+	UserID     uuid.UUID
+	Reason     string
+	ExpiresAt  time.Time
+	EndedAt    *time.Time
+	CreatedAt  time.Time
+}
+
+// ImpersonationRequest is one entry in an impersonation's audit log.
+// StatusCode is nil until the request has been answered.
+type ImpersonationRequest struct {
+	ID              uuid.UUID
+	ImpersonationID uuid.UUID
+	Method          string
+	Path            string
+	StatusCode      *int
+	IPAddress       string
+	CreatedAt       time.Time
+}
+
+// NewImpersonation checks that actor may act as user. Admins cannot be
+// impersonated, so an impersonation never carries admin rights.
+func NewImpersonation(actor *AuthUser, user *AuthUser, reason string) (*Impersonation, error) {
+	if !actor.IsAdmin() || actor.ID == user.ID || user.IsAdmin() {
+		return nil, ErrImpersonationDenied
+	}
+	return &Impersonation{
+		ActorID:    actor.ID,
+		ActorEmail: actor.Email,
+		UserID:     user.ID,
+		Reason:     reason,
+		ExpiresAt:  time.Now().Add(ImpersonationTimeToLive),
+	}, nil
+}
+
+func (impersonation *Impersonation) IsActive() bool {
+	return impersonation.EndedAt == nil && time.Now().Before(impersonation.ExpiresAt)
+}
+
+// Claims identify the impersonation token well enough to revoke it.
+func (impersonation *Impersonation) Claims() *AccessTokenClaims {
+	claims := newAccessTokenClaims("", impersonation.ID, impersonation.UserID, "", "", impersonation.ExpiresAt)
+	return &claims
+}
@@ -0,0 +1,101 @@
+package authentication
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestImpersonatedRequestsAreAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keySet := newTestKeySet(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"allowed", http.MethodGet, "/user/me?fields=email", http.StatusNoContent},
+		{"rejected while impersonating", http.MethodPut, "/auth/password", http.StatusForbidden},
+		{"handler error", http.MethodGet, "/user/fail", http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeAuthenticationRepository()
+			actor := &AuthUser{ID: uuid.New(), Email: "admin@example.org", Roles: []string{RoleAdmin}}
+			user := &AuthUser{ID: uuid.New(), Email: "ada@example.org", Roles: []string{RoleUser}}
+			repository.authUsers[actor.ID] = actor
+			repository.authUsers[user.ID] = user
+
+			impersonation := &Impersonation{ID: uuid.New(), ActorID: actor.ID, UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+			token, err := keySet.GenerateImpersonationJWT(impersonation, user, actor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			authMiddleware := AuthenticationMiddleware{AuthenticationRepository: repository, KeySet: keySet, RevocationStore: &fakeRevocationStore{}}
+			router := gin.New()
+			router.Use(authMiddleware.Authenticate())
+			router.GET("/user/me", authMiddleware.RequireAuthUser(), func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+			router.GET("/user/fail", authMiddleware.RequireAuthUser(), func(ctx *gin.Context) { ctx.Status(http.StatusInternalServerError) })
+			router.PUT("/auth/password", authMiddleware.RequireAuthUser(), authMiddleware.RejectImpersonation(), func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(test.method, test.path, nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Fatalf("request = %d %s, want %d", recorder.Code, recorder.Body, test.status)
+			}
+			if len(repository.impersonationRequests) != 1 {
+				t.Fatalf("audit log has %d entries, want 1", len(repository.impersonationRequests))
+			}
+			audited := repository.impersonationRequests[0]
+			if audited.ImpersonationID != impersonation.ID || audited.Method != test.method || audited.Path != test.path {
+				t.Errorf("audited %s %s for %s, want %s %s for %s", audited.Method, audited.Path, audited.ImpersonationID, test.method, test.path, impersonation.ID)
+			}
+			if audited.StatusCode == nil || *audited.StatusCode != test.status {
+				t.Errorf("audited status %v, want %d", audited.StatusCode, test.status)
+			}
+		})
+	}
+}
+
+// Once the admin behind an impersonation loses the role, its token stops
+// working and nothing more is done as the user.
+func TestImpersonationEndsWhenActorIsNoLongerAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keySet := newTestKeySet(t)
+	repository := newFakeAuthenticationRepository()
+	actor := &AuthUser{ID: uuid.New(), Email: "admin@example.org", Roles: []string{RoleUser}}
+	user := &AuthUser{ID: uuid.New(), Email: "ada@example.org", Roles: []string{RoleUser}}
+	repository.authUsers[actor.ID] = actor
+	repository.authUsers[user.ID] = user
+
+	impersonation := &Impersonation{ID: uuid.New(), ActorID: actor.ID, UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	token, err := keySet.GenerateImpersonationJWT(impersonation, user, actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authMiddleware := AuthenticationMiddleware{AuthenticationRepository: repository, KeySet: keySet, RevocationStore: &fakeRevocationStore{}}
+	router := gin.New()
+	router.GET("/user/me", authMiddleware.Authenticate(), authMiddleware.RequireAuthUser(), func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/user/me", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("request = %d %s, want 401", recorder.Code, recorder.Body)
+	}
+	if len(repository.impersonationRequests) != 0 {
+		t.Errorf("audit log has %d entries, want none", len(repository.impersonationRequests))
+	}
+}
@@ -0,0 +1,299 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultImpersonationListLimit = 50
+	maxImpersonationListLimit     = 200
+)
+
+// ImpersonationApiDto has no actorId once the admin's account is erased.
+type ImpersonationApiDto struct {
+	ID         uuid.UUID  `json:"id"`
+	ActorID    *uuid.UUID `json:"actorId"`
+	ActorEmail string     `json:"actorEmail"`
+	UserID     uuid.UUID  `json:"userId"`
+	Reason     string     `json:"reason"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	EndedAt    *time.Time `json:"endedAt"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type ImpersonationRequestApiDto struct {
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode *int      `json:"statusCode"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type CreateImpersonationApiDto struct {
+	UserID uuid.UUID `json:"userId" validate:"required"`
+	Reason string    `json:"reason" validate:"required,max=500"`
+}
+
+func (dto *CreateImpersonationApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type CreatedImpersonationApiDto struct {
+	ImpersonationApiDto
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+type ImpersonationHandler struct {
+	repository      AuthenticationRepository
+	revocationStore RevocationStore
+	keySet          *KeySet
+	logger          *log.Logger
+}
+
+func NewImpersonationHandler(authenticationRepo AuthenticationRepository, revocationStore RevocationStore, keySet *KeySet, logger *log.Logger) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		repository:      authenticationRepo,
+		revocationStore: revocationStore,
+		keySet:          keySet,
+		logger:          logger,
+	}
+}
+
+// @Summary Impersonate a user
+// @Description Issues a 15 minute access token that acts as the user, with the admin named in its act claim. The token has the user's scopes without admin or authentication, cannot be refreshed and is kept away from destructive routes. Every request made with it is written to the impersonation's audit log. Admins cannot be impersonated. Admins only.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param impersonation body CreateImpersonationApiDto true "User to impersonate and why"
+// @Success 201 {object} CreatedImpersonationApiDto "Impersonation and its token"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope, or the user cannot be impersonated"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/impersonations [post]
+func (handler *ImpersonationHandler) CreateImpersonation(ctx *gin.Context) {
+	actor := GetAuthUser(ctx)
+
+	var createApiDto CreateImpersonationApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&createApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeCreateImpersonationApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+	createApiDto.Reason = strings.TrimSpace(createApiDto.Reason)
+
+	err = createApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateCreateImpersonationApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	user, err := handler.repository.FindAuthUserByID(ctx.Request.Context(), createApiDto.UserID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindAuthUserByID: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if user == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	impersonation, err := NewImpersonation(actor, user, createApiDto.Reason)
+	if errors.Is(err, ErrImpersonationDenied) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: newImpersonation: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	impersonation.ID, err = handler.repository.CreateImpersonation(ctx.Request.Context(), impersonation)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateImpersonation: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	impersonation.CreatedAt = time.Now()
+
+	token, err := handler.keySet.GenerateImpersonationJWT(impersonation, user, actor)
+	if err != nil {
+		handler.logger.Printf("ERROR: generateImpersonationJWT: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, CreatedImpersonationApiDto{
+		ImpersonationApiDto: newImpersonationApiDto(impersonation),
+		Token:               token,
+		ExpiresIn:           int(ImpersonationTimeToLive.Seconds()),
+	})
+}
+
+// @Summary List impersonations
+// @Description Lists the most recent impersonations, newest first. Admins only.
+// @Tags auth
+// @Produce json
+// @Param limit query int false "Number of impersonations to return, at most 200" default(50)
+// @Success 200 {object} map[string][]ImpersonationApiDto "Impersonations"
+// @Failure 400 {object} map[string]string "Invalid limit"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/impersonations [get]
+func (handler *ImpersonationHandler) ListImpersonations(ctx *gin.Context) {
+	limit := defaultImpersonationListLimit
+	if limitParam := ctx.Query("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxImpersonationListLimit {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = parsedLimit
+	}
+
+	impersonations, err := handler.repository.ListImpersonations(ctx.Request.Context(), limit)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListImpersonations: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	impersonationApiDtos := make([]ImpersonationApiDto, 0, len(impersonations))
+	for _, impersonation := range impersonations {
+		impersonationApiDtos = append(impersonationApiDtos, newImpersonationApiDto(impersonation))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"impersonations": impersonationApiDtos})
+}
+
+// @Summary Get an impersonation's audit log
+// @Description Returns the impersonation and every request made with its token, oldest first. Admins only.
+// @Tags auth
+// @Produce json
+// @Param id path string true "Impersonation ID"
+// @Success 200 {object} map[string]interface{} "Impersonation and its requests"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Impersonation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/impersonations/{id}/requests [get]
+func (handler *ImpersonationHandler) ListImpersonationRequests(ctx *gin.Context) {
+	impersonationID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Impersonation ID"})
+		return
+	}
+
+	impersonation, err := handler.repository.FindImpersonation(ctx.Request.Context(), impersonationID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindImpersonation: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if impersonation == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	requests, err := handler.repository.ListImpersonationRequests(ctx.Request.Context(), impersonationID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListImpersonationRequests: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	requestApiDtos := make([]ImpersonationRequestApiDto, 0, len(requests))
+	for _, request := range requests {
+		requestApiDtos = append(requestApiDtos, ImpersonationRequestApiDto{
+			Method:     request.Method,
+			Path:       request.Path,
+			StatusCode: request.StatusCode,
+			IPAddress:  request.IPAddress,
+			CreatedAt:  request.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"impersonation": newImpersonationApiDto(impersonation), "requests": requestApiDtos})
+}
+
+// @Summary End an impersonation
+// @Description Ends an impersonation before it expires. Its token stops working straight away. Admins only.
+// @Tags auth
+// @Param id path string true "Impersonation ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Impersonation not found or already ended"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/impersonations/{id} [delete]
+func (handler *ImpersonationHandler) EndImpersonation(ctx *gin.Context) {
+	impersonationID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Impersonation ID"})
+		return
+	}
+
+	impersonation, err := handler.repository.EndImpersonation(ctx.Request.Context(), impersonationID)
+	if errors.Is(err, ErrImpersonationNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryEndImpersonation: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if time.Now().Before(impersonation.ExpiresAt) {
+		err = handler.revocationStore.RevokeToken(ctx.Request.Context(), impersonation.Claims())
+		if err != nil {
+			handler.logger.Printf("ERROR: revocationStoreRevokeToken: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func newImpersonationApiDto(impersonation *Impersonation) ImpersonationApiDto {
+	impersonationApiDto := ImpersonationApiDto{
+		ID:         impersonation.ID,
+		ActorEmail: impersonation.ActorEmail,
+		UserID:     impersonation.UserID,
+		Reason:     impersonation.Reason,
+		ExpiresAt:  impersonation.ExpiresAt,
+		EndedAt:    impersonation.EndedAt,
+		Active:     impersonation.IsActive(),
+		CreatedAt:  impersonation.CreatedAt,
+	}
+	if impersonation.ActorID != uuid.Nil {
+		impersonationApiDto.ActorID = &impersonation.ActorID
+	}
+	return impersonationApiDto
+}
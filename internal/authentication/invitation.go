@@ -0,0 +1,39 @@
+package authentication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvitationTimeToLive is how long an invitation can be accepted for. Once
+// accepted it stays valid until revoked.
+const InvitationTimeToLive = 14 * 24 * time.Hour
+
+var (
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationExists   = errors.New("an invitation for this email already exists")
+)
+
+// Invitation lets the holder of an email address in past the admission rules.
+type Invitation struct {
+	ID         uuid.UUID
+	Email      string
+	InvitedBy  uuid.UUID
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func NewInvitation(email string, invitedBy uuid.UUID) *Invitation {
+	return &Invitation{
+		Email:     email,
+		InvitedBy: invitedBy,
+		ExpiresAt: time.Now().Add(InvitationTimeToLive),
+	}
+}
+
+func (invitation *Invitation) IsExpired() bool {
+	return invitation.AcceptedAt == nil && time.Now().After(invitation.ExpiresAt)
+}
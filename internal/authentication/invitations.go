@@ -0,0 +1,196 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"catalyst.api/internal/mailer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+// invitationMailTimeout bounds how long an invitation email may take to send
+// after the request has been answered.
+const invitationMailTimeout = 30 * time.Second
+
+type InvitationApiDto struct {
+	ID         uuid.UUID  `json:"id"`
+	Email      string     `json:"email"`
+	InvitedBy  *uuid.UUID `json:"invitedBy"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	AcceptedAt *time.Time `json:"acceptedAt"`
+	Expired    bool       `json:"expired"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateInvitationApiDto struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (dto *CreateInvitationApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type InvitationHandler struct {
+	repository  AuthenticationRepository
+	mailer      mailer.Mailer
+	frontendURL string
+	logger      *log.Logger
+}
+
+func NewInvitationHandler(authenticationRepo AuthenticationRepository, mailer mailer.Mailer, frontendURL string, logger *log.Logger) *InvitationHandler {
+	return &InvitationHandler{
+		repository:  authenticationRepo,
+		mailer:      mailer,
+		frontendURL: frontendURL,
+		logger:      logger,
+	}
+}
+
+// @Summary List invitations
+// @Description Lists invitations that have not been revoked, including accepted and expired ones. Admins only.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]InvitationApiDto "Invitations"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/invitations [get]
+func (handler *InvitationHandler) ListInvitations(ctx *gin.Context) {
+	invitations, err := handler.repository.ListInvitations(ctx.Request.Context())
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListInvitations: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	invitationApiDtos := make([]InvitationApiDto, 0, len(invitations))
+	for _, invitation := range invitations {
+		invitationApiDtos = append(invitationApiDtos, newInvitationApiDto(invitation))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"invitations": invitationApiDtos})
+}
+
+// @Summary Invite someone
+// @Description Lets the holder of an email address sign in regardless of the admission rules, and emails them a link to the sign-in page. The invitation must be accepted within 14 days by signing in with a magic link or a provider that confirms the address. Admins only.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param invitation body CreateInvitationApiDto true "Email to invite"
+// @Success 201 {object} InvitationApiDto "Created invitation"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 409 {object} map[string]string "Email already invited"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/invitations [post]
+func (handler *InvitationHandler) CreateInvitation(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	var createApiDto CreateInvitationApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&createApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeCreateInvitationApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+	createApiDto.Email = strings.TrimSpace(createApiDto.Email)
+
+	err = createApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateCreateInvitationApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	invitation := NewInvitation(createApiDto.Email, authUser.ID)
+	invitation.ID, err = handler.repository.CreateInvitation(ctx.Request.Context(), invitation)
+	if errors.Is(err, ErrInvitationExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateInvitation: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	invitation.CreatedAt = time.Now()
+
+	go handler.sendInvitationMail(invitation)
+
+	ctx.JSON(http.StatusCreated, newInvitationApiDto(invitation))
+}
+
+// @Summary Revoke an invitation
+// @Description Revokes an invitation. Accounts already created with it are kept, but no longer get past the admission rules on the strength of it. Admins only.
+// @Tags auth
+// @Param id path string true "Invitation ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Invitation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/invitations/{id} [delete]
+func (handler *InvitationHandler) RevokeInvitation(ctx *gin.Context) {
+	invitationID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	err = handler.repository.RevokeInvitation(ctx.Request.Context(), invitationID)
+	if errors.Is(err, ErrInvitationNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRevokeInvitation: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *InvitationHandler) sendInvitationMail(invitation *Invitation) {
+	ctx, cancel := context.WithTimeout(context.Background(), invitationMailTimeout)
+	defer cancel()
+
+	message := mailer.Message{
+		To:      invitation.Email,
+		Subject: "You're invited to catalyst",
+		Body: fmt.Sprintf("You've been invited to catalyst.\n\n"+
+			"Sign in within %d days using an account with this email address:\n\n%s\n", int(InvitationTimeToLive.Hours()/24), handler.frontendURL),
+	}
+
+	err := handler.mailer.Send(ctx, message)
+	if err != nil {
+		handler.logger.Printf("ERROR: mailerSend: %v", err)
+	}
+}
+
+func newInvitationApiDto(invitation *Invitation) InvitationApiDto {
+	invitationApiDto := InvitationApiDto{
+		ID:         invitation.ID,
+		Email:      invitation.Email,
+		ExpiresAt:  invitation.ExpiresAt,
+		AcceptedAt: invitation.AcceptedAt,
+		Expired:    invitation.IsExpired(),
+		CreatedAt:  invitation.CreatedAt,
+	}
+	if invitation.InvitedBy != uuid.Nil {
+		invitationApiDto.InvitedBy = &invitation.InvitedBy
+	}
+	return invitationApiDto
+}
@@ -0,0 +1,31 @@
+package authentication
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keySet *KeySet
+	logger *log.Logger
+}
+
+func NewJWKSHandler(keySet *KeySet, logger *log.Logger) *JWKSHandler {
+	return &JWKSHandler{
+		keySet: keySet,
+		logger: logger,
+	}
+}
+
+// @Summary JSON Web Key Set
+// @Description Publishes the public keys that access tokens may be signed with, keyed by the kid token header.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func (handler JWKSHandler) GetJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, handler.keySet.JWKS())
+}
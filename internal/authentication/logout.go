@@ -1,36 +1,113 @@
 package authentication
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/markbates/goth/gothic"
 )
 
 type LogoutHandler struct {
-	logger *log.Logger
+	repository      AuthenticationRepository
+	tokenIssuer     *TokenIssuer
+	revocationStore RevocationStore
+	cookies         *Cookies
+	logger          *log.Logger
 }
 
-func NewLogoutHandler(logger *log.Logger) *LogoutHandler {
+func NewLogoutHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, revocationStore RevocationStore, cookies *Cookies, logger *log.Logger) *LogoutHandler {
 	return &LogoutHandler{
-		logger: logger,
+		repository:      authenticationRepo,
+		tokenIssuer:     tokenIssuer,
+		revocationStore: revocationStore,
+		cookies:         cookies,
+		logger:          logger,
 	}
 }
 
 // @Summary Logout user
-// @Description Logs out the currently authenticated user via the configured provider session.
+// @Description Logs out the currently authenticated user via the configured provider session, revoking the bearer token, its session and the refresh token used for the request. Logging out with a personal access token revokes that token.
 // @Tags auth
 // @Produce json
-// @Success 302 {string} string "Redirect to /"
+// @Param provider path string true "Provider name"
+// @Success 204 {string} string "No Content"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /auth/logout/{provider} [get]
+// @Router /auth/logout/{provider} [post]
 func (handler LogoutHandler) Logout(ctx *gin.Context) {
-	err := gothic.Logout(ctx.Writer, ctx.Request)
+	claims := GetAccessTokenClaims(ctx)
+	if personalAccessToken := GetPersonalAccessToken(ctx); personalAccessToken != nil {
+		err := handler.repository.RevokePersonalAccessToken(ctx.Request.Context(), personalAccessToken.UserID, personalAccessToken.ID)
+		if err != nil && !errors.Is(err, ErrPersonalAccessTokenNotFound) {
+			handler.logger.Printf("ERROR: repositoryRevokePersonalAccessToken: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	} else if claims != nil {
+		err := handler.revocationStore.RevokeToken(ctx.Request.Context(), claims)
+		if err != nil {
+			handler.logger.Printf("ERROR: revocationStoreRevokeToken: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		authUserID, _ := claims.UserID()
+		if sessionID := claims.Session(); sessionID != uuid.Nil {
+			err = handler.revocationStore.RevokeSession(ctx.Request.Context(), authUserID, sessionID)
+			if err != nil && !errors.Is(err, ErrSessionNotFound) {
+				handler.logger.Printf("ERROR: revocationStoreRevokeSession: %v", err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+				return
+			}
+		}
+	}
+
+	refreshToken, err := ctx.Cookie(RefreshTokenCookieName)
+	if err == nil && refreshToken != "" {
+		err = handler.tokenIssuer.Revoke(ctx.Request.Context(), refreshToken)
+		if err != nil {
+			handler.logger.Printf("ERROR: tokenIssuerRevoke: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+	handler.cookies.clearRefreshToken(ctx)
+
+	err = gothic.Logout(ctx.Writer, ctx.Request)
 	if err != nil {
 		handler.logger.Printf("ERROR: gothicLogout: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
-	ctx.Redirect(http.StatusTemporaryRedirect, "/")
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Logout everywhere
+// @Description Revokes every access token, refresh token and personal access token issued to the current user, ending all of their sessions.
+// @Tags auth
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/logout/all [post]
+func (handler LogoutHandler) LogoutEverywhere(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	err := handler.revocationStore.RevokeAllForUser(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: revocationStoreRevokeAllForUser: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = handler.repository.RevokeRefreshTokensForUser(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRevokeRefreshTokensForUser: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	handler.cookies.clearRefreshToken(ctx)
+	ctx.Writer.WriteHeader(http.StatusNoContent)
 }
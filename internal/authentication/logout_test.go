@@ -0,0 +1,98 @@
+package authentication
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"github.com/markbates/goth/gothic"
+)
+
+func TestLogoutRevokesPersonalAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keySet := newTestKeySet(t)
+	// logout also clears the provider session, which needs a working store
+	previousStore := gothic.Store
+	gothic.Store = sessions.NewCookieStore([]byte("catalyst-test-session-key"))
+	t.Cleanup(func() { gothic.Store = previousStore })
+
+	tests := []struct {
+		name string
+		path string
+		// the user's other token is expected to stop working too
+		revokesOthers bool
+	}{
+		{"logout revokes the token used", "/auth/logout/github", false},
+		{"logout everywhere revokes every token", "/auth/logout/all", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeAuthenticationRepository()
+			authUser := &AuthUser{ID: uuid.New(), Email: "ada@example.org", Roles: []string{RoleUser}}
+			repository.authUsers[authUser.ID] = authUser
+			otherUser := &AuthUser{ID: uuid.New(), Email: "grace@example.org", Roles: []string{RoleUser}}
+			repository.authUsers[otherUser.ID] = otherUser
+
+			usedToken := createTestPersonalAccessToken(t, repository, authUser)
+			siblingToken := createTestPersonalAccessToken(t, repository, authUser)
+			otherUsersToken := createTestPersonalAccessToken(t, repository, otherUser)
+
+			revocationStore := NewCachedRevocationStore(repository, RevocationCacheTimeToLive)
+			authMiddleware := AuthenticationMiddleware{AuthenticationRepository: repository, KeySet: keySet, RevocationStore: revocationStore}
+			handler := NewLogoutHandler(repository, NewTokenIssuer(repository, keySet), revocationStore, NewCookies(keySet, false), log.New(io.Discard, "", 0))
+
+			router := gin.New()
+			router.Use(authMiddleware.Authenticate())
+			router.POST("/auth/logout/all", authMiddleware.RequireAuthUser(), handler.LogoutEverywhere)
+			router.POST("/auth/logout/:provider", handler.Logout)
+			router.GET("/user/me", authMiddleware.RequireAuthUser(), func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, test.path, nil)
+			request.Header.Set("Authorization", "Bearer "+usedToken)
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusNoContent {
+				t.Fatalf("logout = %d %s, want 204", recorder.Code, recorder.Body)
+			}
+
+			wantStatus := map[string]int{
+				"used token":         http.StatusUnauthorized,
+				"sibling token":      http.StatusNoContent,
+				"other user's token": http.StatusNoContent,
+			}
+			if test.revokesOthers {
+				wantStatus["sibling token"] = http.StatusUnauthorized
+			}
+			plainTokens := map[string]string{"used token": usedToken, "sibling token": siblingToken, "other user's token": otherUsersToken}
+			for name, plainToken := range plainTokens {
+				recorder := httptest.NewRecorder()
+				request := httptest.NewRequest(http.MethodGet, "/user/me", nil)
+				request.Header.Set("Authorization", "Bearer "+plainToken)
+				router.ServeHTTP(recorder, request)
+				if recorder.Code != wantStatus[name] {
+					t.Errorf("%s after logout = %d, want %d", name, recorder.Code, wantStatus[name])
+				}
+			}
+		})
+	}
+}
+
+func createTestPersonalAccessToken(t *testing.T, repository *fakeAuthenticationRepository, authUser *AuthUser) string {
+	t.Helper()
+	personalAccessToken, plainToken, err := NewPersonalAccessToken(authUser.ID, "ci", DefaultUserScopes, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repository.CreatePersonalAccessToken(context.Background(), personalAccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plainToken
+}
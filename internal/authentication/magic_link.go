@@ -0,0 +1,81 @@
+package authentication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const MagicLinkTimeToLive = 15 * time.Minute
+
+var ErrInvalidMagicLink = errors.New("magic link is invalid or has expired")
+
+// MagicLink signs the holder of an email address in. The link carries a signed
+// token whose ID is the magic_links row, which is marked used the first time
+// the link is followed. FirstName and LastName name the account if the link
+// creates one.
+type MagicLink struct {
+	ID        uuid.UUID
+	Email     string
+	FirstName string
+	LastName  string
+	ExpiresAt time.Time
+}
+
+// MagicLinkClaims are the claims in the token sent in a magic link.
+type MagicLinkClaims struct {
+	Email     string `json:"email"`
+	FirstName string `json:"given_name,omitempty"`
+	LastName  string `json:"family_name,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func NewMagicLink(email string, firstName string, lastName string) *MagicLink {
+	return &MagicLink{
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+		ExpiresAt: time.Now().Add(MagicLinkTimeToLive),
+	}
+}
+
+// Sign returns the token for the link. The link must have been stored first so
+// it has an ID.
+func (magicLink *MagicLink) Sign(keySet *KeySet) (string, error) {
+	claims := MagicLinkClaims{
+		Email:     magicLink.Email,
+		FirstName: magicLink.FirstName,
+		LastName:  magicLink.LastName,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        magicLink.ID.String(),
+			Issuer:    keySet.issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(magicLink.ExpiresAt),
+		},
+	}
+	return keySet.sign(claims, magicLinkTokenType)
+}
+
+// ParseMagicLink checks the token's signature and expiry. Whether it has been
+// used is only known once it is consumed.
+func ParseMagicLink(keySet *KeySet, magicLinkToken string) (*MagicLink, error) {
+	claims := &MagicLinkClaims{}
+	_, err := keySet.parse(magicLinkToken, claims, magicLinkTokenType)
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	magicLinkID, err := uuid.Parse(claims.ID)
+	if err != nil || claims.ExpiresAt == nil {
+		return nil, ErrInvalidMagicLink
+	}
+	return &MagicLink{
+		ID:        magicLinkID,
+		Email:     claims.Email,
+		FirstName: claims.FirstName,
+		LastName:  claims.LastName,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
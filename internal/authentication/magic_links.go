@@ -0,0 +1,279 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"catalyst.api/internal/mailer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+// magicLinkMailTimeout bounds how long a magic link email may take to send
+// after the request has been answered.
+const magicLinkMailTimeout = 30 * time.Second
+
+// maxNameLength matches the first_name and last_name columns.
+const maxNameLength = 50
+
+type SendMagicLinkApiDto struct {
+	Email     string `json:"email" validate:"required,email,max=255"`
+	FirstName string `json:"firstName" validate:"max=50"`
+	LastName  string `json:"lastName" validate:"max=50"`
+}
+
+func (dto *SendMagicLinkApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type VerifyMagicLinkApiDto struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (dto *VerifyMagicLinkApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type MagicLinkHandler struct {
+	repository  AuthenticationRepository
+	tokenIssuer *TokenIssuer
+	admission   *AdmissionPolicy
+	mailer      mailer.Mailer
+	keySet      *KeySet
+	cookies     *Cookies
+	frontendURL string
+	logger      *log.Logger
+}
+
+func NewMagicLinkHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, admission *AdmissionPolicy, mailer mailer.Mailer, keySet *KeySet, cookies *Cookies, frontendURL string, logger *log.Logger) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		repository:  authenticationRepo,
+		tokenIssuer: tokenIssuer,
+		admission:   admission,
+		mailer:      mailer,
+		keySet:      keySet,
+		cookies:     cookies,
+		frontendURL: frontendURL,
+		logger:      logger,
+	}
+}
+
+// @Summary Email a magic link
+// @Description Emails a single-use sign-in link that expires after 15 minutes. The link opens the front-end's /magic-link page, which posts its token to /auth/magic-link/verify. First and last name are used if the link creates an account. The response is the same whether or not an account uses the email.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param magicLink body SendMagicLinkApiDto true "Email, and names for a new account"
+// @Success 202 {object} map[string]string "Accepted"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/magic-link [post]
+func (handler *MagicLinkHandler) SendMagicLink(ctx *gin.Context) {
+	var sendApiDto SendMagicLinkApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&sendApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeSendMagicLinkApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+	sendApiDto.Email = strings.TrimSpace(sendApiDto.Email)
+
+	err = sendApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateSendMagicLinkApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	magicLink := NewMagicLink(sendApiDto.Email, strings.TrimSpace(sendApiDto.FirstName), strings.TrimSpace(sendApiDto.LastName))
+	magicLink.ID, err = handler.repository.CreateMagicLink(ctx.Request.Context(), magicLink)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateMagicLink: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	magicLinkToken, err := magicLink.Sign(handler.keySet)
+	if err != nil {
+		handler.logger.Printf("ERROR: magicLinkSign: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	go handler.sendMagicLinkMail(magicLink.Email, magicLinkToken)
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "a sign-in link has been sent"})
+}
+
+// @Summary Sign in with a magic link
+// @Description Exchanges the token from a magic link for tokens, creating the account on first use. Signs the user in the same way as the OAuth callback: the access token is returned and the refresh token is set as a cookie. Users with an authenticator app get mfaRequired and an mfaToken for /auth/mfa/verify instead. Each link works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param magicLink body VerifyMagicLinkApiDto true "Token from the magic link"
+// @Success 200 {object} map[string]interface{} "Access token, or MFA challenge token"
+// @Failure 400 {object} map[string]string "Invalid, used or expired link"
+// @Failure 403 {object} map[string]string "Turned away by the admission rules, or account deactivated, with an error code"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/magic-link/verify [post]
+func (handler *MagicLinkHandler) VerifyMagicLink(ctx *gin.Context) {
+	var verifyApiDto VerifyMagicLinkApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&verifyApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeVerifyMagicLinkApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = verifyApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateVerifyMagicLinkApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	magicLink, err := ParseMagicLink(handler.keySet, verifyApiDto.Token)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email, err := handler.repository.ConsumeMagicLink(ctx.Request.Context(), magicLink.ID)
+	if errors.Is(err, ErrInvalidMagicLink) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryConsumeMagicLink: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	magicLink.Email = email
+
+	// any account with this email, whether or not it has a password
+	credentials, err := handler.repository.FindPasswordCredentials(ctx.Request.Context(), magicLink.Email)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindPasswordCredentials: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	// following the link proves the email belongs to the user
+	candidate := AdmissionCandidate{Email: magicLink.Email, EmailVerified: true, Existing: credentials != nil}
+	if !admitCandidate(ctx, handler.admission, handler.logger, candidate) {
+		return
+	}
+
+	var authUserID uuid.UUID
+	if credentials != nil {
+		authUserID = credentials.UserID
+	} else {
+		authUserID, err = handler.registerAuthUser(ctx.Request.Context(), magicLink)
+		if err != nil {
+			handler.logger.Printf("ERROR: handlerRegisterAuthUser: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+
+	sessionID, err := startSession(ctx, handler.tokenIssuer, authUserID, SessionProviderMagicLink)
+	if errors.Is(err, ErrAccountDeactivated) {
+		respondAccountDeactivated(ctx)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: startSession: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Issue(ctx.Request.Context(), authUserID, sessionID)
+	if errors.Is(err, ErrAccountDeactivated) {
+		respondAccountDeactivated(ctx)
+		return
+	}
+	if errors.Is(err, ErrMFARequired) {
+		respondMFARequired(ctx, handler.tokenIssuer, handler.logger, authUserID, sessionID)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssue: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	handler.cookies.setRefreshToken(ctx, tokens.RefreshToken, tokens.RefreshTokenExpiresAt)
+	ctx.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken})
+}
+
+// registerAuthUser creates the account for a magic link's first use. Without
+// names from the request, the first name falls back to the email's local part.
+func (handler *MagicLinkHandler) registerAuthUser(ctx context.Context, magicLink *MagicLink) (uuid.UUID, error) {
+	authUser := &AuthUser{
+		Email:     magicLink.Email,
+		FirstName: magicLink.FirstName,
+		LastName:  magicLink.LastName,
+	}
+	if authUser.FirstName == "" {
+		localPart, _, _ := strings.Cut(magicLink.Email, "@")
+		authUser.FirstName = truncateRunes(localPart, maxNameLength)
+	}
+
+	authUserID, err := handler.repository.RegisterEmailAuthUser(ctx, authUser)
+	if errors.Is(err, ErrEmailTaken) {
+		// another link for the same email created the account first
+		credentials, err := handler.repository.FindPasswordCredentials(ctx, magicLink.Email)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if credentials == nil {
+			return uuid.Nil, ErrEmailTaken
+		}
+		return credentials.UserID, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = handler.repository.AcceptInvitation(ctx, magicLink.Email)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryAcceptInvitation: %v", err)
+	}
+	return authUserID, nil
+}
+
+func (handler *MagicLinkHandler) sendMagicLinkMail(email string, magicLinkToken string) {
+	ctx, cancel := context.WithTimeout(context.Background(), magicLinkMailTimeout)
+	defer cancel()
+
+	magicLinkURL := fmt.Sprintf("%s/magic-link?token=%s", handler.frontendURL, url.QueryEscape(magicLinkToken))
+	message := mailer.Message{
+		To:      email,
+		Subject: "Your catalyst sign-in link",
+		Body: fmt.Sprintf("Use this link within %d minutes to sign in to catalyst:\n\n%s\n\n"+
+			"The link works once. If you didn't ask for it, you can ignore this email.\n", int(MagicLinkTimeToLive.Minutes()), magicLinkURL),
+	}
+
+	err := handler.mailer.Send(ctx, message)
+	if err != nil {
+		handler.logger.Printf("ERROR: mailerSend: %v", err)
+	}
+}
+
+func truncateRunes(value string, maxLength int) string {
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[:maxLength])
+}
@@ -0,0 +1,262 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"catalyst.api/config"
+	"catalyst.api/internal/mailer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const testFrontendURL = "http://frontend.test"
+
+var magicLinkTokenPattern = regexp.MustCompile(`/magic-link\?token=(\S+)`)
+
+type magicLinkTest struct {
+	repository *fakeAuthenticationRepository
+	mailer     *mailer.MemoryMailer
+	keySet     *KeySet
+	router     *gin.Engine
+}
+
+func newTestKeySet(t *testing.T) *KeySet {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.AuthenticationConfig.JWTIssuer = "catalyst-test"
+	keySet, err := LoadKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keySet
+}
+
+func newMagicLinkTest(t *testing.T) *magicLinkTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	test := &magicLinkTest{
+		repository: newFakeAuthenticationRepository(),
+		mailer:     mailer.NewMemoryMailer(),
+		keySet:     newTestKeySet(t),
+		router:     gin.New(),
+	}
+	tokenIssuer := NewTokenIssuer(test.repository, test.keySet)
+	admission := NewAdmissionPolicy(test.repository, &AdmissionRules{}, nil)
+	handler := NewMagicLinkHandler(test.repository, tokenIssuer, admission, test.mailer, test.keySet, NewCookies(test.keySet, false), testFrontendURL, log.New(io.Discard, "", 0))
+
+	test.router.POST("/auth/magic-link", handler.SendMagicLink)
+	test.router.POST("/auth/magic-link/verify", handler.VerifyMagicLink)
+	return test
+}
+
+func (test *magicLinkTest) post(path string, body any) *httptest.ResponseRecorder {
+	encoded, _ := json.Marshal(body)
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(encoded)))
+	request.Header.Set("Content-Type", "application/json")
+	test.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// send asks for a magic link and returns the token from the email, which is
+// sent after the response.
+func (test *magicLinkTest) send(t *testing.T, dto SendMagicLinkApiDto) string {
+	t.Helper()
+	recorder := test.post("/auth/magic-link", dto)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("send magic link = %d %s, want 202", recorder.Code, recorder.Body)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		message, ok := test.mailer.LastMessageTo(dto.Email)
+		if ok {
+			if !strings.Contains(message.Body, testFrontendURL+"/magic-link?token=") {
+				t.Fatalf("mail body %q does not link to the front-end", message.Body)
+			}
+			match := magicLinkTokenPattern.FindStringSubmatch(message.Body)
+			token, err := url.QueryUnescape(match[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no magic link was mailed to %s", dto.Email)
+	return ""
+}
+
+func (test *magicLinkTest) verify(token string) *httptest.ResponseRecorder {
+	return test.post("/auth/magic-link/verify", VerifyMagicLinkApiDto{Token: token})
+}
+
+// signedInUser returns the user the access token in a successful verify
+// response was issued to.
+func (test *magicLinkTest) signedInUser(t *testing.T, recorder *httptest.ResponseRecorder) *AuthUser {
+	t.Helper()
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("verify magic link = %d %s, want 200", recorder.Code, recorder.Body)
+	}
+	var response struct {
+		Token string `json:"token"`
+	}
+	err := json.NewDecoder(recorder.Body).Decode(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := test.keySet.VerifyJWTToken(response.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasRefreshToken := false
+	for _, cookie := range recorder.Result().Cookies() {
+		hasRefreshToken = hasRefreshToken || (cookie.Name == RefreshTokenCookieName && cookie.Value != "")
+	}
+	if !hasRefreshToken {
+		t.Error("verify did not set the refresh token cookie")
+	}
+
+	authUser := test.repository.authUsers[userID]
+	if authUser == nil {
+		t.Fatalf("token was issued to unknown user %s", userID)
+	}
+	return authUser
+}
+
+func TestMagicLinkCreatesAccountForUnknownEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		dto           SendMagicLinkApiDto
+		wantFirstName string
+		wantLastName  string
+	}{
+		{"with names", SendMagicLinkApiDto{Email: "ada@example.org", FirstName: " Ada ", LastName: "Lovelace"}, "Ada", "Lovelace"},
+		{"without names", SendMagicLinkApiDto{Email: "grace@example.org"}, "grace", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			magicLinkTest := newMagicLinkTest(t)
+			magicLinkTest.repository.invitations[test.dto.Email] = true
+
+			token := magicLinkTest.send(t, test.dto)
+			if len(magicLinkTest.repository.authUsers) != 0 {
+				t.Fatal("sending a magic link created an account")
+			}
+
+			authUser := magicLinkTest.signedInUser(t, magicLinkTest.verify(token))
+			if authUser.Email != test.dto.Email || authUser.FirstName != test.wantFirstName || authUser.LastName != test.wantLastName {
+				t.Errorf("created %+v, want %s %s <%s>", authUser, test.wantFirstName, test.wantLastName, test.dto.Email)
+			}
+			if magicLinkTest.repository.invitations[test.dto.Email] {
+				t.Error("the invitation for the new account was not accepted")
+			}
+		})
+	}
+}
+
+func TestMagicLinkSignsInExistingAccount(t *testing.T) {
+	magicLinkTest := newMagicLinkTest(t)
+	existing := &AuthUser{ID: uuid.New(), Email: "Ada@example.org", FirstName: "Ada", Roles: []string{RoleUser}}
+	magicLinkTest.repository.authUsers[existing.ID] = existing
+
+	token := magicLinkTest.send(t, SendMagicLinkApiDto{Email: "ada@example.org", FirstName: "Someone", LastName: "Else"})
+
+	authUser := magicLinkTest.signedInUser(t, magicLinkTest.verify(token))
+	if authUser.ID != existing.ID || authUser.FirstName != "Ada" {
+		t.Errorf("signed in as %+v, want the existing account", authUser)
+	}
+	if len(magicLinkTest.repository.authUsers) != 1 {
+		t.Errorf("%d accounts, want the existing one only", len(magicLinkTest.repository.authUsers))
+	}
+}
+
+func TestMagicLinkWorksOnce(t *testing.T) {
+	magicLinkTest := newMagicLinkTest(t)
+	token := magicLinkTest.send(t, SendMagicLinkApiDto{Email: "ada@example.org"})
+
+	magicLinkTest.signedInUser(t, magicLinkTest.verify(token))
+
+	recorder := magicLinkTest.verify(token)
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), ErrInvalidMagicLink.Error()) {
+		t.Errorf("second verify = %d %s, want 400 %q", recorder.Code, recorder.Body, ErrInvalidMagicLink)
+	}
+}
+
+func TestMagicLinkRejectsInvalidTokens(t *testing.T) {
+	magicLinkTest := newMagicLinkTest(t)
+
+	expired := NewMagicLink("ada@example.org", "", "")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	expired.ID, _ = magicLinkTest.repository.CreateMagicLink(context.Background(), expired)
+	expiredToken, err := expired.Sign(magicLinkTest.keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the row expires with the token, but is checked on its own as well
+	expiredRow := NewMagicLink("ada@example.org", "", "")
+	expiredRow.ID, _ = magicLinkTest.repository.CreateMagicLink(context.Background(), expiredRow)
+	magicLinkTest.repository.magicLinks[expiredRow.ID].expiresAt = time.Now().Add(-time.Minute)
+	expiredRowToken, err := expiredRow.Sign(magicLinkTest.keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := NewMagicLink("ada@example.org", "", "")
+	unknown.ID = uuid.New()
+	unknownToken, err := unknown.Sign(magicLinkTest.keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKeySetToken, err := expiredRow.Sign(newTestKeySet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accessToken, err := magicLinkTest.keySet.GenerateJWT(uuid.New(), "ada@example.org", "", uuid.Nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", expiredToken},
+		{"expired row", expiredRowToken},
+		{"unknown link", unknownToken},
+		{"signed with another key", otherKeySetToken},
+		{"access token", accessToken},
+		{"garbage", "not-a-token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := magicLinkTest.verify(test.token)
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("verify = %d %s, want 400", recorder.Code, recorder.Body)
+			}
+		})
+	}
+	if len(magicLinkTest.repository.authUsers) != 0 {
+		t.Error("an invalid magic link created an account")
+	}
+}
@@ -0,0 +1,111 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MFAChallengeTimeToLive is how long a user has, after the first factor, to
+	// enter a code. MaxMFAAttempts wrong codes end the sign-in.
+	MFAChallengeTimeToLive = 5 * time.Minute
+	MaxMFAAttempts         = 5
+
+	RecoveryCodeCount = 10
+	// recoveryCodeAlphabet is lower case base32, so codes survive being read
+	// aloud or written down.
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	recoveryCodeLength   = 10
+)
+
+var (
+	ErrMFARequired          = errors.New("second factor required")
+	ErrMFAChallengeNotFound = errors.New("mfa challenge not found")
+	ErrTOTPAlreadyEnabled   = errors.New("authenticator app already enabled")
+	ErrTOTPNotPending       = errors.New("no authenticator app enrolment in progress")
+)
+
+// MFAChallenge holds a sign-in that has passed its first factor back until a
+// TOTP or recovery code is entered. Tokens are not issued for its session
+// while it exists.
+type MFAChallenge struct {
+	SessionID      uuid.UUID
+	UserID         uuid.UUID
+	FailedAttempts int
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+func NewMFAChallenge(userID uuid.UUID, sessionID uuid.UUID) *MFAChallenge {
+	return &MFAChallenge{
+		SessionID: sessionID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(MFAChallengeTimeToLive),
+	}
+}
+
+func (challenge *MFAChallenge) IsExpired() bool {
+	return time.Now().After(challenge.ExpiresAt)
+}
+
+// NewRecoveryCodes returns a fresh set of plain recovery codes formatted for
+// display, e.g. k3x9p-2vq7m. Only their hashes are stored.
+func NewRecoveryCodes() ([]string, error) {
+	alphabetLength := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	recoveryCodes := make([]string, 0, RecoveryCodeCount)
+	for range RecoveryCodeCount {
+		var builder strings.Builder
+		for index := range recoveryCodeLength {
+			if index == recoveryCodeLength/2 {
+				builder.WriteByte('-')
+			}
+			position, err := rand.Int(rand.Reader, alphabetLength)
+			if err != nil {
+				return nil, err
+			}
+			builder.WriteByte(recoveryCodeAlphabet[position.Int64()])
+		}
+		recoveryCodes = append(recoveryCodes, builder.String())
+	}
+	return recoveryCodes, nil
+}
+
+// HashRecoveryCode normalizes the code the way people type it, in any case and
+// with or without the dash and spaces, before hashing it.
+func HashRecoveryCode(plainCode string) string {
+	plainCode = strings.ToLower(plainCode)
+	plainCode = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, plainCode)
+	return hashOpaqueToken(plainCode)
+}
+
+func HashRecoveryCodes(plainCodes []string) []string {
+	codeHashes := make([]string, 0, len(plainCodes))
+	for _, plainCode := range plainCodes {
+		codeHashes = append(codeHashes, HashRecoveryCode(plainCode))
+	}
+	return codeHashes
+}
+
+// IsTOTPCode reports whether code looks like it came from an authenticator app
+// rather than the recovery code list.
+func IsTOTPCode(code string) bool {
+	if len(code) != TOTPDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
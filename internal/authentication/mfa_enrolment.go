@@ -0,0 +1,324 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+type MFAStatusApiDto struct {
+	TOTPEnabled            bool `json:"totpEnabled"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+type TOTPEnrolmentApiDto struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type MFACodeApiDto struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+func (dto *MFACodeApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type RecoveryCodesApiDto struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type MFAHandler struct {
+	repository      AuthenticationRepository
+	tokenIssuer     *TokenIssuer
+	revocationStore RevocationStore
+	cookies         *Cookies
+	logger          *log.Logger
+}
+
+func NewMFAHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, revocationStore RevocationStore, cookies *Cookies, logger *log.Logger) *MFAHandler {
+	return &MFAHandler{
+		repository:      authenticationRepo,
+		tokenIssuer:     tokenIssuer,
+		revocationStore: revocationStore,
+		cookies:         cookies,
+		logger:          logger,
+	}
+}
+
+// @Summary MFA status
+// @Description Reports whether the current user has an authenticator app and how many recovery codes they have left.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} MFAStatusApiDto "MFA status"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/mfa [get]
+func (handler *MFAHandler) GetStatus(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	credential, err := handler.repository.FindTOTPCredential(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindTOTPCredential: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	recoveryCodesRemaining, err := handler.repository.CountUnusedRecoveryCodes(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCountUnusedRecoveryCodes: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, MFAStatusApiDto{
+		TOTPEnabled:            credential.IsConfirmed(),
+		RecoveryCodesRemaining: recoveryCodesRemaining,
+	})
+}
+
+// @Summary Start authenticator app enrolment
+// @Description Generates a TOTP secret for the current user. Show the otpauth URI as a QR code, then confirm with a code from the app at /auth/mfa/totp/confirm. Starting again replaces an unconfirmed secret.
+// @Tags auth
+// @Produce json
+// @Success 201 {object} TOTPEnrolmentApiDto "Secret and otpauth URI"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 409 {object} map[string]string "Authenticator app already enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/mfa/totp [post]
+func (handler *MFAHandler) EnrolTOTP(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	authUser := GetAuthUser(ctx)
+
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		handler.logger.Printf("ERROR: newTOTPSecret: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	err = handler.repository.StartTOTPEnrolment(ctx.Request.Context(), authUser.ID, secret)
+	if errors.Is(err, ErrTOTPAlreadyEnabled) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryStartTOTPEnrolment: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	credential := &TOTPCredential{UserID: authUser.ID, Secret: secret}
+	ctx.JSON(http.StatusCreated, TOTPEnrolmentApiDto{
+		Secret:     secret,
+		OtpauthURI: credential.URI(authUser.Email),
+	})
+}
+
+// @Summary Confirm authenticator app
+// @Description Turns on the authenticator app once a code from it checks out, and returns a new set of recovery codes. They are shown only once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body MFACodeApiDto true "Code from the authenticator app"
+// @Success 200 {object} RecoveryCodesApiDto "Recovery codes"
+// @Failure 400 {object} map[string]string "Invalid input or code"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 409 {object} map[string]string "No enrolment in progress"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/mfa/totp/confirm [post]
+func (handler *MFAHandler) ConfirmTOTP(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	authUser := GetAuthUser(ctx)
+
+	mfaCodeApiDto, ok := handler.readCode(ctx)
+	if !ok {
+		return
+	}
+
+	credential, err := handler.repository.FindTOTPCredential(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindTOTPCredential: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if credential == nil || credential.IsConfirmed() {
+		ctx.JSON(http.StatusConflict, gin.H{"error": ErrTOTPNotPending.Error()})
+		return
+	}
+
+	step, ok := credential.MatchStep(normalizeMFACode(mfaCodeApiDto.Code), time.Now())
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	recoveryCodes, err := NewRecoveryCodes()
+	if err != nil {
+		handler.logger.Printf("ERROR: newRecoveryCodes: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	err = handler.repository.ConfirmTOTPCredential(ctx.Request.Context(), authUser.ID, step, HashRecoveryCodes(recoveryCodes))
+	if errors.Is(err, ErrTOTPNotPending) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryConfirmTOTPCredential: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RecoveryCodesApiDto{RecoveryCodes: recoveryCodes})
+}
+
+// @Summary Turn off authenticator app
+// @Description Removes the current user's authenticator app and recovery codes. Requires a current code from the app or an unused recovery code.
+// @Tags auth
+// @Accept json
+// @Param code body MFACodeApiDto true "Code from the authenticator app, or a recovery code"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid input or code"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/mfa/totp [delete]
+func (handler *MFAHandler) DisableTOTP(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	mfaCodeApiDto, ok := handler.readCode(ctx)
+	if !ok {
+		return
+	}
+
+	verified, err := handler.checkSecondFactor(ctx, authUser.ID, mfaCodeApiDto.Code, true)
+	if err != nil {
+		handler.logger.Printf("ERROR: checkSecondFactor: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if !verified {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	err = handler.repository.DisableTOTP(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryDisableTOTP: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replaces the current user's recovery codes. Requires a current code from the authenticator app. The new codes are shown only once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body MFACodeApiDto true "Code from the authenticator app"
+// @Success 200 {object} RecoveryCodesApiDto "Recovery codes"
+// @Failure 400 {object} map[string]string "Invalid input or code"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/mfa/recovery-codes [post]
+func (handler *MFAHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	authUser := GetAuthUser(ctx)
+
+	mfaCodeApiDto, ok := handler.readCode(ctx)
+	if !ok {
+		return
+	}
+
+	verified, err := handler.checkSecondFactor(ctx, authUser.ID, mfaCodeApiDto.Code, false)
+	if err != nil {
+		handler.logger.Printf("ERROR: checkSecondFactor: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if !verified {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	recoveryCodes, err := NewRecoveryCodes()
+	if err != nil {
+		handler.logger.Printf("ERROR: newRecoveryCodes: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	err = handler.repository.ReplaceRecoveryCodes(ctx.Request.Context(), authUser.ID, HashRecoveryCodes(recoveryCodes))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryReplaceRecoveryCodes: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RecoveryCodesApiDto{RecoveryCodes: recoveryCodes})
+}
+
+func (handler *MFAHandler) readCode(ctx *gin.Context) (*MFACodeApiDto, bool) {
+	var mfaCodeApiDto MFACodeApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&mfaCodeApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeMFACodeApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return nil, false
+	}
+
+	err = mfaCodeApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateMFACodeApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return nil, false
+	}
+	return &mfaCodeApiDto, true
+}
+
+// checkSecondFactor spends code if it is a valid code from the user's
+// authenticator app or, when allowed, one of their unused recovery codes.
+func (handler *MFAHandler) checkSecondFactor(ctx *gin.Context, userID uuid.UUID, code string, allowRecoveryCode bool) (bool, error) {
+	credential, err := handler.repository.FindTOTPCredential(ctx.Request.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+	if !credential.IsConfirmed() {
+		return false, nil
+	}
+
+	code = normalizeMFACode(code)
+	if IsTOTPCode(code) {
+		step, ok := credential.MatchStep(code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return handler.repository.UseTOTPStep(ctx.Request.Context(), userID, step)
+	}
+
+	if !allowRecoveryCode {
+		return false, nil
+	}
+	return handler.repository.UseRecoveryCode(ctx.Request.Context(), userID, HashRecoveryCode(code))
+}
+
+// normalizeMFACode drops the spaces some apps show in the middle of a code.
+func normalizeMFACode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
@@ -0,0 +1,132 @@
+package authentication
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// @Summary Submit second factor
+// @Description Completes a sign-in that is waiting for a second factor. Send the mfaToken from the sign-in as the bearer token, with a code from the authenticator app or an unused recovery code. The access token is returned and the refresh token is set as a cookie. Too many wrong codes end the sign-in.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body MFACodeApiDto true "Code from the authenticator app, or a recovery code"
+// @Success 200 {object} map[string]string "Access token"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Invalid code, or the sign-in has expired"
+// @Failure 403 {object} map[string]string "Account deactivated, with the account_deactivated code"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/mfa/verify [post]
+func (handler *MFAHandler) Verify(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	claims := GetAccessTokenClaims(ctx)
+	userID, err := claims.UserID()
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
+		return
+	}
+	sessionID := claims.Session()
+
+	mfaCodeApiDto, ok := handler.readCode(ctx)
+	if !ok {
+		return
+	}
+
+	challenge, err := handler.repository.FindMFAChallenge(ctx.Request.Context(), sessionID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindMFAChallenge: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if challenge == nil || challenge.UserID != userID || challenge.IsExpired() {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in has expired, please sign in again"})
+		return
+	}
+
+	verified, err := handler.checkSecondFactor(ctx, userID, mfaCodeApiDto.Code, true)
+	if err != nil {
+		handler.logger.Printf("ERROR: checkSecondFactor: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if !verified {
+		handler.rejectCode(ctx, userID, sessionID)
+		return
+	}
+
+	err = handler.repository.CompleteMFAChallenge(ctx.Request.Context(), sessionID)
+	if errors.Is(err, ErrMFAChallengeNotFound) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in has expired, please sign in again"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCompleteMFAChallenge: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	// the mfa token has done its job
+	err = handler.revocationStore.RevokeToken(ctx.Request.Context(), claims)
+	if err != nil {
+		handler.logger.Printf("ERROR: revocationStoreRevokeToken: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Issue(ctx.Request.Context(), userID, sessionID)
+	if errors.Is(err, ErrAccountDeactivated) {
+		respondAccountDeactivated(ctx)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssue: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	handler.cookies.setRefreshToken(ctx, tokens.RefreshToken, tokens.RefreshTokenExpiresAt)
+	ctx.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken})
+}
+
+// rejectCode counts a wrong code against the sign-in, and ends the sign-in
+// once MaxMFAAttempts is reached.
+func (handler *MFAHandler) rejectCode(ctx *gin.Context, userID uuid.UUID, sessionID uuid.UUID) {
+	failedAttempts, err := handler.repository.RecordFailedMFAAttempt(ctx.Request.Context(), sessionID)
+	if errors.Is(err, ErrMFAChallengeNotFound) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in has expired, please sign in again"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRecordFailedMFAAttempt: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if failedAttempts >= MaxMFAAttempts {
+		err = handler.revocationStore.RevokeSession(ctx.Request.Context(), userID, sessionID)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			handler.logger.Printf("ERROR: revocationStoreRevokeSession: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "too many incorrect codes, please sign in again"})
+		return
+	}
+
+	ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+}
+
+// respondMFARequired answers a sign-in that is waiting for a second factor
+// with the token for /auth/mfa/verify in place of the access token.
+func respondMFARequired(ctx *gin.Context, tokenIssuer *TokenIssuer, logger *log.Logger, userID uuid.UUID, sessionID uuid.UUID) {
+	mfaToken, err := tokenIssuer.IssueMFAChallenge(ctx.Request.Context(), userID, sessionID)
+	if err != nil {
+		logger.Printf("ERROR: tokenIssuerIssueMFAChallenge: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"mfaRequired": true, "mfaToken": mfaToken})
+}
@@ -0,0 +1,128 @@
+package authentication
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"catalyst.api/config"
+
+	"github.com/markbates/goth"
+	"github.com/markbates/goth/providers/github"
+	"github.com/markbates/goth/providers/gitlab"
+	"github.com/markbates/goth/providers/google"
+	"github.com/markbates/goth/providers/microsoftonline"
+	"github.com/markbates/goth/providers/openidConnect"
+)
+
+// EnabledProvider describes a login provider for the front-end login page.
+type EnabledProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	LoginURL    string `json:"loginUrl"`
+}
+
+// providerHTTPTimeout bounds each call the goth providers make to their
+// provider.
+const providerHTTPTimeout = 10 * time.Second
+
+var (
+	providerNamePattern   = regexp.MustCompile(`^[a-z0-9-]+$`)
+	reservedProviderNames = []string{"providers", "refresh", "logout", "identities", "tokens", "device", "sessions", "mfa", "invitations", "magic-link", "impersonations", "saml", "scim"}
+)
+
+// read:org lets the admission rules check GitHub organization and team membership.
+var defaultProviderScopes = map[string][]string{
+	"github":    {"user", "repo", "read:org"},
+	"gitlab":    {"read_user"},
+	"google":    {"email", "profile"},
+	"microsoft": {"openid", "offline_access", "user.read"},
+	"oidc":      {"email", "profile"},
+}
+
+var defaultProviderDisplayNames = map[string]string{
+	"github":    "GitHub",
+	"gitlab":    "GitLab",
+	"google":    "Google",
+	"microsoft": "Microsoft",
+	"oidc":      "Single Sign-On",
+}
+
+// registerProviders builds a goth provider for every configured provider and
+// registers them with goth.UseProviders. It returns the providers for the
+// login page and the type of each provider by name.
+func registerProviders(providerConfigs []config.OAuthProviderConfig) ([]EnabledProvider, map[string]string, error) {
+	providers := []goth.Provider{}
+	enabled := []EnabledProvider{}
+	types := map[string]string{}
+
+	for _, providerConfig := range providerConfigs {
+		if !providerNamePattern.MatchString(providerConfig.Name) || slices.Contains(reservedProviderNames, providerConfig.Name) {
+			return nil, nil, fmt.Errorf("invalid auth provider name %q", providerConfig.Name)
+		}
+
+		provider, err := newGothProvider(providerConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		provider.SetName(providerConfig.Name)
+		providers = append(providers, provider)
+		types[providerConfig.Name] = providerConfig.Type
+
+		displayName := providerConfig.DisplayName
+		if displayName == "" {
+			displayName = defaultProviderDisplayNames[providerConfig.Type]
+		}
+		enabled = append(enabled, EnabledProvider{
+			Name:        providerConfig.Name,
+			DisplayName: displayName,
+			LoginURL:    "/auth/" + providerConfig.Name,
+		})
+	}
+
+	goth.ClearProviders()
+	goth.UseProviders(providers...)
+	return enabled, types, nil
+}
+
+func newGothProvider(providerConfig config.OAuthProviderConfig) (goth.Provider, error) {
+	callbackURL := fmt.Sprintf("%s/auth/%s/callback", strings.TrimSuffix(providerConfig.CallbackBaseURL, "/"), providerConfig.Name)
+	scopes := providerConfig.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultProviderScopes[providerConfig.Type]
+	}
+
+	httpClient := &http.Client{Timeout: providerHTTPTimeout}
+	switch providerConfig.Type {
+	case "github":
+		provider := github.New(providerConfig.ClientID, providerConfig.ClientSecret, callbackURL, scopes...)
+		provider.HTTPClient = httpClient
+		return provider, nil
+	case "gitlab":
+		provider := gitlab.New(providerConfig.ClientID, providerConfig.ClientSecret, callbackURL, scopes...)
+		provider.HTTPClient = httpClient
+		return provider, nil
+	case "google":
+		provider := google.New(providerConfig.ClientID, providerConfig.ClientSecret, callbackURL, scopes...)
+		provider.HTTPClient = httpClient
+		return provider, nil
+	case "microsoft":
+		provider := microsoftonline.New(providerConfig.ClientID, providerConfig.ClientSecret, callbackURL, scopes...)
+		provider.HTTPClient = httpClient
+		return provider, nil
+	case "oidc":
+		if providerConfig.DiscoveryURL == "" {
+			return nil, fmt.Errorf("auth provider %q needs a discovery URL", providerConfig.Name)
+		}
+		provider, err := openidConnect.New(providerConfig.ClientID, providerConfig.ClientSecret, callbackURL, providerConfig.DiscoveryURL, scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("auth provider %q: %w", providerConfig.Name, err)
+		}
+		provider.HTTPClient = httpClient
+		return provider, nil
+	}
+	return nil, fmt.Errorf("auth provider %q has unsupported type %q", providerConfig.Name, providerConfig.Type)
+}
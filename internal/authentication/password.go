@@ -0,0 +1,94 @@
+package authentication
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordHashCost             = 12
+	PasswordMinLength            = 12
+	PasswordResetTokenTimeToLive = time.Hour
+)
+
+var (
+	ErrEmailTaken                 = errors.New("an account with this email already exists")
+	ErrInvalidPasswordResetToken  = errors.New("invalid or expired password reset token")
+	ErrPasswordTooShort           = errors.New("password must be at least 12 characters")
+	ErrPasswordTooLong            = errors.New("password must be at most 72 bytes")
+	ErrPasswordCredentialsInvalid = errors.New("invalid email or password")
+)
+
+// PasswordCredentials is what login needs to check a password. PasswordHash is
+// empty for users who have only signed in with a provider.
+type PasswordCredentials struct {
+	UserID       uuid.UUID
+	PasswordHash string
+}
+
+// PasswordResetToken is a single-use token mailed to the user. Only the hash
+// is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+func NewPasswordResetToken(userID uuid.UUID, timeToLive time.Duration) (*PasswordResetToken, string, error) {
+	plainToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	resetToken := &PasswordResetToken{
+		UserID:    userID,
+		TokenHash: HashPasswordResetToken(plainToken),
+		ExpiresAt: time.Now().Add(timeToLive),
+	}
+	return resetToken, plainToken, nil
+}
+
+func HashPasswordResetToken(plainToken string) string {
+	return hashOpaqueToken(plainToken)
+}
+
+// ValidatePassword checks the length limits. bcrypt ignores anything past 72
+// bytes, so longer passwords are rejected rather than silently truncated.
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < PasswordMinLength {
+		return ErrPasswordTooShort
+	}
+	if len(password) > 72 {
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordHashCost)
+	if err != nil {
+		return "", err
+	}
+	return string(passwordHash), nil
+}
+
+// CheckPassword compares password against passwordHash. An empty hash is
+// compared against a throwaway hash so unknown emails take as long as wrong
+// passwords.
+func CheckPassword(passwordHash string, password string) bool {
+	if passwordHash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
+
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), PasswordHashCost)
+	return passwordHash
+})
@@ -0,0 +1,300 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"catalyst.api/internal/mailer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+// passwordResetMailTimeout bounds how long a reset email may take to send
+// after the request has been answered.
+const passwordResetMailTimeout = 30 * time.Second
+
+type ChangePasswordApiDto struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
+func (dto *ChangePasswordApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type ForgotPasswordApiDto struct {
+	Email string `json:"email" validate:"required"`
+}
+
+func (dto *ForgotPasswordApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type ResetPasswordApiDto struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+func (dto *ResetPasswordApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+// @Summary Change password
+// @Description Changes the current user's password, or sets one for users who have only signed in with a provider. The current password is required when one is set; setting a first password requires having signed in within the last 10 minutes, or the response is a 403 with code recent_sign_in_required. Every other session is signed out and new tokens are returned for this one. Only a signed-in session may change the password, not a personal access token.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param password body ChangePasswordApiDto true "Current and new password"
+// @Success 200 {object} map[string]string "Access token"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized or wrong current password"
+// @Failure 403 {object} map[string]string "Made with a personal access token, without the authentication scope, or without a recent sign-in"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/password [put]
+func (handler *PasswordHandler) ChangePassword(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	// the new tokens continue the caller's session, so there has to be one
+	sessionID := GetAccessTokenClaims(ctx).Session()
+	if sessionID == uuid.Nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "a signed-in session is required to change the password"})
+		return
+	}
+
+	var changePasswordApiDto ChangePasswordApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&changePasswordApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeChangePasswordApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = changePasswordApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateChangePasswordApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = ValidatePassword(changePasswordApiDto.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentPasswordHash, err := handler.repository.FindPasswordHash(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindPasswordHash: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if currentPasswordHash != "" && !CheckPassword(currentPasswordHash, changePasswordApiDto.CurrentPassword) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+		return
+	}
+	// with no password to check, a first password needs a fresh sign-in instead
+	if currentPasswordHash == "" {
+		session, err := handler.repository.FindActiveSession(ctx.Request.Context(), authUser.ID, sessionID)
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryFindActiveSession: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		if session == nil || !session.IsRecentSignIn() {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "sign in again to set a password", "code": RecentSignInRequiredCode})
+			return
+		}
+	}
+
+	passwordHash, err := HashPassword(changePasswordApiDto.NewPassword)
+	if err != nil {
+		handler.logger.Printf("ERROR: hashPassword: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	err = handler.repository.UpdatePasswordHash(ctx.Request.Context(), authUser.ID, passwordHash)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryUpdatePasswordHash: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	err = handler.repository.RevokeRefreshTokensForUser(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRevokeRefreshTokensForUser: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = handler.revocationStore.RevokeOtherSessions(ctx.Request.Context(), authUser.ID, sessionID)
+	if err != nil {
+		handler.logger.Printf("ERROR: revocationStoreRevokeOtherSessions: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Issue(ctx.Request.Context(), authUser.ID, sessionID)
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssue: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	handler.cookies.setRefreshToken(ctx, tokens.RefreshToken, tokens.RefreshTokenExpiresAt)
+	ctx.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken})
+}
+
+// @Summary Request a password reset
+// @Description Emails a single-use password reset link if an account uses this email. The response is the same whether or not it does.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body ForgotPasswordApiDto true "Account email"
+// @Success 202 {object} map[string]string "Accepted"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/password/forgot [post]
+func (handler *PasswordHandler) ForgotPassword(ctx *gin.Context) {
+	var forgotPasswordApiDto ForgotPasswordApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&forgotPasswordApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeForgotPasswordApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = forgotPasswordApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateForgotPasswordApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	email := strings.TrimSpace(forgotPasswordApiDto.Email)
+	credentials, err := handler.repository.FindPasswordCredentials(ctx.Request.Context(), email)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindPasswordCredentials: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if credentials != nil {
+		resetToken, plainResetToken, err := NewPasswordResetToken(credentials.UserID, PasswordResetTokenTimeToLive)
+		if err != nil {
+			handler.logger.Printf("ERROR: newPasswordResetToken: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		resetToken.ID, err = handler.repository.CreatePasswordResetToken(ctx.Request.Context(), resetToken)
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryCreatePasswordResetToken: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		// send after responding so the response time does not reveal the account
+		go handler.sendPasswordResetMail(email, plainResetToken)
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "if an account uses this email, a reset link has been sent"})
+}
+
+func (handler *PasswordHandler) sendPasswordResetMail(email string, plainResetToken string) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetMailTimeout)
+	defer cancel()
+
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", handler.frontendURL, url.QueryEscape(plainResetToken))
+	message := mailer.Message{
+		To:      email,
+		Subject: "Reset your catalyst password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your catalyst account.\n\n"+
+			"Use this link within %s to choose a new password:\n\n%s\n\n"+
+			"If this wasn't you, you can ignore this email.\n", PasswordResetTokenTimeToLive, resetURL),
+	}
+
+	err := handler.mailer.Send(ctx, message)
+	if err != nil {
+		handler.logger.Printf("ERROR: mailerSend: %v", err)
+	}
+}
+
+// @Summary Reset password
+// @Description Sets a new password using a token from the reset email. Every existing session for the account is signed out and its personal access tokens are revoked.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body ResetPasswordApiDto true "Reset token and new password"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid input or reset token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/password/reset [post]
+func (handler *PasswordHandler) ResetPassword(ctx *gin.Context) {
+	var resetPasswordApiDto ResetPasswordApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&resetPasswordApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeResetPasswordApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = resetPasswordApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateResetPasswordApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = ValidatePassword(resetPasswordApiDto.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordHash, err := HashPassword(resetPasswordApiDto.NewPassword)
+	if err != nil {
+		handler.logger.Printf("ERROR: hashPassword: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	userID, err := handler.repository.ResetPassword(ctx.Request.Context(), HashPasswordResetToken(resetPasswordApiDto.Token), passwordHash)
+	if errors.Is(err, ErrInvalidPasswordResetToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryResetPassword: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	// a reset usually means the old password is compromised, so sign out everywhere
+	err = handler.revocationStore.RevokeAllForUser(ctx.Request.Context(), userID)
+	if err != nil {
+		handler.logger.Printf("ERROR: revocationStoreRevokeAllForUser: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = handler.repository.RevokeRefreshTokensForUser(ctx.Request.Context(), userID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRevokeRefreshTokensForUser: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	handler.cookies.clearRefreshToken(ctx)
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package authentication
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"catalyst.api/internal/mailer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestChangePasswordRejectsPersonalAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repository := newFakeAuthenticationRepository()
+	authUser := &AuthUser{ID: uuid.New(), Email: "ada@example.org", Roles: []string{RoleUser}}
+	repository.authUsers[authUser.ID] = authUser
+
+	// a token holding every scope the user has still must not change the password
+	personalAccessToken, plainToken, err := NewPersonalAccessToken(authUser.ID, "ci", GrantedScopes(authUser), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repository.CreatePersonalAccessToken(context.Background(), personalAccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keySet := newTestKeySet(t)
+	authMiddleware := AuthenticationMiddleware{AuthenticationRepository: repository, KeySet: keySet}
+	tokenIssuer := NewTokenIssuer(repository, keySet)
+	handler := NewPasswordHandler(repository, tokenIssuer, nil, mailer.NewMemoryMailer(), NewAdmissionPolicy(repository, &AdmissionRules{}, nil), NewCookies(keySet, false), testFrontendURL, log.New(io.Discard, "", 0))
+
+	tests := []struct {
+		name       string
+		middleware []gin.HandlerFunc
+	}{
+		{"through the route's middleware", []gin.HandlerFunc{authMiddleware.Authenticate(), authMiddleware.RequireAuthUser(), authMiddleware.RejectImpersonation(), authMiddleware.RejectPersonalAccessTokens()}},
+		// the handler itself refuses callers without a session
+		{"handler alone", []gin.HandlerFunc{authMiddleware.Authenticate()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.PUT("/auth/password", append(test.middleware, handler.ChangePassword)...)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/auth/password", strings.NewReader(`{"newPassword":"correct horse battery staple"}`))
+			request.Header.Set("Authorization", "Bearer "+plainToken)
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusForbidden {
+				t.Errorf("change password = %d %s, want 403", recorder.Code, recorder.Body)
+			}
+			if cookies := recorder.Result().Cookies(); len(cookies) != 0 {
+				t.Errorf("change password set cookies %v", cookies)
+			}
+		})
+	}
+}
+
+func TestChangePasswordSettingFirstPasswordNeedsRecentSignIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keySet := newTestKeySet(t)
+
+	tests := []struct {
+		name       string
+		signedInAt time.Duration
+		scopes     []string
+		status     int
+	}{
+		{"recent sign-in", time.Minute, DefaultUserScopes, http.StatusOK},
+		{"old sign-in", RecentSignInWindow + time.Minute, DefaultUserScopes, http.StatusForbidden},
+		{"without the authentication scope", time.Minute, []string{"projects:read"}, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeAuthenticationRepository()
+			authUser := &AuthUser{ID: uuid.New(), Email: "ada@example.org", Roles: []string{RoleUser}}
+			repository.authUsers[authUser.ID] = authUser
+			session := NewSession(authUser.ID, "github", "", "")
+			session.CreatedAt = time.Now().Add(-test.signedInAt)
+			sessionID, _ := repository.CreateSession(context.Background(), session)
+
+			accessToken, err := keySet.GenerateJWT(authUser.ID, authUser.Email, JoinScopes(test.scopes), sessionID, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			revocationStore := &fakeRevocationStore{}
+			authMiddleware := AuthenticationMiddleware{AuthenticationRepository: repository, RevocationStore: revocationStore, KeySet: keySet}
+			handler := NewPasswordHandler(repository, NewTokenIssuer(repository, keySet), revocationStore, mailer.NewMemoryMailer(), NewAdmissionPolicy(repository, &AdmissionRules{}, nil), NewCookies(keySet, false), testFrontendURL, log.New(io.Discard, "", 0))
+			router := gin.New()
+			router.PUT("/auth/password", authMiddleware.Authenticate(), authMiddleware.RequireAuthUser(), authMiddleware.RequireScopes(AuthScope), authMiddleware.RejectImpersonation(), authMiddleware.RejectPersonalAccessTokens(), handler.ChangePassword)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/auth/password", strings.NewReader(`{"newPassword":"correct horse battery staple"}`))
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Errorf("change password = %d %s, want %d", recorder.Code, recorder.Body, test.status)
+			}
+			if passwordSet := repository.passwordHashes[authUser.ID] != ""; passwordSet != (test.status == http.StatusOK) {
+				t.Errorf("password set = %v, want %v", passwordSet, test.status == http.StatusOK)
+			}
+		})
+	}
+}
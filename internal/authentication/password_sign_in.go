@@ -0,0 +1,230 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"catalyst.api/internal/mailer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+)
+
+type RegisterApiDto struct {
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required"`
+	FirstName string `json:"firstName" validate:"required"`
+	LastName  string `json:"lastName" validate:"required"`
+}
+
+func (dto *RegisterApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type LoginApiDto struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (dto *LoginApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type PasswordHandler struct {
+	repository      AuthenticationRepository
+	tokenIssuer     *TokenIssuer
+	revocationStore RevocationStore
+	mailer          mailer.Mailer
+	admission       *AdmissionPolicy
+	cookies         *Cookies
+	frontendURL     string
+	logger          *log.Logger
+}
+
+func NewPasswordHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, revocationStore RevocationStore, mailer mailer.Mailer, admission *AdmissionPolicy, cookies *Cookies, frontendURL string, logger *log.Logger) *PasswordHandler {
+	return &PasswordHandler{
+		repository:      authenticationRepo,
+		tokenIssuer:     tokenIssuer,
+		revocationStore: revocationStore,
+		mailer:          mailer,
+		admission:       admission,
+		cookies:         cookies,
+		frontendURL:     frontendURL,
+		logger:          logger,
+	}
+}
+
+// @Summary Register with email and password
+// @Description Creates an account that signs in with a password, then signs it in the same way as the OAuth callback: the access token is returned and the refresh token is set as a cookie. The email is not confirmed, so while invitations or email domains restrict sign-in new accounts must come through a provider or a magic link instead.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body RegisterApiDto true "Registration payload"
+// @Success 201 {object} map[string]string "Access token"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 403 {object} map[string]string "Turned away by the admission rules, with an error code"
+// @Failure 409 {object} map[string]string "Email already in use"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/register [post]
+func (handler *PasswordHandler) Register(ctx *gin.Context) {
+	var registerApiDto RegisterApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&registerApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeRegisterApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+	registerApiDto.Email = strings.TrimSpace(registerApiDto.Email)
+
+	err = registerApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateRegisterApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = ValidatePassword(registerApiDto.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the unique constraint on email is case sensitive, so look for a match first
+	existingCredentials, err := handler.repository.FindPasswordCredentials(ctx.Request.Context(), registerApiDto.Email)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindPasswordCredentials: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if existingCredentials != nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": ErrEmailTaken.Error()})
+		return
+	}
+
+	candidate := AdmissionCandidate{Email: registerApiDto.Email}
+	if !admitCandidate(ctx, handler.admission, handler.logger, candidate) {
+		return
+	}
+
+	passwordHash, err := HashPassword(registerApiDto.Password)
+	if err != nil {
+		handler.logger.Printf("ERROR: hashPassword: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	authUser := &AuthUser{
+		Email:     registerApiDto.Email,
+		FirstName: registerApiDto.FirstName,
+		LastName:  registerApiDto.LastName,
+	}
+	authUserID, err := handler.repository.RegisterPasswordAuthUser(ctx.Request.Context(), authUser, passwordHash)
+	if errors.Is(err, ErrEmailTaken) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": ErrEmailTaken.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRegisterPasswordAuthUser: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	sessionID, err := startSession(ctx, handler.tokenIssuer, authUserID, SessionProviderPassword)
+	if err != nil {
+		handler.logger.Printf("ERROR: startSession: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Issue(ctx.Request.Context(), authUserID, sessionID)
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssue: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	handler.cookies.setRefreshToken(ctx, tokens.RefreshToken, tokens.RefreshTokenExpiresAt)
+	ctx.JSON(http.StatusCreated, gin.H{"token": tokens.AccessToken})
+}
+
+// @Summary Sign in with email and password
+// @Description Checks the password and signs the user in the same way as the OAuth callback: the access token is returned and the refresh token is set as a cookie. Users with an authenticator app get mfaRequired and an mfaToken for /auth/mfa/verify instead.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginApiDto true "Email and password"
+// @Success 200 {object} map[string]interface{} "Access token, or MFA challenge token"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Invalid email or password"
+// @Failure 403 {object} map[string]string "Turned away by the admission rules, or account deactivated, with an error code"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/login [post]
+func (handler *PasswordHandler) Login(ctx *gin.Context) {
+	var loginApiDto LoginApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&loginApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeLoginApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = loginApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateLoginApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	credentials, err := handler.repository.FindPasswordCredentials(ctx.Request.Context(), strings.TrimSpace(loginApiDto.Email))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindPasswordCredentials: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if credentials == nil {
+		credentials = &PasswordCredentials{}
+	}
+
+	// unknown emails and accounts without a password still run a comparison
+	if !CheckPassword(credentials.PasswordHash, loginApiDto.Password) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": ErrPasswordCredentialsInvalid.Error()})
+		return
+	}
+
+	candidate := AdmissionCandidate{Email: strings.TrimSpace(loginApiDto.Email), Existing: true}
+	if !admitCandidate(ctx, handler.admission, handler.logger, candidate) {
+		return
+	}
+
+	sessionID, err := startSession(ctx, handler.tokenIssuer, credentials.UserID, SessionProviderPassword)
+	if errors.Is(err, ErrAccountDeactivated) {
+		respondAccountDeactivated(ctx)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: startSession: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Issue(ctx.Request.Context(), credentials.UserID, sessionID)
+	if errors.Is(err, ErrAccountDeactivated) {
+		respondAccountDeactivated(ctx)
+		return
+	}
+	if errors.Is(err, ErrMFARequired) {
+		respondMFARequired(ctx, handler.tokenIssuer, handler.logger, credentials.UserID, sessionID)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssue: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	handler.cookies.setRefreshToken(ctx, tokens.RefreshToken, tokens.RefreshTokenExpiresAt)
+	ctx.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken})
+}
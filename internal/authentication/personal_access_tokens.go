@@ -0,0 +1,186 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+type PersonalAccessTokenApiDto struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	TokenHint  string     `json:"tokenHint"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreatePersonalAccessTokenApiDto struct {
+	Name          string   `json:"name" validate:"required,max=255"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" validate:"required,min=1,max=365"`
+}
+
+func (dto *CreatePersonalAccessTokenApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type CreatedPersonalAccessTokenApiDto struct {
+	PersonalAccessTokenApiDto
+	Token string `json:"token"`
+}
+
+type PersonalAccessTokenHandler struct {
+	repository AuthenticationRepository
+	logger     *log.Logger
+}
+
+func NewPersonalAccessTokenHandler(authenticationRepo AuthenticationRepository, logger *log.Logger) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		repository: authenticationRepo,
+		logger:     logger,
+	}
+}
+
+// @Summary List personal access tokens
+// @Description Lists the current user's personal access tokens that have not been revoked. Token values are never returned after creation.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]PersonalAccessTokenApiDto "Personal access tokens"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/tokens [get]
+func (handler PersonalAccessTokenHandler) ListPersonalAccessTokens(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	personalAccessTokens, err := handler.repository.ListPersonalAccessTokens(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListPersonalAccessTokens: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	personalAccessTokenApiDtos := make([]PersonalAccessTokenApiDto, 0, len(personalAccessTokens))
+	for _, personalAccessToken := range personalAccessTokens {
+		personalAccessTokenApiDtos = append(personalAccessTokenApiDtos, newPersonalAccessTokenApiDto(personalAccessToken))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tokens": personalAccessTokenApiDtos})
+}
+
+// @Summary Create a personal access token
+// @Description Creates a token for scripts and CI, sent as a bearer token like an access token. Scopes are limited to those the user holds. The token value is only returned in this response.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body CreatePersonalAccessTokenApiDto true "Token name, scopes and lifetime"
+// @Success 201 {object} CreatedPersonalAccessTokenApiDto "Created token"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/tokens [post]
+func (handler PersonalAccessTokenHandler) CreatePersonalAccessToken(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	var createApiDto CreatePersonalAccessTokenApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&createApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeCreatePersonalAccessTokenApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = createApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateCreatePersonalAccessTokenApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	granted := GrantedScopes(authUser)
+	for _, scope := range createApiDto.Scopes {
+		if !HasScopes(granted, scope) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("scope %q cannot be granted", scope)})
+			return
+		}
+	}
+
+	expiresAt := time.Now().Add(time.Duration(createApiDto.ExpiresInDays) * 24 * time.Hour)
+	personalAccessToken, plainToken, err := NewPersonalAccessToken(authUser.ID, createApiDto.Name, createApiDto.Scopes, expiresAt)
+	if err != nil {
+		handler.logger.Printf("ERROR: newPersonalAccessToken: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	personalAccessToken.ID, err = handler.repository.CreatePersonalAccessToken(ctx.Request.Context(), personalAccessToken)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreatePersonalAccessToken: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	personalAccessToken.CreatedAt = time.Now()
+
+	ctx.JSON(http.StatusCreated, CreatedPersonalAccessTokenApiDto{
+		PersonalAccessTokenApiDto: newPersonalAccessTokenApiDto(personalAccessToken),
+		Token:                     plainToken,
+	})
+}
+
+// @Summary Revoke a personal access token
+// @Description Revokes one of the current user's personal access tokens. It stops working immediately.
+// @Tags auth
+// @Param id path string true "Token ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Token not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/tokens/{id} [delete]
+func (handler PersonalAccessTokenHandler) RevokePersonalAccessToken(ctx *gin.Context) {
+	personalAccessTokenID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Token ID"})
+		return
+	}
+	authUser := GetAuthUser(ctx)
+
+	err = handler.repository.RevokePersonalAccessToken(ctx.Request.Context(), authUser.ID, personalAccessTokenID)
+	if errors.Is(err, ErrPersonalAccessTokenNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRevokePersonalAccessToken: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func newPersonalAccessTokenApiDto(personalAccessToken *PersonalAccessToken) PersonalAccessTokenApiDto {
+	return PersonalAccessTokenApiDto{
+		ID:         personalAccessToken.ID,
+		Name:       personalAccessToken.Name,
+		TokenHint:  personalAccessToken.TokenHint,
+		Scopes:     personalAccessToken.Scopes,
+		ExpiresAt:  personalAccessToken.ExpiresAt,
+		LastUsedAt: personalAccessToken.LastUsedAt,
+		CreatedAt:  personalAccessToken.CreatedAt,
+	}
+}
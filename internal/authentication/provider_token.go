@@ -0,0 +1,144 @@
+package authentication
+
+import (
+	"errors"
+	"time"
+
+	"catalyst.api/internal/encryption"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// providerTokenExpiryDelta treats tokens as expired a little early, so a token
+// handed out does not run out while the caller is still using it.
+const providerTokenExpiryDelta = time.Minute
+
+var (
+	// ErrProviderTokenNotFound means there is no usable token for the identity;
+	// the user has to sign in with the provider again.
+	ErrProviderTokenNotFound = errors.New("provider token not found")
+	// ErrProviderTokenExpired means the token has expired and the provider
+	// does not support refreshing it.
+	ErrProviderTokenExpired = errors.New("provider token expired")
+)
+
+// ProviderToken is the OAuth token a provider issued for one linked identity,
+// kept so the API can call the provider on the user's behalf.
+type ProviderToken struct {
+	AuthUserProviderID uuid.UUID
+	AccessToken        string
+	RefreshToken       string
+	ExpiresAt          *time.Time
+}
+
+// EncryptedProviderToken is how a ProviderToken is stored. Both tokens are
+// encrypted with a data key of their own, which is in turn wrapped by the
+// keyring key named by KeyID.
+type EncryptedProviderToken struct {
+	AuthUserProviderID uuid.UUID
+	KeyID              string
+	WrappedKey         []byte
+	AccessToken        []byte
+	RefreshToken       []byte
+	ExpiresAt          *time.Time
+}
+
+func (providerToken *ProviderToken) IsExpired() bool {
+	if providerToken.ExpiresAt == nil {
+		return false
+	}
+	return time.Now().Add(providerTokenExpiryDelta).After(*providerToken.ExpiresAt)
+}
+
+func (providerToken *ProviderToken) OAuth2Token() *oauth2.Token {
+	oauth2Token := &oauth2.Token{
+		AccessToken:  providerToken.AccessToken,
+		TokenType:    "Bearer",
+		RefreshToken: providerToken.RefreshToken,
+	}
+	if providerToken.ExpiresAt != nil {
+		oauth2Token.Expiry = *providerToken.ExpiresAt
+	}
+	return oauth2Token
+}
+
+// sealProviderToken encrypts providerToken under a fresh data key. The row ID
+// and field name are bound into each ciphertext so they cannot be swapped
+// between rows or columns.
+func sealProviderToken(keyring *encryption.Keyring, providerToken *ProviderToken) (*EncryptedProviderToken, error) {
+	rowID := providerToken.AuthUserProviderID[:]
+	dataKey, err := keyring.NewDataKey(rowID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := dataKey.Seal([]byte(providerToken.AccessToken), providerTokenAssociatedData(providerToken.AuthUserProviderID, "access_token"))
+	if err != nil {
+		return nil, err
+	}
+
+	var refreshToken []byte
+	if providerToken.RefreshToken != "" {
+		refreshToken, err = dataKey.Seal([]byte(providerToken.RefreshToken), providerTokenAssociatedData(providerToken.AuthUserProviderID, "refresh_token"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	encryptedProviderToken := &EncryptedProviderToken{
+		AuthUserProviderID: providerToken.AuthUserProviderID,
+		KeyID:              dataKey.KeyID,
+		WrappedKey:         dataKey.WrappedKey,
+		AccessToken:        accessToken,
+		RefreshToken:       refreshToken,
+		ExpiresAt:          providerToken.ExpiresAt,
+	}
+	return encryptedProviderToken, nil
+}
+
+// openProviderToken decrypts a stored token. If its data key was wrapped with a
+// key that has since been rotated out, it is re-wrapped with the current key
+// and rewrapped is true, so the caller can store it again.
+func openProviderToken(keyring *encryption.Keyring, encryptedProviderToken *EncryptedProviderToken) (providerToken *ProviderToken, rewrapped bool, err error) {
+	rowID := encryptedProviderToken.AuthUserProviderID[:]
+	dataKey, err := keyring.OpenDataKey(encryptedProviderToken.KeyID, encryptedProviderToken.WrappedKey, rowID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	accessToken, err := dataKey.Open(encryptedProviderToken.AccessToken, providerTokenAssociatedData(encryptedProviderToken.AuthUserProviderID, "access_token"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var refreshToken []byte
+	if len(encryptedProviderToken.RefreshToken) > 0 {
+		refreshToken, err = dataKey.Open(encryptedProviderToken.RefreshToken, providerTokenAssociatedData(encryptedProviderToken.AuthUserProviderID, "refresh_token"))
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if !keyring.IsCurrent(dataKey) {
+		err = keyring.Rewrap(dataKey, rowID)
+		if err != nil {
+			return nil, false, err
+		}
+		encryptedProviderToken.KeyID = dataKey.KeyID
+		encryptedProviderToken.WrappedKey = dataKey.WrappedKey
+		rewrapped = true
+	}
+
+	providerToken = &ProviderToken{
+		AuthUserProviderID: encryptedProviderToken.AuthUserProviderID,
+		AccessToken:        string(accessToken),
+		RefreshToken:       string(refreshToken),
+		ExpiresAt:          encryptedProviderToken.ExpiresAt,
+	}
+	return providerToken, rewrapped, nil
+}
+
+func providerTokenAssociatedData(authUserProviderID uuid.UUID, field string) []byte {
+	return append(authUserProviderID[:], field...)
+}
@@ -0,0 +1,250 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"catalyst.api/config"
+	"catalyst.api/internal/encryption"
+
+	"github.com/google/uuid"
+	"github.com/markbates/goth"
+	"golang.org/x/oauth2"
+)
+
+// providerTokenRefreshTimeout bounds how long a provider may take to refresh a
+// token.
+const providerTokenRefreshTimeout = 10 * time.Second
+
+// ProviderTokenSource stores the tokens providers issue at sign-in and hands
+// out working access tokens for them, so other packages can call a provider's
+// API as the user.
+type ProviderTokenSource struct {
+	repository AuthenticationRepository
+	keyring    *encryption.Keyring
+}
+
+func NewProviderTokenSource(authenticationRepo AuthenticationRepository, keyring *encryption.Keyring) *ProviderTokenSource {
+	return &ProviderTokenSource{
+		repository: authenticationRepo,
+		keyring:    keyring,
+	}
+}
+
+// Save stores the tokens from a provider sign-in against the identity they were
+// issued for. Providers that only send a refresh token on first consent keep
+// the one stored earlier.
+func (source *ProviderTokenSource) Save(ctx context.Context, gothUser goth.User) error {
+	if gothUser.AccessToken == "" {
+		return nil
+	}
+
+	authUserProviderID, err := source.repository.FindAuthUserProviderID(ctx, gothUser.Provider, gothUser.UserID)
+	if err != nil {
+		return err
+	}
+	if authUserProviderID == uuid.Nil {
+		return fmt.Errorf("identity %s/%s is not linked", gothUser.Provider, gothUser.UserID)
+	}
+
+	providerToken := &ProviderToken{
+		AuthUserProviderID: authUserProviderID,
+		AccessToken:        gothUser.AccessToken,
+		RefreshToken:       gothUser.RefreshToken,
+	}
+	if !gothUser.ExpiresAt.IsZero() {
+		providerToken.ExpiresAt = &gothUser.ExpiresAt
+	}
+
+	if providerToken.RefreshToken == "" {
+		existing, err := source.find(ctx, func() (*EncryptedProviderToken, error) {
+			return source.repository.FindProviderToken(ctx, authUserProviderID)
+		})
+		if err != nil && !errors.Is(err, ErrProviderTokenNotFound) {
+			return err
+		}
+		if existing != nil {
+			providerToken.RefreshToken = existing.RefreshToken
+		}
+	}
+
+	return source.save(ctx, providerToken)
+}
+
+// Token returns a working access token for the user's identity at provider,
+// refreshing it first if it has expired. ErrProviderTokenNotFound and
+// ErrProviderTokenExpired mean the user has to sign in with the provider again.
+func (source *ProviderTokenSource) Token(ctx context.Context, userID uuid.UUID, provider string) (*oauth2.Token, error) {
+	providerToken, err := source.findForUser(ctx, userID, provider)
+	if err != nil {
+		return nil, err
+	}
+	if !providerToken.IsExpired() {
+		return providerToken.OAuth2Token(), nil
+	}
+
+	gothProvider, err := goth.GetProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// the row stays locked until the refreshed token is stored, so a rotating
+	// refresh token is never spent twice, by this instance or another
+	err = source.repository.UpdateProviderToken(ctx, providerToken.AuthUserProviderID, func(encryptedProviderToken *EncryptedProviderToken) (*EncryptedProviderToken, error) {
+		lockedToken, rewrapped, err := openProviderToken(source.keyring, encryptedProviderToken)
+		if errors.Is(err, encryption.ErrUnknownKey) {
+			return nil, fmt.Errorf("%w: %v", ErrProviderTokenNotFound, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		providerToken = lockedToken
+
+		// another request may have refreshed it while this one waited
+		if !providerToken.IsExpired() {
+			if rewrapped {
+				return encryptedProviderToken, nil
+			}
+			return nil, nil
+		}
+
+		err = source.refresh(ctx, gothProvider, providerToken)
+		if err != nil {
+			return nil, err
+		}
+		return sealProviderToken(source.keyring, providerToken)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return providerToken.OAuth2Token(), nil
+}
+
+// refresh swaps providerToken's refresh token for a new access token. It
+// gives up after providerTokenRefreshTimeout, as the token's row is locked
+// while it waits.
+func (source *ProviderTokenSource) refresh(ctx context.Context, gothProvider goth.Provider, providerToken *ProviderToken) error {
+	if providerToken.RefreshToken == "" || !gothProvider.RefreshTokenAvailable() {
+		return ErrProviderTokenExpired
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, providerTokenRefreshTimeout)
+	defer cancel()
+
+	// goth's RefreshToken takes no context, so wait for it on the side
+	type refreshResult struct {
+		token *oauth2.Token
+		err   error
+	}
+	results := make(chan refreshResult, 1)
+	go func() {
+		refreshed, err := gothProvider.RefreshToken(providerToken.RefreshToken)
+		results <- refreshResult{refreshed, err}
+	}()
+
+	var refreshed *oauth2.Token
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("refresh %s token: %w", gothProvider.Name(), ctx.Err())
+	case result := <-results:
+		if result.err != nil {
+			return fmt.Errorf("%w: %v", ErrProviderTokenExpired, result.err)
+		}
+		refreshed = result.token
+	}
+
+	providerToken.AccessToken = refreshed.AccessToken
+	if refreshed.RefreshToken != "" {
+		providerToken.RefreshToken = refreshed.RefreshToken
+	}
+	providerToken.ExpiresAt = nil
+	if !refreshed.Expiry.IsZero() {
+		expiresAt := refreshed.Expiry
+		providerToken.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// TokenSource adapts Token for oauth2.NewClient. The token is reused until it
+// expires.
+func (source *ProviderTokenSource) TokenSource(ctx context.Context, userID uuid.UUID, provider string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, &userProviderTokenSource{
+		ctx:      ctx,
+		source:   source,
+		userID:   userID,
+		provider: provider,
+	})
+}
+
+func (source *ProviderTokenSource) findForUser(ctx context.Context, userID uuid.UUID, provider string) (*ProviderToken, error) {
+	return source.find(ctx, func() (*EncryptedProviderToken, error) {
+		return source.repository.FindProviderTokenForUser(ctx, userID, provider)
+	})
+}
+
+// find loads and decrypts a token, storing it again if its data key had to be
+// re-wrapped after a key rotation. Tokens wrapped with a key that is no longer
+// configured are treated as missing.
+func (source *ProviderTokenSource) find(ctx context.Context, load func() (*EncryptedProviderToken, error)) (*ProviderToken, error) {
+	encryptedProviderToken, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if encryptedProviderToken == nil {
+		return nil, ErrProviderTokenNotFound
+	}
+
+	providerToken, rewrapped, err := openProviderToken(source.keyring, encryptedProviderToken)
+	if errors.Is(err, encryption.ErrUnknownKey) {
+		return nil, fmt.Errorf("%w: %v", ErrProviderTokenNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rewrapped {
+		err = source.repository.SaveProviderToken(ctx, encryptedProviderToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return providerToken, nil
+}
+
+func (source *ProviderTokenSource) save(ctx context.Context, providerToken *ProviderToken) error {
+	encryptedProviderToken, err := sealProviderToken(source.keyring, providerToken)
+	if err != nil {
+		return err
+	}
+	return source.repository.SaveProviderToken(ctx, encryptedProviderToken)
+}
+
+type userProviderTokenSource struct {
+	ctx      context.Context
+	source   *ProviderTokenSource
+	userID   uuid.UUID
+	provider string
+}
+
+func (tokenSource *userProviderTokenSource) Token() (*oauth2.Token, error) {
+	return tokenSource.source.Token(tokenSource.ctx, tokenSource.userID, tokenSource.provider)
+}
+
+// LoadProviderTokenKeyring reads the provider token keys named in the config.
+// Outside of production a missing key is replaced with a throwaway one, so
+// stored provider tokens do not survive a restart.
+func LoadProviderTokenKeyring(cfg *config.Config) (*encryption.Keyring, error) {
+	authConfig := cfg.AuthenticationConfig
+	if authConfig.ProviderTokenKey.Key == "" {
+		if cfg.HttpConfig.IsProduction {
+			return nil, errors.New("PROVIDER_TOKEN_KEY must be set in production")
+		}
+		return encryption.NewEphemeralKeyring()
+	}
+	if authConfig.ProviderTokenKey.KeyID == "" {
+		return nil, errors.New("PROVIDER_TOKEN_KEY_ID must be set alongside PROVIDER_TOKEN_KEY")
+	}
+	return encryption.NewKeyring(authConfig.ProviderTokenKey, authConfig.ProviderTokenPreviousKeys)
+}
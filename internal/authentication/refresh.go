@@ -0,0 +1,93 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RefreshTokenCookieName = "refresh_token"
+
+type RefreshTokenApiDto struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshHandler struct {
+	tokenIssuer *TokenIssuer
+	cookies     *Cookies
+	logger      *log.Logger
+}
+
+func NewRefreshHandler(tokenIssuer *TokenIssuer, cookies *Cookies, logger *log.Logger) *RefreshHandler {
+	return &RefreshHandler{
+		tokenIssuer: tokenIssuer,
+		cookies:     cookies,
+		logger:      logger,
+	}
+}
+
+// @Summary Refresh access token
+// @Description Exchanges a refresh token for a new access token and rotates the refresh token. The refresh token is read from the refresh_token cookie, or from the request body for clients without cookies.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refreshToken body RefreshTokenApiDto false "Refresh token, when not sent as a cookie"
+// @Success 200 {object} map[string]string "New access token"
+// @Failure 401 {object} map[string]string "Invalid or reused refresh token"
+// @Failure 403 {object} map[string]string "Account deactivated, with the account_deactivated code"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/refresh [post]
+func (handler *RefreshHandler) Refresh(ctx *gin.Context) {
+	plainRefreshToken, err := ctx.Cookie(RefreshTokenCookieName)
+	fromCookie := err == nil && plainRefreshToken != ""
+	if !fromCookie {
+		var refreshTokenApiDto RefreshTokenApiDto
+		err = json.NewDecoder(ctx.Request.Body).Decode(&refreshTokenApiDto)
+		if err != nil && !errors.Is(err, io.EOF) {
+			handler.logger.Printf("ERROR: decodeRefreshTokenApiDto: %v", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+			return
+		}
+		plainRefreshToken = refreshTokenApiDto.RefreshToken
+	}
+
+	if plainRefreshToken == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "missing refresh token"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Rotate(ctx.Request.Context(), plainRefreshToken)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		handler.logger.Printf("WARNING: refresh token reuse detected, token family revoked")
+		handler.cookies.clearRefreshToken(ctx)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token reuse detected"})
+		return
+	}
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		handler.cookies.clearRefreshToken(ctx)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+	if errors.Is(err, ErrAccountDeactivated) {
+		handler.cookies.clearRefreshToken(ctx)
+		respondAccountDeactivated(ctx)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerRotate: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if !fromCookie {
+		ctx.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken, "refreshToken": tokens.RefreshToken})
+		return
+	}
+
+	handler.cookies.setRefreshToken(ctx, tokens.RefreshToken, tokens.RefreshTokenExpiresAt)
+	ctx.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken})
+}
@@ -0,0 +1,134 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type refreshTest struct {
+	repository *fakeAuthenticationRepository
+	issuer     *TokenIssuer
+	authUser   *AuthUser
+}
+
+func newRefreshTest(t *testing.T) *refreshTest {
+	t.Helper()
+	repository := newFakeAuthenticationRepository()
+	authUser := &AuthUser{ID: uuid.New(), Email: "ada@example.org", Roles: []string{RoleUser}}
+	repository.authUsers[authUser.ID] = authUser
+	return &refreshTest{
+		repository: repository,
+		issuer:     NewTokenIssuer(repository, newTestKeySet(t)),
+		authUser:   authUser,
+	}
+}
+
+func (test *refreshTest) issue(t *testing.T) string {
+	t.Helper()
+	tokens, err := test.issuer.Issue(context.Background(), test.authUser.ID, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.RefreshToken
+}
+
+func TestRotateIssuesNextTokenInFamily(t *testing.T) {
+	test := newRefreshTest(t)
+	first := test.issue(t)
+
+	rotated, err := test.issuer.Rotate(context.Background(), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.RefreshToken == "" || rotated.RefreshToken == first {
+		t.Fatalf("Rotate returned refresh token %q, want a new one", rotated.RefreshToken)
+	}
+	if rotated.AccessToken == "" {
+		t.Error("Rotate returned no access token")
+	}
+
+	firstToken := test.repository.refreshTokens[HashRefreshToken(first)]
+	nextToken := test.repository.refreshTokens[HashRefreshToken(rotated.RefreshToken)]
+	if firstToken.UsedAt == nil {
+		t.Error("rotated token was not marked used")
+	}
+	if nextToken.FamilyID != firstToken.FamilyID {
+		t.Errorf("next token is in family %s, want %s", nextToken.FamilyID, firstToken.FamilyID)
+	}
+
+	_, err = test.issuer.Rotate(context.Background(), rotated.RefreshToken)
+	if err != nil {
+		t.Errorf("Rotate with the next token = %v, want it to work", err)
+	}
+}
+
+// Replaying a rotated token means it leaked, so the token the owner holds now
+// has to stop working as well.
+func TestRotateRevokesFamilyOnReuse(t *testing.T) {
+	test := newRefreshTest(t)
+	first := test.issue(t)
+	rotated, err := test.issuer.Rotate(context.Background(), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = test.issuer.Rotate(context.Background(), first)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Rotate with a rotated token = %v, want ErrRefreshTokenReused", err)
+	}
+
+	_, err = test.issuer.Rotate(context.Background(), rotated.RefreshToken)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("Rotate with the family's latest token = %v, want ErrRefreshTokenReused", err)
+	}
+
+	// a separate sign-in is a family of its own and keeps working
+	other := test.issue(t)
+	_, err = test.issuer.Rotate(context.Background(), other)
+	if err != nil {
+		t.Errorf("Rotate with another family's token = %v, want it to work", err)
+	}
+}
+
+func TestRotateRejectsUnusableTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(test *refreshTest, refreshToken *RefreshToken)
+		want    error
+	}{
+		{"expired", func(test *refreshTest, refreshToken *RefreshToken) {
+			refreshToken.ExpiresAt = time.Now().Add(-time.Minute)
+		}, ErrInvalidRefreshToken},
+		{"user deleted", func(test *refreshTest, refreshToken *RefreshToken) {
+			delete(test.repository.authUsers, test.authUser.ID)
+		}, ErrInvalidRefreshToken},
+		{"user deactivated", func(test *refreshTest, refreshToken *RefreshToken) {
+			deactivatedAt := time.Now()
+			test.authUser.DeactivatedAt = &deactivatedAt
+		}, ErrAccountDeactivated},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			refresh := newRefreshTest(t)
+			plainRefreshToken := refresh.issue(t)
+			test.prepare(refresh, refresh.repository.refreshTokens[HashRefreshToken(plainRefreshToken)])
+
+			_, err := refresh.issuer.Rotate(context.Background(), plainRefreshToken)
+			if !errors.Is(err, test.want) {
+				t.Errorf("Rotate = %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		refresh := newRefreshTest(t)
+		_, err := refresh.issuer.Rotate(context.Background(), "not-a-refresh-token")
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Rotate = %v, want ErrInvalidRefreshToken", err)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package authentication
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const RevocationCacheTimeToLive = 30 * time.Second
+
+// TokenRevocationState is what Postgres knows about a single access token:
+// whether its jti or its session was revoked, and when every token for its user
+// was last revoked. UserDeleted is set once its user has been purged.
+type TokenRevocationState struct {
+	TokenRevoked    bool
+	SessionRevoked  bool
+	TokensRevokedAt *time.Time
+	UserDeleted     bool
+}
+
+type RevocationStore interface {
+	IsRevoked(ctx context.Context, claims *AccessTokenClaims) (bool, error)
+	RevokeToken(ctx context.Context, claims *AccessTokenClaims) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	RevokeSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keepSessionID uuid.UUID) error
+}
+
+type cachedRevocation struct {
+	userID     uuid.UUID
+	sessionID  uuid.UUID
+	revoked    bool
+	validUntil time.Time
+}
+
+// CachedRevocationStore keeps revocations in Postgres and caches lookups in
+// memory. Revoked tokens stay cached until they expire; tokens found to be
+// valid are re-checked after cacheTimeToLive, which bounds how long another
+// instance can keep accepting a token revoked elsewhere.
+type CachedRevocationStore struct {
+	repository      AuthenticationRepository
+	cacheTimeToLive time.Duration
+	mutex           sync.RWMutex
+	revocations     map[string]cachedRevocation
+	lastSweep       time.Time
+}
+
+func NewCachedRevocationStore(authenticationRepo AuthenticationRepository, cacheTimeToLive time.Duration) *CachedRevocationStore {
+	return &CachedRevocationStore{
+		repository:      authenticationRepo,
+		cacheTimeToLive: cacheTimeToLive,
+		revocations:     make(map[string]cachedRevocation),
+	}
+}
+
+func (store *CachedRevocationStore) IsRevoked(ctx context.Context, claims *AccessTokenClaims) (bool, error) {
+	now := time.Now()
+
+	store.mutex.RLock()
+	cached, found := store.revocations[claims.ID]
+	store.mutex.RUnlock()
+	if found && now.Before(cached.validUntil) {
+		return cached.revoked, nil
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return false, err
+	}
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		// tokens without a jti can only be revoked along with every other token for the user
+		jti = uuid.Nil
+	}
+
+	sessionID := claims.Session()
+	state, err := store.repository.FindTokenRevocationState(ctx, jti, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	revoked := state.TokenRevoked || state.SessionRevoked || state.UserDeleted
+	if state.TokensRevokedAt != nil && claims.IssuedBefore(*state.TokensRevokedAt) {
+		revoked = true
+	}
+
+	validUntil := now.Add(store.cacheTimeToLive)
+	if revoked && claims.ExpiresAt != nil {
+		validUntil = claims.ExpiresAt.Time
+	}
+	if claims.ID != "" {
+		store.remember(claims.ID, cachedRevocation{userID: userID, sessionID: sessionID, revoked: revoked, validUntil: validUntil})
+	}
+
+	return revoked, nil
+}
+
+func (store *CachedRevocationStore) RevokeToken(ctx context.Context, claims *AccessTokenClaims) error {
+	userID, err := claims.UserID()
+	if err != nil {
+		return err
+	}
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(AccessTokenTimeToLive)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	err = store.repository.RevokeToken(ctx, jti, userID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	store.remember(claims.ID, cachedRevocation{userID: userID, sessionID: claims.Session(), revoked: true, validUntil: expiresAt})
+	return nil
+}
+
+// RevokeAllForUser revokes every access token issued to the user so far,
+// personal access tokens included, and ends all of their sessions.
+func (store *CachedRevocationStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := store.repository.RevokeAllTokensForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// personal access tokens are looked up on every request, so this takes
+	// effect straight away
+	err = store.repository.RevokePersonalAccessTokensForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = store.repository.RevokeSessionsForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// drop anything cached for this user so the next request re-reads the cut-off
+	store.forget(func(cached cachedRevocation) bool {
+		return cached.userID == userID
+	})
+	return nil
+}
+
+// RevokeSession ends one of the user's sessions along with its refresh tokens.
+// ErrSessionNotFound is returned if the session is not theirs or already ended.
+func (store *CachedRevocationStore) RevokeSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	err := store.repository.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	store.forget(func(cached cachedRevocation) bool {
+		return cached.sessionID == sessionID
+	})
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except keepSessionID.
+func (store *CachedRevocationStore) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keepSessionID uuid.UUID) error {
+	err := store.repository.RevokeOtherSessions(ctx, userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	store.forget(func(cached cachedRevocation) bool {
+		return cached.userID == userID && cached.sessionID != keepSessionID
+	})
+	return nil
+}
+
+// forget drops cached lookups that match, so the next request re-reads them.
+func (store *CachedRevocationStore) forget(match func(cached cachedRevocation) bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for jti, cached := range store.revocations {
+		if match(cached) {
+			delete(store.revocations, jti)
+		}
+	}
+}
+
+func (store *CachedRevocationStore) remember(jti string, revocation cachedRevocation) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(store.lastSweep) > store.cacheTimeToLive {
+		for cachedJti, cached := range store.revocations {
+			if now.After(cached.validUntil) {
+				delete(store.revocations, cachedJti)
+			}
+		}
+		store.lastSweep = now
+	}
+	store.revocations[jti] = revocation
+}
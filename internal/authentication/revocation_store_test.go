@@ -0,0 +1,119 @@
+package authentication
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRevocationStoreCutOff(t *testing.T) {
+	cutoff := time.Date(2026, time.March, 2, 10, 0, 0, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name string
+		// issuedAt is the token's issue time; legacy tokens only carry it in
+		// whole seconds
+		issuedAt time.Time
+		legacy   bool
+		revoked  bool
+	}{
+		{"issued a millisecond before the cut-off", cutoff.Add(-time.Millisecond), false, true},
+		{"issued a millisecond after the cut-off", cutoff.Add(time.Millisecond), false, false},
+		{"issued the second before", cutoff.Add(-time.Second), false, true},
+		{"legacy token in the cut-off's second", cutoff.Add(time.Millisecond), true, true},
+		{"legacy token the second after", cutoff.Add(time.Second), true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeAuthenticationRepository()
+			userID := uuid.New()
+			repository.authUsers[userID] = &AuthUser{ID: userID, Email: "ada@example.org", Roles: []string{RoleUser}}
+			repository.tokensRevokedAt[userID] = cutoff
+			store := NewCachedRevocationStore(repository, RevocationCacheTimeToLive)
+
+			claims := newAccessTokenClaims("catalyst-test", uuid.New(), userID, "ada@example.org", JoinScopes(DefaultUserScopes), test.issuedAt.Add(time.Hour))
+			claims.IssuedAt = jwt.NewNumericDate(test.issuedAt)
+			claims.IssuedAtMicro = test.issuedAt.UnixMicro()
+			if test.legacy {
+				claims.IssuedAtMicro = 0
+			}
+
+			revoked, err := store.IsRevoked(context.Background(), &claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != test.revoked {
+				t.Errorf("IsRevoked = %v, want %v", revoked, test.revoked)
+			}
+		})
+	}
+}
+
+func TestRevocationStoreKeepsTokensIssuedAfterRevokeAll(t *testing.T) {
+	repository := newFakeAuthenticationRepository()
+	keySet := newTestKeySet(t)
+	userID := uuid.New()
+	repository.authUsers[userID] = &AuthUser{ID: userID, Email: "ada@example.org", Roles: []string{RoleUser}}
+	store := NewCachedRevocationStore(repository, RevocationCacheTimeToLive)
+
+	before, err := keySet.GenerateJWT(userID, "ada@example.org", JoinScopes(DefaultUserScopes), uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cut-off is taken after the first token, as a database clock would
+	time.Sleep(time.Millisecond)
+	err = store.RevokeAllForUser(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	// signing in again straight away, within the same second, has to work
+	after, err := keySet.GenerateJWT(userID, "ada@example.org", JoinScopes(DefaultUserScopes), uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for token, wantRevoked := range map[string]bool{before: true, after: false} {
+		claims, err := keySet.VerifyJWTToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revoked, err := store.IsRevoked(context.Background(), claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != wantRevoked {
+			t.Errorf("token issued at %d: IsRevoked = %v, want %v", claims.IssuedAtMicro, revoked, wantRevoked)
+		}
+	}
+}
+
+func TestAuthenticateRejectsTokensOfPurgedUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repository := newFakeAuthenticationRepository()
+	keySet := newTestKeySet(t)
+	authMiddleware := AuthenticationMiddleware{AuthenticationRepository: repository, KeySet: keySet, RevocationStore: NewCachedRevocationStore(repository, RevocationCacheTimeToLive)}
+
+	// the user was purged after the token was issued, so nothing is left of them
+	token, err := keySet.GenerateJWT(uuid.New(), "ada@example.org", JoinScopes(DefaultUserScopes), uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/user/me", authMiddleware.Authenticate(), authMiddleware.RequireAuthUser(), func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/user/me", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("request = %d %s, want 401", recorder.Code, recorder.Body)
+	}
+}
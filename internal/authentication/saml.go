@@ -0,0 +1,300 @@
+package authentication
+
+import (
+	"cmp"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"catalyst.api/config"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/google/uuid"
+	"github.com/markbates/goth"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	SAMLRequestTimeToLive = 10 * time.Minute
+
+	// samlProviderPrefix starts the provider name of identities from a SAML
+	// connection. OAuth provider names cannot contain a colon, so the two never
+	// clash.
+	samlProviderPrefix = "saml:"
+
+	defaultSAMLEmailAttribute     = "email"
+	defaultSAMLFirstNameAttribute = "firstName"
+	defaultSAMLLastNameAttribute  = "lastName"
+)
+
+// Codes sent to the front-end callback when a SAML sign-in fails.
+const (
+	SAMLErrorInvalidResponse = "saml_response_invalid"
+	SAMLErrorEmailMissing    = "saml_email_missing"
+	SAMLErrorTransientNameID = "saml_name_id_transient"
+	SAMLErrorAccountExists   = AccountExistsCode
+)
+
+var (
+	ErrSAMLConnectionNotFound = errors.New("SAML connection not found")
+	ErrSAMLConnectionExists   = errors.New("workspace already has a SAML connection")
+	ErrInvalidSAMLRequest     = errors.New("SAML sign-in request is invalid or has expired")
+	ErrInvalidWorkspace       = errors.New("workspace must be 1 to 63 lowercase letters, digits or hyphens, not starting or ending with a hyphen")
+)
+
+var (
+	workspacePattern   = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	reservedWorkspaces = []string{"connections"}
+)
+
+// SAMLKeyPair is the key and certificate the API presents as a service provider.
+type SAMLKeyPair struct {
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+func LoadSAMLKeyPair(cfg *config.Config) (*SAMLKeyPair, error) {
+	samlConfig := cfg.AuthenticationConfig.SAML
+	if samlConfig.KeyPath == "" && samlConfig.CertificatePath == "" {
+		return nil, nil
+	}
+	if samlConfig.KeyPath == "" || samlConfig.CertificatePath == "" {
+		return nil, errors.New("SAML_SP_KEY_FILE and SAML_SP_CERTIFICATE_FILE must be set together")
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(samlConfig.CertificatePath, samlConfig.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load SAML service provider key: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("SAML service provider key must be an RSA key")
+	}
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse SAML service provider certificate: %w", err)
+	}
+	return &SAMLKeyPair{Key: key, Certificate: certificate}, nil
+}
+
+// SAMLAttributeMapping names the assertion attributes that hold the user's
+// email and names. Attributes are matched on their Name or FriendlyName.
+type SAMLAttributeMapping struct {
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+// SAMLConnection lets the members of a workspace sign in with their
+// organization's SAML identity provider. The API is the service provider, with
+// its own metadata, entity ID and assertion consumer service per workspace.
+type SAMLConnection struct {
+	ID        uuid.UUID
+	Workspace string
+	// EntityID identifies the API to the identity provider. It defaults to the
+	// workspace's metadata URL.
+	EntityID         string
+	IdPEntityID      string
+	IdPMetadata      string
+	AttributeMapping SAMLAttributeMapping
+	Enabled          bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func NewSAMLConnection(publicURL string, workspace string, entityID string, idpMetadata string, attributeMapping SAMLAttributeMapping, enabled bool) (*SAMLConnection, error) {
+	if !workspacePattern.MatchString(workspace) || slices.Contains(reservedWorkspaces, workspace) {
+		return nil, ErrInvalidWorkspace
+	}
+
+	connection := &SAMLConnection{
+		Workspace: workspace,
+		Enabled:   enabled,
+	}
+	err := connection.Configure(publicURL, entityID, idpMetadata, attributeMapping)
+	if err != nil {
+		return nil, err
+	}
+	return connection, nil
+}
+
+// Configure replaces the connection's settings. Empty settings fall back to
+// their defaults.
+func (connection *SAMLConnection) Configure(publicURL string, entityID string, idpMetadata string, attributeMapping SAMLAttributeMapping) error {
+	idpDescriptor, err := parseIdPMetadata(idpMetadata)
+	if err != nil {
+		return err
+	}
+
+	connection.EntityID = entityID
+	if connection.EntityID == "" {
+		connection.EntityID = connection.MetadataURL(publicURL)
+	}
+	connection.IdPEntityID = idpDescriptor.EntityID
+	connection.IdPMetadata = idpMetadata
+	connection.AttributeMapping = SAMLAttributeMapping{
+		Email:     cmp.Or(attributeMapping.Email, defaultSAMLEmailAttribute),
+		FirstName: cmp.Or(attributeMapping.FirstName, defaultSAMLFirstNameAttribute),
+		LastName:  cmp.Or(attributeMapping.LastName, defaultSAMLLastNameAttribute),
+	}
+	return nil
+}
+
+// Provider is the provider name of identities signed in through the connection.
+func (connection *SAMLConnection) Provider() string {
+	return samlProviderPrefix + connection.Workspace
+}
+
+// MetadataURL is where the connection's service provider metadata is served,
+// under the API's publicURL.
+func (connection *SAMLConnection) MetadataURL(publicURL string) string {
+	return fmt.Sprintf("%s/auth/saml/%s/metadata", publicURL, connection.Workspace)
+}
+
+func (connection *SAMLConnection) AssertionConsumerServiceURL(publicURL string) string {
+	return fmt.Sprintf("%s/auth/saml/%s/acs", publicURL, connection.Workspace)
+}
+
+func (connection *SAMLConnection) SignInURL(publicURL string) string {
+	return fmt.Sprintf("%s/auth/saml/%s", publicURL, connection.Workspace)
+}
+
+// ServiceProvider builds the crewjam/saml service provider for the connection.
+// Requests are signed, and encrypted assertions accepted, when keyPair is set.
+func (connection *SAMLConnection) ServiceProvider(publicURL string, keyPair *SAMLKeyPair) (*saml.ServiceProvider, error) {
+	idpDescriptor, err := parseIdPMetadata(connection.IdPMetadata)
+	if err != nil {
+		return nil, err
+	}
+	metadataURL, err := url.Parse(connection.MetadataURL(publicURL))
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(connection.AssertionConsumerServiceURL(publicURL))
+	if err != nil {
+		return nil, err
+	}
+
+	serviceProvider := &saml.ServiceProvider{
+		EntityID:          connection.EntityID,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpDescriptor,
+		AuthnNameIDFormat: saml.PersistentNameIDFormat,
+	}
+	if keyPair != nil {
+		serviceProvider.Key = keyPair.Key
+		serviceProvider.Certificate = keyPair.Certificate
+		serviceProvider.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+	return serviceProvider, nil
+}
+
+// AssertionUser reads the signed-in user from a verified assertion, in the
+// same shape the OAuth providers hand back. The NameID identifies the user, so
+// it has to stay the same from one sign-in to the next.
+func (connection *SAMLConnection) AssertionUser(assertion *saml.Assertion) (goth.User, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return goth.User{}, &SAMLSignInError{Code: SAMLErrorInvalidResponse}
+	}
+	nameID := assertion.Subject.NameID
+	if nameID.Format == string(saml.TransientNameIDFormat) {
+		return goth.User{}, &SAMLSignInError{Code: SAMLErrorTransientNameID}
+	}
+
+	attributes := samlAssertionAttributes(assertion)
+	email := attributes[connection.AttributeMapping.Email]
+	if email == "" && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		email = nameID.Value
+	}
+	if email == "" {
+		return goth.User{}, &SAMLSignInError{Code: SAMLErrorEmailMissing}
+	}
+
+	return goth.User{
+		Provider:  connection.Provider(),
+		UserID:    nameID.Value,
+		Email:     email,
+		FirstName: attributes[connection.AttributeMapping.FirstName],
+		LastName:  attributes[connection.AttributeMapping.LastName],
+	}, nil
+}
+
+// SAMLSignInError is returned when an assertion cannot sign anyone in. Code is
+// one of the SAMLError constants.
+type SAMLSignInError struct {
+	Code string
+}
+
+func (err *SAMLSignInError) Error() string {
+	return "SAML sign-in failed: " + err.Code
+}
+
+// SAMLRequest remembers an authentication request sent to an identity
+// provider until its response comes back. Its ID travels as the RelayState, so
+// the response is tied to the request, and through it to the PKCE challenge,
+// without relying on cookies the identity provider's cross-site POST would not
+// carry.
+type SAMLRequest struct {
+	ID            uuid.UUID
+	ConnectionID  uuid.UUID
+	RequestID     string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+func NewSAMLRequest(connectionID uuid.UUID, requestID string, codeChallenge string) *SAMLRequest {
+	return &SAMLRequest{
+		ConnectionID:  connectionID,
+		RequestID:     requestID,
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     time.Now().Add(SAMLRequestTimeToLive),
+	}
+}
+
+// parseIdPMetadata accepts an EntityDescriptor, or an EntitiesDescriptor
+// holding one, for an identity provider with a single sign-on service.
+func parseIdPMetadata(idpMetadata string) (*saml.EntityDescriptor, error) {
+	idpDescriptor, err := samlsp.ParseMetadata([]byte(idpMetadata))
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity provider metadata: %w", err)
+	}
+	if idpDescriptor.EntityID == "" || len(idpDescriptor.IDPSSODescriptors) == 0 {
+		return nil, errors.New("invalid identity provider metadata: no identity provider found")
+	}
+
+	for _, ssoService := range idpDescriptor.IDPSSODescriptors[0].SingleSignOnServices {
+		if ssoService.Binding == saml.HTTPRedirectBinding || ssoService.Binding == saml.HTTPPostBinding {
+			return idpDescriptor, nil
+		}
+	}
+	return nil, errors.New("invalid identity provider metadata: no HTTP-Redirect or HTTP-POST single sign-on service")
+}
+
+// samlAssertionAttributes returns the first value of every attribute, under
+// both its Name and its FriendlyName.
+func samlAssertionAttributes(assertion *saml.Assertion) map[string]string {
+	attributes := map[string]string{}
+	for _, attributeStatement := range assertion.AttributeStatements {
+		for _, attribute := range attributeStatement.Attributes {
+			if len(attribute.Values) == 0 {
+				continue
+			}
+			value := strings.TrimSpace(attribute.Values[0].Value)
+			for _, name := range []string{attribute.Name, attribute.FriendlyName} {
+				if _, found := attributes[name]; name != "" && !found {
+					attributes[name] = value
+				}
+			}
+		}
+	}
+	return attributes
+}
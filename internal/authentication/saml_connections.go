@@ -0,0 +1,283 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+type SAMLAttributeMappingApiDto struct {
+	Email     string `json:"email" validate:"max=255"`
+	FirstName string `json:"firstName" validate:"max=255"`
+	LastName  string `json:"lastName" validate:"max=255"`
+}
+
+type SAMLConnectionApiDto struct {
+	ID                          uuid.UUID                  `json:"id"`
+	Workspace                   string                     `json:"workspace"`
+	EntityID                    string                     `json:"entityId"`
+	IdPEntityID                 string                     `json:"idpEntityId"`
+	MetadataURL                 string                     `json:"metadataUrl"`
+	AssertionConsumerServiceURL string                     `json:"acsUrl"`
+	SignInURL                   string                     `json:"signInUrl"`
+	AttributeMapping            SAMLAttributeMappingApiDto `json:"attributeMapping"`
+	Enabled                     bool                       `json:"enabled"`
+	CreatedAt                   time.Time                  `json:"createdAt"`
+	UpdatedAt                   time.Time                  `json:"updatedAt"`
+}
+
+type CreateSAMLConnectionApiDto struct {
+	Workspace        string                     `json:"workspace" validate:"required,max=63"`
+	EntityID         string                     `json:"entityId" validate:"max=1024"`
+	IdPMetadata      string                     `json:"idpMetadata" validate:"required"`
+	AttributeMapping SAMLAttributeMappingApiDto `json:"attributeMapping"`
+	Enabled          *bool                      `json:"enabled"`
+}
+
+func (dto *CreateSAMLConnectionApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type UpdateSAMLConnectionApiDto struct {
+	EntityID         string                     `json:"entityId" validate:"max=1024"`
+	IdPMetadata      string                     `json:"idpMetadata" validate:"required"`
+	AttributeMapping SAMLAttributeMappingApiDto `json:"attributeMapping"`
+	Enabled          bool                       `json:"enabled"`
+}
+
+func (dto *UpdateSAMLConnectionApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type SAMLConnectionHandler struct {
+	repository AuthenticationRepository
+	publicURL  string
+	logger     *log.Logger
+}
+
+func NewSAMLConnectionHandler(authenticationRepo AuthenticationRepository, publicURL string, logger *log.Logger) *SAMLConnectionHandler {
+	return &SAMLConnectionHandler{
+		repository: authenticationRepo,
+		publicURL:  publicURL,
+		logger:     logger,
+	}
+}
+
+// @Summary List SAML connections
+// @Description Lists the workspaces that sign in with SAML, with the URLs to give their identity providers. Admins only.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]SAMLConnectionApiDto "SAML connections"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/saml/connections [get]
+func (handler *SAMLConnectionHandler) ListSAMLConnections(ctx *gin.Context) {
+	connections, err := handler.repository.ListSAMLConnections(ctx.Request.Context())
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListSAMLConnections: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	connectionApiDtos := make([]SAMLConnectionApiDto, 0, len(connections))
+	for _, connection := range connections {
+		connectionApiDtos = append(connectionApiDtos, newSAMLConnectionApiDto(connection, handler.publicURL))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"connections": connectionApiDtos})
+}
+
+// @Summary Create a SAML connection
+// @Description Lets a workspace sign in with its SAML identity provider. idpMetadata is the identity provider's metadata XML. The entity ID defaults to the workspace's metadata URL, and the attribute mapping to the email, firstName and lastName attributes. Connections are enabled unless enabled is false. Admins only.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param connection body CreateSAMLConnectionApiDto true "Workspace and identity provider"
+// @Success 201 {object} SAMLConnectionApiDto "Created connection"
+// @Failure 400 {object} map[string]string "Invalid input or metadata"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 409 {object} map[string]string "Workspace already has a connection"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/saml/connections [post]
+func (handler *SAMLConnectionHandler) CreateSAMLConnection(ctx *gin.Context) {
+	var createApiDto CreateSAMLConnectionApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&createApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeCreateSAMLConnectionApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = createApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateCreateSAMLConnectionApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	enabled := createApiDto.Enabled == nil || *createApiDto.Enabled
+	connection, err := NewSAMLConnection(handler.publicURL, strings.TrimSpace(createApiDto.Workspace), strings.TrimSpace(createApiDto.EntityID), createApiDto.IdPMetadata, newSAMLAttributeMapping(createApiDto.AttributeMapping), enabled)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	connection.ID, err = handler.repository.CreateSAMLConnection(ctx.Request.Context(), connection)
+	if errors.Is(err, ErrSAMLConnectionExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateSAMLConnection: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	connection.CreatedAt = time.Now()
+	connection.UpdatedAt = connection.CreatedAt
+
+	ctx.JSON(http.StatusCreated, newSAMLConnectionApiDto(connection, handler.publicURL))
+}
+
+// @Summary Update a SAML connection
+// @Description Replaces a connection's identity provider metadata, entity ID, attribute mapping and enabled flag. Its workspace cannot change. Disabling a connection stops new sign-ins through it. Admins only.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param id path string true "SAML connection ID"
+// @Param connection body UpdateSAMLConnectionApiDto true "Connection settings"
+// @Success 200 {object} SAMLConnectionApiDto "Updated connection"
+// @Failure 400 {object} map[string]string "Invalid input or metadata"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Connection not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/saml/connections/{id} [put]
+func (handler *SAMLConnectionHandler) UpdateSAMLConnection(ctx *gin.Context) {
+	connectionID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid SAML Connection ID"})
+		return
+	}
+
+	var updateApiDto UpdateSAMLConnectionApiDto
+	err = json.NewDecoder(ctx.Request.Body).Decode(&updateApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeUpdateSAMLConnectionApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = updateApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateUpdateSAMLConnectionApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	connection, err := handler.repository.FindSAMLConnection(ctx.Request.Context(), connectionID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindSAMLConnection: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if connection == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	err = connection.Configure(handler.publicURL, strings.TrimSpace(updateApiDto.EntityID), updateApiDto.IdPMetadata, newSAMLAttributeMapping(updateApiDto.AttributeMapping))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	connection.Enabled = updateApiDto.Enabled
+
+	err = handler.repository.UpdateSAMLConnection(ctx.Request.Context(), connection)
+	if errors.Is(err, ErrSAMLConnectionNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryUpdateSAMLConnection: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	connection.UpdatedAt = time.Now()
+
+	ctx.JSON(http.StatusOK, newSAMLConnectionApiDto(connection, handler.publicURL))
+}
+
+// @Summary Delete a SAML connection
+// @Description Stops a workspace signing in with SAML. Accounts created through the connection are kept but can only sign in some other way. Admins only.
+// @Tags auth
+// @Param id path string true "SAML connection ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Connection not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/saml/connections/{id} [delete]
+func (handler *SAMLConnectionHandler) DeleteSAMLConnection(ctx *gin.Context) {
+	connectionID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid SAML Connection ID"})
+		return
+	}
+
+	err = handler.repository.DeleteSAMLConnection(ctx.Request.Context(), connectionID)
+	if errors.Is(err, ErrSAMLConnectionNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryDeleteSAMLConnection: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func newSAMLAttributeMapping(attributeMappingApiDto SAMLAttributeMappingApiDto) SAMLAttributeMapping {
+	return SAMLAttributeMapping{
+		Email:     strings.TrimSpace(attributeMappingApiDto.Email),
+		FirstName: strings.TrimSpace(attributeMappingApiDto.FirstName),
+		LastName:  strings.TrimSpace(attributeMappingApiDto.LastName),
+	}
+}
+
+func newSAMLConnectionApiDto(connection *SAMLConnection, publicURL string) SAMLConnectionApiDto {
+	return SAMLConnectionApiDto{
+		ID:                          connection.ID,
+		Workspace:                   connection.Workspace,
+		EntityID:                    connection.EntityID,
+		IdPEntityID:                 connection.IdPEntityID,
+		MetadataURL:                 connection.MetadataURL(publicURL),
+		AssertionConsumerServiceURL: connection.AssertionConsumerServiceURL(publicURL),
+		SignInURL:                   connection.SignInURL(publicURL),
+		AttributeMapping: SAMLAttributeMappingApiDto{
+			Email:     connection.AttributeMapping.Email,
+			FirstName: connection.AttributeMapping.FirstName,
+			LastName:  connection.AttributeMapping.LastName,
+		},
+		Enabled:   connection.Enabled,
+		CreatedAt: connection.CreatedAt,
+		UpdatedAt: connection.UpdatedAt,
+	}
+}
@@ -0,0 +1,321 @@
+package authentication
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/markbates/goth"
+)
+
+type SAMLHandler struct {
+	repository  AuthenticationRepository
+	tokenIssuer *TokenIssuer
+	admission   *AdmissionPolicy
+	publicURL   string
+	keyPair     *SAMLKeyPair
+	frontendURL string
+	logger      *log.Logger
+}
+
+func NewSAMLHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, admission *AdmissionPolicy, publicURL string, keyPair *SAMLKeyPair, frontendURL string, logger *log.Logger) *SAMLHandler {
+	return &SAMLHandler{
+		repository:  authenticationRepo,
+		tokenIssuer: tokenIssuer,
+		admission:   admission,
+		publicURL:   publicURL,
+		keyPair:     keyPair,
+		frontendURL: frontendURL,
+		logger:      logger,
+	}
+}
+
+// @Summary SAML service provider metadata
+// @Description Returns the metadata to load into the workspace's identity provider: the entity ID, the assertion consumer service and, when configured, the certificate used to sign requests and receive encrypted assertions.
+// @Tags auth
+// @Produce xml
+// @Param workspace path string true "Workspace"
+// @Success 200 {string} string "SAML metadata"
+// @Failure 404 {object} map[string]string "Workspace has no SAML connection"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/saml/{workspace}/metadata [get]
+func (handler *SAMLHandler) Metadata(ctx *gin.Context) {
+	connection, err := handler.repository.FindSAMLConnectionByWorkspace(ctx.Request.Context(), ctx.Param("workspace"))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindSAMLConnectionByWorkspace: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if connection == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	serviceProvider, err := connection.ServiceProvider(handler.publicURL, handler.keyPair)
+	if err != nil {
+		handler.logger.Printf("ERROR: samlConnectionServiceProvider: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	metadata, err := xml.MarshalIndent(serviceProvider.Metadata(), "", "  ")
+	if err != nil {
+		handler.logger.Printf("ERROR: xmlMarshalIndent: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	ctx.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// @Summary Start SAML sign-in
+// @Description Sends the browser to the workspace's identity provider. The front-end sends a PKCE S256 code challenge; once the identity provider posts back to the assertion consumer service, the browser is redirected to the front-end with a one-time code to exchange at /auth/token, as with OAuth providers.
+// @Tags auth
+// @Param workspace path string true "Workspace"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Produce html
+// @Success 302 {string} string "Redirect to the identity provider"
+// @Success 200 {string} string "Form that posts to the identity provider"
+// @Failure 400 {object} map[string]string "Invalid code challenge"
+// @Failure 404 {object} map[string]string "Workspace has no enabled SAML connection"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/saml/{workspace} [get]
+func (handler *SAMLHandler) SignIn(ctx *gin.Context) {
+	connection, ok := handler.findEnabledConnection(ctx)
+	if !ok {
+		return
+	}
+
+	codeChallenge := ctx.Query("code_challenge")
+	if !ValidCodeChallenge(codeChallenge, ctx.Query("code_challenge_method")) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code challenge, expected S256"})
+		return
+	}
+
+	serviceProvider, err := connection.ServiceProvider(handler.publicURL, handler.keyPair)
+	if err != nil {
+		handler.logger.Printf("ERROR: samlConnectionServiceProvider: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	// prefer the redirect binding, falling back to a form that posts itself
+	binding := saml.HTTPRedirectBinding
+	location := serviceProvider.GetSSOBindingLocation(binding)
+	if location == "" {
+		binding = saml.HTTPPostBinding
+		location = serviceProvider.GetSSOBindingLocation(binding)
+	}
+
+	authnRequest, err := serviceProvider.MakeAuthenticationRequest(location, binding, saml.HTTPPostBinding)
+	if err != nil {
+		handler.logger.Printf("ERROR: serviceProviderMakeAuthenticationRequest: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	samlRequest := NewSAMLRequest(connection.ID, authnRequest.ID, codeChallenge)
+	samlRequest.ID, err = handler.repository.CreateSAMLRequest(ctx.Request.Context(), samlRequest)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateSAMLRequest: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	relayState := samlRequest.ID.String()
+
+	if binding == saml.HTTPPostBinding {
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", authnRequest.Post(relayState))
+		return
+	}
+
+	redirectURL, err := authnRequest.Redirect(relayState, serviceProvider)
+	if err != nil {
+		handler.logger.Printf("ERROR: authnRequestRedirect: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	ctx.Redirect(http.StatusFound, redirectURL.String())
+}
+
+// @Summary SAML assertion consumer service
+// @Description Receives the identity provider's response to a sign-in started at /auth/saml/{workspace}. Only responses to those requests are accepted, once each. The user is found by the assertion's NameID, which must be persistent. A new NameID claims the account provisioned over SCIM with the mapped email if nobody has signed in to it yet, or else creates an account from the mapped email and name attributes. The user is then signed in like the OAuth callback does. Failures redirect to the front-end callback with an error code: invalid_sign_in_request, saml_response_invalid, saml_name_id_transient, saml_email_missing, account_exists, account_deactivated, or one of the admission rule codes.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Param workspace path string true "Workspace"
+// @Param SAMLResponse formData string true "Base64 encoded SAML response"
+// @Param RelayState formData string true "Relay state sent with the request"
+// @Success 303 {string} string "Redirect to the front-end"
+// @Failure 404 {object} map[string]string "Workspace has no enabled SAML connection"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/saml/{workspace}/acs [post]
+func (handler *SAMLHandler) AssertionConsumerService(ctx *gin.Context) {
+	connection, ok := handler.findEnabledConnection(ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Request.ParseForm()
+	if err != nil {
+		handler.rejectSignIn(ctx, "invalid_sign_in_request")
+		return
+	}
+	samlRequestID, err := uuid.Parse(ctx.Request.PostForm.Get("RelayState"))
+	if err != nil {
+		handler.rejectSignIn(ctx, "invalid_sign_in_request")
+		return
+	}
+
+	samlRequest, err := handler.repository.ConsumeSAMLRequest(ctx.Request.Context(), samlRequestID, connection.ID)
+	if errors.Is(err, ErrInvalidSAMLRequest) {
+		handler.rejectSignIn(ctx, "invalid_sign_in_request")
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryConsumeSAMLRequest: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	serviceProvider, err := connection.ServiceProvider(handler.publicURL, handler.keyPair)
+	if err != nil {
+		handler.logger.Printf("ERROR: samlConnectionServiceProvider: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	assertion, err := serviceProvider.ParseResponse(ctx.Request, []string{samlRequest.RequestID})
+	if err != nil {
+		var invalidResponseErr *saml.InvalidResponseError
+		if errors.As(err, &invalidResponseErr) {
+			err = invalidResponseErr.PrivateErr
+		}
+		handler.logger.Printf("ERROR: serviceProviderParseResponse: workspace %s: %v", connection.Workspace, err)
+		handler.rejectSignIn(ctx, SAMLErrorInvalidResponse)
+		return
+	}
+
+	gothUser, err := connection.AssertionUser(assertion)
+	var signInErr *SAMLSignInError
+	if errors.As(err, &signInErr) {
+		handler.rejectSignIn(ctx, signInErr.Code)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: samlConnectionAssertionUser: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	authUserID, err := handler.repository.FindUserIDByProvider(ctx.Request.Context(), gothUser.Provider, gothUser.UserID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindUserIDByProvider: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	provisionedUserID := uuid.Nil
+	if authUserID == uuid.Nil {
+		provisionedUserID, err = handler.repository.FindUnclaimedProvisionedUserID(ctx.Request.Context(), gothUser.Email)
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryFindUnclaimedProvisionedUserID: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+
+	// the workspace's identity provider vouches for the email
+	candidate := AdmissionCandidate{Email: gothUser.Email, EmailVerified: true, Existing: authUserID != uuid.Nil || provisionedUserID != uuid.Nil}
+	err = handler.admission.Admit(ctx.Request.Context(), candidate)
+	var admissionErr *AdmissionError
+	if errors.As(err, &admissionErr) {
+		handler.rejectSignIn(ctx, admissionErr.Code)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: admissionPolicyAdmit: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if provisionedUserID != uuid.Nil {
+		// accounts provisioned over SCIM wait for their first sign-in to get an identity
+		_, err = handler.repository.LinkAuthProvider(ctx.Request.Context(), provisionedUserID, gothUser)
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryLinkAuthProvider: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		authUserID = provisionedUserID
+	}
+	if authUserID == uuid.Nil {
+		authUserID, err = handler.registerAuthUser(ctx.Request.Context(), gothUser)
+		if errors.Is(err, ErrEmailTaken) {
+			handler.rejectSignIn(ctx, SAMLErrorAccountExists)
+			return
+		}
+		if err != nil {
+			handler.logger.Printf("ERROR: handlerRegisterAuthUser: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+
+	redirectWithAuthorizationCode(ctx, handler.repository, handler.tokenIssuer, handler.frontendURL, handler.logger, authUserID, gothUser.Provider, samlRequest.CodeChallenge)
+}
+
+func (handler *SAMLHandler) findEnabledConnection(ctx *gin.Context) (*SAMLConnection, bool) {
+	connection, err := handler.repository.FindSAMLConnectionByWorkspace(ctx.Request.Context(), ctx.Param("workspace"))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindSAMLConnectionByWorkspace: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return nil, false
+	}
+	if connection == nil || !connection.Enabled {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return nil, false
+	}
+	return connection, true
+}
+
+// registerAuthUser creates the account for a SAML identity's first sign-in. An
+// account that already uses the email is not taken over: its owner has to sign
+// in the way they did before. It returns ErrEmailTaken in that case.
+func (handler *SAMLHandler) registerAuthUser(ctx context.Context, gothUser goth.User) (uuid.UUID, error) {
+	credentials, err := handler.repository.FindPasswordCredentials(ctx, gothUser.Email)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if credentials != nil {
+		return uuid.Nil, ErrEmailTaken
+	}
+
+	gothUser.FirstName = truncateRunes(gothUser.FirstName, maxNameLength)
+	gothUser.LastName = truncateRunes(gothUser.LastName, maxNameLength)
+	if gothUser.FirstName == "" {
+		localPart, _, _ := strings.Cut(gothUser.Email, "@")
+		gothUser.FirstName = truncateRunes(localPart, maxNameLength)
+	}
+
+	authUserID, err := handler.repository.RegisterAuthUser(ctx, gothUser)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = handler.repository.AcceptInvitation(ctx, gothUser.Email)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryAcceptInvitation: %v", err)
+	}
+	return authUserID, nil
+}
+
+func (handler *SAMLHandler) rejectSignIn(ctx *gin.Context, code string) {
+	ctx.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/callback?error=%s", handler.frontendURL, url.QueryEscape(code)))
+}
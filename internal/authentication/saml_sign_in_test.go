@@ -0,0 +1,286 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"html"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"catalyst.api/internal/samlidp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	samlResponseFormPattern  = regexp.MustCompile(`<form method="post" action="([^"]*)"`)
+	samlResponseInputPattern = regexp.MustCompile(`<input type="hidden" name="(SAMLResponse|RelayState)" value="([^"]*)"`)
+)
+
+func newTestCertificate(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, certificate
+}
+
+// samlTest runs the API's SAML routes and the stand-in identity provider from
+// cmd/saml-idp on test servers, with the workspace "acme" connected to it.
+type samlTest struct {
+	repository *fakeAuthenticationRepository
+	apiURL     string
+	// client stops at redirects, so each step of the flow can be checked
+	client *http.Client
+}
+
+func newSAMLTest(t *testing.T, keyPair *SAMLKeyPair) *samlTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := log.New(io.Discard, "", 0)
+
+	var identityProvider http.Handler
+	idpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identityProvider.ServeHTTP(w, r)
+	}))
+	t.Cleanup(idpServer.Close)
+	idpKey, idpCertificate := newTestCertificate(t, "saml-idp")
+	identityProvider, err := samlidp.New(idpServer.URL, idpKey, idpCertificate, "", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	apiServer := httptest.NewServer(router)
+	t.Cleanup(apiServer.Close)
+
+	test := &samlTest{
+		repository: newFakeAuthenticationRepository(),
+		apiURL:     apiServer.URL,
+		client: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+	}
+	keySet := newTestKeySet(t)
+	admission := NewAdmissionPolicy(test.repository, &AdmissionRules{}, nil)
+	handler := NewSAMLHandler(test.repository, NewTokenIssuer(test.repository, keySet), admission, apiServer.URL, keyPair, testFrontendURL, logger)
+	router.GET("/auth/saml/:workspace", handler.SignIn)
+	router.GET("/auth/saml/:workspace/metadata", handler.Metadata)
+	router.POST("/auth/saml/:workspace/acs", handler.AssertionConsumerService)
+
+	idpMetadata := test.get(t, idpServer.URL+"/metadata", http.StatusOK)
+	connection, err := NewSAMLConnection(apiServer.URL, "acme", "", idpMetadata, SAMLAttributeMapping{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection.ID = uuid.New()
+	test.repository.samlConnections[connection.Workspace] = connection
+	return test
+}
+
+func (test *samlTest) do(t *testing.T, request *http.Request, wantStatus int) *http.Response {
+	t.Helper()
+	response, err := test.client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != wantStatus {
+		body, _ := io.ReadAll(response.Body)
+		t.Fatalf("%s %s = %d %s, want %d", request.Method, request.URL, response.StatusCode, body, wantStatus)
+	}
+	return response
+}
+
+func (test *samlTest) get(t *testing.T, url string, wantStatus int) string {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := test.do(t, request, wantStatus)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// signIn starts a sign-in at the API, fills in the identity provider's form
+// and returns the form the identity provider posts back to the API.
+func (test *samlTest) signIn(t *testing.T, email string, firstName string) (string, url.Values) {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodGet, test.apiURL+"/auth/saml/acme?code_challenge="+rfc7636CodeChallenge+"&code_challenge_method=S256", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := test.do(t, request, http.StatusFound)
+	response.Body.Close()
+
+	idpURL, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := idpURL.Query()
+	query.Set("email", email)
+	query.Set("firstName", firstName)
+	idpURL.RawQuery = query.Encode()
+	responseForm := test.get(t, idpURL.String(), http.StatusOK)
+
+	action := samlResponseFormPattern.FindStringSubmatch(responseForm)
+	if action == nil {
+		t.Fatalf("identity provider answered %s, want a form posting to the API", responseForm)
+	}
+	form := url.Values{}
+	for _, input := range samlResponseInputPattern.FindAllStringSubmatch(responseForm, -1) {
+		form.Set(input[1], html.UnescapeString(input[2]))
+	}
+	return html.UnescapeString(action[1]), form
+}
+
+// postResponse posts the identity provider's form and returns the front-end
+// callback the API redirects to.
+func (test *samlTest) postResponse(t *testing.T, action string, form url.Values) url.Values {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, action, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response := test.do(t, request, http.StatusSeeOther)
+	response.Body.Close()
+
+	callback, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(callback.String(), testFrontendURL+"/callback?") {
+		t.Fatalf("redirected to %s, want the front-end callback", callback)
+	}
+	return callback.Query()
+}
+
+// signedInUser returns the user the authorization code in callback was issued
+// to.
+func (test *samlTest) signedInUser(t *testing.T, callback url.Values) *AuthUser {
+	t.Helper()
+	code := callback.Get("code")
+	if code == "" {
+		t.Fatalf("callback %v has no code", callback)
+	}
+	for _, authorizationCode := range test.repository.authorizationCodes {
+		if authorizationCode.CodeHash == HashAuthorizationCode(code) {
+			if authorizationCode.CodeChallenge != rfc7636CodeChallenge {
+				t.Errorf("code challenge = %q, want the one sent to /auth/saml/acme", authorizationCode.CodeChallenge)
+			}
+			return test.repository.authUsers[authorizationCode.UserID]
+		}
+	}
+	t.Fatalf("code %q was not stored", code)
+	return nil
+}
+
+func TestSAMLSignInRoundTrip(t *testing.T) {
+	spKey, spCertificate := newTestCertificate(t, "catalyst")
+
+	tests := []struct {
+		name    string
+		keyPair *SAMLKeyPair
+	}{
+		{"unsigned requests", nil},
+		{"signed requests and encrypted assertions", &SAMLKeyPair{Key: spKey, Certificate: spCertificate}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			samlTest := newSAMLTest(t, test.keyPair)
+
+			action, form := samlTest.signIn(t, "ada@example.org", "Ada")
+			if action != samlTest.apiURL+"/auth/saml/acme/acs" {
+				t.Errorf("identity provider posts to %s, want the workspace's assertion consumer service", action)
+			}
+			samlResponse, err := base64.StdEncoding.DecodeString(form.Get("SAMLResponse"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encrypted := strings.Contains(string(samlResponse), "EncryptedAssertion"); encrypted != (test.keyPair != nil) {
+				t.Errorf("assertion encrypted = %v, want %v", encrypted, test.keyPair != nil)
+			}
+			authUser := samlTest.signedInUser(t, samlTest.postResponse(t, action, form))
+			if authUser == nil || authUser.Email != "ada@example.org" || authUser.FirstName != "Ada" {
+				t.Fatalf("signed in as %+v, want a new account for ada@example.org", authUser)
+			}
+
+			// the response is only accepted once
+			replayed := samlTest.postResponse(t, action, form)
+			if replayed.Get("error") != "invalid_sign_in_request" {
+				t.Errorf("replayed response redirected with %v, want invalid_sign_in_request", replayed)
+			}
+
+			// the persistent NameID finds the same account next time
+			action, form = samlTest.signIn(t, "ada@example.org", "Someone else")
+			again := samlTest.signedInUser(t, samlTest.postResponse(t, action, form))
+			if again == nil || again.ID != authUser.ID {
+				t.Errorf("second sign-in as %+v, want %s", again, authUser.ID)
+			}
+			if len(samlTest.repository.authUsers) != 1 {
+				t.Errorf("%d accounts, want 1", len(samlTest.repository.authUsers))
+			}
+		})
+	}
+}
+
+func TestSAMLSignInDoesNotTakeOverAccounts(t *testing.T) {
+	samlTest := newSAMLTest(t, nil)
+	existing := &AuthUser{ID: uuid.New(), Email: "ada@example.org", FirstName: "Ada", Roles: []string{RoleUser}}
+	samlTest.repository.authUsers[existing.ID] = existing
+
+	action, form := samlTest.signIn(t, "ada@example.org", "Ada")
+	callback := samlTest.postResponse(t, action, form)
+	if callback.Get("error") != SAMLErrorAccountExists {
+		t.Errorf("callback %v, want %s", callback, SAMLErrorAccountExists)
+	}
+}
+
+func TestSAMLSignInRejectsTamperedResponses(t *testing.T) {
+	samlTest := newSAMLTest(t, nil)
+	action, form := samlTest.signIn(t, "ada@example.org", "Ada")
+
+	tampered := url.Values{}
+	tampered.Set("RelayState", form.Get("RelayState"))
+	tampered.Set("SAMLResponse", form.Get("SAMLResponse")[:len(form.Get("SAMLResponse"))/2])
+
+	callback := samlTest.postResponse(t, action, tampered)
+	if callback.Get("error") != SAMLErrorInvalidResponse {
+		t.Errorf("callback %v, want %s", callback, SAMLErrorInvalidResponse)
+	}
+	if len(samlTest.repository.authUsers) != 0 {
+		t.Error("a tampered response created an account")
+	}
+}
@@ -0,0 +1,62 @@
+package authentication
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SCIMTokenPrefix marks bearer tokens that an identity provider uses to
+	// provision users over SCIM. They do not sign anyone in.
+	SCIMTokenPrefix     = "cat_scim_"
+	scimTokenHintLength = len(SCIMTokenPrefix) + 4
+)
+
+var ErrSCIMTokenNotFound = errors.New("SCIM token not found")
+
+// SCIMToken lets an identity provider call the SCIM endpoints. It is created
+// by an admin and stays valid until revoked, since identity providers are
+// configured with it once. Only the hash is stored.
+type SCIMToken struct {
+	ID         uuid.UUID
+	Name       string
+	TokenHash  string
+	TokenHint  string
+	CreatedBy  uuid.UUID
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// NewSCIMToken returns the token along with its plain value, which must be
+// shown to the admin once and then discarded.
+func NewSCIMToken(name string, createdBy uuid.UUID) (*SCIMToken, string, error) {
+	randomPart, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plainToken := SCIMTokenPrefix + randomPart
+
+	scimToken := &SCIMToken{
+		Name:      name,
+		TokenHash: HashSCIMToken(plainToken),
+		TokenHint: plainToken[:scimTokenHintLength],
+		CreatedBy: createdBy,
+	}
+	return scimToken, plainToken, nil
+}
+
+func HashSCIMToken(plainToken string) string {
+	return hashOpaqueToken(plainToken)
+}
+
+func IsSCIMToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, SCIMTokenPrefix)
+}
+
+func (scimToken *SCIMToken) IsRevoked() bool {
+	return scimToken.RevokedAt != nil
+}
@@ -0,0 +1,169 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+type SCIMTokenApiDto struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	TokenHint  string     `json:"tokenHint"`
+	CreatedBy  uuid.UUID  `json:"createdBy"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateSCIMTokenApiDto struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+func (dto *CreateSCIMTokenApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type CreatedSCIMTokenApiDto struct {
+	SCIMTokenApiDto
+	Token string `json:"token"`
+}
+
+type SCIMTokenHandler struct {
+	repository AuthenticationRepository
+	logger     *log.Logger
+}
+
+func NewSCIMTokenHandler(authenticationRepo AuthenticationRepository, logger *log.Logger) *SCIMTokenHandler {
+	return &SCIMTokenHandler{
+		repository: authenticationRepo,
+		logger:     logger,
+	}
+}
+
+// @Summary List SCIM tokens
+// @Description Lists the SCIM tokens that have not been revoked. Token values are never returned after creation. Admins only.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]SCIMTokenApiDto "SCIM tokens"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/scim/tokens [get]
+func (handler SCIMTokenHandler) ListSCIMTokens(ctx *gin.Context) {
+	scimTokens, err := handler.repository.ListSCIMTokens(ctx.Request.Context())
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListSCIMTokens: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	scimTokenApiDtos := make([]SCIMTokenApiDto, 0, len(scimTokens))
+	for _, scimToken := range scimTokens {
+		scimTokenApiDtos = append(scimTokenApiDtos, newSCIMTokenApiDto(scimToken))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tokens": scimTokenApiDtos})
+}
+
+// @Summary Create a SCIM token
+// @Description Creates the bearer token an identity provider uses to provision users and groups at /scim/v2. It opens nothing else and stays valid until revoked. The token value is only returned in this response. Admins only.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body CreateSCIMTokenApiDto true "Token name"
+// @Success 201 {object} CreatedSCIMTokenApiDto "Created token"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/scim/tokens [post]
+func (handler SCIMTokenHandler) CreateSCIMToken(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+
+	var createApiDto CreateSCIMTokenApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&createApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeCreateSCIMTokenApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = createApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateCreateSCIMTokenApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	scimToken, plainToken, err := NewSCIMToken(createApiDto.Name, authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: newSCIMToken: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	scimToken.ID, err = handler.repository.CreateSCIMToken(ctx.Request.Context(), scimToken)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateSCIMToken: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	scimToken.CreatedAt = time.Now()
+
+	ctx.JSON(http.StatusCreated, CreatedSCIMTokenApiDto{
+		SCIMTokenApiDto: newSCIMTokenApiDto(scimToken),
+		Token:           plainToken,
+	})
+}
+
+// @Summary Revoke a SCIM token
+// @Description Revokes a SCIM token. The identity provider using it is turned away immediately. Admins only.
+// @Tags auth
+// @Param id path string true "Token ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Token not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/scim/tokens/{id} [delete]
+func (handler SCIMTokenHandler) RevokeSCIMToken(ctx *gin.Context) {
+	scimTokenID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Token ID"})
+		return
+	}
+
+	err = handler.repository.RevokeSCIMToken(ctx.Request.Context(), scimTokenID)
+	if errors.Is(err, ErrSCIMTokenNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRevokeSCIMToken: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func newSCIMTokenApiDto(scimToken *SCIMToken) SCIMTokenApiDto {
+	return SCIMTokenApiDto{
+		ID:         scimToken.ID,
+		Name:       scimToken.Name,
+		TokenHint:  scimToken.TokenHint,
+		CreatedBy:  scimToken.CreatedBy,
+		LastUsedAt: scimToken.LastUsedAt,
+		CreatedAt:  scimToken.CreatedAt,
+	}
+}
@@ -1,5 +1,65 @@
-package authentication 
+package authentication
+
+import (
+	"slices"
+	"strings"
+)
 
 const (
-	AuthScope = "authentication"
+	AuthScope          = "authentication"
+	UsersReadScope     = "users:read"
+	UsersWriteScope    = "users:write"
+	ProjectsReadScope  = "projects:read"
+	ProjectsWriteScope = "projects:write"
+	AdminScope         = "admin"
+
+	// MFAScope is the only scope on the short-lived token handed out while a
+	// sign-in waits for its second factor. It is never granted alongside others.
+	MFAScope = "mfa"
 )
+
+// DefaultUserScopes are granted to every signed in user.
+var DefaultUserScopes = []string{
+	AuthScope,
+	UsersReadScope,
+	UsersWriteScope,
+	ProjectsReadScope,
+	ProjectsWriteScope,
+}
+
+// GrantedScopes returns the scopes an access token issued to authUser should carry.
+// Every user receives DefaultUserScopes, and admins also receive AdminScope.
+func GrantedScopes(authUser *AuthUser) []string {
+	scopes := slices.Clone(DefaultUserScopes)
+	if authUser.IsAdmin() {
+		scopes = append(scopes, AdminScope)
+	}
+	return scopes
+}
+
+// ImpersonationScopes are the scopes of a token an admin uses to act as
+// authUser. Admin rights and the user's sign-in settings are left out.
+func ImpersonationScopes(authUser *AuthUser) []string {
+	return slices.DeleteFunc(GrantedScopes(authUser), func(scope string) bool {
+		return scope == AdminScope || scope == AuthScope
+	})
+}
+
+// JoinScopes formats scopes the way they are stored in the scopes claim.
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func ParseScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
+
+// HasScopes reports whether every required scope is in granted.
+func HasScopes(granted []string, required ...string) bool {
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package authentication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SessionProviderPassword, SessionProviderDevice and SessionProviderMagicLink
+	// label sessions that did not start at an OAuth provider.
+	SessionProviderPassword  = "password"
+	SessionProviderDevice    = "device"
+	SessionProviderMagicLink = "magic_link"
+
+	maxSessionUserAgentLength = 512
+
+	// RecentSignInWindow is how long after signing in a session counts as
+	// having just proven who the user is, for changes that need it.
+	RecentSignInWindow = 10 * time.Minute
+
+	// RecentSignInRequiredCode is sent alongside the error when a change needs
+	// the user to sign in again first.
+	RecentSignInRequiredCode = "recent_sign_in_required"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is one sign-in on one browser or device. Refresh tokens and access
+// tokens issued for it carry its ID, so revoking the session ends them all.
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Provider   string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
+
+func NewSession(userID uuid.UUID, provider string, userAgent string, ipAddress string) *Session {
+	if len(userAgent) > maxSessionUserAgentLength {
+		userAgent = userAgent[:maxSessionUserAgentLength]
+	}
+	return &Session{
+		UserID:    userID,
+		Provider:  provider,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+	}
+}
+
+// IsRecentSignIn reports whether the session started within RecentSignInWindow.
+func (session *Session) IsRecentSignIn() bool {
+	return time.Since(session.CreatedAt) < RecentSignInWindow
+}
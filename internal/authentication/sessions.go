@@ -0,0 +1,119 @@
+package authentication
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionApiDto struct {
+	ID         uuid.UUID `json:"id"`
+	Provider   string    `json:"provider"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
+
+type SessionHandler struct {
+	repository      AuthenticationRepository
+	revocationStore RevocationStore
+	cookies         *Cookies
+	logger          *log.Logger
+}
+
+func NewSessionHandler(authenticationRepo AuthenticationRepository, revocationStore RevocationStore, cookies *Cookies, logger *log.Logger) *SessionHandler {
+	return &SessionHandler{
+		repository:      authenticationRepo,
+		revocationStore: revocationStore,
+		cookies:         cookies,
+		logger:          logger,
+	}
+}
+
+// startSession records a sign-in from the browser or device making this
+// request. Users with an authenticator app still have to pass an MFAChallenge.
+func startSession(ctx *gin.Context, tokenIssuer *TokenIssuer, userID uuid.UUID, provider string) (uuid.UUID, error) {
+	session := NewSession(userID, provider, ctx.Request.UserAgent(), ctx.ClientIP())
+	return tokenIssuer.StartSignIn(ctx.Request.Context(), session)
+}
+
+// @Summary List sessions
+// @Description Lists where the current user is signed in. The session making the request is marked as current.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]SessionApiDto "Active sessions"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/sessions [get]
+func (handler SessionHandler) ListSessions(ctx *gin.Context) {
+	authUser := GetAuthUser(ctx)
+	currentSessionID := GetAccessTokenClaims(ctx).Session()
+
+	sessions, err := handler.repository.ListActiveSessions(ctx.Request.Context(), authUser.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListActiveSessions: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	sessionApiDtos := make([]SessionApiDto, 0, len(sessions))
+	for _, session := range sessions {
+		sessionApiDtos = append(sessionApiDtos, SessionApiDto{
+			ID:         session.ID,
+			Provider:   session.Provider,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sessions": sessionApiDtos})
+}
+
+// @Summary Revoke a session
+// @Description Signs one of the current user's sessions out. Its access and refresh tokens stop working.
+// @Tags auth
+// @Param id path string true "Session ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 404 {object} map[string]string "Session not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/sessions/{id} [delete]
+func (handler SessionHandler) RevokeSession(ctx *gin.Context) {
+	sessionID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Session ID"})
+		return
+	}
+	authUser := GetAuthUser(ctx)
+
+	err = handler.revocationStore.RevokeSession(ctx.Request.Context(), authUser.ID, sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: revocationStoreRevokeSession: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if sessionID == GetAccessTokenClaims(ctx).Session() {
+		handler.cookies.clearRefreshToken(ctx)
+	}
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
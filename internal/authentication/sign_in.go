@@ -2,11 +2,11 @@ package authentication
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"time"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,20 +14,38 @@ import (
 	"github.com/markbates/goth/gothic"
 )
 
+// AccountDeactivatedCode is the error code for a sign-in by a deactivated
+// user, sent to the front-end callback or alongside the error in JSON.
+const AccountDeactivatedCode = "account_deactivated"
+
+// AccountExistsCode is the error code for a provider sign-in whose email
+// belongs to an account that signs in another way.
+const AccountExistsCode = "account_exists"
+
 type SignInHandler struct {
-	repository AuthenticationRepository
-	logger     *log.Logger
+	repository     AuthenticationRepository
+	tokenIssuer    *TokenIssuer
+	providerTokens *ProviderTokenSource
+	admission      *AdmissionPolicy
+	cookies        *Cookies
+	frontendURL    string
+	logger         *log.Logger
 }
 
-func NewSignInHandler(authenticationRepo AuthenticationRepository, logger *log.Logger) *SignInHandler {
+func NewSignInHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, providerTokens *ProviderTokenSource, admission *AdmissionPolicy, cookies *Cookies, frontendURL string, logger *log.Logger) *SignInHandler {
 	return &SignInHandler{
-		repository: authenticationRepo,
-		logger:     logger,
+		repository:     authenticationRepo,
+		tokenIssuer:    tokenIssuer,
+		providerTokens: providerTokens,
+		admission:      admission,
+		cookies:        cookies,
+		frontendURL:    frontendURL,
+		logger:         logger,
 	}
 }
 
 // @Summary OAuth callback
-// @Description Handle the callback from the OAuth provider and generate a JWT token.
+// @Description Handle the callback from the OAuth provider. Users turned away by the admission rules are redirected to the front-end callback with an error code: invitation_required, organization_membership_required, email_domain_not_allowed or email_not_verified, deactivated users with account_deactivated, and new identities whose email another account already uses with account_exists; that account's owner has to sign in the way they did before and link the provider from there. Sign-ins redirect to the front-end with a one-time code bound to the PKCE challenge sent to /auth/{provider}, to be exchanged at /auth/token. Users with an authenticator app are left pending a second factor, which /auth/token asks for.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -45,39 +63,195 @@ func (handler *SignInHandler) SignInCallback(ctx *gin.Context) {
 		return
 	}
 
-	authUserID, err := handler.repository.FindUserIDByProvider(ctx.Request.Context(), gothUser.UserID)
-	if err == sql.ErrNoRows {
+	intent := handler.cookies.popSignInIntent(ctx)
+	if intent == nil || intent.Provider != gothUser.Provider {
+		ctx.Redirect(http.StatusTemporaryRedirect, handler.frontendURL+"/callback?error=invalid_sign_in_request")
+		return
+	}
+	if intent.Purpose == SignInIntentLinkIdentity {
+		handler.linkIdentity(ctx, intent, gothUser)
+		return
+	}
+
+	authUserID, err := handler.repository.FindUserIDByProvider(ctx.Request.Context(), gothUser.Provider, gothUser.UserID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindUserIDByProvider: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	err = handler.admission.Admit(ctx.Request.Context(), handler.admission.ProviderCandidate(gothUser, authUserID != uuid.Nil))
+	var admissionErr *AdmissionError
+	if errors.As(err, &admissionErr) {
+		handler.rejectSignIn(ctx, intent, admissionErr)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: admissionPolicyAdmit: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if authUserID == uuid.Nil {
 		// create new user
 		authUserID, err = handler.registerAuthUser(ctx, gothUser)
+		if errors.Is(err, ErrEmailTaken) {
+			handler.rejectExistingAccount(ctx, intent)
+			return
+		}
 		if err != nil {
 			handler.logger.Printf("ERROR: handlerRegisterAuthUser: %v", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 			return
 		}
 	}
+	handler.saveProviderToken(ctx, gothUser)
+
+	if intent.Purpose == SignInIntentDeviceAuthorization {
+		handler.approveDeviceAuthorization(ctx, intent, authUserID)
+		return
+	}
+
+	// found user, record the session and hand the front-end a code to exchange for its tokens
+	redirectWithAuthorizationCode(ctx, handler.repository, handler.tokenIssuer, handler.frontendURL, handler.logger, authUserID, gothUser.Provider, intent.CodeChallenge)
+}
+
+// redirectWithAuthorizationCode records a browser sign-in and redirects to the
+// front-end with a one-time code bound to codeChallenge, to be exchanged at
+// /auth/token. The redirect is a 303 so that a sign-in posted back by a SAML
+// identity provider arrives at the front-end as a GET.
+func redirectWithAuthorizationCode(ctx *gin.Context, repository AuthenticationRepository, tokenIssuer *TokenIssuer, frontendURL string, logger *log.Logger, authUserID uuid.UUID, provider string, codeChallenge string) {
+	sessionID, err := startSession(ctx, tokenIssuer, authUserID, provider)
+	if errors.Is(err, ErrAccountDeactivated) {
+		ctx.Redirect(http.StatusSeeOther, fmt.Sprintf("%s/callback?error=%s", frontendURL, AccountDeactivatedCode))
+		return
+	}
 	if err != nil {
-		handler.logger.Printf("ERROR: repositoryFindUserIDByProvider: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		logger.Printf("ERROR: startSession: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
 
-	// found user, generate token etc
-	token, err := GenerateJWT(authUserID, gothUser.Email, AuthScope, 2*time.Hour)
+	authorizationCode, plainCode, err := NewAuthorizationCode(authUserID, sessionID, codeChallenge)
 	if err != nil {
-		handler.logger.Printf("ERROR: tokenGenerateJWT: %v", err)
+		logger.Printf("ERROR: newAuthorizationCode: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
 
-	redirectUrl := fmt.Sprintf("http://localhost:4200/callback?token=%s", token)
-	ctx.Redirect(http.StatusTemporaryRedirect, redirectUrl)
+	authorizationCode.ID, err = repository.CreateAuthorizationCode(ctx.Request.Context(), authorizationCode)
+	if err != nil {
+		logger.Printf("ERROR: repositoryCreateAuthorizationCode: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	redirectUrl := fmt.Sprintf("%s/callback?code=%s", frontendURL, url.QueryEscape(plainCode))
+	ctx.Redirect(http.StatusSeeOther, redirectUrl)
 }
 
 func (handler *SignInHandler) registerAuthUser(ctx *gin.Context, gothUser goth.User) (uuid.UUID, error) {
 	userID, err := handler.repository.RegisterAuthUser(ctx.Request.Context(), gothUser)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return uuid.Nil, err
 	}
+
+	if gothUser.Email != "" {
+		err = handler.repository.AcceptInvitation(ctx.Request.Context(), gothUser.Email)
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryAcceptInvitation: %v", err)
+		}
+	}
 	return userID, nil
 }
+
+// rejectSignIn tells the user why the admission rules turned them away, on the
+// front-end or, for device sign-ins, on the device page.
+func (handler *SignInHandler) rejectSignIn(ctx *gin.Context, intent *SignInIntent, admissionErr *AdmissionError) {
+	if intent.Purpose == SignInIntentDeviceAuthorization {
+		renderDeviceResultPage(ctx, http.StatusForbidden, "Device not connected", admissionErr.Error())
+		return
+	}
+	ctx.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/callback?error=%s", handler.frontendURL, url.QueryEscape(admissionErr.Code)))
+}
+
+// rejectExistingAccount turns away a new provider identity whose email is
+// already used by an account. Signing in would otherwise hand that account to
+// whoever controls the identity.
+func (handler *SignInHandler) rejectExistingAccount(ctx *gin.Context, intent *SignInIntent) {
+	if intent.Purpose == SignInIntentDeviceAuthorization {
+		renderDeviceResultPage(ctx, http.StatusConflict, "Device not connected", "An account already uses this email. Sign in the way you did before.")
+		return
+	}
+	ctx.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/callback?error=%s", handler.frontendURL, AccountExistsCode))
+}
+
+// saveProviderToken keeps the provider's tokens so the API can call it as the
+// user later. Failing to store them does not fail the sign-in.
+func (handler *SignInHandler) saveProviderToken(ctx *gin.Context, gothUser goth.User) {
+	err := handler.providerTokens.Save(ctx.Request.Context(), gothUser)
+	if err != nil {
+		handler.logger.Printf("ERROR: providerTokensSave: %v", err)
+	}
+}
+
+// linkIdentity attaches the provider identity to the user who started the link,
+// unless that identity already belongs to someone else.
+func (handler *SignInHandler) linkIdentity(ctx *gin.Context, intent *SignInIntent, gothUser goth.User) {
+	userID, err := intent.UserID()
+	if err != nil {
+		ctx.Redirect(http.StatusTemporaryRedirect, handler.frontendURL+"/callback?error=invalid_link_request")
+		return
+	}
+
+	existingUserID, err := handler.repository.FindUserIDByProvider(ctx.Request.Context(), gothUser.Provider, gothUser.UserID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindUserIDByProvider: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if existingUserID != uuid.Nil && existingUserID != userID {
+		ctx.Redirect(http.StatusTemporaryRedirect, handler.frontendURL+"/callback?error=identity_already_linked")
+		return
+	}
+
+	if existingUserID == uuid.Nil {
+		_, err = handler.repository.LinkAuthProvider(ctx.Request.Context(), userID, gothUser)
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryLinkAuthProvider: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+	}
+	handler.saveProviderToken(ctx, gothUser)
+
+	ctx.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/callback?linked=%s", handler.frontendURL, url.QueryEscape(gothUser.Provider)))
+}
+
+// approveDeviceAuthorization lets the device that showed the user code poll for
+// tokens. This browser is not signed in.
+func (handler *SignInHandler) approveDeviceAuthorization(ctx *gin.Context, intent *SignInIntent, authUserID uuid.UUID) {
+	deviceAuthorizationID, err := uuid.Parse(intent.DeviceAuthorizationID)
+	if err != nil {
+		renderDeviceResultPage(ctx, http.StatusBadRequest, "Device not connected", "Something went wrong. Start again from your device.")
+		return
+	}
+
+	err = handler.repository.ApproveDeviceAuthorization(ctx.Request.Context(), deviceAuthorizationID, authUserID)
+	if errors.Is(err, ErrDeviceAuthorizationNotFound) {
+		renderDeviceResultPage(ctx, http.StatusBadRequest, "Device not connected", "The code has expired. Start again from your device.")
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryApproveDeviceAuthorization: %v", err)
+		renderDeviceResultPage(ctx, http.StatusInternalServerError, "Something went wrong", "Please try again from your device.")
+		return
+	}
+
+	renderDeviceResultPage(ctx, http.StatusOK, "Device connected", "You can close this window and return to your device.")
+}
+
+// respondAccountDeactivated turns away a deactivated user at a JSON endpoint.
+func respondAccountDeactivated(ctx *gin.Context) {
+	ctx.JSON(http.StatusForbidden, gin.H{"error": ErrAccountDeactivated.Error(), "code": AccountDeactivatedCode})
+}
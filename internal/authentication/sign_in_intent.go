@@ -0,0 +1,96 @@
+package authentication
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	SignInIntentCookieName   = "auth_intent"
+	SignInIntentTimeToLive   = 10 * time.Minute
+	SignInIntentLinkIdentity = "link_identity"
+	// SignInIntentSignIn signs the browser in, issuing an authorization code
+	// bound to CodeChallenge.
+	SignInIntentSignIn = "sign_in"
+	// SignInIntentDeviceAuthorization approves DeviceAuthorizationID for the
+	// user who signs in, instead of signing this browser in.
+	SignInIntentDeviceAuthorization = "device_authorization"
+)
+
+// SignInIntent records why a browser was sent to an OAuth provider, for the
+// callback to act on. It travels in a signed HttpOnly cookie rather than the
+// URL so it cannot be handed to, or replayed from, another browser.
+type SignInIntent struct {
+	Purpose               string `json:"purpose"`
+	Provider              string `json:"provider"`
+	DeviceAuthorizationID string `json:"deviceAuthorizationId,omitempty"`
+	CodeChallenge         string `json:"codeChallenge,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func NewSignInIntent(purpose string, provider string, userID uuid.UUID) *SignInIntent {
+	now := time.Now()
+	return &SignInIntent{
+		Purpose:  purpose,
+		Provider: provider,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(SignInIntentTimeToLive)),
+		},
+	}
+}
+
+func (intent *SignInIntent) UserID() (uuid.UUID, error) {
+	return uuid.Parse(intent.Subject)
+}
+
+func (cookies *Cookies) setSignInIntent(ctx *gin.Context, intent *SignInIntent) error {
+	intent.Issuer = cookies.keySet.issuer
+	intentToken, err := cookies.keySet.sign(intent, intentTokenType)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     SignInIntentCookieName,
+		Value:    intentToken,
+		Path:     "/auth",
+		MaxAge:   int(SignInIntentTimeToLive.Seconds()),
+		HttpOnly: true,
+		Secure:   cookies.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// popSignInIntent returns the intent for this browser, if any, and clears it so
+// it is only acted on once. Invalid or expired intents are treated as absent.
+func (cookies *Cookies) popSignInIntent(ctx *gin.Context) *SignInIntent {
+	intentToken, err := ctx.Cookie(SignInIntentCookieName)
+	if err != nil || intentToken == "" {
+		return nil
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     SignInIntentCookieName,
+		Value:    "",
+		Path:     "/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   cookies.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	intent := &SignInIntent{}
+	_, err = cookies.keySet.parse(intentToken, intent, intentTokenType)
+	if err != nil {
+		return nil
+	}
+	return intent
+}
@@ -0,0 +1,223 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"catalyst.api/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key used to sign or verify access tokens. PrivateKey is nil
+// for keys that are only kept around to verify tokens issued before a rotation.
+type SigningKey struct {
+	KeyID      string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeySet holds the active signing key and every key tokens may still be verified with.
+type KeySet struct {
+	issuer           string
+	signingKey       *SigningKey
+	verificationKeys map[string]*SigningKey
+}
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// LoadKeySet reads the signing and verification keys named in the config. Outside
+// of production a missing signing key is replaced with a throwaway Ed25519 key,
+// so tokens do not survive a restart.
+func LoadKeySet(cfg *config.Config) (*KeySet, error) {
+	authConfig := cfg.AuthenticationConfig
+	keySet := &KeySet{
+		issuer:           authConfig.JWTIssuer,
+		verificationKeys: make(map[string]*SigningKey),
+	}
+
+	if authConfig.JWTSigningKey.Path == "" {
+		if cfg.HttpConfig.IsProduction {
+			return nil, errors.New("JWT_SIGNING_KEY_FILE must be set in production")
+		}
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		keySet.signingKey = newSigningKey("development", privateKey.Public(), privateKey)
+	} else {
+		if authConfig.JWTSigningKey.KeyID == "" {
+			return nil, errors.New("JWT_SIGNING_KEY_ID must be set alongside JWT_SIGNING_KEY_FILE")
+		}
+		signingKey, err := readSigningKey(authConfig.JWTSigningKey)
+		if err != nil {
+			return nil, err
+		}
+		if signingKey.PrivateKey == nil {
+			return nil, fmt.Errorf("signing key %q is not a private key", signingKey.KeyID)
+		}
+		keySet.signingKey = signingKey
+	}
+	keySet.verificationKeys[keySet.signingKey.KeyID] = keySet.signingKey
+
+	for _, keyConfig := range authConfig.JWTVerificationKeys {
+		if _, exists := keySet.verificationKeys[keyConfig.KeyID]; exists {
+			return nil, fmt.Errorf("duplicate JWT key id %q", keyConfig.KeyID)
+		}
+		verificationKey, err := readSigningKey(keyConfig)
+		if err != nil {
+			return nil, err
+		}
+		keySet.verificationKeys[verificationKey.KeyID] = verificationKey
+	}
+
+	return keySet, nil
+}
+
+// Token types set in the typ header, so a token minted for one purpose is never
+// accepted for another.
+const (
+	accessTokenType    = "JWT"
+	intentTokenType    = "intent+jwt"
+	magicLinkTokenType = "magic-link+jwt"
+)
+
+func (keySet *KeySet) sign(claims jwt.Claims, tokenType string) (string, error) {
+	token := jwt.NewWithClaims(keySet.signingKey.Method, claims)
+	token.Header["kid"] = keySet.signingKey.KeyID
+	token.Header["typ"] = tokenType
+	return token.SignedString(keySet.signingKey.PrivateKey)
+}
+
+func (keySet *KeySet) parse(tokenString string, claims jwt.Claims, tokenType string) (*jwt.Token, error) {
+	token, err := jwt.ParseWithClaims(tokenString, claims, keySet.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(keySet.issuer),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if token.Header["typ"] != tokenType {
+		return nil, fmt.Errorf("unexpected token type: %v", token.Header["typ"])
+	}
+	return token, nil
+}
+
+func (keySet *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	key, found := keySet.verificationKeys[keyID]
+	if !found {
+		return nil, fmt.Errorf("unknown signing key: %q", keyID)
+	}
+	// check algorithm
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// JWKS publishes the public half of every verification key.
+func (keySet *KeySet) JWKS() JSONWebKeySet {
+	keyIDs := make([]string, 0, len(keySet.verificationKeys))
+	for keyID := range keySet.verificationKeys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	jwks := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, keyID := range keyIDs {
+		key := keySet.verificationKeys[keyID]
+		jwk := JSONWebKey{
+			KeyID:     key.KeyID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+func readSigningKey(keyConfig config.JWTKeyConfig) (*SigningKey, error) {
+	pemBytes, err := os.ReadFile(keyConfig.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWT key %q: %w", keyConfig.KeyID, err)
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key %q is not PEM encoded", keyConfig.KeyID)
+	}
+
+	var parsedKey interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsedKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse JWT key %q: %w", keyConfig.KeyID, err)
+	}
+
+	switch key := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		return newSigningKey(keyConfig.KeyID, &key.PublicKey, key), nil
+	case *rsa.PublicKey:
+		return newSigningKey(keyConfig.KeyID, key, nil), nil
+	case ed25519.PrivateKey:
+		return newSigningKey(keyConfig.KeyID, key.Public(), key), nil
+	case ed25519.PublicKey:
+		return newSigningKey(keyConfig.KeyID, key, nil), nil
+	}
+	return nil, fmt.Errorf("JWT key %q must be an RSA or Ed25519 key", keyConfig.KeyID)
+}
+
+func newSigningKey(keyID string, publicKey crypto.PublicKey, privateKey crypto.Signer) *SigningKey {
+	var method jwt.SigningMethod = jwt.SigningMethodEdDSA
+	if _, isRSA := publicKey.(*rsa.PublicKey); isRSA {
+		method = jwt.SigningMethodRS256
+	}
+	return &SigningKey{
+		KeyID:      keyID,
+		Method:     method,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}
+}
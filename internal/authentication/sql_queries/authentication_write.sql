@@ -1,12 +1,21 @@
 -- name: FindUserIDByProvider :one
 SELECT user_id 
 FROM auth_user_providers 
-WHERE provider_user_id = $1;
+WHERE provider = $1 AND provider_user_id = $2;
 
 -- name: FindAuthUserByID :one
-SELECT id, email, first_name, last_name, mobile_number, created_at, updated_at 
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.deactivated_at, auth_users.deletion_scheduled_at
 FROM users
-WHERE id = $1;
+JOIN auth_users ON auth_users.id = users.id
+WHERE users.id = $1;
+
+-- name: FindUnclaimedProvisionedUserID :one
+SELECT id
+FROM auth_users
+WHERE lower(email) = lower(sqlc.arg(email))
+  AND provisioned
+  AND password_hash IS NULL
+  AND NOT EXISTS (SELECT 1 FROM auth_user_providers WHERE auth_user_providers.user_id = auth_users.id);
 
 -- name: CreateAuthUser :one
 WITH new_auth_user AS (
@@ -21,5 +30,9 @@ new_provider AS (
 new_user AS (
     INSERT INTO users (id, email, first_name, last_name)
     SELECT id, $1, $2, $3 FROM new_auth_user
+),
+new_role AS (
+    INSERT INTO user_roles (user_id, role)
+    SELECT id, 'user' FROM new_auth_user
 )
 SELECT id FROM new_auth_user;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- name: CreateAuthorizationCode :one
+INSERT INTO authorization_codes (user_id, code_hash, code_challenge, expires_at, session_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: ConsumeAuthorizationCode :one
+UPDATE authorization_codes
+SET used_at = CURRENT_TIMESTAMP
+WHERE code_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id, code_challenge, session_id;
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- name: CreateDeviceAuthorization :one
+INSERT INTO device_authorizations (device_code_hash, user_code, client_id, interval_seconds, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: FindDeviceAuthorizationByDeviceCodeHash :one
+SELECT id, device_code_hash, user_code, client_id, status, user_id, interval_seconds, last_polled_at, expires_at, created_at
+FROM device_authorizations
+WHERE device_code_hash = $1;
+
+-- name: FindDeviceAuthorizationByUserCode :one
+SELECT id, device_code_hash, user_code, client_id, status, user_id, interval_seconds, last_polled_at, expires_at, created_at
+FROM device_authorizations
+WHERE user_code = $1;
+
+-- name: UpdateDeviceAuthorizationPoll :exec
+UPDATE device_authorizations
+SET last_polled_at = CURRENT_TIMESTAMP, interval_seconds = $2
+WHERE id = $1;
+
+-- name: ApproveDeviceAuthorization :execresult
+UPDATE device_authorizations
+SET status = 'approved', user_id = $2
+WHERE id = $1 AND status = 'pending' AND expires_at > CURRENT_TIMESTAMP;
+
+-- name: ConsumeDeviceAuthorization :one
+UPDATE device_authorizations
+SET status = 'consumed'
+WHERE id = $1 AND status = 'approved'
+RETURNING user_id;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: DeleteExpiredAuthorizationCodes :execresult
+DELETE FROM authorization_codes
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredDeviceAuthorizations :execresult
+DELETE FROM device_authorizations
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredMagicLinks :execresult
+DELETE FROM magic_links
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredPasswordResetTokens :execresult
+DELETE FROM password_reset_tokens
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredRefreshTokens :execresult
+DELETE FROM refresh_tokens
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredRevokedTokens :execresult
+DELETE FROM revoked_tokens
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredSAMLRequests :execresult
+DELETE FROM saml_requests
+WHERE expires_at < CURRENT_TIMESTAMP;
\ No newline at end of file
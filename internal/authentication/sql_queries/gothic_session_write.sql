@@ -0,0 +1,20 @@
+-- name: DeleteExpiredGothicSessions :execresult
+DELETE FROM gothic_sessions
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: DeleteGothicSession :exec
+DELETE FROM gothic_sessions
+WHERE id = $1;
+
+-- name: FindGothicSession :one
+SELECT id, data, expires_at, created_at, updated_at
+FROM gothic_sessions
+WHERE id = $1 AND expires_at > CURRENT_TIMESTAMP;
+
+-- name: UpsertGothicSession :exec
+INSERT INTO gothic_sessions (id, data, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE
+SET data = EXCLUDED.data,
+    expires_at = EXCLUDED.expires_at,
+    updated_at = CURRENT_TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: ListAuthUserProvidersByUserID :many
+SELECT id, user_id, provider, provider_user_id, created_at
+FROM auth_user_providers
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: CreateAuthUserProvider :one
+INSERT INTO auth_user_providers (user_id, provider, provider_user_id)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: DeleteAuthUserProvider :execresult
+DELETE FROM auth_user_providers
+WHERE id = $1 AND user_id = $2
+  AND (
+    (SELECT count(*) FROM auth_user_providers WHERE user_id = $2) > 1
+    OR EXISTS (SELECT 1 FROM auth_users WHERE id = $2 AND password_hash IS NOT NULL)
+  );
\ No newline at end of file
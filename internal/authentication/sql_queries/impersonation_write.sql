@@ -0,0 +1,37 @@
+-- name: CreateImpersonation :one
+INSERT INTO impersonations (actor_id, actor_email, user_id, reason, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: CreateImpersonationRequest :one
+INSERT INTO impersonation_requests (impersonation_id, method, path, ip_address)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: EndImpersonation :one
+UPDATE impersonations
+SET ended_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND ended_at IS NULL
+RETURNING id, actor_id, user_id, reason, expires_at, ended_at, created_at, actor_email;
+
+-- name: FindImpersonationByID :one
+SELECT id, actor_id, user_id, reason, expires_at, ended_at, created_at, actor_email
+FROM impersonations
+WHERE id = $1;
+
+-- name: ListImpersonationRequests :many
+SELECT id, impersonation_id, method, path, status_code, ip_address, created_at
+FROM impersonation_requests
+WHERE impersonation_id = $1
+ORDER BY created_at;
+
+-- name: ListImpersonations :many
+SELECT id, actor_id, user_id, reason, expires_at, ended_at, created_at, actor_email
+FROM impersonations
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- name: RecordImpersonationResponse :exec
+UPDATE impersonation_requests
+SET status_code = $2
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: AcceptInvitation :exec
+UPDATE invitations
+SET accepted_at = CURRENT_TIMESTAMP
+WHERE lower(email) = lower(sqlc.arg(email)) AND revoked_at IS NULL AND accepted_at IS NULL;
+
+-- name: CreateInvitation :one
+INSERT INTO invitations (email, invited_by, expires_at)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: HasInvitation :one
+SELECT EXISTS (
+  SELECT 1 FROM invitations
+  WHERE lower(email) = lower(sqlc.arg(email)) AND revoked_at IS NULL
+    AND (accepted_at IS NOT NULL OR expires_at > CURRENT_TIMESTAMP)
+);
+
+-- name: ListInvitations :many
+SELECT id, email, invited_by, expires_at, accepted_at, revoked_at, created_at
+FROM invitations
+WHERE revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokeInvitation :execresult
+UPDATE invitations
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL;
\ No newline at end of file
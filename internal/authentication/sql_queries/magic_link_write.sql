@@ -0,0 +1,10 @@
+-- name: ConsumeMagicLink :one
+UPDATE magic_links
+SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+RETURNING email;
+
+-- name: CreateMagicLink :one
+INSERT INTO magic_links (email, expires_at)
+VALUES ($1, $2)
+RETURNING id;
\ No newline at end of file
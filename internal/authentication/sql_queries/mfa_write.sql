@@ -0,0 +1,62 @@
+-- name: CompleteMFAChallenge :execresult
+DELETE FROM mfa_challenges
+WHERE session_id = $1;
+
+-- name: ConfirmTOTPCredential :execresult
+UPDATE totp_credentials
+SET confirmed_at = CURRENT_TIMESTAMP, last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (session_id, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: DeleteRecoveryCodesForUser :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: DeleteTOTPCredential :exec
+DELETE FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: FindMFAChallengeBySessionID :one
+SELECT session_id, user_id, failed_attempts, expires_at, created_at
+FROM mfa_challenges
+WHERE session_id = $1;
+
+-- name: FindTOTPCredentialByUserID :one
+SELECT user_id, secret, confirmed_at, last_used_step, created_at
+FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: RecordFailedMFAAttempt :one
+UPDATE mfa_challenges
+SET failed_attempts = failed_attempts + 1
+WHERE session_id = $1
+RETURNING failed_attempts;
+
+-- name: StartTOTPEnrolment :execresult
+INSERT INTO totp_credentials (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, created_at = CURRENT_TIMESTAMP
+WHERE totp_credentials.confirmed_at IS NULL;
+
+-- name: UseRecoveryCode :execresult
+UPDATE mfa_recovery_codes
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: UseTOTPStep :execresult
+UPDATE totp_credentials
+SET last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2;
\ No newline at end of file
@@ -0,0 +1,41 @@
+-- name: CreatePasswordAuthUser :one
+WITH new_auth_user AS (
+    INSERT INTO auth_users (email, first_name, last_name, password_hash)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id
+),
+new_user AS (
+    INSERT INTO users (id, email, first_name, last_name)
+    SELECT id, $1, $2, $3 FROM new_auth_user
+),
+new_role AS (
+    INSERT INTO user_roles (user_id, role)
+    SELECT id, 'user' FROM new_auth_user
+)
+SELECT id FROM new_auth_user;
+
+-- name: FindPasswordCredentialsByEmail :one
+SELECT id, password_hash
+FROM auth_users
+WHERE lower(email) = lower(sqlc.arg(email));
+
+-- name: FindPasswordHashByUserID :one
+SELECT password_hash
+FROM auth_users
+WHERE id = $1;
+
+-- name: UpdatePasswordHash :execresult
+UPDATE auth_users
+SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2;
+
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: ConsumePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id;
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, token_hint, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id;
+
+-- name: FindPersonalAccessTokenByHash :one
+SELECT id, user_id, name, token_hash, token_hint, scopes, expires_at, last_used_at, revoked_at, created_at
+FROM personal_access_tokens
+WHERE token_hash = $1;
+
+-- name: ListPersonalAccessTokensByUserID :many
+SELECT id, user_id, name, token_hash, token_hint, scopes, expires_at, last_used_at, revoked_at, created_at
+FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokePersonalAccessToken :execresult
+UPDATE personal_access_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokePersonalAccessTokensForUser :exec
+UPDATE personal_access_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute');
\ No newline at end of file
@@ -0,0 +1,34 @@
+-- name: FindAuthUserProviderID :one
+SELECT id
+FROM auth_user_providers
+WHERE provider = $1 AND provider_user_id = $2;
+
+-- name: FindProviderTokenByAuthUserProviderID :one
+SELECT auth_user_provider_id, key_id, wrapped_key, access_token, refresh_token, expires_at, updated_at
+FROM provider_tokens
+WHERE auth_user_provider_id = $1;
+
+-- name: FindProviderTokenByUserID :one
+SELECT provider_tokens.auth_user_provider_id, provider_tokens.key_id, provider_tokens.wrapped_key, provider_tokens.access_token, provider_tokens.refresh_token, provider_tokens.expires_at, provider_tokens.updated_at
+FROM provider_tokens
+JOIN auth_user_providers ON auth_user_providers.id = provider_tokens.auth_user_provider_id
+WHERE auth_user_providers.user_id = $1 AND auth_user_providers.provider = $2
+ORDER BY provider_tokens.updated_at DESC
+LIMIT 1;
+
+-- name: LockProviderToken :one
+SELECT auth_user_provider_id, key_id, wrapped_key, access_token, refresh_token, expires_at, updated_at
+FROM provider_tokens
+WHERE auth_user_provider_id = $1
+FOR UPDATE;
+
+-- name: UpsertProviderToken :exec
+INSERT INTO provider_tokens (auth_user_provider_id, key_id, wrapped_key, access_token, refresh_token, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (auth_user_provider_id) DO UPDATE
+SET key_id = EXCLUDED.key_id,
+    wrapped_key = EXCLUDED.wrapped_key,
+    access_token = EXCLUDED.access_token,
+    refresh_token = EXCLUDED.refresh_token,
+    expires_at = EXCLUDED.expires_at,
+    updated_at = CURRENT_TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, session_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: FindRefreshTokenByHash :one
+SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at, session_id
+FROM refresh_tokens
+WHERE token_hash = $1;
+
+-- name: MarkRefreshTokenUsed :execresult
+UPDATE refresh_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :execresult
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensForSession :execresult
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE session_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensForUser :execresult
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- name: ListRolesByUserID :many
+SELECT role
+FROM user_roles
+WHERE user_id = $1
+ORDER BY role;
\ No newline at end of file
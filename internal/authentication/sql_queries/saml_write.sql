@@ -0,0 +1,39 @@
+-- name: ConsumeSAMLRequest :one
+UPDATE saml_requests
+SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND connection_id = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+RETURNING request_id, code_challenge;
+
+-- name: CreateSAMLConnection :one
+INSERT INTO saml_connections (workspace, entity_id, idp_entity_id, idp_metadata, email_attribute, first_name_attribute, last_name_attribute, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id;
+
+-- name: CreateSAMLRequest :one
+INSERT INTO saml_requests (connection_id, request_id, code_challenge, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: DeleteSAMLConnection :execresult
+DELETE FROM saml_connections
+WHERE id = $1;
+
+-- name: FindSAMLConnectionByID :one
+SELECT id, workspace, entity_id, idp_entity_id, idp_metadata, email_attribute, first_name_attribute, last_name_attribute, enabled, created_at, updated_at
+FROM saml_connections
+WHERE id = $1;
+
+-- name: FindSAMLConnectionByWorkspace :one
+SELECT id, workspace, entity_id, idp_entity_id, idp_metadata, email_attribute, first_name_attribute, last_name_attribute, enabled, created_at, updated_at
+FROM saml_connections
+WHERE workspace = $1;
+
+-- name: ListSAMLConnections :many
+SELECT id, workspace, entity_id, idp_entity_id, idp_metadata, email_attribute, first_name_attribute, last_name_attribute, enabled, created_at, updated_at
+FROM saml_connections
+ORDER BY workspace;
+
+-- name: UpdateSAMLConnection :execresult
+UPDATE saml_connections
+SET entity_id = $2, idp_entity_id = $3, idp_metadata = $4, email_attribute = $5, first_name_attribute = $6, last_name_attribute = $7, enabled = $8, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
\ No newline at end of file
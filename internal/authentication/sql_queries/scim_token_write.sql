@@ -0,0 +1,25 @@
+-- name: CreateSCIMToken :one
+INSERT INTO scim_tokens (name, token_hash, token_hint, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: FindSCIMTokenByHash :one
+SELECT id, name, token_hash, token_hint, created_by, last_used_at, revoked_at, created_at
+FROM scim_tokens
+WHERE token_hash = $1;
+
+-- name: ListSCIMTokens :many
+SELECT id, name, token_hash, token_hint, created_by, last_used_at, revoked_at, created_at
+FROM scim_tokens
+WHERE revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokeSCIMToken :execresult
+UPDATE scim_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: TouchSCIMToken :exec
+UPDATE scim_tokens
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute');
\ No newline at end of file
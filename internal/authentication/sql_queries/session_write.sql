@@ -0,0 +1,40 @@
+-- name: CreateSession :one
+INSERT INTO sessions (user_id, provider, user_agent, ip_address)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: FindActiveSession :one
+SELECT id, user_id, provider, user_agent, ip_address, created_at, last_seen_at, revoked_at
+FROM sessions
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: ListActiveSessionsByUserID :many
+SELECT id, user_id, provider, user_agent, ip_address, created_at, last_seen_at, revoked_at
+FROM sessions
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY last_seen_at DESC;
+
+-- name: RevokeSession :execresult
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessionsForUser :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensForOtherSessions :exec
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND session_id IS DISTINCT FROM $2 AND revoked_at IS NULL;
+
+-- name: RevokeSessionsForUser :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 minute';
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: FindTokenRevocationState :one
+SELECT
+    EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = sqlc.arg(jti)) AS token_revoked,
+    EXISTS (SELECT 1 FROM sessions WHERE sessions.id = sqlc.arg(session_id) AND revoked_at IS NOT NULL) AS session_revoked,
+    tokens_revoked_at
+FROM auth_users
+WHERE auth_users.id = sqlc.arg(user_id);
+
+-- name: RevokeAllTokensForUser :one
+UPDATE auth_users
+SET tokens_revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING tokens_revoked_at;
\ No newline at end of file
@@ -0,0 +1,101 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+)
+
+type TokenExchangeApiDto struct {
+	Code         string `json:"code" validate:"required"`
+	CodeVerifier string `json:"codeVerifier" validate:"required"`
+}
+
+func (dto *TokenExchangeApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type TokenExchangeHandler struct {
+	repository  AuthenticationRepository
+	tokenIssuer *TokenIssuer
+	cookies     *Cookies
+	logger      *log.Logger
+}
+
+func NewTokenExchangeHandler(authenticationRepo AuthenticationRepository, tokenIssuer *TokenIssuer, cookies *Cookies, logger *log.Logger) *TokenExchangeHandler {
+	return &TokenExchangeHandler{
+		repository:  authenticationRepo,
+		tokenIssuer: tokenIssuer,
+		cookies:     cookies,
+		logger:      logger,
+	}
+}
+
+// @Summary Exchange authorization code
+// @Description Exchanges the one-time code from the OAuth callback redirect, with the PKCE verifier for the challenge sent to /auth/{provider}, for an access token. The refresh token is set as a cookie. Users with an authenticator app get mfaRequired and an mfaToken for /auth/mfa/verify instead.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param exchange body TokenExchangeApiDto true "Code and PKCE verifier"
+// @Success 200 {object} map[string]interface{} "Access token, or MFA challenge token"
+// @Failure 400 {object} map[string]string "Invalid input or code"
+// @Failure 403 {object} map[string]string "Account deactivated, with the account_deactivated code"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/token [post]
+func (handler *TokenExchangeHandler) ExchangeCode(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+
+	var tokenExchangeApiDto TokenExchangeApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&tokenExchangeApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeTokenExchangeApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = tokenExchangeApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateTokenExchangeApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	// the code is spent before the verifier is checked, so a wrong guess burns it
+	authorizationCode, err := handler.repository.ConsumeAuthorizationCode(ctx.Request.Context(), HashAuthorizationCode(tokenExchangeApiDto.Code))
+	if errors.Is(err, ErrInvalidAuthorizationCode) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired code"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryConsumeAuthorizationCode: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if !VerifyCodeVerifier(authorizationCode.CodeChallenge, tokenExchangeApiDto.CodeVerifier) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired code"})
+		return
+	}
+
+	tokens, err := handler.tokenIssuer.Issue(ctx.Request.Context(), authorizationCode.UserID, authorizationCode.SessionID)
+	if errors.Is(err, ErrAccountDeactivated) {
+		respondAccountDeactivated(ctx)
+		return
+	}
+	if errors.Is(err, ErrMFARequired) {
+		respondMFARequired(ctx, handler.tokenIssuer, handler.logger, authorizationCode.UserID, authorizationCode.SessionID)
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: tokenIssuerIssue: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	handler.cookies.setRefreshToken(ctx, tokens.RefreshToken, tokens.RefreshTokenExpiresAt)
+	ctx.JSON(http.StatusOK, gin.H{"token": tokens.AccessToken})
+}
@@ -0,0 +1,225 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrAccountDeactivated  = errors.New("account is deactivated")
+)
+
+type IssuedTokens struct {
+	AccessToken           string
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+type TokenIssuer struct {
+	repository AuthenticationRepository
+	keySet     *KeySet
+}
+
+func NewTokenIssuer(authenticationRepo AuthenticationRepository, keySet *KeySet) *TokenIssuer {
+	return &TokenIssuer{
+		repository: authenticationRepo,
+		keySet:     keySet,
+	}
+}
+
+// StartSession records a new sign-in. Tokens for it are then issued with Issue.
+// Deactivated users cannot sign in and get ErrAccountDeactivated.
+func (issuer *TokenIssuer) StartSession(ctx context.Context, session *Session) (uuid.UUID, error) {
+	authUser, err := issuer.repository.FindAuthUserByID(ctx, session.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if authUser == nil {
+		return uuid.Nil, fmt.Errorf("auth user %s not found", session.UserID)
+	}
+	if authUser.IsDeactivated() {
+		return uuid.Nil, ErrAccountDeactivated
+	}
+
+	sessionID, err := issuer.repository.CreateSession(ctx, session)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	session.ID = sessionID
+	return sessionID, nil
+}
+
+// StartSignIn records a new sign-in that has passed its first factor. If the
+// user has an authenticator app the session is held back by an MFAChallenge,
+// and Issue returns ErrMFARequired until it is completed.
+func (issuer *TokenIssuer) StartSignIn(ctx context.Context, session *Session) (uuid.UUID, error) {
+	sessionID, err := issuer.StartSession(ctx, session)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	credential, err := issuer.repository.FindTOTPCredential(ctx, session.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if credential.IsConfirmed() {
+		err = issuer.repository.CreateMFAChallenge(ctx, NewMFAChallenge(session.UserID, sessionID))
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return sessionID, nil
+}
+
+// Issue creates an access token and starts a new refresh token family, both
+// tied to sessionID. ErrMFARequired is returned while the session still waits
+// for a second factor, and ErrAccountDeactivated if the user was deactivated
+// in the meantime.
+func (issuer *TokenIssuer) Issue(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (*IssuedTokens, error) {
+	if sessionID != uuid.Nil {
+		challenge, err := issuer.repository.FindMFAChallenge(ctx, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if challenge != nil {
+			return nil, ErrMFARequired
+		}
+	}
+
+	authUser, err := issuer.repository.FindAuthUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if authUser == nil {
+		return nil, fmt.Errorf("auth user %s not found", userID)
+	}
+	if authUser.IsDeactivated() {
+		return nil, ErrAccountDeactivated
+	}
+
+	refreshToken, plainRefreshToken, err := NewRefreshToken(userID, uuid.Nil, sessionID, RefreshTokenTimeToLive)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken.ID, err = issuer.repository.CreateRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := issuer.accessToken(authUser, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedTokens{
+		AccessToken:           accessToken,
+		RefreshToken:          plainRefreshToken,
+		RefreshTokenExpiresAt: refreshToken.ExpiresAt,
+	}, nil
+}
+
+// IssueMFAChallenge creates the short-lived token a sign-in held back by an
+// MFAChallenge uses to submit its second factor. It carries only MFAScope.
+func (issuer *TokenIssuer) IssueMFAChallenge(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (string, error) {
+	authUser, err := issuer.repository.FindAuthUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if authUser == nil {
+		return "", fmt.Errorf("auth user %s not found", userID)
+	}
+	return issuer.keySet.GenerateJWT(authUser.ID, authUser.Email, MFAScope, sessionID, MFAChallengeTimeToLive)
+}
+
+// Rotate exchanges a refresh token for a new token pair in the same family.
+// Presenting a token that has already been rotated revokes the whole family,
+// so a stolen token stops working for both the thief and the owner.
+func (issuer *TokenIssuer) Rotate(ctx context.Context, plainRefreshToken string) (*IssuedTokens, error) {
+	current, err := issuer.repository.FindRefreshTokenByHash(ctx, HashRefreshToken(plainRefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if current.IsSpent() {
+		err = issuer.repository.RevokeRefreshTokenFamily(ctx, current.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if current.IsExpired() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	authUser, err := issuer.repository.FindAuthUserByID(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if authUser == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if authUser.IsDeactivated() {
+		return nil, ErrAccountDeactivated
+	}
+
+	next, plainNextToken, err := NewRefreshToken(current.UserID, current.FamilyID, current.SessionID, RefreshTokenTimeToLive)
+	if err != nil {
+		return nil, err
+	}
+
+	err = issuer.repository.RotateRefreshToken(ctx, current, next)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		revokeErr := issuer.repository.RevokeRefreshTokenFamily(ctx, current.FamilyID)
+		if revokeErr != nil {
+			return nil, revokeErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current.SessionID != uuid.Nil {
+		err = issuer.repository.TouchSession(ctx, current.SessionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accessToken, err := issuer.accessToken(authUser, current.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedTokens{
+		AccessToken:           accessToken,
+		RefreshToken:          plainNextToken,
+		RefreshTokenExpiresAt: next.ExpiresAt,
+	}, nil
+}
+
+// Revoke ends the refresh token family that plainRefreshToken belongs to.
+func (issuer *TokenIssuer) Revoke(ctx context.Context, plainRefreshToken string) error {
+	refreshToken, err := issuer.repository.FindRefreshTokenByHash(ctx, HashRefreshToken(plainRefreshToken))
+	if err != nil {
+		return err
+	}
+	if refreshToken == nil {
+		return nil
+	}
+	return issuer.repository.RevokeRefreshTokenFamily(ctx, refreshToken.FamilyID)
+}
+
+func (issuer *TokenIssuer) accessToken(authUser *AuthUser, sessionID uuid.UUID) (string, error) {
+	scopes := JoinScopes(GrantedScopes(authUser))
+	return issuer.keySet.GenerateJWT(authUser.ID, authUser.Email, scopes, sessionID, AccessTokenTimeToLive)
+}
@@ -0,0 +1,108 @@
+package authentication
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 appendix B test vectors,
+// "12345678901234567890", in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	secret, err := totpSecretEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the RFC lists eight digits; six-digit codes are the last six of them
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		step := totpStep(time.Unix(test.unix, 0))
+		if code := totpCode(secret, step); code != test.code {
+			t.Errorf("totpCode at %d = %s, want %s", test.unix, code, test.code)
+		}
+	}
+}
+
+func TestTOTPMatchStep(t *testing.T) {
+	credential := &TOTPCredential{Secret: rfc6238Secret}
+	now := time.Unix(1111111111, 0)
+	currentStep := totpStep(now)
+
+	secret, err := totpSecretEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		code  string
+		step  int64
+		match bool
+	}{
+		{"current period", totpCode(secret, currentStep), currentStep, true},
+		{"previous period", totpCode(secret, currentStep-1), currentStep - 1, true},
+		{"next period", totpCode(secret, currentStep+1), currentStep + 1, true},
+		{"two periods ago", totpCode(secret, currentStep-2), 0, false},
+		{"two periods ahead", totpCode(secret, currentStep+2), 0, false},
+		{"too short", "28708", 0, false},
+		{"too long", "2870820", 0, false},
+		{"wrong code", "000000", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			step, match := credential.MatchStep(test.code, now)
+			if match != test.match || step != test.step {
+				t.Errorf("MatchStep(%q) = %d, %v, want %d, %v", test.code, step, match, test.step, test.match)
+			}
+		})
+	}
+}
+
+func TestTOTPMatchStepRejectsInvalidSecret(t *testing.T) {
+	credential := &TOTPCredential{Secret: "not base32!"}
+	if _, match := credential.MatchStep("123456", time.Now()); match {
+		t.Error("MatchStep matched a code for a secret that does not decode")
+	}
+}
+
+func TestNewTOTPSecretRoundTrips(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := totpSecretEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q does not decode: %v", secret, err)
+	}
+	if len(decoded) != totpSecretBytes {
+		t.Errorf("secret has %d bytes, want %d", len(decoded), totpSecretBytes)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	credential := &TOTPCredential{Secret: rfc6238Secret}
+	uri := credential.URI("ada@example.com")
+
+	wantPrefix := "otpauth://totp/Catalyst:ada@example.com?"
+	if !strings.HasPrefix(uri, wantPrefix) {
+		t.Errorf("URI = %s, want prefix %s", uri, wantPrefix)
+	}
+	for _, param := range []string{"secret=" + rfc6238Secret, "issuer=Catalyst", "digits=6", "period=30", "algorithm=SHA1"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("URI = %s, missing %s", uri, param)
+		}
+	}
+}
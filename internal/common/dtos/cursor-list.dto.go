@@ -0,0 +1,26 @@
+package dtos
+
+// CursorListDto places a page of a list fetched by cursor. Next and Prev are
+// the cursors of the neighbouring pages, or nil at either end of the list.
+type CursorListDto struct {
+	PageSize int     `json:"pageSize"`
+	Next     *string `json:"next"`
+	Prev     *string `json:"prev"`
+}
+
+// CursorResponseDto is the envelope list endpoints return when asked for a
+// page by cursor rather than by number.
+type CursorResponseDto[T any] struct {
+	Items []T `json:"items"`
+	CursorListDto
+}
+
+func NewCursorResponseDto[T any](items []T, cursors CursorListDto) CursorResponseDto[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return CursorResponseDto[T]{
+		Items:         items,
+		CursorListDto: cursors,
+	}
+}
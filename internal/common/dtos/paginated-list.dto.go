@@ -1,7 +1,48 @@
 package dtos
 
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
 type PaginatedListDto struct {
-	Page       int
-	PageSize   int
-	TotalCount int
+	Page       int `json:"page"`
+	PageSize   int `json:"pageSize"`
+	TotalCount int `json:"totalCount"`
+	TotalPages int `json:"totalPages"`
+}
+
+func NewPaginatedListDto(page int, pageSize int, totalCount int) PaginatedListDto {
+	totalPages := 0
+	if pageSize > 0 {
+		totalPages = (totalCount + pageSize - 1) / pageSize
+	}
+	return PaginatedListDto{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+	}
+}
+
+// Offset is the number of items before the first one on the page.
+func (dto PaginatedListDto) Offset() int {
+	return (dto.Page - 1) * dto.PageSize
+}
+
+// PaginatedResponseDto is the envelope list endpoints return: one page of
+// items along with where that page sits in the whole list.
+type PaginatedResponseDto[T any] struct {
+	Items []T `json:"items"`
+	PaginatedListDto
+}
+
+func NewPaginatedResponseDto[T any](items []T, pagination PaginatedListDto) PaginatedResponseDto[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return PaginatedResponseDto[T]{
+		Items:            items,
+		PaginatedListDto: pagination,
+	}
 }
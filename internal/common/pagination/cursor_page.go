@@ -0,0 +1,183 @@
+package pagination
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"catalyst.api/internal/common/dtos"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CursorRequest asks for the page of a list that follows Cursor, or the first
+// page when Cursor is nil. Sort names the sort column and order, and Filter is
+// the Fingerprint of the other query parameters.
+type CursorRequest struct {
+	Sort     string
+	Filter   string
+	PageSize int
+	Cursor   *Cursor
+}
+
+// IsCursorRequest reports whether a list is asked for by cursor. The cursor
+// parameter is left empty to ask for the first page.
+func IsCursorRequest(ctx *gin.Context) bool {
+	_, present := ctx.GetQuery("cursor")
+	return present
+}
+
+// ReadCursorRequest decodes the cursor parameter and checks it was handed out
+// for a list with the same sort and filters.
+func ReadCursorRequest(ctx *gin.Context, codec *CursorCodec, sort string, filter string, pageSize int) (CursorRequest, error) {
+	request := CursorRequest{
+		Sort:     sort,
+		Filter:   filter,
+		PageSize: pageSize,
+	}
+
+	encoded := ctx.Query("cursor")
+	if encoded == "" {
+		return request, nil
+	}
+
+	cursor, err := codec.Decode(encoded)
+	if err != nil {
+		return request, err
+	}
+	if cursor.Sort != sort || cursor.Filter != filter {
+		return request, fmt.Errorf("%w: it belongs to a list with a different sort or filter", ErrInvalidCursor)
+	}
+	request.Cursor = &cursor
+	return request, nil
+}
+
+func (request CursorRequest) Backward() bool {
+	return request.Cursor != nil && request.Cursor.Backward
+}
+
+// QueryDesc is the order rows must be read in. A backward page reads the list
+// in reverse from the cursor and NewCursorPage flips it back.
+func (request CursorRequest) QueryDesc(desc bool) bool {
+	return desc != request.Backward()
+}
+
+// Limit is one more than the page size, so NewCursorPage can tell whether
+// there is another page.
+func (request CursorRequest) Limit() int {
+	return request.PageSize + 1
+}
+
+// CursorPage is a page of a list along with the cursors of its neighbours.
+type CursorPage[T any] struct {
+	Items []T
+	Next  *string
+	Prev  *string
+}
+
+// NewCursorPage trims rows read with request.Limit() down to the page and
+// builds the cursors either side of it. keyOf returns the sort key and ID of
+// a row, the same pair the query compares cursors against.
+func NewCursorPage[T any](codec *CursorCodec, request CursorRequest, rows []T, keyOf func(T) (string, uuid.UUID)) (CursorPage[T], error) {
+	hasMore := len(rows) > request.PageSize
+	items := rows[:min(len(rows), request.PageSize)]
+
+	hasNext, hasPrev := hasMore, request.Cursor != nil
+	if request.Backward() {
+		items = slices.Clone(items)
+		slices.Reverse(items)
+		hasNext, hasPrev = true, hasMore
+	}
+
+	page := CursorPage[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	var err error
+	if hasNext {
+		key, id := keyOf(items[len(items)-1])
+		page.Next, err = request.encode(codec, key, id, false)
+		if err != nil {
+			return page, err
+		}
+	}
+	if hasPrev {
+		key, id := keyOf(items[0])
+		page.Prev, err = request.encode(codec, key, id, true)
+		if err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+func (request CursorRequest) encode(codec *CursorCodec, key string, id uuid.UUID, backward bool) (*string, error) {
+	encoded, err := codec.Encode(Cursor{
+		Sort:     request.Sort,
+		Filter:   request.Filter,
+		Key:      key,
+		ID:       id,
+		Backward: backward,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &encoded, nil
+}
+
+func (page CursorPage[T]) Dto(pageSize int) dtos.CursorListDto {
+	return dtos.CursorListDto{
+		PageSize: pageSize,
+		Next:     page.Next,
+		Prev:     page.Prev,
+	}
+}
+
+// SetCursorLinks points the Link header at the neighbouring pages of a list
+// read by cursor.
+func SetCursorLinks(ctx *gin.Context, next *string, prev *string) {
+	links := []string{}
+	if next != nil {
+		links = append(links, link(ctx, "next", "cursor", *next))
+	}
+	if prev != nil {
+		links = append(links, link(ctx, "prev", "cursor", *prev))
+	}
+	setLinkHeader(ctx, links)
+}
+
+// SetPageLinks points the Link header at the first, last and neighbouring
+// pages of a list read by page number.
+func SetPageLinks(ctx *gin.Context, pagination dtos.PaginatedListDto) {
+	lastPage := max(pagination.TotalPages, 1)
+	links := []string{link(ctx, "first", "page", "1")}
+	if pagination.Page > 1 {
+		links = append(links, link(ctx, "prev", "page", strconv.Itoa(min(pagination.Page-1, lastPage))))
+	}
+	if pagination.Page < lastPage {
+		links = append(links, link(ctx, "next", "page", strconv.Itoa(pagination.Page+1)))
+	}
+	links = append(links, link(ctx, "last", "page", strconv.Itoa(lastPage)))
+	setLinkHeader(ctx, links)
+}
+
+// link is the request URL with parameter set to value, as a Link header entry.
+func link(ctx *gin.Context, rel string, parameter string, value string) string {
+	target := *ctx.Request.URL
+	query := target.Query()
+	query.Set(parameter, value)
+	if parameter == "cursor" {
+		query.Del("page")
+	}
+	target.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, target.RequestURI(), rel)
+}
+
+func setLinkHeader(ctx *gin.Context, links []string) {
+	if len(links) > 0 {
+		ctx.Header("Link", strings.Join(links, ", "))
+	}
+}
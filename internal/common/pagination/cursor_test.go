@@ -0,0 +1,162 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"catalyst.api/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newTestCursorCodec(t *testing.T, key string) *CursorCodec {
+	t.Helper()
+	codec, err := NewCursorCodec(config.PaginationConfig{CursorKey: base64.StdEncoding.EncodeToString([]byte(key))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return codec
+}
+
+func TestNewCursorCodecRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"not base64", "not base64!"},
+		{"too short", base64.StdEncoding.EncodeToString([]byte("short"))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewCursorCodec(config.PaginationConfig{CursorKey: test.key}); err == nil {
+				t.Error("NewCursorCodec succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrips(t *testing.T) {
+	codec := newTestCursorCodec(t, strings.Repeat("k", 32))
+	cursor := Cursor{Sort: "email", Filter: Fingerprint("active"), Key: "ada@example.com", ID: uuid.New(), Backward: true}
+
+	encoded, err := codec.Encode(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := codec.Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != cursor {
+		t.Errorf("Decode = %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestDecodeRejectsForgedCursors(t *testing.T) {
+	codec := newTestCursorCodec(t, strings.Repeat("k", 32))
+	encoded, err := codec.Encode(Cursor{Sort: "email", Key: "ada@example.com", ID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedPayload, signature, _ := strings.Cut(encoded, ".")
+
+	otherCodec := newTestCursorCodec(t, strings.Repeat("o", 32))
+	otherEncoded, err := otherCodec.Encode(Cursor{Sort: "email", Key: "ada@example.com", ID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forgedPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"email","k":"zzz","i":"00000000-0000-0000-0000-000000000000"}`))
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"no signature", encodedPayload},
+		{"empty signature", encodedPayload + "."},
+		{"changed payload", forgedPayload + "." + signature},
+		{"changed signature", encodedPayload + "." + strings.Repeat("A", len(signature))},
+		{"signed with another key", otherEncoded},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := codec.Decode(test.encoded); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestReadCursorRequestRejectsCursorsOfOtherLists(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	codec := newTestCursorCodec(t, strings.Repeat("k", 32))
+	encoded, err := codec.Encode(Cursor{Sort: "email", Filter: Fingerprint("active"), Key: "ada@example.com", ID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		sort   string
+		filter string
+		valid  bool
+	}{
+		{"same list", "email", Fingerprint("active"), true},
+		{"other sort", "name", Fingerprint("active"), false},
+		{"other filter", "email", Fingerprint("deactivated"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/user?cursor="+encoded, nil)
+
+			request, err := ReadCursorRequest(ctx, codec, test.sort, test.filter, 10)
+			if test.valid && (err != nil || request.Cursor == nil) {
+				t.Errorf("ReadCursorRequest = %+v, %v, want the cursor", request, err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("ReadCursorRequest = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestNewCursorPage(t *testing.T) {
+	codec := newTestCursorCodec(t, strings.Repeat("k", 32))
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	keyOf := func(id uuid.UUID) (string, uuid.UUID) { return id.String(), id }
+
+	firstPage, err := NewCursorPage(codec, CursorRequest{Sort: "id", PageSize: 2}, ids, keyOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(firstPage.Items) != 2 || firstPage.Next == nil || firstPage.Prev != nil {
+		t.Fatalf("first page = %d items, next %v, prev %v, want 2 items and only a next cursor", len(firstPage.Items), firstPage.Next, firstPage.Prev)
+	}
+	next, err := codec.Decode(*firstPage.Next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != ids[1] || next.Backward {
+		t.Errorf("next cursor = %+v, want forward from the last item", next)
+	}
+
+	// a backward page is read in reverse and flipped back
+	backward := CursorRequest{Sort: "id", PageSize: 2, Cursor: &Cursor{Sort: "id", Backward: true}}
+	reversed := []uuid.UUID{ids[2], ids[1]}
+	prevPage, err := NewCursorPage(codec, backward, reversed, keyOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prevPage.Items) != 2 || prevPage.Items[0] != ids[1] || prevPage.Items[1] != ids[2] {
+		t.Errorf("backward page = %v, want %v", prevPage.Items, ids[1:])
+	}
+	if prevPage.Next == nil || prevPage.Prev != nil {
+		t.Errorf("backward page next %v, prev %v, want only a next cursor", prevPage.Next, prevPage.Prev)
+	}
+}
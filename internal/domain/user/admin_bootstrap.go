@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const adminBootstrapInterval = time.Minute
+
+// AdminBootstrap makes the user with the configured email the first admin, so
+// a new deployment can be administered without editing the database. It does
+// nothing once there is an admin, and waits for the user to sign up if they
+// have no account yet. Later admins are granted through /user/:id/roles.
+type AdminBootstrap struct {
+	repository UserRepository
+	email      string
+	logger     *log.Logger
+}
+
+func NewAdminBootstrap(repository UserRepository, email string, logger *log.Logger) *AdminBootstrap {
+	return &AdminBootstrap{
+		repository: repository,
+		email:      email,
+		logger:     logger,
+	}
+}
+
+// Run checks now and then every minute until there is an admin.
+func (bootstrap *AdminBootstrap) Run() {
+	if bootstrap.email == "" {
+		return
+	}
+
+	ticker := time.NewTicker(adminBootstrapInterval)
+	defer ticker.Stop()
+	waiting := false
+	for {
+		done, err := bootstrap.repository.BootstrapAdmin(context.Background(), bootstrap.email)
+		if err != nil {
+			bootstrap.logger.Printf("ERROR: repositoryBootstrapAdmin: %v", err)
+		}
+		if done {
+			return
+		}
+		if err == nil && !waiting {
+			bootstrap.logger.Printf("INFO: no admin yet, waiting for %s to sign up", bootstrap.email)
+			waiting = true
+		}
+		<-ticker.C
+	}
+}
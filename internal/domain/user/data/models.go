@@ -71,12 +71,20 @@ func AllFiletypeValues() []Filetype {
 }
 
 type AuthUser struct {
-	ID        uuid.UUID
-	Email     string
-	FirstName string
-	LastName  string
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
+	ID                  uuid.UUID
+	Email               string
+	FirstName           string
+	LastName            string
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	TokensRevokedAt     pgtype.Timestamptz
+	PasswordHash        *string
+	ExternalID          *string
+	Provisioned         bool
+	DeactivatedAt       pgtype.Timestamptz
+	DeletionRequestedAt pgtype.Timestamptz
+	DeletionRequestedBy pgtype.UUID
+	DeletionScheduledAt pgtype.Timestamptz
 }
 
 type AuthUserProvider struct {
@@ -87,6 +95,51 @@ type AuthUserProvider struct {
 	CreatedAt      pgtype.Timestamptz
 }
 
+type AuthorizationCode struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	CodeHash      string
+	CodeChallenge string
+	ExpiresAt     pgtype.Timestamptz
+	UsedAt        pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+	SessionID     pgtype.UUID
+}
+
+type DataExport struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	Archive     []byte
+	Error       *string
+	CreatedAt   pgtype.Timestamptz
+	CompletedAt pgtype.Timestamptz
+	ExpiresAt   pgtype.Timestamptz
+}
+
+type DeletionReceipt struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	RequestedBy       pgtype.UUID
+	RequestedAt       pgtype.Timestamptz
+	PurgedRows        []byte
+	PurgedAt          pgtype.Timestamptz
+	PseudonymisedRows []byte
+}
+
+type DeviceAuthorization struct {
+	ID              uuid.UUID
+	DeviceCodeHash  string
+	UserCode        string
+	ClientID        string
+	Status          string
+	UserID          pgtype.UUID
+	IntervalSeconds int32
+	LastPolledAt    pgtype.Timestamptz
+	ExpiresAt       pgtype.Timestamptz
+	CreatedAt       pgtype.Timestamptz
+}
+
 type Diagram struct {
 	ID         uuid.UUID
 	ProjectID  pgtype.UUID
@@ -96,6 +149,105 @@ type Diagram struct {
 	UploadedAt pgtype.Timestamptz
 }
 
+type GothicSession struct {
+	ID        string
+	Data      string
+	ExpiresAt pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type Group struct {
+	ID          uuid.UUID
+	DisplayName string
+	ExternalID  *string
+	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
+}
+
+type GroupMember struct {
+	GroupID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt pgtype.Timestamptz
+}
+
+type Impersonation struct {
+	ID         uuid.UUID
+	ActorID    pgtype.UUID
+	UserID     uuid.UUID
+	Reason     string
+	ExpiresAt  pgtype.Timestamptz
+	EndedAt    pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+	ActorEmail string
+}
+
+type ImpersonationRequest struct {
+	ID              uuid.UUID
+	ImpersonationID uuid.UUID
+	Method          string
+	Path            string
+	StatusCode      *int32
+	IpAddress       string
+	CreatedAt       pgtype.Timestamptz
+}
+
+type Invitation struct {
+	ID         uuid.UUID
+	Email      string
+	InvitedBy  pgtype.UUID
+	ExpiresAt  pgtype.Timestamptz
+	AcceptedAt pgtype.Timestamptz
+	RevokedAt  pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+}
+
+type MagicLink struct {
+	ID        uuid.UUID
+	Email     string
+	ExpiresAt pgtype.Timestamptz
+	UsedAt    pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type MfaChallenge struct {
+	SessionID      uuid.UUID
+	UserID         uuid.UUID
+	FailedAttempts int32
+	ExpiresAt      pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+	UsedAt    pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	TokenHint  string
+	Scopes     string
+	ExpiresAt  pgtype.Timestamptz
+	LastUsedAt pgtype.Timestamptz
+	RevokedAt  pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+}
+
 type Project struct {
 	ID          uuid.UUID
 	UserID      uuid.UUID
@@ -105,6 +257,89 @@ type Project struct {
 	UpdatedAt   pgtype.Timestamptz
 }
 
+type ProviderToken struct {
+	AuthUserProviderID uuid.UUID
+	KeyID              string
+	WrappedKey         []byte
+	AccessToken        []byte
+	RefreshToken       []byte
+	ExpiresAt          pgtype.Timestamptz
+	UpdatedAt          pgtype.Timestamptz
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+	UsedAt    pgtype.Timestamptz
+	RevokedAt pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+	SessionID pgtype.UUID
+}
+
+type RevokedToken struct {
+	Jti       uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt pgtype.Timestamptz
+	RevokedAt pgtype.Timestamptz
+}
+
+type SamlConnection struct {
+	ID                 uuid.UUID
+	Workspace          string
+	EntityID           string
+	IdpEntityID        string
+	IdpMetadata        string
+	EmailAttribute     string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	Enabled            bool
+	CreatedAt          pgtype.Timestamptz
+	UpdatedAt          pgtype.Timestamptz
+}
+
+type SamlRequest struct {
+	ID            uuid.UUID
+	ConnectionID  uuid.UUID
+	RequestID     string
+	CodeChallenge string
+	ExpiresAt     pgtype.Timestamptz
+	UsedAt        pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+}
+
+type ScimToken struct {
+	ID         uuid.UUID
+	Name       string
+	TokenHash  string
+	TokenHint  string
+	CreatedBy  pgtype.UUID
+	LastUsedAt pgtype.Timestamptz
+	RevokedAt  pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+}
+
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Provider   string
+	UserAgent  string
+	IpAddress  string
+	CreatedAt  pgtype.Timestamptz
+	LastSeenAt pgtype.Timestamptz
+	RevokedAt  pgtype.Timestamptz
+}
+
+type TotpCredential struct {
+	UserID       uuid.UUID
+	Secret       string
+	ConfirmedAt  pgtype.Timestamptz
+	LastUsedStep int64
+	CreatedAt    pgtype.Timestamptz
+}
+
 type User struct {
 	ID           uuid.UUID
 	Email        string
@@ -114,3 +349,9 @@ type User struct {
 	CreatedAt    pgtype.Timestamptz
 	UpdatedAt    pgtype.Timestamptz
 }
+
+type UserRole struct {
+	UserID    uuid.UUID
+	Role      string
+	CreatedAt pgtype.Timestamptz
+}
@@ -9,8 +9,87 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countSearchUsers = `-- name: CountSearchUsers :one
+SELECT COUNT(*)
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE ($1::text IS NULL
+    OR users.email ILIKE '%' || $1 || '%'
+    OR (users.first_name || ' ' || users.last_name) ILIKE '%' || $1 || '%')
+  AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = $2))
+  AND ($3::text IS NULL
+    OR ($3 = 'active' AND auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR ($3 = 'deactivated' AND auth_users.deactivated_at IS NOT NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR ($3 = 'pending_deletion' AND auth_users.deletion_scheduled_at IS NOT NULL))
+`
+
+type CountSearchUsersParams struct {
+	Search *string
+	Role   *string
+	Status *string
+}
+
+func (q *Queries) CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSearchUsers, arg.Search, arg.Role, arg.Status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*)
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE ($1::text IS NULL OR lower(users.email) = lower($1))
+  AND ($2::text IS NULL OR auth_users.external_id = $2)
+  AND ($3::boolean IS NULL OR (auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL) = $3)
+  AND ($4::boolean IS NULL OR auth_users.provisioned = $4)
+`
+
+type CountUsersParams struct {
+	Email       *string
+	ExternalID  *string
+	Active      *bool
+	Provisioned *bool
+}
+
+func (q *Queries) CountUsers(ctx context.Context, arg CountUsersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsers,
+		arg.Email,
+		arg.ExternalID,
+		arg.Active,
+		arg.Provisioned,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getDeletionReceiptByUserID = `-- name: GetDeletionReceiptByUserID :one
+SELECT id, user_id, requested_by, requested_at, purged_rows, purged_at, pseudonymised_rows
+FROM deletion_receipts
+WHERE user_id = $1
+`
+
+func (q *Queries) GetDeletionReceiptByUserID(ctx context.Context, userID uuid.UUID) (DeletionReceipt, error) {
+	row := q.db.QueryRow(ctx, getDeletionReceiptByUserID, userID)
+	var i DeletionReceipt
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RequestedBy,
+		&i.RequestedAt,
+		&i.PurgedRows,
+		&i.PurgedAt,
+		&i.PseudonymisedRows,
+	)
+	return i, err
+}
+
 const getUserDetailByID = `-- name: GetUserDetailByID :one
 SELECT id, email, first_name, last_name, mobile_number 
 FROM users
@@ -37,3 +116,262 @@ func (q *Queries) GetUserDetailByID(ctx context.Context, id uuid.UUID) (GetUserD
 	)
 	return i, err
 }
+
+const getUserProfileByID = `-- name: GetUserProfileByID :one
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.deactivated_at, auth_users.deletion_scheduled_at,
+    (auth_users.password_hash IS NOT NULL)::boolean AS has_password,
+    ARRAY(SELECT user_roles.role FROM user_roles WHERE user_roles.user_id = users.id ORDER BY user_roles.role)::text[] AS roles,
+    (SELECT MAX(sessions.created_at) FROM sessions WHERE sessions.user_id = users.id)::timestamptz AS last_login_at,
+    (SELECT email_changes.new_email FROM email_changes WHERE email_changes.user_id = users.id AND email_changes.expires_at > CURRENT_TIMESTAMP ORDER BY email_changes.created_at DESC LIMIT 1) AS pending_email
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE users.id = $1
+`
+
+type GetUserProfileByIDRow struct {
+	ID                  uuid.UUID
+	Email               string
+	FirstName           string
+	LastName            string
+	MobileNumber        *string
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	DeactivatedAt       pgtype.Timestamptz
+	DeletionScheduledAt pgtype.Timestamptz
+	HasPassword         bool
+	Roles               []string
+	LastLoginAt         pgtype.Timestamptz
+	PendingEmail        *string
+}
+
+func (q *Queries) GetUserProfileByID(ctx context.Context, id uuid.UUID) (GetUserProfileByIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserProfileByID, id)
+	var i GetUserProfileByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.MobileNumber,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeactivatedAt,
+		&i.DeletionScheduledAt,
+		&i.HasPassword,
+		&i.Roles,
+		&i.LastLoginAt,
+		&i.PendingEmail,
+	)
+	return i, err
+}
+
+const listUserProviders = `-- name: ListUserProviders :many
+SELECT provider, created_at
+FROM auth_user_providers
+WHERE user_id = $1
+ORDER BY created_at, provider
+`
+
+type ListUserProvidersRow struct {
+	Provider  string
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) ListUserProviders(ctx context.Context, userID uuid.UUID) ([]ListUserProvidersRow, error) {
+	rows, err := q.db.Query(ctx, listUserProviders, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserProvidersRow
+	for rows.Next() {
+		var i ListUserProvidersRow
+		if err := rows.Scan(&i.Provider, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.external_id, auth_users.deactivated_at, auth_users.deletion_scheduled_at, auth_users.provisioned
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE ($1::text IS NULL OR lower(users.email) = lower($1))
+  AND ($2::text IS NULL OR auth_users.external_id = $2)
+  AND ($3::boolean IS NULL OR (auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL) = $3)
+  AND ($4::boolean IS NULL OR auth_users.provisioned = $4)
+ORDER BY users.created_at, users.id
+LIMIT $5 OFFSET $6
+`
+
+type ListUsersParams struct {
+	Email       *string
+	ExternalID  *string
+	Active      *bool
+	Provisioned *bool
+	PageLimit   int32
+	PageOffset  int32
+}
+
+type ListUsersRow struct {
+	ID                  uuid.UUID
+	Email               string
+	FirstName           string
+	LastName            string
+	MobileNumber        *string
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	ExternalID          *string
+	DeactivatedAt       pgtype.Timestamptz
+	DeletionScheduledAt pgtype.Timestamptz
+	Provisioned         bool
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.Query(ctx, listUsers,
+		arg.Email,
+		arg.ExternalID,
+		arg.Active,
+		arg.Provisioned,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.FirstName,
+			&i.LastName,
+			&i.MobileNumber,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.DeactivatedAt,
+			&i.DeletionScheduledAt,
+			&i.Provisioned,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, auth_users.deactivated_at, auth_users.deletion_scheduled_at,
+    ARRAY(SELECT user_roles.role FROM user_roles WHERE user_roles.user_id = users.id ORDER BY user_roles.role)::text[] AS roles
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE ($1::text IS NULL
+    OR users.email ILIKE '%' || $1 || '%'
+    OR (users.first_name || ' ' || users.last_name) ILIKE '%' || $1 || '%')
+  AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = $2))
+  AND ($3::text IS NULL
+    OR ($3 = 'active' AND auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR ($3 = 'deactivated' AND auth_users.deactivated_at IS NOT NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR ($3 = 'pending_deletion' AND auth_users.deletion_scheduled_at IS NOT NULL))
+  AND ($4::uuid IS NULL
+    OR ($5::text = 'name' AND NOT $6::boolean
+      AND (lower(users.last_name || ' ' || users.first_name), users.id) > (lower($7::text), $4))
+    OR ($5::text = 'name' AND $6::boolean
+      AND (lower(users.last_name || ' ' || users.first_name), users.id) < (lower($7::text), $4))
+    OR ($5::text = 'email' AND NOT $6::boolean
+      AND (lower(users.email), users.id) > (lower($7::text), $4))
+    OR ($5::text = 'email' AND $6::boolean
+      AND (lower(users.email), users.id) < (lower($7::text), $4))
+    OR ($5::text = 'created_at' AND NOT $6::boolean
+      AND (users.created_at, users.id) > ($8::timestamptz, $4))
+    OR ($5::text = 'created_at' AND $6::boolean
+      AND (users.created_at, users.id) < ($8::timestamptz, $4)))
+ORDER BY
+  CASE WHEN $5::text = 'name' AND NOT $6::boolean THEN lower(users.last_name || ' ' || users.first_name) END ASC,
+  CASE WHEN $5::text = 'name' AND $6::boolean THEN lower(users.last_name || ' ' || users.first_name) END DESC,
+  CASE WHEN $5::text = 'email' AND NOT $6::boolean THEN lower(users.email) END ASC,
+  CASE WHEN $5::text = 'email' AND $6::boolean THEN lower(users.email) END DESC,
+  CASE WHEN $5::text = 'created_at' AND NOT $6::boolean THEN users.created_at END ASC,
+  CASE WHEN $5::text = 'created_at' AND $6::boolean THEN users.created_at END DESC,
+  CASE WHEN $6::boolean THEN users.id END DESC,
+  users.id ASC
+LIMIT $9 OFFSET $10
+`
+
+type SearchUsersParams struct {
+	Search     *string
+	Role       *string
+	Status     *string
+	CursorID   pgtype.UUID
+	SortBy     string
+	SortDesc   bool
+	CursorText *string
+	CursorTime pgtype.Timestamptz
+	PageLimit  int32
+	PageOffset int32
+}
+
+type SearchUsersRow struct {
+	ID                  uuid.UUID
+	Email               string
+	FirstName           string
+	LastName            string
+	MobileNumber        *string
+	CreatedAt           pgtype.Timestamptz
+	DeactivatedAt       pgtype.Timestamptz
+	DeletionScheduledAt pgtype.Timestamptz
+	Roles               []string
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.Query(ctx, searchUsers,
+		arg.Search,
+		arg.Role,
+		arg.Status,
+		arg.CursorID,
+		arg.SortBy,
+		arg.SortDesc,
+		arg.CursorText,
+		arg.CursorTime,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersRow
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.FirstName,
+			&i.LastName,
+			&i.MobileNumber,
+			&i.CreatedAt,
+			&i.DeactivatedAt,
+			&i.DeletionScheduledAt,
+			&i.Roles,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
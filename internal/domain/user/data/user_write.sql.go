@@ -44,23 +44,52 @@ func (q *Queries) AddUser(ctx context.Context, arg AddUserParams) (AddUserRow, e
 	return i, err
 }
 
-const deleteUser = `-- name: DeleteUser :execresult
-DELETE from users WHERE id = $1
+const cancelAuthUserDeletion = `-- name: CancelAuthUserDeletion :execresult
+UPDATE auth_users
+SET deletion_requested_at = NULL, deletion_requested_by = NULL, deletion_scheduled_at = NULL
+WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (pgconn.CommandTag, error) {
-	return q.db.Exec(ctx, deleteUser, id)
+func (q *Queries) CancelAuthUserDeletion(ctx context.Context, id uuid.UUID) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, cancelAuthUserDeletion, id)
+}
+
+const deactivateAuthUser = `-- name: DeactivateAuthUser :execresult
+UPDATE auth_users
+SET deactivated_at = CURRENT_TIMESTAMP, tokens_revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deactivated_at IS NULL
+`
+
+func (q *Queries) DeactivateAuthUser(ctx context.Context, id uuid.UUID) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, deactivateAuthUser, id)
 }
 
 const findUserByID = `-- name: FindUserByID :one
-SELECT id, email, first_name, last_name, mobile_number, created_at, updated_at
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.external_id, auth_users.deactivated_at, auth_users.deletion_requested_at, auth_users.deletion_requested_by, auth_users.deletion_scheduled_at, auth_users.provisioned
 FROM users
-WHERE id = $1
+JOIN auth_users ON auth_users.id = users.id
+WHERE users.id = $1
 `
 
-func (q *Queries) FindUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+type FindUserByIDRow struct {
+	ID                  uuid.UUID
+	Email               string
+	FirstName           string
+	LastName            string
+	MobileNumber        *string
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	ExternalID          *string
+	DeactivatedAt       pgtype.Timestamptz
+	DeletionRequestedAt pgtype.Timestamptz
+	DeletionRequestedBy pgtype.UUID
+	DeletionScheduledAt pgtype.Timestamptz
+	Provisioned         bool
+}
+
+func (q *Queries) FindUserByID(ctx context.Context, id uuid.UUID) (FindUserByIDRow, error) {
 	row := q.db.QueryRow(ctx, findUserByID, id)
-	var i User
+	var i FindUserByIDRow
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
@@ -69,10 +98,152 @@ func (q *Queries) FindUserByID(ctx context.Context, id uuid.UUID) (User, error)
 		&i.MobileNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExternalID,
+		&i.DeactivatedAt,
+		&i.DeletionRequestedAt,
+		&i.DeletionRequestedBy,
+		&i.DeletionScheduledAt,
+		&i.Provisioned,
+	)
+	return i, err
+}
+
+const isEmailTaken = `-- name: IsEmailTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM auth_users WHERE lower(email) = lower($1) AND id <> $2
+)
+`
+
+type IsEmailTakenParams struct {
+	Email string
+	ID    uuid.UUID
+}
+
+func (q *Queries) IsEmailTaken(ctx context.Context, arg IsEmailTakenParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isEmailTaken, arg.Email, arg.ID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const provisionUser = `-- name: ProvisionUser :one
+WITH new_auth_user AS (
+    INSERT INTO auth_users (email, first_name, last_name, external_id, provisioned, deactivated_at)
+    VALUES ($1, $2, $3, $4, TRUE, $5)
+    RETURNING id, created_at
+),
+new_user AS (
+    INSERT INTO users (id, email, first_name, last_name, mobile_number)
+    SELECT id, $1, $2, $3, $6 FROM new_auth_user
+),
+new_role AS (
+    INSERT INTO user_roles (user_id, role)
+    SELECT id, 'user' FROM new_auth_user
+)
+SELECT id, created_at FROM new_auth_user
+`
+
+type ProvisionUserParams struct {
+	Email         string
+	FirstName     string
+	LastName      string
+	ExternalID    *string
+	DeactivatedAt pgtype.Timestamptz
+	MobileNumber  *string
+}
+
+type ProvisionUserRow struct {
+	ID        uuid.UUID
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) ProvisionUser(ctx context.Context, arg ProvisionUserParams) (ProvisionUserRow, error) {
+	row := q.db.QueryRow(ctx, provisionUser,
+		arg.Email,
+		arg.FirstName,
+		arg.LastName,
+		arg.ExternalID,
+		arg.DeactivatedAt,
+		arg.MobileNumber,
 	)
+	var i ProvisionUserRow
+	err := row.Scan(&i.ID, &i.CreatedAt)
 	return i, err
 }
 
+const reactivateAuthUser = `-- name: ReactivateAuthUser :exec
+UPDATE auth_users
+SET deactivated_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ReactivateAuthUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, reactivateAuthUser, id)
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, revokeUserSessions, userID)
+	return err
+}
+
+const scheduleAuthUserDeletion = `-- name: ScheduleAuthUserDeletion :execresult
+UPDATE auth_users
+SET deletion_requested_at = CURRENT_TIMESTAMP, deletion_requested_by = $1, deletion_scheduled_at = $2, tokens_revoked_at = CURRENT_TIMESTAMP
+WHERE id = $3 AND deletion_scheduled_at IS NULL
+`
+
+type ScheduleAuthUserDeletionParams struct {
+	DeletionRequestedBy pgtype.UUID
+	DeletionScheduledAt pgtype.Timestamptz
+	ID                  uuid.UUID
+}
+
+func (q *Queries) ScheduleAuthUserDeletion(ctx context.Context, arg ScheduleAuthUserDeletionParams) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, scheduleAuthUserDeletion, arg.DeletionRequestedBy, arg.DeletionScheduledAt, arg.ID)
+}
+
+const updateAuthUser = `-- name: UpdateAuthUser :execresult
+UPDATE auth_users
+SET email = $1, first_name = $2, last_name = $3, external_id = $4, updated_at = CURRENT_TIMESTAMP
+WHERE id = $5
+`
+
+type UpdateAuthUserParams struct {
+	Email      string
+	FirstName  string
+	LastName   string
+	ExternalID *string
+	ID         uuid.UUID
+}
+
+func (q *Queries) UpdateAuthUser(ctx context.Context, arg UpdateAuthUserParams) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, updateAuthUser,
+		arg.Email,
+		arg.FirstName,
+		arg.LastName,
+		arg.ExternalID,
+		arg.ID,
+	)
+}
+
 const updateUser = `-- name: UpdateUser :execresult
 UPDATE users 
 SET email = $1, first_name = $2, last_name = $3, mobile_number = $4, updated_at = CURRENT_TIMESTAMP
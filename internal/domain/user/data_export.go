@@ -0,0 +1,190 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DataExportApiDto struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	// DownloadURL is set once the archive is ready.
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+// DataExportHandler lets users download everything stored about them. The
+// archive is built in the background by a DataExportWorker; clients poll the
+// export until it is completed and then download it.
+type DataExportHandler struct {
+	repository UserRepository
+	worker     *DataExportWorker
+	logger     *log.Logger
+}
+
+func NewDataExportHandler(repository UserRepository, worker *DataExportWorker, logger *log.Logger) *DataExportHandler {
+	return &DataExportHandler{
+		repository: repository,
+		worker:     worker,
+		logger:     logger,
+	}
+}
+
+// @Summary Request an export of your personal data
+// @Description Starts building a ZIP archive of JSON files with everything stored about the signed-in user. Poll the export at the Location header until it is completed, then download it. Only one export is built at a time; while one is pending the request is rejected and its Location header points to it. Not allowed while impersonating.
+// @Tags users
+// @Produce json
+// @Success 202 {object} DataExportApiDto "Export being built"
+// @Failure 401 {object} map[string]string "Not signed in"
+// @Failure 403 {object} map[string]string "Impersonating"
+// @Failure 409 {object} map[string]string "An export is already pending"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 503 {object} map[string]string "Too many exports are waiting to be built"
+// @Router /user/me/exports [post]
+func (handler DataExportHandler) RequestExport(ctx *gin.Context) {
+	userID := authentication.GetAuthUser(ctx).ID
+
+	export, err := handler.repository.FindActiveDataExport(ctx.Request.Context(), userID, time.Now().Add(-dataExportTimeout))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindActiveDataExport: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if export != nil {
+		ctx.Header("Location", dataExportPath(export.ID))
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Export Already Pending"})
+		return
+	}
+
+	// a stale export would otherwise still count as pending
+	err = handler.repository.FailStaleDataExports(ctx.Request.Context(), time.Now().Add(-dataExportTimeout))
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFailStaleDataExports: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	export, err = handler.repository.CreateDataExport(ctx.Request.Context(), userID)
+	if errors.Is(err, ErrDataExportPending) {
+		// another request created one in the meantime
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Export Already Pending"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCreateDataExport: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if !handler.worker.Enqueue(export) {
+		err = handler.repository.FailDataExport(ctx.Request.Context(), export.ID, "too many exports were waiting to be built")
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryFailDataExport: %v", err)
+		}
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service Unavailable"})
+		return
+	}
+
+	ctx.Header("Location", dataExportPath(export.ID))
+	ctx.JSON(http.StatusAccepted, newDataExportApiDto(export))
+}
+
+// @Summary Get the status of a personal data export
+// @Description Returns whether the export is pending, running, completed or failed, and where to download it once completed.
+// @Tags users
+// @Param id path string true "Export ID"
+// @Produce json
+// @Success 200 {object} DataExportApiDto "Export"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Not signed in"
+// @Failure 404 {object} map[string]string "Export not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/me/exports/{id} [get]
+func (handler DataExportHandler) GetExport(ctx *gin.Context) {
+	exportID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Export ID"})
+		return
+	}
+
+	export, err := handler.repository.FindDataExport(ctx.Request.Context(), exportID, authentication.GetAuthUser(ctx).ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindDataExport: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if export == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newDataExportApiDto(export))
+}
+
+// @Summary Download a personal data export
+// @Description Downloads the ZIP archive of a completed export. Archives expire seven days after they are built. Not allowed while impersonating.
+// @Tags users
+// @Param id path string true "Export ID"
+// @Produce application/zip
+// @Success 200 {file} file "ZIP archive"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "Not signed in"
+// @Failure 403 {object} map[string]string "Impersonating"
+// @Failure 404 {object} map[string]string "Export not found, not completed or expired"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/me/exports/{id}/download [get]
+func (handler DataExportHandler) DownloadExport(ctx *gin.Context) {
+	exportID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Export ID"})
+		return
+	}
+
+	archive, err := handler.repository.FindDataExportArchive(ctx.Request.Context(), exportID, authentication.GetAuthUser(ctx).ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindDataExportArchive: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if archive == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="catalyst-export-%s.zip"`, exportID))
+	ctx.Data(http.StatusOK, "application/zip", archive)
+}
+
+func newDataExportApiDto(export *DataExport) DataExportApiDto {
+	exportApiDto := DataExportApiDto{
+		ID:          export.ID,
+		Status:      export.Status,
+		Error:       export.Error,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+	if export.IsCompleted() {
+		exportApiDto.DownloadURL = dataExportPath(export.ID) + "/download"
+	}
+	return exportApiDto
+}
+
+func dataExportPath(exportID uuid.UUID) string {
+	return "/user/me/exports/" + exportID.String()
+}
@@ -0,0 +1,32 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Data export statuses, in the order an export goes through them.
+const (
+	DataExportPending   = "pending"
+	DataExportRunning   = "running"
+	DataExportCompleted = "completed"
+	DataExportFailed    = "failed"
+)
+
+// DataExport is an archive of everything stored about a user, requested to
+// answer a subject-access request. It is built in the background and can be
+// downloaded until ExpiresAt.
+type DataExport struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	Error       string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time
+}
+
+func (export *DataExport) IsCompleted() bool {
+	return export.Status == DataExportCompleted
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// dataExportQueueSize is how many exports may wait to be built.
+const dataExportQueueSize = 100
+
+// DataExportWorker builds requested data exports one at a time, each within
+// dataExportTimeout. Exports are queued in memory, so those left pending or
+// running when the process stopped are never built; Run fails them on startup
+// so their users can request another.
+type DataExportWorker struct {
+	repository UserRepository
+	exporter   *DataExporter
+	queue      chan *DataExport
+	startedAt  time.Time
+	logger     *log.Logger
+}
+
+func NewDataExportWorker(repository UserRepository, exporter *DataExporter, logger *log.Logger) *DataExportWorker {
+	return &DataExportWorker{
+		repository: repository,
+		exporter:   exporter,
+		queue:      make(chan *DataExport, dataExportQueueSize),
+		startedAt:  time.Now(),
+		logger:     logger,
+	}
+}
+
+// Enqueue queues a pending export to be built. It reports false, leaving the
+// export pending, when the queue is full.
+func (worker *DataExportWorker) Enqueue(export *DataExport) bool {
+	select {
+	case worker.queue <- export:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run fails the exports interrupted by the last shutdown and then builds
+// queued exports for as long as the process runs.
+func (worker *DataExportWorker) Run() {
+	err := worker.repository.FailStaleDataExports(context.Background(), worker.startedAt)
+	if err != nil {
+		worker.logger.Printf("ERROR: repositoryFailStaleDataExports: %v", err)
+	}
+
+	for export := range worker.queue {
+		worker.build(export)
+	}
+}
+
+func (worker *DataExportWorker) build(export *DataExport) {
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+	worker.exporter.Build(ctx, export)
+}
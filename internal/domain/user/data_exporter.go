@@ -0,0 +1,264 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"catalyst.api/internal/domain/user/data"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// dataExportTimeToLive is how long a finished archive can be downloaded.
+	dataExportTimeToLive = 7 * 24 * time.Hour
+	// dataExportTimeout is how long an export may take to build. The build is
+	// cancelled after that, and an export still unfinished counts as
+	// interrupted, so another can be requested.
+	dataExportTimeout = time.Hour
+)
+
+// DataExportSection is one JSON file of a data export. Collect returns what is
+// stored about the user, which is written to FileName.
+type DataExportSection struct {
+	FileName    string
+	Description string
+	Collect     func(ctx context.Context, userID uuid.UUID) (any, error)
+}
+
+type DataExportManifestApiDto struct {
+	UserID      uuid.UUID                          `json:"userId"`
+	GeneratedAt time.Time                          `json:"generatedAt"`
+	Files       []DataExportManifestFileApiDto     `json:"files"`
+	Omitted     []DataExportManifestOmissionApiDto `json:"omitted"`
+}
+
+type DataExportManifestFileApiDto struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// DataExportManifestOmissionApiDto tells the user about something stored about
+// them that the archive leaves out, and why.
+type DataExportManifestOmissionApiDto struct {
+	Data   string `json:"data"`
+	Reason string `json:"reason"`
+}
+
+// dataExportOmissions are the secrets left out of every archive. Anyone who
+// got hold of the archive could use them to act as the user.
+var dataExportOmissions = []DataExportManifestOmissionApiDto{
+	{
+		Data:   "Your password",
+		Reason: "Only a hash of it is stored, which could help someone guess it",
+	},
+	{
+		Data:   "Your session, refresh, personal access, sign-in link, password reset and email confirmation tokens",
+		Reason: "Only hashes of them are stored, and they would let someone sign in as you",
+	},
+	{
+		Data:   "Your authenticator app secret and recovery codes",
+		Reason: "They would let someone pass your second sign-in step",
+	},
+	{
+		Data:   "The access and refresh tokens sign-in providers issued for you",
+		Reason: "They would let someone act as you at the provider",
+	},
+	{
+		Data:   "The codes of device sign-ins",
+		Reason: "They are one-time secrets that are only useful until the device signs in",
+	},
+}
+
+// DataExporter builds the ZIP archive of a DataExport, with a JSON file per
+// section and a manifest.json listing them. It covers the tables of the user
+// and authentication domains. Secrets such as password hashes, token hashes
+// and TOTP secrets are left out, and listed in the manifest as omitted. A
+// domain that stores more about users adds a section for it with AddSection.
+type DataExporter struct {
+	repository UserRepository
+	sections   []DataExportSection
+	logger     *log.Logger
+}
+
+func NewDataExporter(repository UserRepository, queries *data.Queries, logger *log.Logger) *DataExporter {
+	exporter := &DataExporter{
+		repository: repository,
+		logger:     logger,
+	}
+
+	exporter.AddSection(DataExportSection{
+		FileName:    "profile.json",
+		Description: "Your account: name, email, mobile number and account status",
+		Collect:     exportRow(queries.ExportUserProfile),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "roles.json",
+		Description: "The roles you hold",
+		Collect:     exportRows(queries.ExportUserRoles),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "providers.json",
+		Description: "The sign-in providers linked to your account",
+		Collect:     exportRows(queries.ExportUserProviders),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "sessions.json",
+		Description: "Your sign-ins, with the browser and IP address they came from",
+		Collect:     exportRows(queries.ExportUserSessions),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "refresh_tokens.json",
+		Description: "When the refresh tokens of your sessions were issued, used and revoked",
+		Collect:     exportRows(queries.ExportUserRefreshTokens),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "personal_access_tokens.json",
+		Description: "Your personal access tokens, without the tokens themselves",
+		Collect:     exportRows(queries.ExportUserPersonalAccessTokens),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "provider_tokens.json",
+		Description: "Which sign-in providers we hold an access token for and when it expires, without the tokens themselves. Providers do not tell us the scopes they granted, so none are stored",
+		Collect:     exportRows(queries.ExportUserProviderTokens),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "device_authorizations.json",
+		Description: "The devices you signed in to with a code, and whether you approved them",
+		Collect:     exportRows(queries.ExportUserDeviceAuthorizations),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "magic_links.json",
+		Description: "When sign-in links were emailed to you and used, without the links themselves",
+		Collect:     exportRows(queries.ExportUserMagicLinks),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "password_resets.json",
+		Description: "When password reset links were emailed to you and used, without the links themselves",
+		Collect:     exportRows(queries.ExportUserPasswordResets),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "email_changes.json",
+		Description: "A new email address you asked to change to and have not confirmed yet, without the confirmation link",
+		Collect:     exportRows(queries.ExportUserEmailChanges),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "totp_credentials.json",
+		Description: "When you enrolled an authenticator app, without its secret",
+		Collect:     exportRows(queries.ExportUserTOTPCredentials),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "groups.json",
+		Description: "The groups you are a member of",
+		Collect:     exportRows(queries.ExportUserGroups),
+	})
+	exporter.AddSection(DataExportSection{
+		FileName:    "impersonations.json",
+		Description: "Times an admin acted as you, or you acted as another user, with the admin's email",
+		Collect:     exportRows(queries.ExportUserImpersonations),
+	})
+	return exporter
+}
+
+func (exporter *DataExporter) AddSection(section DataExportSection) {
+	exporter.sections = append(exporter.sections, section)
+}
+
+// Build builds the archive of a pending export and records the outcome. It
+// does nothing when the export was already started. The outcome is recorded
+// even when ctx has been cancelled.
+func (exporter *DataExporter) Build(ctx context.Context, export *DataExport) {
+	started, err := exporter.repository.StartDataExport(ctx, export.ID)
+	if err != nil {
+		exporter.logger.Printf("ERROR: repositoryStartDataExport: %v", err)
+		return
+	}
+	if !started {
+		return
+	}
+
+	archive, err := exporter.buildArchive(ctx, export.UserID)
+	if err != nil {
+		exporter.logger.Printf("ERROR: buildArchive %s: %v", export.ID, err)
+		err = exporter.repository.FailDataExport(context.WithoutCancel(ctx), export.ID, "the archive could not be built")
+		if err != nil {
+			exporter.logger.Printf("ERROR: repositoryFailDataExport: %v", err)
+		}
+		return
+	}
+
+	err = exporter.repository.CompleteDataExport(ctx, export.ID, archive, time.Now().Add(dataExportTimeToLive))
+	if err != nil {
+		exporter.logger.Printf("ERROR: repositoryCompleteDataExport: %v", err)
+	}
+}
+
+func (exporter *DataExporter) buildArchive(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	manifest := DataExportManifestApiDto{
+		UserID:      userID,
+		GeneratedAt: time.Now(),
+		Files:       make([]DataExportManifestFileApiDto, 0, len(exporter.sections)),
+		Omitted:     dataExportOmissions,
+	}
+	for _, section := range exporter.sections {
+		content, err := section.Collect(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("collect %s: %w", section.FileName, err)
+		}
+		err = writeArchiveJSON(archive, section.FileName, content)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, DataExportManifestFileApiDto{
+			Name:        section.FileName,
+			Description: section.Description,
+		})
+	}
+
+	err := writeArchiveJSON(archive, "manifest.json", manifest)
+	if err != nil {
+		return nil, err
+	}
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeArchiveJSON(archive *zip.Writer, fileName string, content any) error {
+	file, err := archive.Create(fileName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(content)
+}
+
+// exportRow adapts a query returning one row to DataExportSection.Collect.
+func exportRow[T any](query func(context.Context, uuid.UUID) (T, error)) func(context.Context, uuid.UUID) (any, error) {
+	return func(ctx context.Context, userID uuid.UUID) (any, error) {
+		return query(ctx, userID)
+	}
+}
+
+// exportRows adapts a query returning many rows to DataExportSection.Collect,
+// writing no rows as an empty list rather than null.
+func exportRows[T any](query func(context.Context, uuid.UUID) ([]T, error)) func(context.Context, uuid.UUID) (any, error) {
+	return func(ctx context.Context, userID uuid.UUID) (any, error) {
+		rows, err := query(ctx, userID)
+		if rows == nil {
+			rows = []T{}
+		}
+		return rows, err
+	}
+}
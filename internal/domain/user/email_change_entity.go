@@ -0,0 +1,50 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChangeTimeToLive is how long the confirmation link mailed to a new
+// email address works.
+const EmailChangeTimeToLive = 24 * time.Hour
+
+var ErrInvalidEmailChange = errors.New("invalid or expired email confirmation link")
+
+// EmailChange is a new email address waiting to be confirmed from a link
+// mailed to it. The account keeps its old email until then. Only the hash of
+// the link's token is stored.
+type EmailChange struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	NewEmail  string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+func NewEmailChange(userID uuid.UUID, newEmail string) (*EmailChange, string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, "", err
+	}
+	plainToken := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	emailChange := &EmailChange{
+		UserID:    userID,
+		NewEmail:  newEmail,
+		TokenHash: HashEmailChangeToken(plainToken),
+		ExpiresAt: time.Now().Add(EmailChangeTimeToLive),
+	}
+	return emailChange, plainToken, nil
+}
+
+func HashEmailChangeToken(plainToken string) string {
+	hash := sha256.Sum256([]byte(plainToken))
+	return hex.EncodeToString(hash[:])
+}
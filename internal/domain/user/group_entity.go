@@ -0,0 +1,66 @@
+package user
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Group is a set of users kept in step with the identity provider over SCIM.
+type Group struct {
+	ID          uuid.UUID
+	DisplayName string
+	ExternalID  string
+	Members     []GroupMember
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type GroupMember struct {
+	UserID uuid.UUID
+	// Display is the member's name, filled in when the group is read.
+	Display string
+}
+
+func CreateGroup(displayName string, externalID string) *Group {
+	return &Group{
+		DisplayName: displayName,
+		ExternalID:  externalID,
+		Members:     []GroupMember{},
+	}
+}
+
+func (group *Group) HasMember(userID uuid.UUID) bool {
+	return slices.ContainsFunc(group.Members, func(member GroupMember) bool {
+		return member.UserID == userID
+	})
+}
+
+// AddMembers adds the users who are not members yet.
+func (group *Group) AddMembers(userIDs ...uuid.UUID) {
+	for _, userID := range userIDs {
+		if !group.HasMember(userID) {
+			group.Members = append(group.Members, GroupMember{UserID: userID})
+		}
+	}
+}
+
+func (group *Group) RemoveMembers(userIDs ...uuid.UUID) {
+	group.Members = slices.DeleteFunc(group.Members, func(member GroupMember) bool {
+		return slices.Contains(userIDs, member.UserID)
+	})
+}
+
+func (group *Group) ReplaceMembers(userIDs ...uuid.UUID) {
+	group.Members = []GroupMember{}
+	group.AddMembers(userIDs...)
+}
+
+func (group *Group) MemberIDs() []uuid.UUID {
+	userIDs := make([]uuid.UUID, 0, len(group.Members))
+	for _, member := range group.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	return userIDs
+}
@@ -0,0 +1,296 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scimUserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimPatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	scimContentType = "application/scim+json"
+
+	scimDefaultCount = 100
+	scimMaxCount     = 200
+)
+
+// scimType values from RFC 7644 section 3.12.
+const (
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeInvalidValue  = "invalidValue"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeInvalidSyntax = "invalidSyntax"
+	scimTypeUniqueness    = "uniqueness"
+)
+
+type SCIMMetaApiDto struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+type SCIMListResponseApiDto struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    any      `json:"Resources"`
+}
+
+type SCIMErrorApiDto struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+type SCIMPatchApiDto struct {
+	Schemas    []string                   `json:"schemas"`
+	Operations []SCIMPatchOperationApiDto `json:"Operations"`
+}
+
+type SCIMPatchOperationApiDto struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+}
+
+type SCIMServiceProviderConfigApiDto struct {
+	Schemas               []string                   `json:"schemas"`
+	Patch                 SCIMSupportedApiDto        `json:"patch"`
+	Bulk                  SCIMBulkApiDto             `json:"bulk"`
+	Filter                SCIMFilterSupportApiDto    `json:"filter"`
+	ChangePassword        SCIMSupportedApiDto        `json:"changePassword"`
+	Sort                  SCIMSupportedApiDto        `json:"sort"`
+	Etag                  SCIMSupportedApiDto        `json:"etag"`
+	AuthenticationSchemes []SCIMAuthenticationApiDto `json:"authenticationSchemes"`
+}
+
+type SCIMSupportedApiDto struct {
+	Supported bool `json:"supported"`
+}
+
+type SCIMBulkApiDto struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type SCIMFilterSupportApiDto struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type SCIMAuthenticationApiDto struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// scimError is a failure reported to the identity provider in the SCIM error format.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (err *scimError) Error() string {
+	return err.detail
+}
+
+func newSCIMError(status int, scimType string, format string, args ...any) *scimError {
+	return &scimError{status: status, scimType: scimType, detail: fmt.Sprintf(format, args...)}
+}
+
+// SCIMHandler serves the SCIM 2.0 endpoints identity providers use to
+// provision users and groups. Requests are authenticated with a SCIM token.
+type SCIMHandler struct {
+	repository UserRepository
+	logger     *log.Logger
+}
+
+func NewSCIMHandler(repository UserRepository, logger *log.Logger) *SCIMHandler {
+	return &SCIMHandler{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// @Summary Get the SCIM service provider configuration
+// @Description Describes which SCIM features are supported: PATCH and eq/and filters, but not bulk operations, sorting or ETags.
+// @Tags scim
+// @Produce json
+// @Success 200 {object} SCIMServiceProviderConfigApiDto "Service provider configuration"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (handler SCIMHandler) GetServiceProviderConfig(ctx *gin.Context) {
+	respondSCIM(ctx, http.StatusOK, SCIMServiceProviderConfigApiDto{
+		Schemas:        []string{scimServiceProviderConfigSchema},
+		Patch:          SCIMSupportedApiDto{Supported: true},
+		Bulk:           SCIMBulkApiDto{Supported: false},
+		Filter:         SCIMFilterSupportApiDto{Supported: true, MaxResults: scimMaxCount},
+		ChangePassword: SCIMSupportedApiDto{Supported: false},
+		Sort:           SCIMSupportedApiDto{Supported: false},
+		Etag:           SCIMSupportedApiDto{Supported: false},
+		AuthenticationSchemes: []SCIMAuthenticationApiDto{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "A SCIM token created by an admin at /auth/scim/tokens",
+				Primary:     true,
+			},
+		},
+	})
+}
+
+func respondSCIM(ctx *gin.Context, status int, body any) {
+	ctx.Header("Content-Type", scimContentType)
+	ctx.JSON(status, body)
+}
+
+func respondSCIMError(ctx *gin.Context, err *scimError) {
+	respondSCIM(ctx, err.status, SCIMErrorApiDto{
+		Schemas:  []string{scimErrorSchema},
+		Status:   strconv.Itoa(err.status),
+		ScimType: err.scimType,
+		Detail:   err.detail,
+	})
+}
+
+func respondSCIMInternalError(ctx *gin.Context) {
+	respondSCIMError(ctx, newSCIMError(http.StatusInternalServerError, "", "Internal Server Error"))
+}
+
+func respondSCIMNotFound(ctx *gin.Context, resourceType string) {
+	respondSCIMError(ctx, newSCIMError(http.StatusNotFound, "", "%s %s not found", resourceType, ctx.Param("id")))
+}
+
+// readSCIMPage reads the 1-based startIndex and the count query parameters.
+// Out-of-range values are clamped rather than rejected, as RFC 7644 asks.
+func readSCIMPage(ctx *gin.Context) (startIndex int, count int, err *scimError) {
+	startIndex = 1
+	count = scimDefaultCount
+
+	if value := ctx.Query("startIndex"); value != "" {
+		parsed, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return 0, 0, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "startIndex must be an integer")
+		}
+		startIndex = max(parsed, 1)
+	}
+	if value := ctx.Query("count"); value != "" {
+		parsed, parseErr := strconv.Atoi(value)
+		if parseErr != nil {
+			return 0, 0, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "count must be an integer")
+		}
+		count = min(max(parsed, 0), scimMaxCount)
+	}
+	return startIndex, count, nil
+}
+
+func newSCIMListResponse(resources any, totalResults int, startIndex int, itemsPerPage int) SCIMListResponseApiDto {
+	return SCIMListResponseApiDto{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: itemsPerPage,
+		Resources:    resources,
+	}
+}
+
+// decodeSCIMPatch reads a PatchOp request body and checks each operation is one we know.
+func decodeSCIMPatch(ctx *gin.Context) (*SCIMPatchApiDto, *scimError) {
+	var patchApiDto SCIMPatchApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&patchApiDto)
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "request body is not a valid PatchOp")
+	}
+	if len(patchApiDto.Operations) == 0 {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "Operations must not be empty")
+	}
+
+	for index := range patchApiDto.Operations {
+		operation := &patchApiDto.Operations[index]
+		operation.Op = strings.ToLower(operation.Op)
+		switch operation.Op {
+		case "add", "replace", "remove":
+		default:
+			return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "unsupported op %q", operation.Op)
+		}
+		if operation.Op == "remove" && operation.Path == "" {
+			return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "remove requires a path")
+		}
+	}
+	return &patchApiDto, nil
+}
+
+// patchValues returns the attributes a PATCH operation sets, keyed by path.
+// An operation without a path carries an object of attributes instead, and
+// nested objects such as name are flattened to `name.givenName`.
+func patchValues(operation SCIMPatchOperationApiDto) (map[string]json.RawMessage, *scimError) {
+	if operation.Path != "" {
+		return map[string]json.RawMessage{operation.Path: operation.Value}, nil
+	}
+
+	var attributes map[string]json.RawMessage
+	err := json.Unmarshal(operation.Value, &attributes)
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "an operation without a path needs an object value")
+	}
+
+	values := map[string]json.RawMessage{}
+	for attribute, value := range attributes {
+		attribute = stripSCIMSchema(attribute)
+
+		var nested map[string]json.RawMessage
+		if strings.EqualFold(attribute, "name") && json.Unmarshal(value, &nested) == nil {
+			for subAttribute, subValue := range nested {
+				values[attribute+"."+subAttribute] = subValue
+			}
+			continue
+		}
+		values[attribute] = value
+	}
+	return values, nil
+}
+
+func decodeSCIMString(attribute string, value json.RawMessage) (string, *scimError) {
+	var decoded string
+	err := json.Unmarshal(value, &decoded)
+	if err != nil {
+		return "", newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "%s must be a string", attribute)
+	}
+	return decoded, nil
+}
+
+// decodeSCIMBool also accepts "True" and "False" as strings, which some
+// identity providers send in PATCH requests.
+func decodeSCIMBool(attribute string, value json.RawMessage) (bool, *scimError) {
+	var decoded bool
+	if json.Unmarshal(value, &decoded) == nil {
+		return decoded, nil
+	}
+
+	var text string
+	if json.Unmarshal(value, &text) == nil {
+		parsed, err := strconv.ParseBool(text)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return false, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "%s must be a boolean", attribute)
+}
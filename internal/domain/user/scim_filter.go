@@ -0,0 +1,141 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// scimComparison is one `attribute eq value` term of a SCIM filter. Value is
+// either a string or a bool.
+type scimComparison struct {
+	Attribute string
+	Value     any
+}
+
+// parseSCIMFilter reads the subset of the SCIM filter grammar identity
+// providers send when looking up a resource: `eq` comparisons joined by
+// `and`. Attribute names are lowercased, without the core schema URN.
+func parseSCIMFilter(filter string) ([]scimComparison, error) {
+	tokens, err := tokenizeSCIMFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("filter is empty")
+	}
+
+	comparisons := []scimComparison{}
+	for index := 0; index < len(tokens); index += 4 {
+		if index+3 > len(tokens) {
+			return nil, fmt.Errorf("incomplete comparison in filter %q", filter)
+		}
+		if !strings.EqualFold(tokens[index+1], "eq") {
+			return nil, fmt.Errorf("unsupported operator %q, only eq is supported", tokens[index+1])
+		}
+
+		value, err := parseSCIMFilterValue(tokens[index+2])
+		if err != nil {
+			return nil, err
+		}
+		comparisons = append(comparisons, scimComparison{
+			Attribute: normalizeSCIMAttribute(tokens[index]),
+			Value:     value,
+		})
+
+		if index+3 < len(tokens) && !strings.EqualFold(tokens[index+3], "and") {
+			return nil, fmt.Errorf("unsupported logical operator %q, only and is supported", tokens[index+3])
+		}
+		if index+3 == len(tokens)-1 {
+			return nil, fmt.Errorf("filter %q ends with %q", filter, tokens[index+3])
+		}
+	}
+	return comparisons, nil
+}
+
+// tokenizeSCIMFilter splits the filter on spaces outside of quoted strings.
+// Quoted strings keep their quotes so values can tell them apart from bools.
+func tokenizeSCIMFilter(filter string) ([]string, error) {
+	tokens := []string{}
+	var token strings.Builder
+	inString := false
+	escaped := false
+
+	for _, char := range filter {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && char == '\\':
+			escaped = true
+		case char == '"':
+			inString = !inString
+		case !inString && char == ' ':
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+			continue
+		}
+		token.WriteRune(char)
+	}
+	if inString {
+		return nil, errors.New("filter has an unterminated string")
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+func parseSCIMFilterValue(token string) (any, error) {
+	if strings.HasPrefix(token, `"`) {
+		var value string
+		err := json.Unmarshal([]byte(token), &value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s in filter", token)
+		}
+		return value, nil
+	}
+
+	switch strings.ToLower(token) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return nil, fmt.Errorf("unsupported value %s in filter", token)
+}
+
+// splitSCIMPath breaks a PATCH path such as `phoneNumbers[type eq "mobile"].value`
+// into its attribute, the filter between the brackets and the sub-attribute.
+// The attribute and sub-attribute are normalized like filter attributes.
+func splitSCIMPath(path string) (attribute string, filter string, subAttribute string) {
+	path = stripSCIMSchema(strings.TrimSpace(path))
+
+	open := strings.Index(path, "[")
+	close := strings.LastIndex(path, "]")
+	if open >= 0 && close > open {
+		attribute = path[:open]
+		filter = path[open+1 : close]
+		subAttribute = strings.TrimPrefix(path[close+1:], ".")
+		return strings.ToLower(attribute), filter, strings.ToLower(subAttribute)
+	}
+
+	return strings.ToLower(path), "", ""
+}
+
+func normalizeSCIMAttribute(attribute string) string {
+	return strings.ToLower(stripSCIMSchema(attribute))
+}
+
+// stripSCIMSchema drops a core schema URN prefix, so that
+// `urn:ietf:params:scim:schemas:core:2.0:User:userName` reads as `userName`.
+func stripSCIMSchema(attribute string) string {
+	for _, schema := range []string{scimUserSchema, scimGroupSchema} {
+		if len(attribute) > len(schema) && strings.EqualFold(attribute[:len(schema)+1], schema+":") {
+			return attribute[len(schema)+1:]
+		}
+	}
+	return attribute
+}
@@ -0,0 +1,94 @@
+package user
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   []scimComparison
+	}{
+		{"string", `userName eq "ada@example.com"`, []scimComparison{{"username", "ada@example.com"}}},
+		{"bool", `active eq true`, []scimComparison{{"active", true}}},
+		{"operator in capitals", `active EQ False`, []scimComparison{{"active", false}}},
+		{"schema URN", `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "ada"`, []scimComparison{{"username", "ada"}}},
+		{"spaces in a string", `displayName eq "Ada Lovelace"`, []scimComparison{{"displayname", "Ada Lovelace"}}},
+		{"escaped quotes", `displayName eq "Ada \"Countess\" Lovelace"`, []scimComparison{{"displayname", `Ada "Countess" Lovelace`}}},
+		{"extra spaces", `  userName  eq  "ada"  `, []scimComparison{{"username", "ada"}}},
+		{
+			"and",
+			`externalId eq "42" and active eq true`,
+			[]scimComparison{{"externalid", "42"}, {"active", true}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			comparisons, err := parseSCIMFilter(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(comparisons, test.want) {
+				t.Errorf("parseSCIMFilter = %+v, want %+v", comparisons, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSCIMFilterRejectsMalformedFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"empty", ""},
+		{"only spaces", "   "},
+		{"attribute only", "userName"},
+		{"no value", "userName eq"},
+		{"unsupported operator", `userName co "ada"`},
+		{"unquoted value", "userName eq ada"},
+		{"unterminated string", `userName eq "ada`},
+		{"escaped closing quote", `userName eq "ada\"`},
+		{"or", `userName eq "ada" or userName eq "grace"`},
+		{"trailing and", `userName eq "ada" and`},
+		{"incomplete second comparison", `userName eq "ada" and active`},
+		{"presence operator", "title pr"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			comparisons, err := parseSCIMFilter(test.filter)
+			if err == nil {
+				t.Errorf("parseSCIMFilter = %+v, want an error", comparisons)
+			}
+		})
+	}
+}
+
+func TestSplitSCIMPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		attribute    string
+		filter       string
+		subAttribute string
+	}{
+		{"attribute", "active", "active", "", ""},
+		{"sub-attribute without a filter", "name.givenName", "name.givenname", "", ""},
+		{"schema URN", "urn:ietf:params:scim:schemas:core:2.0:User:userName", "username", "", ""},
+		{"filter", `emails[type eq "work"]`, "emails", `type eq "work"`, ""},
+		{"filter and sub-attribute", `phoneNumbers[type eq "mobile"].value`, "phonenumbers", `type eq "mobile"`, "value"},
+		{"brackets inside the filter", `members[value eq "a]b"]`, "members", `value eq "a]b"`, ""},
+		{"surrounding spaces", "  displayName ", "displayname", "", ""},
+		{"unclosed bracket", `emails[type eq "work"`, `emails[type eq "work"`, "", ""},
+		{"closing bracket first", "emails]type[", "emails]type[", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attribute, filter, subAttribute := splitSCIMPath(test.path)
+			if attribute != test.attribute || filter != test.filter || subAttribute != test.subAttribute {
+				t.Errorf("splitSCIMPath = %q, %q, %q, want %q, %q, %q", attribute, filter, subAttribute, test.attribute, test.filter, test.subAttribute)
+			}
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package user
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+type SCIMGroupApiDto struct {
+	Schemas     []string           `json:"schemas"`
+	ID          string             `json:"id,omitempty"`
+	ExternalID  string             `json:"externalId,omitempty"`
+	DisplayName string             `json:"displayName" validate:"required,max=255"`
+	Members     []SCIMMemberApiDto `json:"members,omitempty"`
+	Meta        *SCIMMetaApiDto    `json:"meta,omitempty"`
+}
+
+func (dto *SCIMGroupApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type SCIMMemberApiDto struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// @Summary List SCIM groups
+// @Description Lists groups with their members. The filter supports eq comparisons on displayName and externalId, joined by and. Pass excludedAttributes=members to leave the members out.
+// @Tags scim
+// @Produce json
+// @Param filter query string false "Filter, e.g. displayName eq \"Engineering\""
+// @Param excludedAttributes query string false "members to leave the members out"
+// @Param startIndex query int false "1-based index of the first result" default(1)
+// @Param count query int false "Results per page, at most 200" default(100)
+// @Success 200 {object} SCIMListResponseApiDto "Groups"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid filter"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Groups [get]
+func (handler SCIMHandler) ListGroups(ctx *gin.Context) {
+	startIndex, count, scimErr := readSCIMPage(ctx)
+	if scimErr != nil {
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	filter, scimErr := scimGroupFilter(ctx.Query("filter"))
+	if scimErr != nil {
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	groups, totalCount, err := handler.repository.ListGroups(ctx.Request.Context(), filter, startIndex-1, count)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListGroups: %v", err)
+		respondSCIMInternalError(ctx)
+		return
+	}
+
+	groupApiDtos := make([]SCIMGroupApiDto, 0, len(groups))
+	for _, group := range groups {
+		groupApiDtos = append(groupApiDtos, newSCIMGroupApiDto(group, excludesMembers(ctx)))
+	}
+	respondSCIM(ctx, http.StatusOK, newSCIMListResponse(groupApiDtos, totalCount, startIndex, len(groupApiDtos)))
+}
+
+// @Summary Get a SCIM group
+// @Tags scim
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param excludedAttributes query string false "members to leave the members out"
+// @Success 200 {object} SCIMGroupApiDto "Group"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 404 {object} SCIMErrorApiDto "Group not found"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Groups/{id} [get]
+func (handler SCIMHandler) GetGroup(ctx *gin.Context) {
+	group, found := handler.findSCIMGroup(ctx)
+	if !found {
+		return
+	}
+	respondSCIM(ctx, http.StatusOK, newSCIMGroupApiDto(group, excludesMembers(ctx)))
+}
+
+// @Summary Create a SCIM group
+// @Description Creates a group. Members are user IDs as returned by /scim/v2/Users.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param group body SCIMGroupApiDto true "Group"
+// @Success 201 {object} SCIMGroupApiDto "Created group"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid input or unknown member"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 409 {object} SCIMErrorApiDto "displayName already in use"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Groups [post]
+func (handler SCIMHandler) CreateGroup(ctx *gin.Context) {
+	groupApiDto, scimErr := decodeSCIMGroup(ctx)
+	if scimErr != nil {
+		handler.logger.Printf("ERROR: decodeSCIMGroupApiDto: %v", scimErr)
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	memberIDs, scimErr := scimMemberIDs(groupApiDto.Members)
+	if scimErr != nil {
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	group := CreateGroup(groupApiDto.DisplayName, groupApiDto.ExternalID)
+	group.AddMembers(memberIDs...)
+
+	groupID, err := handler.repository.CreateGroup(ctx.Request.Context(), group)
+	if err != nil {
+		handler.respondGroupWriteError(ctx, group, err)
+		return
+	}
+
+	// read it back so members come with their display names
+	group, found := handler.findSCIMGroupByID(ctx, groupID)
+	if !found {
+		return
+	}
+	ctx.Header("Location", scimGroupLocation(group.ID))
+	respondSCIM(ctx, http.StatusCreated, newSCIMGroupApiDto(group, false))
+}
+
+// @Summary Replace a SCIM group
+// @Description Replaces the group's name, externalId and members.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param group body SCIMGroupApiDto true "Group"
+// @Success 200 {object} SCIMGroupApiDto "Updated group"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid input or unknown member"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 404 {object} SCIMErrorApiDto "Group not found"
+// @Failure 409 {object} SCIMErrorApiDto "displayName already in use"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Groups/{id} [put]
+func (handler SCIMHandler) ReplaceGroup(ctx *gin.Context) {
+	group, found := handler.findSCIMGroup(ctx)
+	if !found {
+		return
+	}
+
+	groupApiDto, scimErr := decodeSCIMGroup(ctx)
+	if scimErr != nil {
+		handler.logger.Printf("ERROR: decodeSCIMGroupApiDto: %v", scimErr)
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	memberIDs, scimErr := scimMemberIDs(groupApiDto.Members)
+	if scimErr != nil {
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	group.DisplayName = groupApiDto.DisplayName
+	group.ExternalID = groupApiDto.ExternalID
+	group.ReplaceMembers(memberIDs...)
+
+	handler.saveSCIMGroup(ctx, group)
+}
+
+// @Summary Patch a SCIM group
+// @Description Applies add, replace and remove operations to displayName, externalId and members. A single member is removed with the path members[value eq "<user id>"].
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param patch body SCIMPatchApiDto true "PatchOp"
+// @Success 200 {object} SCIMGroupApiDto "Updated group"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid operation or unknown member"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 404 {object} SCIMErrorApiDto "Group not found"
+// @Failure 409 {object} SCIMErrorApiDto "displayName already in use"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Groups/{id} [patch]
+func (handler SCIMHandler) PatchGroup(ctx *gin.Context) {
+	group, found := handler.findSCIMGroup(ctx)
+	if !found {
+		return
+	}
+
+	patchApiDto, scimErr := decodeSCIMPatch(ctx)
+	if scimErr != nil {
+		handler.logger.Printf("ERROR: decodeSCIMPatchApiDto: %v", scimErr)
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	for _, operation := range patchApiDto.Operations {
+		scimErr = applySCIMGroupPatch(group, operation)
+		if scimErr != nil {
+			handler.logger.Printf("ERROR: applySCIMGroupPatch: %v", scimErr)
+			respondSCIMError(ctx, scimErr)
+			return
+		}
+	}
+
+	handler.saveSCIMGroup(ctx, group)
+}
+
+// @Summary Delete a SCIM group
+// @Description Deletes the group. Its members are not affected.
+// @Tags scim
+// @Param id path string true "Group ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 404 {object} SCIMErrorApiDto "Group not found"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Groups/{id} [delete]
+func (handler SCIMHandler) DeleteGroup(ctx *gin.Context) {
+	groupID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		respondSCIMNotFound(ctx, "Group")
+		return
+	}
+
+	err = handler.repository.DeleteGroup(ctx.Request.Context(), groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondSCIMNotFound(ctx, "Group")
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryDeleteGroup: %v", err)
+		respondSCIMInternalError(ctx)
+		return
+	}
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (handler SCIMHandler) findSCIMGroup(ctx *gin.Context) (*Group, bool) {
+	groupID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		respondSCIMNotFound(ctx, "Group")
+		return nil, false
+	}
+	return handler.findSCIMGroupByID(ctx, groupID)
+}
+
+func (handler SCIMHandler) findSCIMGroupByID(ctx *gin.Context, groupID uuid.UUID) (*Group, bool) {
+	group, err := handler.repository.FindGroupByID(ctx.Request.Context(), groupID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindGroupByID: %v", err)
+		respondSCIMInternalError(ctx)
+		return nil, false
+	}
+	if group == nil {
+		respondSCIMNotFound(ctx, "Group")
+		return nil, false
+	}
+	return group, true
+}
+
+func (handler SCIMHandler) saveSCIMGroup(ctx *gin.Context, group *Group) {
+	err := handler.repository.UpdateGroup(ctx.Request.Context(), group)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondSCIMNotFound(ctx, "Group")
+		return
+	}
+	if err != nil {
+		handler.respondGroupWriteError(ctx, group, err)
+		return
+	}
+
+	group, found := handler.findSCIMGroupByID(ctx, group.ID)
+	if !found {
+		return
+	}
+	respondSCIM(ctx, http.StatusOK, newSCIMGroupApiDto(group, false))
+}
+
+func (handler SCIMHandler) respondGroupWriteError(ctx *gin.Context, group *Group, err error) {
+	switch {
+	case errors.Is(err, ErrGroupNameTaken):
+		respondSCIMError(ctx, newSCIMError(http.StatusConflict, scimTypeUniqueness, "displayName %s is already in use", group.DisplayName))
+	case errors.Is(err, ErrGroupMemberNotFound):
+		respondSCIMError(ctx, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "every member must be an existing user"))
+	default:
+		handler.logger.Printf("ERROR: repositorySaveGroup: %v", err)
+		respondSCIMInternalError(ctx)
+	}
+}
+
+func decodeSCIMGroup(ctx *gin.Context) (*SCIMGroupApiDto, *scimError) {
+	var groupApiDto SCIMGroupApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&groupApiDto)
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "request body is not a valid Group")
+	}
+
+	err = groupApiDto.ValidateApiDto()
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName is required")
+	}
+	return &groupApiDto, nil
+}
+
+func applySCIMGroupPatch(group *Group, operation SCIMPatchOperationApiDto) *scimError {
+	if operation.Op == "remove" {
+		attribute, filter, _ := splitSCIMPath(operation.Path)
+		switch attribute {
+		case "displayname":
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "displayName cannot be removed")
+		case "externalid":
+			group.ExternalID = ""
+		case "members":
+			if filter != "" {
+				memberID, scimErr := scimMemberFilterID(filter)
+				if scimErr != nil {
+					return scimErr
+				}
+				group.RemoveMembers(memberID)
+				return nil
+			}
+			if len(operation.Value) == 0 || string(operation.Value) == "null" {
+				group.ReplaceMembers()
+				return nil
+			}
+			memberIDs, scimErr := decodeSCIMMembers(operation.Value)
+			if scimErr != nil {
+				return scimErr
+			}
+			group.RemoveMembers(memberIDs...)
+		}
+		return nil
+	}
+
+	values, scimErr := patchValues(operation)
+	if scimErr != nil {
+		return scimErr
+	}
+
+	for path, value := range values {
+		attribute, _, _ := splitSCIMPath(path)
+		switch attribute {
+		case "displayname":
+			displayName, scimErr := decodeSCIMString(path, value)
+			if scimErr != nil {
+				return scimErr
+			}
+			if displayName == "" {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName is required")
+			}
+			group.DisplayName = displayName
+		case "externalid":
+			externalID, scimErr := decodeSCIMString(path, value)
+			if scimErr != nil {
+				return scimErr
+			}
+			group.ExternalID = externalID
+		case "members":
+			memberIDs, scimErr := decodeSCIMMembers(value)
+			if scimErr != nil {
+				return scimErr
+			}
+			if operation.Op == "replace" {
+				group.ReplaceMembers(memberIDs...)
+			} else {
+				group.AddMembers(memberIDs...)
+			}
+		}
+	}
+	return nil
+}
+
+func decodeSCIMMembers(value json.RawMessage) ([]uuid.UUID, *scimError) {
+	var members []SCIMMemberApiDto
+	err := json.Unmarshal(value, &members)
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "members must be a list")
+	}
+	return scimMemberIDs(members)
+}
+
+func scimMemberIDs(members []SCIMMemberApiDto) ([]uuid.UUID, *scimError) {
+	memberIDs := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		memberID, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "member %q is not a user ID", member.Value)
+		}
+		memberIDs = append(memberIDs, memberID)
+	}
+	return memberIDs, nil
+}
+
+// scimMemberFilterID reads the user ID out of a `value eq "<id>"` path filter.
+func scimMemberFilterID(filter string) (uuid.UUID, *scimError) {
+	comparisons, err := parseSCIMFilter(filter)
+	if err != nil {
+		return uuid.Nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "%v", err)
+	}
+	if len(comparisons) != 1 || comparisons[0].Attribute != "value" {
+		return uuid.Nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "members can only be selected by value")
+	}
+
+	value, _ := comparisons[0].Value.(string)
+	memberID, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "member %q is not a user ID", value)
+	}
+	return memberID, nil
+}
+
+func scimGroupFilter(filter string) (GroupFilter, *scimError) {
+	groupFilter := GroupFilter{}
+	if filter == "" {
+		return groupFilter, nil
+	}
+
+	comparisons, err := parseSCIMFilter(filter)
+	if err != nil {
+		return groupFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "%v", err)
+	}
+
+	for _, comparison := range comparisons {
+		value, ok := comparison.Value.(string)
+		if !ok {
+			return groupFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "%s must be compared with a string", comparison.Attribute)
+		}
+		switch comparison.Attribute {
+		case "displayname":
+			groupFilter.DisplayName = &value
+		case "externalid":
+			groupFilter.ExternalID = &value
+		default:
+			return groupFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "filtering on %s is not supported", comparison.Attribute)
+		}
+	}
+	return groupFilter, nil
+}
+
+func excludesMembers(ctx *gin.Context) bool {
+	for _, attribute := range strings.Split(ctx.Query("excludedAttributes"), ",") {
+		if normalizeSCIMAttribute(strings.TrimSpace(attribute)) == "members" {
+			return true
+		}
+	}
+	return false
+}
+
+func newSCIMGroupApiDto(group *Group, excludeMembers bool) SCIMGroupApiDto {
+	groupApiDto := SCIMGroupApiDto{
+		Schemas:     []string{scimGroupSchema},
+		ID:          group.ID.String(),
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Meta: &SCIMMetaApiDto{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     scimGroupLocation(group.ID),
+		},
+	}
+	if excludeMembers {
+		return groupApiDto
+	}
+
+	groupApiDto.Members = make([]SCIMMemberApiDto, 0, len(group.Members))
+	for _, member := range group.Members {
+		groupApiDto.Members = append(groupApiDto.Members, SCIMMemberApiDto{
+			Value:   member.UserID.String(),
+			Display: member.Display,
+			Ref:     scimUserLocation(member.UserID),
+		})
+	}
+	return groupApiDto
+}
+
+func scimGroupLocation(groupID uuid.UUID) string {
+	return fmt.Sprintf("/scim/v2/Groups/%s", groupID)
+}
@@ -0,0 +1,509 @@
+package user
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"catalyst.api/internal/authentication"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+type SCIMUserApiDto struct {
+	Schemas      []string               `json:"schemas"`
+	ID           string                 `json:"id,omitempty"`
+	ExternalID   string                 `json:"externalId,omitempty"`
+	UserName     string                 `json:"userName" validate:"required,email,max=255"`
+	Name         SCIMNameApiDto         `json:"name"`
+	DisplayName  string                 `json:"displayName,omitempty"`
+	Emails       []SCIMMultiValueApiDto `json:"emails,omitempty"`
+	PhoneNumbers []SCIMMultiValueApiDto `json:"phoneNumbers,omitempty"`
+	Active       *bool                  `json:"active,omitempty"`
+	Meta         *SCIMMetaApiDto        `json:"meta,omitempty"`
+}
+
+func (dto *SCIMUserApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type SCIMNameApiDto struct {
+	GivenName  string `json:"givenName"`
+	FamilyName string `json:"familyName"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+type SCIMMultiValueApiDto struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// @Summary List SCIM users
+// @Description Lists the users the identity provider provisioned; other accounts are not visible over SCIM. The filter supports eq comparisons on userName, externalId and active, joined by and.
+// @Tags scim
+// @Produce json
+// @Param filter query string false "Filter, e.g. userName eq \"ada@example.com\""
+// @Param startIndex query int false "1-based index of the first result" default(1)
+// @Param count query int false "Results per page, at most 200" default(100)
+// @Success 200 {object} SCIMListResponseApiDto "Users"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid filter"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Users [get]
+func (handler SCIMHandler) ListUsers(ctx *gin.Context) {
+	startIndex, count, scimErr := readSCIMPage(ctx)
+	if scimErr != nil {
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	filter, scimErr := scimUserFilter(ctx.Query("filter"))
+	if scimErr != nil {
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	users, totalCount, err := handler.repository.ListUsers(ctx.Request.Context(), filter, startIndex-1, count)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListUsers: %v", err)
+		respondSCIMInternalError(ctx)
+		return
+	}
+
+	userApiDtos := make([]SCIMUserApiDto, 0, len(users))
+	for _, user := range users {
+		userApiDtos = append(userApiDtos, newSCIMUserApiDto(user))
+	}
+	respondSCIM(ctx, http.StatusOK, newSCIMListResponse(userApiDtos, totalCount, startIndex, len(userApiDtos)))
+}
+
+// @Summary Get a SCIM user
+// @Tags scim
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} SCIMUserApiDto "User"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 404 {object} SCIMErrorApiDto "User not found"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Users/{id} [get]
+func (handler SCIMHandler) GetUser(ctx *gin.Context) {
+	user, found := handler.findSCIMUser(ctx)
+	if !found {
+		return
+	}
+	respondSCIM(ctx, http.StatusOK, newSCIMUserApiDto(user))
+}
+
+// @Summary Provision a SCIM user
+// @Description Creates an account for a user of the identity provider. userName must be the user's email address. The account has no password or linked provider; the user claims it by signing in with the workspace's SAML connection or a magic link.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param user body SCIMUserApiDto true "User"
+// @Success 201 {object} SCIMUserApiDto "Created user"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid input"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 409 {object} SCIMErrorApiDto "userName already in use"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Users [post]
+func (handler SCIMHandler) CreateUser(ctx *gin.Context) {
+	userApiDto, scimErr := decodeSCIMUser(ctx)
+	if scimErr != nil {
+		handler.logger.Printf("ERROR: decodeSCIMUserApiDto: %v", scimErr)
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	user := Create(userApiDto.UserName, userApiDto.Name.GivenName, userApiDto.Name.FamilyName)
+	applySCIMUserApiDto(user, userApiDto)
+
+	userID, err := handler.repository.ProvisionUser(ctx.Request.Context(), user)
+	if errors.Is(err, ErrEmailTaken) {
+		respondSCIMError(ctx, newSCIMError(http.StatusConflict, scimTypeUniqueness, "userName %s is already in use", user.Email))
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryProvisionUser: %v", err)
+		respondSCIMInternalError(ctx)
+		return
+	}
+	user.ID = userID
+
+	ctx.Header("Location", scimUserLocation(user.ID))
+	respondSCIM(ctx, http.StatusCreated, newSCIMUserApiDto(user))
+}
+
+// @Summary Replace a SCIM user
+// @Description Replaces the user's attributes. Setting active to false deactivates the account: the user is signed out everywhere and cannot sign in, but nothing is deleted. Admins cannot be changed over SCIM.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param user body SCIMUserApiDto true "User"
+// @Success 200 {object} SCIMUserApiDto "Updated user"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid input"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 403 {object} SCIMErrorApiDto "User is an admin"
+// @Failure 404 {object} SCIMErrorApiDto "User not found"
+// @Failure 409 {object} SCIMErrorApiDto "userName already in use"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Users/{id} [put]
+func (handler SCIMHandler) ReplaceUser(ctx *gin.Context) {
+	user, found := handler.findChangeableSCIMUser(ctx)
+	if !found {
+		return
+	}
+
+	userApiDto, scimErr := decodeSCIMUser(ctx)
+	if scimErr != nil {
+		handler.logger.Printf("ERROR: decodeSCIMUserApiDto: %v", scimErr)
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	user.Email = userApiDto.UserName
+	user.FirstName = userApiDto.Name.GivenName
+	user.LastName = userApiDto.Name.FamilyName
+	applySCIMUserApiDto(user, userApiDto)
+
+	handler.saveSCIMUser(ctx, user)
+}
+
+// @Summary Patch a SCIM user
+// @Description Applies add, replace and remove operations to userName, name, externalId, phoneNumbers and active. Other attributes are ignored. Setting active to false deactivates the account without deleting anything. Admins cannot be changed over SCIM.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param patch body SCIMPatchApiDto true "PatchOp"
+// @Success 200 {object} SCIMUserApiDto "Updated user"
+// @Failure 400 {object} SCIMErrorApiDto "Invalid operation"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 403 {object} SCIMErrorApiDto "User is an admin"
+// @Failure 404 {object} SCIMErrorApiDto "User not found"
+// @Failure 409 {object} SCIMErrorApiDto "userName already in use"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Users/{id} [patch]
+func (handler SCIMHandler) PatchUser(ctx *gin.Context) {
+	user, found := handler.findChangeableSCIMUser(ctx)
+	if !found {
+		return
+	}
+
+	patchApiDto, scimErr := decodeSCIMPatch(ctx)
+	if scimErr != nil {
+		handler.logger.Printf("ERROR: decodeSCIMPatchApiDto: %v", scimErr)
+		respondSCIMError(ctx, scimErr)
+		return
+	}
+
+	for _, operation := range patchApiDto.Operations {
+		scimErr = applySCIMUserPatch(user, operation)
+		if scimErr != nil {
+			handler.logger.Printf("ERROR: applySCIMUserPatch: %v", scimErr)
+			respondSCIMError(ctx, scimErr)
+			return
+		}
+	}
+
+	handler.saveSCIMUser(ctx, user)
+}
+
+// @Summary Deactivate a SCIM user
+// @Description Deactivates the account rather than deleting it, so the user's data is kept and the identity provider can reactivate them later. Admins cannot be deactivated over SCIM.
+// @Tags scim
+// @Param id path string true "User ID"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} map[string]string "SCIM token required"
+// @Failure 403 {object} SCIMErrorApiDto "User is an admin"
+// @Failure 404 {object} SCIMErrorApiDto "User not found"
+// @Failure 500 {object} SCIMErrorApiDto "Internal server error"
+// @Router /scim/v2/Users/{id} [delete]
+func (handler SCIMHandler) DeleteUser(ctx *gin.Context) {
+	user, found := handler.findChangeableSCIMUser(ctx)
+	if !found {
+		return
+	}
+
+	user.SetActive(false)
+	err := handler.repository.UpdateUserAccount(ctx.Request.Context(), user)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryUpdateUserAccount: %v", err)
+		respondSCIMInternalError(ctx)
+		return
+	}
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// findSCIMUser loads the user named by the id parameter, responding with an
+// error when it cannot. Users that were not provisioned over SCIM, such as
+// those who signed up themselves, are not found.
+func (handler SCIMHandler) findSCIMUser(ctx *gin.Context) (*User, bool) {
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		respondSCIMNotFound(ctx, "User")
+		return nil, false
+	}
+
+	user, err := handler.repository.FindUserByID(ctx.Request.Context(), userID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindUserByID: %v", err)
+		respondSCIMInternalError(ctx)
+		return nil, false
+	}
+	if user == nil || !user.Provisioned {
+		respondSCIMNotFound(ctx, "User")
+		return nil, false
+	}
+	return user, true
+}
+
+// findChangeableSCIMUser is findSCIMUser for changes. Admins are managed in
+// the app, so an identity provider cannot change or deactivate them.
+func (handler SCIMHandler) findChangeableSCIMUser(ctx *gin.Context) (*User, bool) {
+	user, found := handler.findSCIMUser(ctx)
+	if !found {
+		return nil, false
+	}
+
+	roles, err := handler.repository.ListUserRoles(ctx.Request.Context(), user.ID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListUserRoles: %v", err)
+		respondSCIMInternalError(ctx)
+		return nil, false
+	}
+	if slices.Contains(roles, authentication.RoleAdmin) {
+		respondSCIMError(ctx, newSCIMError(http.StatusForbidden, "", "admins cannot be changed over SCIM"))
+		return nil, false
+	}
+	return user, true
+}
+
+func (handler SCIMHandler) saveSCIMUser(ctx *gin.Context, user *User) {
+	err := handler.repository.UpdateUserAccount(ctx.Request.Context(), user)
+	if errors.Is(err, ErrEmailTaken) {
+		respondSCIMError(ctx, newSCIMError(http.StatusConflict, scimTypeUniqueness, "userName %s is already in use", user.Email))
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondSCIMNotFound(ctx, "User")
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryUpdateUserAccount: %v", err)
+		respondSCIMInternalError(ctx)
+		return
+	}
+	respondSCIM(ctx, http.StatusOK, newSCIMUserApiDto(user))
+}
+
+func decodeSCIMUser(ctx *gin.Context) (*SCIMUserApiDto, *scimError) {
+	var userApiDto SCIMUserApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&userApiDto)
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "request body is not a valid User")
+	}
+
+	err = userApiDto.ValidateApiDto()
+	if err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "userName must be an email address")
+	}
+	return &userApiDto, nil
+}
+
+// applySCIMUserApiDto copies the attributes Create does not take. An omitted
+// active leaves the account as it is.
+func applySCIMUserApiDto(user *User, userApiDto *SCIMUserApiDto) {
+	user.ExternalID = userApiDto.ExternalID
+	user.MobileNumber = mobileNumber(userApiDto.PhoneNumbers)
+	if userApiDto.Active != nil {
+		user.SetActive(*userApiDto.Active)
+	}
+}
+
+func applySCIMUserPatch(user *User, operation SCIMPatchOperationApiDto) *scimError {
+	if operation.Op == "remove" {
+		attribute, _, _ := splitSCIMPath(operation.Path)
+		switch attribute {
+		case "username", "active":
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "%s cannot be removed", operation.Path)
+		case "externalid":
+			user.ExternalID = ""
+		case "name":
+			user.FirstName = ""
+			user.LastName = ""
+		case "name.givenname":
+			user.FirstName = ""
+		case "name.familyname":
+			user.LastName = ""
+		case "phonenumbers":
+			user.MobileNumber = ""
+		}
+		return nil
+	}
+
+	values, scimErr := patchValues(operation)
+	if scimErr != nil {
+		return scimErr
+	}
+
+	for path, value := range values {
+		attribute, _, subAttribute := splitSCIMPath(path)
+		switch attribute {
+		case "username":
+			email, scimErr := decodeSCIMString(path, value)
+			if scimErr != nil {
+				return scimErr
+			}
+			if validator.New().Var(email, "required,email,max=255") != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "userName must be an email address")
+			}
+			user.Email = email
+		case "name":
+			var name SCIMNameApiDto
+			if json.Unmarshal(value, &name) != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "name must be an object")
+			}
+			user.FirstName = name.GivenName
+			user.LastName = name.FamilyName
+		case "name.givenname":
+			firstName, scimErr := decodeSCIMString(path, value)
+			if scimErr != nil {
+				return scimErr
+			}
+			user.FirstName = firstName
+		case "name.familyname":
+			lastName, scimErr := decodeSCIMString(path, value)
+			if scimErr != nil {
+				return scimErr
+			}
+			user.LastName = lastName
+		case "externalid":
+			externalID, scimErr := decodeSCIMString(path, value)
+			if scimErr != nil {
+				return scimErr
+			}
+			user.ExternalID = externalID
+		case "active":
+			active, scimErr := decodeSCIMBool(path, value)
+			if scimErr != nil {
+				return scimErr
+			}
+			user.SetActive(active)
+		case "phonenumbers":
+			if subAttribute == "value" {
+				number, scimErr := decodeSCIMString(path, value)
+				if scimErr != nil {
+					return scimErr
+				}
+				user.MobileNumber = number
+				continue
+			}
+			var phoneNumbers []SCIMMultiValueApiDto
+			if json.Unmarshal(value, &phoneNumbers) != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "phoneNumbers must be a list")
+			}
+			user.MobileNumber = mobileNumber(phoneNumbers)
+		}
+	}
+	return nil
+}
+
+// mobileNumber picks the mobile number, or else the primary or first one.
+func mobileNumber(phoneNumbers []SCIMMultiValueApiDto) string {
+	if len(phoneNumbers) == 0 {
+		return ""
+	}
+	for _, phoneNumber := range phoneNumbers {
+		if strings.EqualFold(phoneNumber.Type, "mobile") {
+			return phoneNumber.Value
+		}
+	}
+	for _, phoneNumber := range phoneNumbers {
+		if phoneNumber.Primary {
+			return phoneNumber.Value
+		}
+	}
+	return phoneNumbers[0].Value
+}
+
+func scimUserFilter(filter string) (UserFilter, *scimError) {
+	// identity providers only see the users they provisioned
+	provisioned := true
+	userFilter := UserFilter{Provisioned: &provisioned}
+	if filter == "" {
+		return userFilter, nil
+	}
+
+	comparisons, err := parseSCIMFilter(filter)
+	if err != nil {
+		return userFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "%v", err)
+	}
+
+	for _, comparison := range comparisons {
+		switch comparison.Attribute {
+		case "username", "emails", "emails.value":
+			email, ok := comparison.Value.(string)
+			if !ok {
+				return userFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "userName must be compared with a string")
+			}
+			userFilter.Email = &email
+		case "externalid":
+			externalID, ok := comparison.Value.(string)
+			if !ok {
+				return userFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "externalId must be compared with a string")
+			}
+			userFilter.ExternalID = &externalID
+		case "active":
+			active, ok := comparison.Value.(bool)
+			if !ok {
+				return userFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "active must be compared with a boolean")
+			}
+			userFilter.Active = &active
+		default:
+			return userFilter, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "filtering on %s is not supported", comparison.Attribute)
+		}
+	}
+	return userFilter, nil
+}
+
+func newSCIMUserApiDto(user *User) SCIMUserApiDto {
+	// an account pending deletion is locked, so it is reported as inactive
+	active := user.IsActive() && !user.IsPendingDeletion()
+	userApiDto := SCIMUserApiDto{
+		Schemas:    []string{scimUserSchema},
+		ID:         user.ID.String(),
+		ExternalID: user.ExternalID,
+		UserName:   user.Email,
+		Name: SCIMNameApiDto{
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+			Formatted:  strings.TrimSpace(user.FirstName + " " + user.LastName),
+		},
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Emails:      []SCIMMultiValueApiDto{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &SCIMMetaApiDto{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     scimUserLocation(user.ID),
+		},
+	}
+	if user.MobileNumber != "" {
+		userApiDto.PhoneNumbers = []SCIMMultiValueApiDto{{Value: user.MobileNumber, Type: "mobile"}}
+	}
+	return userApiDto
+}
+
+func scimUserLocation(userID uuid.UUID) string {
+	return fmt.Sprintf("/scim/v2/Users/%s", userID)
+}
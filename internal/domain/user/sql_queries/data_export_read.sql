@@ -0,0 +1,87 @@
+-- name: ExportUserProfile :one
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at,
+    auth_users.external_id, auth_users.provisioned, (auth_users.password_hash IS NOT NULL)::boolean AS has_password,
+    auth_users.tokens_revoked_at, auth_users.deactivated_at, auth_users.deletion_requested_at, auth_users.deletion_scheduled_at
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE users.id = $1;
+
+-- name: ExportUserRoles :many
+SELECT role, created_at
+FROM user_roles
+WHERE user_id = $1
+ORDER BY role;
+
+-- name: ExportUserProviders :many
+SELECT id, provider, provider_user_id, created_at
+FROM auth_user_providers
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportUserSessions :many
+SELECT id, provider, user_agent, ip_address, created_at, last_seen_at, revoked_at
+FROM sessions
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportUserRefreshTokens :many
+SELECT id, session_id, family_id, expires_at, used_at, revoked_at, created_at
+FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportUserPersonalAccessTokens :many
+SELECT id, name, token_hint, scopes, expires_at, last_used_at, revoked_at, created_at
+FROM personal_access_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportUserTOTPCredentials :many
+SELECT confirmed_at, created_at
+FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: ExportUserEmailChanges :many
+SELECT id, new_email, expires_at, created_at
+FROM email_changes
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportUserGroups :many
+SELECT groups.id, groups.display_name, group_members.created_at AS joined_at
+FROM group_members
+JOIN groups ON groups.id = group_members.group_id
+WHERE group_members.user_id = $1
+ORDER BY group_members.created_at;
+
+-- name: ExportUserImpersonations :many
+SELECT id, actor_id, user_id, reason, expires_at, ended_at, created_at, actor_email
+FROM impersonations
+WHERE user_id = sqlc.arg(user_id) OR actor_id = sqlc.arg(user_id)
+ORDER BY created_at;
+
+-- name: ExportUserDeviceAuthorizations :many
+SELECT id, client_id, status, last_polled_at, expires_at, created_at
+FROM device_authorizations
+WHERE user_id = sqlc.arg(user_id)::uuid
+ORDER BY created_at;
+
+-- name: ExportUserMagicLinks :many
+SELECT magic_links.id, magic_links.expires_at, magic_links.used_at, magic_links.created_at
+FROM magic_links
+JOIN auth_users ON lower(auth_users.email) = lower(magic_links.email)
+WHERE auth_users.id = sqlc.arg(user_id)
+ORDER BY magic_links.created_at;
+
+-- name: ExportUserPasswordResets :many
+SELECT id, expires_at, used_at, created_at
+FROM password_reset_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ExportUserProviderTokens :many
+SELECT auth_user_providers.provider, provider_tokens.expires_at, (provider_tokens.refresh_token IS NOT NULL)::boolean AS has_refresh_token, provider_tokens.updated_at
+FROM provider_tokens
+JOIN auth_user_providers ON auth_user_providers.id = provider_tokens.auth_user_provider_id
+WHERE auth_user_providers.user_id = sqlc.arg(user_id)
+ORDER BY auth_user_providers.provider;
\ No newline at end of file
@@ -0,0 +1,45 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (user_id)
+VALUES ($1)
+RETURNING id, status, created_at;
+
+-- name: FindDataExport :one
+SELECT id, user_id, status, error, created_at, completed_at, expires_at
+FROM data_exports
+WHERE id = $1 AND user_id = $2;
+
+-- name: FindActiveDataExport :one
+SELECT id, user_id, status, error, created_at, completed_at, expires_at
+FROM data_exports
+WHERE user_id = $1 AND status IN ('pending', 'running') AND created_at > sqlc.arg(started_after)
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: StartDataExport :execresult
+UPDATE data_exports
+SET status = 'running'
+WHERE id = $1 AND status = 'pending';
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'completed', archive = $1, completed_at = CURRENT_TIMESTAMP, expires_at = $2
+WHERE id = $3;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', error = $1, completed_at = CURRENT_TIMESTAMP
+WHERE id = $2;
+
+-- name: GetDataExportArchive :one
+SELECT archive
+FROM data_exports
+WHERE id = $1 AND user_id = $2 AND status = 'completed' AND expires_at > CURRENT_TIMESTAMP;
+
+-- name: FailStaleDataExports :exec
+UPDATE data_exports
+SET status = 'failed', error = 'interrupted', completed_at = CURRENT_TIMESTAMP
+WHERE status IN ('pending', 'running') AND created_at <= sqlc.arg(started_before);
+
+-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at <= CURRENT_TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: CreateEmailChange :one
+INSERT INTO email_changes (user_id, new_email, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: DeleteEmailChangesForUser :exec
+DELETE FROM email_changes
+WHERE user_id = $1;
+
+-- name: DeleteExpiredEmailChanges :exec
+DELETE FROM email_changes
+WHERE expires_at < CURRENT_TIMESTAMP;
+
+-- name: ConsumeEmailChange :one
+DELETE FROM email_changes
+WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id, new_email;
+
+-- name: UpdateAuthUserEmail :execresult
+UPDATE auth_users
+SET email = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2;
+
+-- name: UpdateUserEmail :execresult
+UPDATE users
+SET email = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2;
\ No newline at end of file
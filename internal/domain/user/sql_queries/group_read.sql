@@ -0,0 +1,25 @@
+-- name: FindGroupByID :one
+SELECT id, display_name, external_id, created_at, updated_at
+FROM groups
+WHERE id = $1;
+
+-- name: ListGroups :many
+SELECT id, display_name, external_id, created_at, updated_at
+FROM groups
+WHERE (sqlc.narg(display_name)::text IS NULL OR lower(display_name) = lower(sqlc.narg(display_name)))
+  AND (sqlc.narg(external_id)::text IS NULL OR external_id = sqlc.narg(external_id))
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountGroups :one
+SELECT COUNT(*)
+FROM groups
+WHERE (sqlc.narg(display_name)::text IS NULL OR lower(display_name) = lower(sqlc.narg(display_name)))
+  AND (sqlc.narg(external_id)::text IS NULL OR external_id = sqlc.narg(external_id));
+
+-- name: ListGroupMembers :many
+SELECT group_members.group_id, group_members.user_id, users.first_name, users.last_name
+FROM group_members
+JOIN users ON users.id = group_members.user_id
+WHERE group_members.group_id = ANY(sqlc.arg(group_ids)::uuid[])
+ORDER BY group_members.created_at, group_members.user_id;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- name: CreateGroup :one
+INSERT INTO groups (display_name, external_id)
+VALUES ($1, $2)
+RETURNING id, created_at, updated_at;
+
+-- name: UpdateGroup :execresult
+UPDATE groups
+SET display_name = $1, external_id = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $3;
+
+-- name: DeleteGroup :execresult
+DELETE FROM groups WHERE id = $1;
+
+-- name: AddGroupMember :exec
+INSERT INTO group_members (group_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (group_id, user_id) DO NOTHING;
+
+-- name: RemoveGroupMembersExcept :exec
+DELETE FROM group_members
+WHERE group_id = sqlc.arg(group_id) AND NOT (user_id = ANY(sqlc.arg(user_ids)::uuid[]));
\ No newline at end of file
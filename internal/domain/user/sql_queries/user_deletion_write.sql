@@ -0,0 +1,110 @@
+-- name: ListUsersDueForDeletion :many
+SELECT id
+FROM auth_users
+WHERE deletion_scheduled_at <= sqlc.arg(due_before)
+ORDER BY deletion_scheduled_at
+LIMIT sqlc.arg(page_limit);
+
+-- name: LockUserDueForDeletion :one
+SELECT id, deletion_requested_at, deletion_requested_by
+FROM auth_users
+WHERE id = $1 AND deletion_scheduled_at <= CURRENT_TIMESTAMP
+FOR UPDATE SKIP LOCKED;
+
+-- name: PurgeUserImpersonationRequests :execresult
+DELETE FROM impersonation_requests
+WHERE impersonation_id IN (SELECT id FROM impersonations WHERE impersonations.user_id = sqlc.arg(user_id));
+
+-- name: PurgeUserImpersonations :execresult
+DELETE FROM impersonations
+WHERE user_id = $1;
+
+-- name: PseudonymiseUserImpersonations :execresult
+UPDATE impersonations
+SET actor_id = NULL
+WHERE actor_id = sqlc.arg(actor_id);
+
+-- name: PurgeUserMFAChallenges :execresult
+DELETE FROM mfa_challenges
+WHERE user_id = $1;
+
+-- name: PurgeUserRefreshTokens :execresult
+DELETE FROM refresh_tokens
+WHERE user_id = $1;
+
+-- name: PurgeUserAuthorizationCodes :execresult
+DELETE FROM authorization_codes
+WHERE user_id = $1;
+
+-- name: PurgeUserSessions :execresult
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: PurgeUserRevokedTokens :execresult
+DELETE FROM revoked_tokens
+WHERE user_id = $1;
+
+-- name: PurgeUserEmailChanges :execresult
+DELETE FROM email_changes
+WHERE user_id = $1;
+
+-- name: PurgeUserPasswordResetTokens :execresult
+DELETE FROM password_reset_tokens
+WHERE user_id = $1;
+
+-- name: PurgeUserPersonalAccessTokens :execresult
+DELETE FROM personal_access_tokens
+WHERE user_id = $1;
+
+-- name: PurgeUserDeviceAuthorizations :execresult
+DELETE FROM device_authorizations
+WHERE user_id = sqlc.arg(user_id)::uuid;
+
+-- name: PurgeUserTOTPCredentials :execresult
+DELETE FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: PurgeUserRecoveryCodes :execresult
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: PurgeUserRoles :execresult
+DELETE FROM user_roles
+WHERE user_id = $1;
+
+-- name: PurgeUserGroupMemberships :execresult
+DELETE FROM group_members
+WHERE user_id = $1;
+
+-- name: PurgeUserProviderTokens :execresult
+DELETE FROM provider_tokens
+WHERE auth_user_provider_id IN (SELECT id FROM auth_user_providers WHERE auth_user_providers.user_id = sqlc.arg(user_id));
+
+-- name: PurgeUserProviders :execresult
+DELETE FROM auth_user_providers
+WHERE user_id = $1;
+
+-- name: PurgeUserInvitations :execresult
+DELETE FROM invitations
+WHERE lower(email) = (SELECT lower(auth_users.email) FROM auth_users WHERE auth_users.id = sqlc.arg(user_id));
+
+-- name: PurgeUserMagicLinks :execresult
+DELETE FROM magic_links
+WHERE lower(email) = (SELECT lower(auth_users.email) FROM auth_users WHERE auth_users.id = sqlc.arg(user_id));
+
+-- name: PurgeUserDataExports :execresult
+DELETE FROM data_exports
+WHERE user_id = $1;
+
+-- name: PurgeUserProfile :execresult
+DELETE FROM users
+WHERE id = sqlc.arg(user_id);
+
+-- name: PurgeAuthUser :execresult
+DELETE FROM auth_users
+WHERE id = sqlc.arg(user_id);
+
+-- name: CreateDeletionReceipt :one
+INSERT INTO deletion_receipts (user_id, requested_by, requested_at, purged_rows, pseudonymised_rows)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, requested_by, requested_at, purged_rows, purged_at, pseudonymised_rows;
\ No newline at end of file
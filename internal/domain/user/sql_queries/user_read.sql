@@ -1,4 +1,97 @@
 -- name: GetUserDetailByID :one
 SELECT id, email, first_name, last_name, mobile_number 
 FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListUsers :many
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.external_id, auth_users.deactivated_at, auth_users.deletion_scheduled_at, auth_users.provisioned
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE (sqlc.narg(email)::text IS NULL OR lower(users.email) = lower(sqlc.narg(email)))
+  AND (sqlc.narg(external_id)::text IS NULL OR auth_users.external_id = sqlc.narg(external_id))
+  AND (sqlc.narg(active)::boolean IS NULL OR (auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL) = sqlc.narg(active))
+  AND (sqlc.narg(provisioned)::boolean IS NULL OR auth_users.provisioned = sqlc.narg(provisioned))
+ORDER BY users.created_at, users.id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountUsers :one
+SELECT COUNT(*)
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE (sqlc.narg(email)::text IS NULL OR lower(users.email) = lower(sqlc.narg(email)))
+  AND (sqlc.narg(external_id)::text IS NULL OR auth_users.external_id = sqlc.narg(external_id))
+  AND (sqlc.narg(active)::boolean IS NULL OR (auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL) = sqlc.narg(active))
+  AND (sqlc.narg(provisioned)::boolean IS NULL OR auth_users.provisioned = sqlc.narg(provisioned));
+
+-- name: SearchUsers :many
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, auth_users.deactivated_at, auth_users.deletion_scheduled_at,
+    ARRAY(SELECT user_roles.role FROM user_roles WHERE user_roles.user_id = users.id ORDER BY user_roles.role)::text[] AS roles
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE (sqlc.narg(search)::text IS NULL
+    OR users.email ILIKE '%' || sqlc.narg(search) || '%'
+    OR (users.first_name || ' ' || users.last_name) ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR EXISTS (
+    SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = sqlc.narg(role)))
+  AND (sqlc.narg(status)::text IS NULL
+    OR (sqlc.narg(status) = 'active' AND auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR (sqlc.narg(status) = 'deactivated' AND auth_users.deactivated_at IS NOT NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR (sqlc.narg(status) = 'pending_deletion' AND auth_users.deletion_scheduled_at IS NOT NULL))
+  AND (sqlc.narg(cursor_id)::uuid IS NULL
+    OR (sqlc.arg(sort_by)::text = 'name' AND NOT sqlc.arg(sort_desc)::boolean
+      AND (lower(users.last_name || ' ' || users.first_name), users.id) > (lower(sqlc.narg(cursor_text)::text), sqlc.narg(cursor_id)))
+    OR (sqlc.arg(sort_by)::text = 'name' AND sqlc.arg(sort_desc)::boolean
+      AND (lower(users.last_name || ' ' || users.first_name), users.id) < (lower(sqlc.narg(cursor_text)::text), sqlc.narg(cursor_id)))
+    OR (sqlc.arg(sort_by)::text = 'email' AND NOT sqlc.arg(sort_desc)::boolean
+      AND (lower(users.email), users.id) > (lower(sqlc.narg(cursor_text)::text), sqlc.narg(cursor_id)))
+    OR (sqlc.arg(sort_by)::text = 'email' AND sqlc.arg(sort_desc)::boolean
+      AND (lower(users.email), users.id) < (lower(sqlc.narg(cursor_text)::text), sqlc.narg(cursor_id)))
+    OR (sqlc.arg(sort_by)::text = 'created_at' AND NOT sqlc.arg(sort_desc)::boolean
+      AND (users.created_at, users.id) > (sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_id)))
+    OR (sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_desc)::boolean
+      AND (users.created_at, users.id) < (sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_id))))
+ORDER BY
+  CASE WHEN sqlc.arg(sort_by)::text = 'name' AND NOT sqlc.arg(sort_desc)::boolean THEN lower(users.last_name || ' ' || users.first_name) END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'name' AND sqlc.arg(sort_desc)::boolean THEN lower(users.last_name || ' ' || users.first_name) END DESC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'email' AND NOT sqlc.arg(sort_desc)::boolean THEN lower(users.email) END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'email' AND sqlc.arg(sort_desc)::boolean THEN lower(users.email) END DESC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND NOT sqlc.arg(sort_desc)::boolean THEN users.created_at END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_desc)::boolean THEN users.created_at END DESC,
+  CASE WHEN sqlc.arg(sort_desc)::boolean THEN users.id END DESC,
+  users.id ASC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountSearchUsers :one
+SELECT COUNT(*)
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE (sqlc.narg(search)::text IS NULL
+    OR users.email ILIKE '%' || sqlc.narg(search) || '%'
+    OR (users.first_name || ' ' || users.last_name) ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR EXISTS (
+    SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = sqlc.narg(role)))
+  AND (sqlc.narg(status)::text IS NULL
+    OR (sqlc.narg(status) = 'active' AND auth_users.deactivated_at IS NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR (sqlc.narg(status) = 'deactivated' AND auth_users.deactivated_at IS NOT NULL AND auth_users.deletion_scheduled_at IS NULL)
+    OR (sqlc.narg(status) = 'pending_deletion' AND auth_users.deletion_scheduled_at IS NOT NULL));
+
+-- name: GetUserProfileByID :one
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.deactivated_at, auth_users.deletion_scheduled_at,
+    (auth_users.password_hash IS NOT NULL)::boolean AS has_password,
+    ARRAY(SELECT user_roles.role FROM user_roles WHERE user_roles.user_id = users.id ORDER BY user_roles.role)::text[] AS roles,
+    (SELECT MAX(sessions.created_at) FROM sessions WHERE sessions.user_id = users.id)::timestamptz AS last_login_at,
+    (SELECT email_changes.new_email FROM email_changes WHERE email_changes.user_id = users.id AND email_changes.expires_at > CURRENT_TIMESTAMP ORDER BY email_changes.created_at DESC LIMIT 1) AS pending_email
+FROM users
+JOIN auth_users ON auth_users.id = users.id
+WHERE users.id = $1;
+
+-- name: ListUserProviders :many
+SELECT provider, created_at
+FROM auth_user_providers
+WHERE user_id = $1
+ORDER BY created_at, provider;
+
+-- name: GetDeletionReceiptByUserID :one
+SELECT id, user_id, requested_by, requested_at, purged_rows, purged_at, pseudonymised_rows
+FROM deletion_receipts
+WHERE user_id = $1;
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- name: ListUserRoles :many
+SELECT role
+FROM user_roles
+WHERE user_id = $1
+ORDER BY role;
+
+-- name: GrantUserRole :exec
+INSERT INTO user_roles (user_id, role)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RevokeUserRole :execresult
+DELETE FROM user_roles
+WHERE user_id = $1 AND role = $2;
+
+-- name: LockUsersWithRole :many
+SELECT user_id
+FROM user_roles
+WHERE role = $1
+FOR UPDATE;
+
+-- name: RevokeAuthUserTokens :exec
+UPDATE auth_users
+SET tokens_revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GrantFirstAdmin :execresult
+INSERT INTO user_roles (user_id, role)
+SELECT id, 'admin'
+FROM auth_users
+WHERE lower(email) = lower(sqlc.arg(email)) AND NOT EXISTS (SELECT 1 FROM user_roles WHERE role = 'admin')
+ON CONFLICT DO NOTHING;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*)
+FROM user_roles
+WHERE role = $1;
\ No newline at end of file
@@ -1,18 +1,72 @@
 -- name: FindUserByID :one
-SELECT id, email, first_name, last_name, mobile_number, created_at, updated_at
+SELECT users.id, users.email, users.first_name, users.last_name, users.mobile_number, users.created_at, users.updated_at, auth_users.external_id, auth_users.deactivated_at, auth_users.deletion_requested_at, auth_users.deletion_requested_by, auth_users.deletion_scheduled_at, auth_users.provisioned
 FROM users
-WHERE id = $1;
+JOIN auth_users ON auth_users.id = users.id
+WHERE users.id = $1;
 
 -- name: AddUser :one
 INSERT INTO users (email, first_name, last_name, mobile_number)	
 VALUES ($1, $2, $3, $4)
 RETURNING id, created_at, updated_at;
 
+-- name: ProvisionUser :one
+WITH new_auth_user AS (
+    INSERT INTO auth_users (email, first_name, last_name, external_id, provisioned, deactivated_at)
+    VALUES ($1, $2, $3, $4, TRUE, $5)
+    RETURNING id, created_at
+),
+new_user AS (
+    INSERT INTO users (id, email, first_name, last_name, mobile_number)
+    SELECT id, $1, $2, $3, $6 FROM new_auth_user
+),
+new_role AS (
+    INSERT INTO user_roles (user_id, role)
+    SELECT id, 'user' FROM new_auth_user
+)
+SELECT id, created_at FROM new_auth_user;
+
 -- name: UpdateUser :execresult
 UPDATE users 
 SET email = $1, first_name = $2, last_name = $3, mobile_number = $4, updated_at = CURRENT_TIMESTAMP
 WHERE id = $5
 RETURNING updated_at;
 
--- name: DeleteUser :execresult
-DELETE from users WHERE id = $1;
+-- name: UpdateAuthUser :execresult
+UPDATE auth_users
+SET email = $1, first_name = $2, last_name = $3, external_id = $4, updated_at = CURRENT_TIMESTAMP
+WHERE id = $5;
+
+-- name: IsEmailTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM auth_users WHERE lower(email) = lower(sqlc.arg(email)) AND id <> sqlc.arg(id)
+);
+
+-- name: DeactivateAuthUser :execresult
+UPDATE auth_users
+SET deactivated_at = CURRENT_TIMESTAMP, tokens_revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deactivated_at IS NULL;
+
+-- name: ReactivateAuthUser :exec
+UPDATE auth_users
+SET deactivated_at = NULL
+WHERE id = $1;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: ScheduleAuthUserDeletion :execresult
+UPDATE auth_users
+SET deletion_requested_at = CURRENT_TIMESTAMP, deletion_requested_by = $1, deletion_scheduled_at = $2, tokens_revoked_at = CURRENT_TIMESTAMP
+WHERE id = $3 AND deletion_scheduled_at IS NULL;
+
+-- name: CancelAuthUserDeletion :execresult
+UPDATE auth_users
+SET deletion_requested_at = NULL, deletion_requested_by = NULL, deletion_scheduled_at = NULL
+WHERE id = $1 AND deletion_scheduled_at IS NOT NULL;
\ No newline at end of file
@@ -2,9 +2,12 @@ package user
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
+	"catalyst.api/internal/authentication"
 	"catalyst.api/internal/utilities"
 
 	"github.com/gin-gonic/gin"
@@ -15,24 +18,46 @@ type UserDeleteCommand struct {
 	ID uuid.UUID
 }
 
+type UserDeletionApiDto struct {
+	DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+}
+
+type DeletionReceiptApiDto struct {
+	ID                uuid.UUID        `json:"id"`
+	UserID            uuid.UUID        `json:"userId"`
+	RequestedBy       *uuid.UUID       `json:"requestedBy"`
+	RequestedAt       time.Time        `json:"requestedAt"`
+	PurgedRows        map[string]int64 `json:"purgedRows"`
+	PseudonymisedRows map[string]int64 `json:"pseudonymisedRows"`
+	PurgedAt          time.Time        `json:"purgedAt"`
+}
+
+// UserDeleteHandler schedules accounts for deletion. Nothing is deleted until
+// gracePeriod has passed, when UserPurger erases the account for good.
 type UserDeleteHandler struct {
-	repository UserRepository
-	logger     *log.Logger
+	repository  UserRepository
+	policy      *UserPolicy
+	gracePeriod time.Duration
+	logger      *log.Logger
 }
 
-func NewUserDeleteHandler(repository UserRepository, logger *log.Logger) *UserDeleteHandler {
+func NewUserDeleteHandler(repository UserRepository, policy *UserPolicy, gracePeriod time.Duration, logger *log.Logger) *UserDeleteHandler {
 	return &UserDeleteHandler{
-		repository: repository,
-		logger:     logger,
+		repository:  repository,
+		policy:      policy,
+		gracePeriod: gracePeriod,
+		logger:      logger,
 	}
 }
 
 // @Summary Delete a user by ID
-// @Description Deletes the specified user if they exist and can be deleted.
+// @Description Schedules the user for deletion after the grace period. Until then the account is locked and an admin can restore it; afterwards everything the user owns is erased and a deletion receipt is kept.
 // @Tags users
 // @Param id path string true "User ID"
-// @Success 204 {string} string "No Content"
+// @Produce json
+// @Success 202 {object} UserDeletionApiDto "When the account will be erased"
 // @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 403 {object} map[string]string "Not allowed to delete this user, or impersonating"
 // @Failure 404 {object} map[string]string "User not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /users/{id} [delete]
@@ -44,6 +69,10 @@ func (handler UserDeleteHandler) DeleteUser(ctx *gin.Context) {
 		return
 	}
 
+	handler.deleteUser(ctx, userID)
+}
+
+func (handler UserDeleteHandler) deleteUser(ctx *gin.Context, userID uuid.UUID) {
 	UserDeleteCommand := UserDeleteCommand{
 		ID: userID,
 	}
@@ -58,22 +87,122 @@ func (handler UserDeleteHandler) DeleteUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
 		return
 	}
-	err = user.CanDelete()
+	authUser := authentication.GetAuthUser(ctx)
+	err = handler.policy.CanDelete(authUser, user)
 	if err != nil {
-		handler.logger.Printf("ERROR: userCanDelete: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		handler.logger.Printf("ERROR: policyCanDelete: %v", err)
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	if !user.IsPendingDeletion() {
+		user.ScheduleDeletion(authUser.ID, handler.gracePeriod)
+		err = handler.repository.ScheduleUserDeletion(ctx.Request.Context(), user)
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryScheduleUserDeletion: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusAccepted, UserDeletionApiDto{DeletionScheduledAt: *user.DeletionScheduledAt})
+}
+
+// @Summary Restore a user pending deletion
+// @Description Cancels the scheduled deletion of a user during the grace period. The user signs in again afterwards, since their sessions were ended. Admins only.
+// @Tags users
+// @Param id path string true "User ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 409 {object} map[string]string "User is not pending deletion"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/{id}/restore [post]
+func (handler UserDeleteHandler) RestoreUser(ctx *gin.Context) {
+	userID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid User ID"})
 		return
 	}
 
-	err = handler.repository.DeleteUser(ctx.Request.Context(), user)
-	if err == sql.ErrNoRows {
+	user, err := handler.repository.FindUserByID(ctx.Request.Context(), userID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryGetUserByID: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if user == nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
 		return
 	}
+
+	err = handler.policy.CanRestore(authentication.GetAuthUser(ctx), user)
 	if err != nil {
-		handler.logger.Printf("ERROR repositoryDeleteUser: %v", err)
+		handler.logger.Printf("ERROR: policyCanRestore: %v", err)
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+	if !user.IsPendingDeletion() {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "User Is Not Pending Deletion"})
+		return
+	}
+
+	user.CancelDeletion()
+	err = handler.repository.CancelUserDeletion(ctx.Request.Context(), user)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "User Is Not Pending Deletion"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryCancelUserDeletion: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
 	}
 	ctx.Writer.WriteHeader(http.StatusNoContent)
 }
+
+// @Summary Get the deletion receipt of a purged user
+// @Description Returns the receipt kept when a user was erased: who asked for the deletion, when, how many rows were deleted from each table, and how many were kept with the user removed from them, such as impersonations they carried out as an admin. Admins only.
+// @Tags users
+// @Param id path string true "User ID"
+// @Produce json
+// @Success 200 {object} DeletionReceiptApiDto "Deletion receipt"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "User has not been erased"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/{id}/deletion-receipt [get]
+func (handler UserDeleteHandler) GetDeletionReceipt(ctx *gin.Context) {
+	userID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid User ID"})
+		return
+	}
+
+	receipt, err := handler.repository.FindDeletionReceipt(ctx.Request.Context(), userID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryFindDeletionReceipt: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if receipt == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+
+	receiptApiDto := DeletionReceiptApiDto{
+		ID:                receipt.ID,
+		UserID:            receipt.UserID,
+		RequestedAt:       receipt.RequestedAt,
+		PurgedRows:        receipt.PurgedRows,
+		PseudonymisedRows: receipt.PseudonymisedRows,
+		PurgedAt:          receipt.PurgedAt,
+	}
+	if receipt.RequestedBy != uuid.Nil {
+		receiptApiDto.RequestedBy = &receipt.RequestedBy
+	}
+	ctx.JSON(http.StatusOK, receiptApiDto)
+}
@@ -6,14 +6,37 @@ import (
 	"github.com/google/uuid"
 )
 
+// Account statuses users can be filtered by.
+const (
+	StatusActive          = "active"
+	StatusDeactivated     = "deactivated"
+	StatusPendingDeletion = "pending_deletion"
+)
+
 type User struct {
 	ID           uuid.UUID
 	Email        string
 	FirstName    string
 	LastName     string
 	MobileNumber string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// ExternalID is the identity provider's own ID for a user provisioned over SCIM.
+	ExternalID string
+	// Provisioned is set for users an identity provider created over SCIM,
+	// the only users SCIM can see or change.
+	Provisioned bool
+	// DeactivatedAt is set while the account is deactivated. A deactivated
+	// user cannot sign in, but nothing they own is deleted.
+	DeactivatedAt *time.Time
+	// DeletionScheduledAt is set once deletion has been requested, by the
+	// user or an admin. The account is locked like a deactivated one until
+	// then, and can still be restored; afterwards everything the user owns is
+	// purged.
+	DeletionScheduledAt *time.Time
+	DeletionRequestedAt *time.Time
+	// DeletionRequestedBy is the user who asked for the deletion.
+	DeletionRequestedBy uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
 }
 
 func Create(email string, firstName string, lastName string) *User {
@@ -26,26 +49,77 @@ func Create(email string, firstName string, lastName string) *User {
 	return user
 }
 
+// Update changes the profile fields. Callers check UserPolicy.CanUpdate first.
 func (usr *User) Update(email string, firstName string, lastName string, mobile string) (*User, error) {
-	err := usr.CanUpdate()
-	if err != nil {
-		return nil, err
-	}
-
 	usr.Email = email
 	usr.FirstName = firstName
 	usr.LastName = lastName
 	usr.MobileNumber = mobile
-	// need to additional update for password
 	return usr, nil
 }
 
-func (usr *User) CanUpdate() error {
-	// add in validation
-	return nil
+func (usr *User) IsActive() bool {
+	return usr.DeactivatedAt == nil
+}
+
+func (usr *User) IsPendingDeletion() bool {
+	return usr.DeletionScheduledAt != nil
+}
+
+func (usr *User) Status() string {
+	if usr.IsPendingDeletion() {
+		return StatusPendingDeletion
+	}
+	if usr.IsActive() {
+		return StatusActive
+	}
+	return StatusDeactivated
+}
+
+// SetActive deactivates or reactivates the account. A deactivated account
+// keeps the time it was first deactivated.
+func (usr *User) SetActive(active bool) {
+	if active {
+		usr.DeactivatedAt = nil
+		return
+	}
+	if usr.DeactivatedAt == nil {
+		now := time.Now()
+		usr.DeactivatedAt = &now
+	}
+}
+
+// ScheduleDeletion marks the account for deletion once gracePeriod has
+// passed. Callers check UserPolicy.CanDelete first. Scheduling an account that
+// is already pending deletion keeps the original schedule.
+func (usr *User) ScheduleDeletion(requestedBy uuid.UUID, gracePeriod time.Duration) {
+	if usr.IsPendingDeletion() {
+		return
+	}
+	now := time.Now()
+	scheduledAt := now.Add(gracePeriod)
+	usr.DeletionRequestedAt = &now
+	usr.DeletionRequestedBy = requestedBy
+	usr.DeletionScheduledAt = &scheduledAt
+}
+
+// CancelDeletion restores an account that is pending deletion.
+func (usr *User) CancelDeletion() {
+	usr.DeletionScheduledAt = nil
+	usr.DeletionRequestedAt = nil
+	usr.DeletionRequestedBy = uuid.Nil
 }
 
-func (usr *User) CanDelete() error {
-	// add in can delete validation
-	return nil
+// DeletionReceipt records that a user was purged. It keeps nothing personal,
+// only who asked for the deletion, when, and how many rows each table lost.
+// PseudonymisedRows counts the rows that were kept for other users' sake but
+// no longer point to the user.
+type DeletionReceipt struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	RequestedBy       uuid.UUID
+	RequestedAt       time.Time
+	PurgedRows        map[string]int64
+	PseudonymisedRows map[string]int64
+	PurgedAt          time.Time
 }
@@ -0,0 +1,259 @@
+package user
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"catalyst.api/internal/common/dtos"
+	"catalyst.api/internal/common/pagination"
+	"catalyst.api/internal/domain/user/data"
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// userSortColumns maps the sort query parameter onto the keys SearchUsers orders by.
+var userSortColumns = map[string]string{
+	"name":      "name",
+	"email":     "email",
+	"createdAt": "created_at",
+}
+
+type UserListQuery struct {
+	Search   *string
+	Role     *string
+	Status   *string
+	SortBy   string
+	SortDesc bool
+	Page     int
+	PageSize int
+}
+
+type UserListQueryApiDto struct {
+	Search string `form:"search" validate:"max=100"`
+	Role   string `form:"role" validate:"max=50"`
+	Status string `form:"status" validate:"omitempty,oneof=active deactivated pending_deletion"`
+	Sort   string `form:"sort" validate:"omitempty,oneof=name email createdAt"`
+	Order  string `form:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+func (dto *UserListQueryApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
+type UserListItemApiDto struct {
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email"`
+	FirstName    string    `json:"firstName"`
+	LastName     string    `json:"lastName"`
+	MobileNumber *string   `json:"mobileNumber"`
+	Roles        []string  `json:"roles"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type UserListHandler struct {
+	queries *data.Queries
+	cursors *pagination.CursorCodec
+	logger  *log.Logger
+}
+
+func NewUserListHandler(queries *data.Queries, cursors *pagination.CursorCodec, logger *log.Logger) *UserListHandler {
+	return &UserListHandler{
+		queries: queries,
+		cursors: cursors,
+		logger:  logger,
+	}
+}
+
+// @Summary List users
+// @Description Lists users a page at a time. search matches any part of the name or email, case-insensitively. Pages are numbered by default. Pass cursor, left empty for the first page, to page by cursor instead: the response then carries next and prev cursors in place of page numbers and totals, and pages stay stable while users are added or removed. Either way the Link header points at the neighbouring pages. Admins only.
+// @Tags users
+// @Produce json
+// @Param page query int false "1-based page number" default(1)
+// @Param cursor query string false "Cursor from a previous response's next or prev, or empty for the first page"
+// @Param pageSize query int false "Users per page, at most 100" default(20)
+// @Param sort query string false "Sort key" Enums(name, email, createdAt) default(createdAt)
+// @Param order query string false "Sort order" Enums(asc, desc) default(desc)
+// @Param search query string false "Text to find in the name or email"
+// @Param role query string false "Only users with this role, e.g. admin"
+// @Param status query string false "Only users with this account status" Enums(active, deactivated, pending_deletion)
+// @Success 200 {object} dtos.PaginatedListDto{items=[]UserListItemApiDto} "Users. Paging by cursor returns items, pageSize, next and prev instead"
+// @Header 200 {string} Link "Links to the neighbouring pages"
+// @Failure 400 {object} map[string]string "Invalid query or cursor"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Insufficient scope"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user [get]
+func (handler UserListHandler) ListUsers(ctx *gin.Context) {
+	page, pageSize, err := utilities.ReadPageParams(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readPageParams: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	var queryApiDto UserListQueryApiDto
+	err = ctx.ShouldBindQuery(&queryApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: bindUserListQueryApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = queryApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateUserListQueryApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	query := UserListQuery{
+		Search:   nullableString(escapeLikePattern(strings.TrimSpace(queryApiDto.Search))),
+		Role:     nullableString(queryApiDto.Role),
+		Status:   nullableString(queryApiDto.Status),
+		SortBy:   userSortColumns[cmp.Or(queryApiDto.Sort, "createdAt")],
+		SortDesc: cmp.Or(queryApiDto.Order, "desc") == "desc",
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	if pagination.IsCursorRequest(ctx) {
+		handler.listUsersByCursor(ctx, query)
+		return
+	}
+	handler.listUsersByPage(ctx, query)
+}
+
+func (handler UserListHandler) listUsersByPage(ctx *gin.Context, query UserListQuery) {
+	totalCount, err := handler.queries.CountSearchUsers(ctx.Request.Context(), data.CountSearchUsersParams{
+		Search: query.Search,
+		Role:   query.Role,
+		Status: query.Status,
+	})
+	if err != nil {
+		handler.logger.Printf("ERROR: queriesCountSearchUsers: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	paginatedList := dtos.NewPaginatedListDto(query.Page, query.PageSize, int(totalCount))
+
+	rows, err := handler.queries.SearchUsers(ctx.Request.Context(), data.SearchUsersParams{
+		Search:     query.Search,
+		Role:       query.Role,
+		Status:     query.Status,
+		SortBy:     query.SortBy,
+		SortDesc:   query.SortDesc,
+		PageLimit:  int32(paginatedList.PageSize),
+		PageOffset: int32(paginatedList.Offset()),
+	})
+	if err != nil {
+		handler.logger.Printf("ERROR: queriesSearchUsers: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	pagination.SetPageLinks(ctx, paginatedList)
+	ctx.JSON(http.StatusOK, dtos.NewPaginatedResponseDto(newUserListItemApiDtos(rows), paginatedList))
+}
+
+func (handler UserListHandler) listUsersByCursor(ctx *gin.Context, query UserListQuery) {
+	sort := fmt.Sprintf("%s:%t", query.SortBy, query.SortDesc)
+	filter := pagination.Fingerprint(stringValue(query.Search), stringValue(query.Role), stringValue(query.Status))
+	request, err := pagination.ReadCursorRequest(ctx, handler.cursors, sort, filter, query.PageSize)
+	if err != nil {
+		handler.logger.Printf("ERROR: readCursorRequest: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Cursor"})
+		return
+	}
+
+	params := data.SearchUsersParams{
+		Search:    query.Search,
+		Role:      query.Role,
+		Status:    query.Status,
+		SortBy:    query.SortBy,
+		SortDesc:  request.QueryDesc(query.SortDesc),
+		PageLimit: int32(request.Limit()),
+	}
+	if request.Cursor != nil {
+		params.CursorID = pgtype.UUID{Bytes: request.Cursor.ID, Valid: true}
+		if query.SortBy == "created_at" {
+			createdAt, err := time.Parse(time.RFC3339Nano, request.Cursor.Key)
+			if err != nil {
+				handler.logger.Printf("ERROR: parseCursorKey: %v", err)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Cursor"})
+				return
+			}
+			params.CursorTime = pgtype.Timestamptz{Time: createdAt, Valid: true}
+		} else {
+			params.CursorText = &request.Cursor.Key
+		}
+	}
+
+	rows, err := handler.queries.SearchUsers(ctx.Request.Context(), params)
+	if err != nil {
+		handler.logger.Printf("ERROR: queriesSearchUsers: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	page, err := pagination.NewCursorPage(handler.cursors, request, rows, func(row data.SearchUsersRow) (string, uuid.UUID) {
+		return userSortKey(query.SortBy, row), row.ID
+	})
+	if err != nil {
+		handler.logger.Printf("ERROR: newCursorPage: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	pagination.SetCursorLinks(ctx, page.Next, page.Prev)
+	ctx.JSON(http.StatusOK, dtos.NewCursorResponseDto(newUserListItemApiDtos(page.Items), page.Dto(query.PageSize)))
+}
+
+// userSortKey is the value SearchUsers compares a cursor against. Text keys
+// are lowercased by the query itself.
+func userSortKey(sortBy string, row data.SearchUsersRow) string {
+	switch sortBy {
+	case "name":
+		return row.LastName + " " + row.FirstName
+	case "email":
+		return row.Email
+	}
+	return row.CreatedAt.Time.Format(time.RFC3339Nano)
+}
+
+func newUserListItemApiDtos(rows []data.SearchUsersRow) []UserListItemApiDto {
+	userApiDtos := make([]UserListItemApiDto, 0, len(rows))
+	for _, row := range rows {
+		userApiDtos = append(userApiDtos, newUserListItemApiDto(row))
+	}
+	return userApiDtos
+}
+
+func newUserListItemApiDto(row data.SearchUsersRow) UserListItemApiDto {
+	user := User{DeactivatedAt: timePointer(row.DeactivatedAt), DeletionScheduledAt: timePointer(row.DeletionScheduledAt)}
+	return UserListItemApiDto{
+		ID:           row.ID,
+		Email:        row.Email,
+		FirstName:    row.FirstName,
+		LastName:     row.LastName,
+		MobileNumber: row.MobileNumber,
+		Roles:        row.Roles,
+		Status:       user.Status(),
+		CreatedAt:    row.CreatedAt.Time,
+	}
+}
+
+// escapeLikePattern makes % and _ in a search term match themselves.
+func escapeLikePattern(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(term)
+}
@@ -0,0 +1,155 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/domain/user/data"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UserProfileApiDto struct {
+	ID           uuid.UUID                   `json:"id"`
+	Email        string                      `json:"email"`
+	PendingEmail *string                     `json:"pendingEmail"`
+	FirstName    string                      `json:"firstName"`
+	LastName     string                      `json:"lastName"`
+	MobileNumber *string                     `json:"mobileNumber"`
+	Status       string                      `json:"status"`
+	Roles        []string                    `json:"roles"`
+	HasPassword  bool                        `json:"hasPassword"`
+	Providers    []UserProfileProviderApiDto `json:"providers"`
+	CreatedAt    time.Time                   `json:"createdAt"`
+	UpdatedAt    time.Time                   `json:"updatedAt"`
+	LastLoginAt  *time.Time                  `json:"lastLoginAt"`
+}
+
+type UserProfileProviderApiDto struct {
+	Provider string    `json:"provider"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+// UserMeHandler serves the signed-in user's own account at /user/me. Updates
+// and deletes go through the same handlers, and policy, as /user/:id.
+type UserMeHandler struct {
+	queries       *data.Queries
+	updateHandler *UserUpdateHandler
+	deleteHandler *UserDeleteHandler
+	logger        *log.Logger
+}
+
+func NewUserMeHandler(queries *data.Queries, updateHandler *UserUpdateHandler, deleteHandler *UserDeleteHandler, logger *log.Logger) *UserMeHandler {
+	return &UserMeHandler{
+		queries:       queries,
+		updateHandler: updateHandler,
+		deleteHandler: deleteHandler,
+		logger:        logger,
+	}
+}
+
+// @Summary Get the signed-in user's profile
+// @Description Returns the signed-in user's profile with their roles, linked sign-in providers, creation time and last sign-in. While impersonating this is the impersonated user.
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]UserProfileApiDto "User profile"
+// @Failure 401 {object} map[string]string "Not signed in"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/me [get]
+func (handler UserMeHandler) GetMe(ctx *gin.Context) {
+	handler.respondProfile(ctx, authentication.GetAuthUser(ctx).ID)
+}
+
+// @Summary Update the signed-in user's profile
+// @Description Updates the name and mobile number of the signed-in user and returns their profile. A new email is not applied straight away: a confirmation link is mailed to it, and the profile's pendingEmail holds it until the link is followed. Changing the email with a personal access token needs the auth scope. Not allowed while impersonating.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body UserUpdateApiDto true "User update payload"
+// @Success 200 {object} map[string]UserProfileApiDto "Updated user profile"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Not signed in"
+// @Failure 403 {object} map[string]string "Impersonating, or changing the email without the auth scope"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 409 {object} map[string]string "Email already in use"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/me [put]
+func (handler UserMeHandler) UpdateMe(ctx *gin.Context) {
+	user, _, ok := handler.updateHandler.updateUser(ctx, authentication.GetAuthUser(ctx).ID)
+	if !ok {
+		return
+	}
+
+	handler.respondProfile(ctx, user.ID)
+}
+
+// @Summary Delete the signed-in user's account
+// @Description Schedules the signed-in user's account for deletion after the grace period, ending every session. Not allowed while impersonating.
+// @Tags users
+// @Produce json
+// @Success 202 {object} UserDeletionApiDto "When the account will be erased"
+// @Failure 401 {object} map[string]string "Not signed in"
+// @Failure 403 {object} map[string]string "Impersonating"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/me [delete]
+func (handler UserMeHandler) DeleteMe(ctx *gin.Context) {
+	handler.deleteHandler.deleteUser(ctx, authentication.GetAuthUser(ctx).ID)
+}
+
+func (handler UserMeHandler) respondProfile(ctx *gin.Context, userID uuid.UUID) {
+	profileApiDto, err := handler.loadProfile(ctx.Request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: loadProfile: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"User": profileApiDto})
+}
+
+func (handler UserMeHandler) loadProfile(ctx context.Context, userID uuid.UUID) (*UserProfileApiDto, error) {
+	profile, err := handler.queries.GetUserProfileByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := handler.queries.ListUserProviders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user := User{DeactivatedAt: timePointer(profile.DeactivatedAt), DeletionScheduledAt: timePointer(profile.DeletionScheduledAt)}
+	profileApiDto := &UserProfileApiDto{
+		ID:           profile.ID,
+		Email:        profile.Email,
+		PendingEmail: profile.PendingEmail,
+		FirstName:    profile.FirstName,
+		LastName:     profile.LastName,
+		MobileNumber: profile.MobileNumber,
+		Status:       user.Status(),
+		Roles:        profile.Roles,
+		HasPassword:  profile.HasPassword,
+		Providers:    make([]UserProfileProviderApiDto, 0, len(providers)),
+		CreatedAt:    profile.CreatedAt.Time,
+		UpdatedAt:    profile.UpdatedAt.Time,
+		LastLoginAt:  timePointer(profile.LastLoginAt),
+	}
+	for _, provider := range providers {
+		profileApiDto.Providers = append(profileApiDto.Providers, UserProfileProviderApiDto{
+			Provider: provider.Provider,
+			LinkedAt: provider.CreatedAt.Time,
+		})
+	}
+	return profileApiDto, nil
+}
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"errors"
+
+	"catalyst.api/internal/authentication"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// UserPolicy decides what the acting AuthUser may do to a User. Users may
+// manage their own account and admins may manage any account.
+type UserPolicy struct{}
+
+func NewUserPolicy() *UserPolicy {
+	return &UserPolicy{}
+}
+
+func (policy *UserPolicy) CanUpdate(actor *authentication.AuthUser, target *User) error {
+	return policy.ownerOrAdmin(actor, target)
+}
+
+func (policy *UserPolicy) CanDelete(actor *authentication.AuthUser, target *User) error {
+	return policy.ownerOrAdmin(actor, target)
+}
+
+// CanRestore only lets admins restore an account pending deletion, since its
+// owner can no longer sign in.
+func (policy *UserPolicy) CanRestore(actor *authentication.AuthUser, target *User) error {
+	if actor.IsAdmin() {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanManageRoles only lets admins grant and revoke roles.
+func (policy *UserPolicy) CanManageRoles(actor *authentication.AuthUser, target *User) error {
+	if actor.IsAdmin() {
+		return nil
+	}
+	return ErrForbidden
+}
+
+func (policy *UserPolicy) ownerOrAdmin(actor *authentication.AuthUser, target *User) error {
+	if actor.IsAnonymous() {
+		return ErrForbidden
+	}
+	if actor.ID == target.ID || actor.IsAdmin() {
+		return nil
+	}
+	return ErrForbidden
+}
@@ -0,0 +1,83 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	userPurgeInterval = time.Hour
+	userPurgeBatch    = 100
+)
+
+// UserPurger erases accounts whose deletion grace period has passed, along
+// with expired data export archives and email changes. Each user is purged in a transaction of
+// its own, so one failure does not hold up the others, and several instances
+// can run side by side.
+type UserPurger struct {
+	repository UserRepository
+	logger     *log.Logger
+}
+
+func NewUserPurger(repository UserRepository, logger *log.Logger) *UserPurger {
+	return &UserPurger{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// Run purges due users now and then every hour, for as long as the process
+// runs.
+func (purger *UserPurger) Run() {
+	purger.purge(context.Background())
+
+	ticker := time.NewTicker(userPurgeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		purger.purge(context.Background())
+	}
+}
+
+func (purger *UserPurger) purge(ctx context.Context) {
+	purger.PurgeDueUsers(ctx)
+
+	err := purger.repository.CleanUpDataExports(ctx, time.Now().Add(-dataExportTimeout))
+	if err != nil {
+		purger.logger.Printf("ERROR: repositoryCleanUpDataExports: %v", err)
+	}
+
+	err = purger.repository.DeleteExpiredEmailChanges(ctx)
+	if err != nil {
+		purger.logger.Printf("ERROR: repositoryDeleteExpiredEmailChanges: %v", err)
+	}
+}
+
+func (purger *UserPurger) PurgeDueUsers(ctx context.Context) {
+	for {
+		userIDs, err := purger.repository.ListUsersDueForDeletion(ctx, time.Now(), userPurgeBatch)
+		if err != nil {
+			purger.logger.Printf("ERROR: repositoryListUsersDueForDeletion: %v", err)
+			return
+		}
+
+		purged := 0
+		for _, userID := range userIDs {
+			receipt, err := purger.repository.PurgeUser(ctx, userID)
+			if err != nil {
+				purger.logger.Printf("ERROR: repositoryPurgeUser %s: %v", userID, err)
+				continue
+			}
+			if receipt != nil {
+				purged++
+				purger.logger.Printf("INFO: purged user %s, deletion receipt %s", userID, receipt.ID)
+			}
+		}
+
+		// stop once a batch is short, or when nothing in it could be purged so
+		// the same failing users are not retried until the next run
+		if len(userIDs) < userPurgeBatch || purged == 0 {
+			return
+		}
+	}
+}
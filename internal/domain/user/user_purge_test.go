@@ -0,0 +1,79 @@
+package user
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+
+	"catalyst.api/migrations"
+)
+
+var (
+	migrationTablePattern     = regexp.MustCompile(`(?i)^(?:CREATE TABLE IF NOT EXISTS|ALTER TABLE)\s+(\w+)`)
+	migrationReferencePattern = regexp.MustCompile(`(?i)REFERENCES\s+(\w+)\s*\(`)
+)
+
+// migrationForeignKeys reads the foreign keys the up migrations create, as a
+// list of referencing table and referenced table. Keys with an ON DELETE
+// action are left out, as Postgres takes care of those rows itself.
+func migrationForeignKeys(t *testing.T) [][2]string {
+	t.Helper()
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foreignKeys := [][2]string{}
+	for _, file := range files {
+		contents, err := fs.ReadFile(migrations.FS, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(contents), "-- +goose Down")
+
+		table := ""
+		for _, line := range strings.Split(up, "\n") {
+			if match := migrationTablePattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+				table = match[1]
+			}
+			match := migrationReferencePattern.FindStringSubmatch(line)
+			if match == nil || strings.Contains(strings.ToUpper(line), "ON DELETE") {
+				continue
+			}
+			foreignKeys = append(foreignKeys, [2]string{table, match[1]})
+		}
+	}
+	return foreignKeys
+}
+
+// Purging fails on any foreign key to a purged row that is not purged first,
+// so every table that points at one needs a step of its own, ahead of the
+// table it points at.
+func TestUserPurgeStepsCoverEveryForeignKey(t *testing.T) {
+	stepIndex := map[string]int{}
+	for index, step := range userPurgeSteps {
+		stepIndex[step.table] = index
+	}
+
+	foreignKeys := migrationForeignKeys(t)
+	if len(foreignKeys) == 0 {
+		t.Fatal("found no foreign keys in the migrations")
+	}
+	for _, foreignKey := range foreignKeys {
+		table, referenced := foreignKey[0], foreignKey[1]
+		referencedIndex, purged := stepIndex[referenced]
+		if !purged || table == referenced {
+			continue
+		}
+
+		tableIndex, ok := stepIndex[table]
+		if !ok {
+			t.Errorf("%s references %s but is not purged", table, referenced)
+			continue
+		}
+		if tableIndex > referencedIndex {
+			t.Errorf("%s is purged after %s, which it references", table, referenced)
+		}
+	}
+}
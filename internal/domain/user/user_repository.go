@@ -3,21 +3,89 @@ package user
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"catalyst.api/internal/authentication"
 	"catalyst.api/internal/domain/user/data"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	_ "golang.org/x/crypto/bcrypt"
 )
 
 type UserRepository interface {
 	FindUserByID(ctx context.Context, id uuid.UUID) (*User, error)
 	RegisterUser(ctx context.Context, cmp *User) (uuid.UUID, error)
 	UpdateUser(ctx context.Context, cmp *User) (*User, error)
-	DeleteUser(ctx context.Context, cmp *User) error
+	RequestEmailChange(ctx context.Context, emailChange *EmailChange) error
+	ConfirmEmailChange(ctx context.Context, tokenHash string) error
+	DeleteExpiredEmailChanges(ctx context.Context) error
+	ListUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	GrantUserRole(ctx context.Context, userID uuid.UUID, role string) error
+	RevokeUserRole(ctx context.Context, userID uuid.UUID, role string) error
+	BootstrapAdmin(ctx context.Context, email string) (bool, error)
+	ScheduleUserDeletion(ctx context.Context, user *User) error
+	CancelUserDeletion(ctx context.Context, user *User) error
+	ListUsersDueForDeletion(ctx context.Context, dueBefore time.Time, limit int) ([]uuid.UUID, error)
+	PurgeUser(ctx context.Context, id uuid.UUID) (*DeletionReceipt, error)
+	FindDeletionReceipt(ctx context.Context, userID uuid.UUID) (*DeletionReceipt, error)
+	CreateDataExport(ctx context.Context, userID uuid.UUID) (*DataExport, error)
+	FindDataExport(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*DataExport, error)
+	FindActiveDataExport(ctx context.Context, userID uuid.UUID, startedAfter time.Time) (*DataExport, error)
+	StartDataExport(ctx context.Context, id uuid.UUID) (bool, error)
+	CompleteDataExport(ctx context.Context, id uuid.UUID, archive []byte, expiresAt time.Time) error
+	FailDataExport(ctx context.Context, id uuid.UUID, reason string) error
+	FindDataExportArchive(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]byte, error)
+	FailStaleDataExports(ctx context.Context, startedBefore time.Time) error
+	CleanUpDataExports(ctx context.Context, startedBefore time.Time) error
+	ListUsers(ctx context.Context, filter UserFilter, offset int, limit int) ([]*User, int, error)
+	ProvisionUser(ctx context.Context, user *User) (uuid.UUID, error)
+	UpdateUserAccount(ctx context.Context, user *User) error
+	ListGroups(ctx context.Context, filter GroupFilter, offset int, limit int) ([]*Group, int, error)
+	FindGroupByID(ctx context.Context, id uuid.UUID) (*Group, error)
+	CreateGroup(ctx context.Context, group *Group) (uuid.UUID, error)
+	UpdateGroup(ctx context.Context, group *Group) error
+	DeleteGroup(ctx context.Context, id uuid.UUID) error
 }
 
+var (
+	ErrEmailTaken     = errors.New("email is already in use")
+	ErrGroupNameTaken = errors.New("group name is already in use")
+	// ErrDataExportPending is returned when the user already has an export
+	// pending or running.
+	ErrDataExportPending = errors.New("a data export is already pending")
+	// ErrGroupMemberNotFound is returned when a group is given a member that
+	// is not a user.
+	ErrGroupMemberNotFound = errors.New("group member is not a user")
+	// ErrLastAdmin is returned when revoking the admin role would leave no
+	// admins.
+	ErrLastAdmin = errors.New("cannot revoke the last admin")
+)
+
+// UserFilter narrows ListUsers. Nil fields match every user.
+type UserFilter struct {
+	Email       *string
+	ExternalID  *string
+	Active      *bool
+	Provisioned *bool
+}
+
+// GroupFilter narrows ListGroups. Nil fields match every group.
+type GroupFilter struct {
+	DisplayName *string
+	ExternalID  *string
+}
+
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
 type UserSqlRepository struct {
 	queries *data.Queries
 	db      *pgxpool.Pool
@@ -34,7 +102,7 @@ func NewUserSqlRepository(db *pgxpool.Pool) *UserSqlRepository {
 func (repository *UserSqlRepository) FindUserByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	userData, err := repository.queries.FindUserByID(ctx, id)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
@@ -42,13 +110,19 @@ func (repository *UserSqlRepository) FindUserByID(ctx context.Context, id uuid.U
 	}
 
 	user := &User{
-		ID:           userData.ID,
-		Email:        userData.Email,
-		FirstName:    userData.FirstName,
-		LastName:     userData.LastName,
-		MobileNumber: *userData.MobileNumber,
-		CreatedAt:    userData.CreatedAt.Time,
-		UpdatedAt:    userData.UpdatedAt.Time,
+		ID:                  userData.ID,
+		Email:               userData.Email,
+		FirstName:           userData.FirstName,
+		LastName:            userData.LastName,
+		MobileNumber:        stringValue(userData.MobileNumber),
+		ExternalID:          stringValue(userData.ExternalID),
+		Provisioned:         userData.Provisioned,
+		DeactivatedAt:       timePointer(userData.DeactivatedAt),
+		DeletionScheduledAt: timePointer(userData.DeletionScheduledAt),
+		DeletionRequestedAt: timePointer(userData.DeletionRequestedAt),
+		DeletionRequestedBy: uuidValue(userData.DeletionRequestedBy),
+		CreatedAt:           userData.CreatedAt.Time,
+		UpdatedAt:           userData.UpdatedAt.Time,
 	}
 
 	return user, nil
@@ -68,7 +142,44 @@ func (repository *UserSqlRepository) RegisterUser(ctx context.Context, user *Use
 	return userResult.ID, err
 }
 
+// UpdateUser saves the profile together with the sign-in account, so the two
+// keep the same email. Users change their email through RequestEmailChange
+// instead. It returns ErrEmailTaken when another account already uses the
+// email, in any letter case.
 func (repository *UserSqlRepository) UpdateUser(ctx context.Context, user *User) (*User, error) {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	emailTaken, err := queries.IsEmailTaken(ctx, data.IsEmailTakenParams{Email: user.Email, ID: user.ID})
+	if err != nil {
+		return nil, err
+	}
+	if emailTaken {
+		return nil, ErrEmailTaken
+	}
+
+	result, err := queries.UpdateAuthUser(ctx, data.UpdateAuthUserParams{
+		Email:      user.Email,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		ExternalID: nullableString(user.ExternalID),
+		ID:         user.ID,
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, sql.ErrNoRows
+	}
+
 	updateUserParams := data.UpdateUserParams{
 		Email:        user.Email,
 		FirstName:    user.FirstName,
@@ -77,7 +188,10 @@ func (repository *UserSqlRepository) UpdateUser(ctx context.Context, user *User)
 		ID:           user.ID,
 	}
 
-	result, err := repository.queries.UpdateUser(ctx, updateUserParams)
+	result, err = queries.UpdateUser(ctx, updateUserParams)
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return nil, ErrEmailTaken
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -86,18 +200,841 @@ func (repository *UserSqlRepository) UpdateUser(ctx context.Context, user *User)
 	if rowsAffected == 0 {
 		return nil, sql.ErrNoRows
 	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
-func (repository *UserSqlRepository) DeleteUser(ctx context.Context, user *User) error {
-	result, err := repository.queries.DeleteUser(ctx, user.ID)
+// RequestEmailChange stores a new email waiting to be confirmed, replacing
+// any earlier one for the user. It returns ErrEmailTaken when another account
+// already uses the email.
+func (repository *UserSqlRepository) RequestEmailChange(ctx context.Context, emailChange *EmailChange) error {
+	tx, err := repository.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	emailTaken, err := queries.IsEmailTaken(ctx, data.IsEmailTakenParams{Email: emailChange.NewEmail, ID: emailChange.UserID})
+	if err != nil {
+		return err
+	}
+	if emailTaken {
+		return ErrEmailTaken
+	}
+
+	err = queries.DeleteEmailChangesForUser(ctx, emailChange.UserID)
+	if err != nil {
+		return err
+	}
+	emailChange.ID, err = queries.CreateEmailChange(ctx, data.CreateEmailChangeParams{
+		UserID:    emailChange.UserID,
+		NewEmail:  emailChange.NewEmail,
+		TokenHash: emailChange.TokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: emailChange.ExpiresAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ConfirmEmailChange consumes the email change with the token hash and moves
+// the account, and its sign-in, to the new email. It returns
+// ErrInvalidEmailChange for an unknown, used or expired token, and
+// ErrEmailTaken when another account took the email in the meantime.
+func (repository *UserSqlRepository) ConfirmEmailChange(ctx context.Context, tokenHash string) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	emailChange, err := queries.ConsumeEmailChange(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidEmailChange
+	}
+	if err != nil {
+		return err
+	}
+
+	emailTaken, err := queries.IsEmailTaken(ctx, data.IsEmailTakenParams{Email: emailChange.NewEmail, ID: emailChange.UserID})
+	if err != nil {
+		return err
+	}
+	if emailTaken {
+		return ErrEmailTaken
+	}
+
+	var pgErr *pgconn.PgError
+	result, err := queries.UpdateAuthUserEmail(ctx, data.UpdateAuthUserEmailParams{Email: emailChange.NewEmail, ID: emailChange.UserID})
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrInvalidEmailChange
+	}
+
+	_, err = queries.UpdateUserEmail(ctx, data.UpdateUserEmailParams{Email: emailChange.NewEmail, ID: emailChange.UserID})
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (repository *UserSqlRepository) DeleteExpiredEmailChanges(ctx context.Context) error {
+	return repository.queries.DeleteExpiredEmailChanges(ctx)
+}
+
+func (repository *UserSqlRepository) ListUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return repository.queries.ListUserRoles(ctx, userID)
+}
+
+// GrantUserRole gives the user a role. Granting a role the user already holds
+// does nothing.
+func (repository *UserSqlRepository) GrantUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	return repository.queries.GrantUserRole(ctx, data.GrantUserRoleParams{UserID: userID, Role: role})
+}
+
+// RevokeUserRole takes a role from the user and revokes their access tokens,
+// which carry the scopes of the role; refreshed tokens are issued without it.
+// It returns ErrLastAdmin instead of revoking the admin role from the only
+// admin.
+func (repository *UserSqlRepository) RevokeUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	// lock the holders so two admins cannot revoke each other at once
+	holders, err := queries.LockUsersWithRole(ctx, role)
+	if err != nil {
+		return err
+	}
+	if role == authentication.RoleAdmin && len(holders) == 1 && holders[0] == userID {
+		return ErrLastAdmin
+	}
+
+	result, err := queries.RevokeUserRole(ctx, data.RevokeUserRoleParams{UserID: userID, Role: role})
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	err = queries.RevokeAuthUserTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// BootstrapAdmin makes the user with email an admin if there are no admins
+// yet. It reports whether there is an admin afterwards, which is false until
+// a user with email signs up.
+func (repository *UserSqlRepository) BootstrapAdmin(ctx context.Context, email string) (bool, error) {
+	result, err := repository.queries.GrantFirstAdmin(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() > 0 {
+		return true, nil
+	}
+
+	admins, err := repository.queries.CountUsersWithRole(ctx, authentication.RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+	return admins > 0, nil
+}
+
+// ScheduleUserDeletion locks the account until user.DeletionScheduledAt and
+// revokes its sessions and refresh tokens. An account already pending deletion
+// keeps its schedule.
+func (repository *UserSqlRepository) ScheduleUserDeletion(ctx context.Context, user *User) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	result, err := queries.ScheduleAuthUserDeletion(ctx, data.ScheduleAuthUserDeletionParams{
+		DeletionRequestedBy: nullableUUID(user.DeletionRequestedBy),
+		DeletionScheduledAt: nullableTime(user.DeletionScheduledAt),
+		ID:                  user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	err = queries.RevokeUserSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	err = queries.RevokeUserRefreshTokens(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CancelUserDeletion restores an account pending deletion. It returns
+// sql.ErrNoRows when the account is not pending deletion.
+func (repository *UserSqlRepository) CancelUserDeletion(ctx context.Context, user *User) error {
+	result, err := repository.queries.CancelAuthUserDeletion(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
 		return sql.ErrNoRows
 	}
+	return nil
+}
 
+func (repository *UserSqlRepository) ListUsersDueForDeletion(ctx context.Context, dueBefore time.Time, limit int) ([]uuid.UUID, error) {
+	return repository.queries.ListUsersDueForDeletion(ctx, data.ListUsersDueForDeletionParams{
+		DueBefore: pgtype.Timestamptz{Time: dueBefore, Valid: true},
+		PageLimit: int32(limit),
+	})
+}
+
+// userPurgeSteps delete everything a user owns, rows that reference other
+// rows of the user first. Every table with a foreign key to auth_users needs a
+// step here, or purging fails on that key; invitations.invited_by,
+// scim_tokens.created_by and impersonations.actor_id are ON DELETE SET NULL and
+// need none. Invitations and magic links are found by email instead.
+var userPurgeSteps = []struct {
+	table string
+	purge func(*data.Queries, context.Context, uuid.UUID) (pgconn.CommandTag, error)
+}{
+	{"impersonation_requests", (*data.Queries).PurgeUserImpersonationRequests},
+	{"impersonations", (*data.Queries).PurgeUserImpersonations},
+	{"mfa_challenges", (*data.Queries).PurgeUserMFAChallenges},
+	{"refresh_tokens", (*data.Queries).PurgeUserRefreshTokens},
+	{"authorization_codes", (*data.Queries).PurgeUserAuthorizationCodes},
+	{"sessions", (*data.Queries).PurgeUserSessions},
+	{"revoked_tokens", (*data.Queries).PurgeUserRevokedTokens},
+	{"password_reset_tokens", (*data.Queries).PurgeUserPasswordResetTokens},
+	{"email_changes", (*data.Queries).PurgeUserEmailChanges},
+	{"personal_access_tokens", (*data.Queries).PurgeUserPersonalAccessTokens},
+	{"device_authorizations", (*data.Queries).PurgeUserDeviceAuthorizations},
+	{"totp_credentials", (*data.Queries).PurgeUserTOTPCredentials},
+	{"mfa_recovery_codes", (*data.Queries).PurgeUserRecoveryCodes},
+	{"user_roles", (*data.Queries).PurgeUserRoles},
+	{"group_members", (*data.Queries).PurgeUserGroupMemberships},
+	{"provider_tokens", (*data.Queries).PurgeUserProviderTokens},
+	{"auth_user_providers", (*data.Queries).PurgeUserProviders},
+	{"data_exports", (*data.Queries).PurgeUserDataExports},
+	{"invitations", (*data.Queries).PurgeUserInvitations},
+	{"magic_links", (*data.Queries).PurgeUserMagicLinks},
+	{"users", (*data.Queries).PurgeUserProfile},
+	{"auth_users", (*data.Queries).PurgeAuthUser},
+}
+
+// PurgeUser deletes everything the user owns in one transaction and keeps a
+// receipt of it. It returns nil when the user is no longer due for deletion,
+// because they were restored or another instance is purging them.
+func (repository *UserSqlRepository) PurgeUser(ctx context.Context, id uuid.UUID) (*DeletionReceipt, error) {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	dueUser, err := queries.LockUserDueForDeletion(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// impersonations the user carried out as an admin stay in the other
+	// user's audit log, naming the admin only by the email recorded then
+	pseudonymised, err := queries.PseudonymiseUserImpersonations(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("pseudonymise impersonations: %w", err)
+	}
+	pseudonymisedRows := map[string]int64{"impersonations": pseudonymised.RowsAffected()}
+
+	purgedRows := map[string]int64{}
+	for _, step := range userPurgeSteps {
+		result, err := step.purge(queries, ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("purge %s: %w", step.table, err)
+		}
+		purgedRows[step.table] = result.RowsAffected()
+	}
+
+	encodedRows, err := json.Marshal(purgedRows)
+	if err != nil {
+		return nil, err
+	}
+	encodedPseudonymisedRows, err := json.Marshal(pseudonymisedRows)
+	if err != nil {
+		return nil, err
+	}
+	receiptRow, err := queries.CreateDeletionReceipt(ctx, data.CreateDeletionReceiptParams{
+		UserID:            id,
+		RequestedBy:       dueUser.DeletionRequestedBy,
+		RequestedAt:       dueUser.DeletionRequestedAt,
+		PurgedRows:        encodedRows,
+		PseudonymisedRows: encodedPseudonymisedRows,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return deletionReceiptFromRow(receiptRow)
+}
+
+// FindDeletionReceipt returns nil when the user has not been purged.
+func (repository *UserSqlRepository) FindDeletionReceipt(ctx context.Context, userID uuid.UUID) (*DeletionReceipt, error) {
+	receiptRow, err := repository.queries.GetDeletionReceiptByUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deletionReceiptFromRow(receiptRow)
+}
+
+// CreateDataExport returns ErrDataExportPending if the user already has an
+// export pending or running, however stale.
+func (repository *UserSqlRepository) CreateDataExport(ctx context.Context, userID uuid.UUID) (*DataExport, error) {
+	created, err := repository.queries.CreateDataExport(ctx, userID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return nil, ErrDataExportPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DataExport{
+		ID:        created.ID,
+		UserID:    userID,
+		Status:    created.Status,
+		CreatedAt: created.CreatedAt.Time,
+	}, nil
+}
+
+// FindDataExport returns nil when the user has no export with that ID.
+func (repository *UserSqlRepository) FindDataExport(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*DataExport, error) {
+	exportRow, err := repository.queries.FindDataExport(ctx, data.FindDataExportParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dataExportFromRow(data.FindActiveDataExportRow(exportRow)), nil
+}
+
+// FindActiveDataExport returns the user's export that is still being built,
+// or nil. Exports started before startedAfter are left out, since they were
+// most likely interrupted.
+func (repository *UserSqlRepository) FindActiveDataExport(ctx context.Context, userID uuid.UUID, startedAfter time.Time) (*DataExport, error) {
+	exportRow, err := repository.queries.FindActiveDataExport(ctx, data.FindActiveDataExportParams{
+		UserID:       userID,
+		StartedAfter: pgtype.Timestamptz{Time: startedAfter, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dataExportFromRow(exportRow), nil
+}
+
+// StartDataExport marks a pending export as running. It reports false when the
+// export was already started.
+func (repository *UserSqlRepository) StartDataExport(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := repository.queries.StartDataExport(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (repository *UserSqlRepository) CompleteDataExport(ctx context.Context, id uuid.UUID, archive []byte, expiresAt time.Time) error {
+	return repository.queries.CompleteDataExport(ctx, data.CompleteDataExportParams{
+		Archive:   archive,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		ID:        id,
+	})
+}
+
+func (repository *UserSqlRepository) FailDataExport(ctx context.Context, id uuid.UUID, reason string) error {
+	return repository.queries.FailDataExport(ctx, data.FailDataExportParams{
+		Error: &reason,
+		ID:    id,
+	})
+}
+
+// FindDataExportArchive returns nil unless the export is completed and has
+// not expired.
+func (repository *UserSqlRepository) FindDataExportArchive(ctx context.Context, id uuid.UUID, userID uuid.UUID) ([]byte, error) {
+	archive, err := repository.queries.GetDataExportArchive(ctx, data.GetDataExportArchiveParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return archive, err
+}
+
+// FailStaleDataExports fails exports requested before startedBefore that are
+// still pending or running.
+func (repository *UserSqlRepository) FailStaleDataExports(ctx context.Context, startedBefore time.Time) error {
+	return repository.queries.FailStaleDataExports(ctx, pgtype.Timestamptz{Time: startedBefore, Valid: true})
+}
+
+// CleanUpDataExports deletes expired archives and fails exports started before
+// startedBefore that never finished.
+func (repository *UserSqlRepository) CleanUpDataExports(ctx context.Context, startedBefore time.Time) error {
+	err := repository.FailStaleDataExports(ctx, startedBefore)
+	if err != nil {
+		return err
+	}
+	return repository.queries.DeleteExpiredDataExports(ctx)
+}
+
+func dataExportFromRow(row data.FindActiveDataExportRow) *DataExport {
+	return &DataExport{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Status:      row.Status,
+		Error:       stringValue(row.Error),
+		CreatedAt:   row.CreatedAt.Time,
+		CompletedAt: timePointer(row.CompletedAt),
+		ExpiresAt:   timePointer(row.ExpiresAt),
+	}
+}
+
+func deletionReceiptFromRow(row data.DeletionReceipt) (*DeletionReceipt, error) {
+	purgedRows := map[string]int64{}
+	err := json.Unmarshal(row.PurgedRows, &purgedRows)
+	if err != nil {
+		return nil, err
+	}
+	pseudonymisedRows := map[string]int64{}
+	err = json.Unmarshal(row.PseudonymisedRows, &pseudonymisedRows)
+	if err != nil {
+		return nil, err
+	}
+	return &DeletionReceipt{
+		ID:                row.ID,
+		UserID:            row.UserID,
+		RequestedBy:       uuidValue(row.RequestedBy),
+		RequestedAt:       row.RequestedAt.Time,
+		PurgedRows:        purgedRows,
+		PseudonymisedRows: pseudonymisedRows,
+		PurgedAt:          row.PurgedAt.Time,
+	}, nil
+}
+
+// ListUsers returns one page of users, oldest first, along with the number of
+// users matching the filter.
+func (repository *UserSqlRepository) ListUsers(ctx context.Context, filter UserFilter, offset int, limit int) ([]*User, int, error) {
+	totalCount, err := repository.queries.CountUsers(ctx, data.CountUsersParams{
+		Email:       filter.Email,
+		ExternalID:  filter.ExternalID,
+		Active:      filter.Active,
+		Provisioned: filter.Provisioned,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repository.queries.ListUsers(ctx, data.ListUsersParams{
+		Email:       filter.Email,
+		ExternalID:  filter.ExternalID,
+		Active:      filter.Active,
+		Provisioned: filter.Provisioned,
+		PageLimit:   int32(limit),
+		PageOffset:  int32(offset),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]*User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, &User{
+			ID:                  row.ID,
+			Email:               row.Email,
+			FirstName:           row.FirstName,
+			LastName:            row.LastName,
+			MobileNumber:        stringValue(row.MobileNumber),
+			ExternalID:          stringValue(row.ExternalID),
+			Provisioned:         row.Provisioned,
+			DeactivatedAt:       timePointer(row.DeactivatedAt),
+			DeletionScheduledAt: timePointer(row.DeletionScheduledAt),
+			CreatedAt:           row.CreatedAt.Time,
+			UpdatedAt:           row.UpdatedAt.Time,
+		})
+	}
+	return users, int(totalCount), nil
+}
+
+// ProvisionUser creates an account on behalf of the identity provider. It has
+// no way to sign in until the user first signs in with SAML or a magic link.
+func (repository *UserSqlRepository) ProvisionUser(ctx context.Context, user *User) (uuid.UUID, error) {
+	provisioned, err := repository.queries.ProvisionUser(ctx, data.ProvisionUserParams{
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		ExternalID:    nullableString(user.ExternalID),
+		DeactivatedAt: nullableTime(user.DeactivatedAt),
+		MobileNumber:  nullableString(user.MobileNumber),
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return uuid.Nil, ErrEmailTaken
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	user.Provisioned = true
+	user.CreatedAt = provisioned.CreatedAt.Time
+	user.UpdatedAt = provisioned.CreatedAt.Time
+	return provisioned.ID, nil
+}
+
+// UpdateUserAccount saves the profile together with the sign-in account and
+// its active state. Deactivating revokes every session and refresh token, so
+// reactivating the account later does not bring them back.
+func (repository *UserSqlRepository) UpdateUserAccount(ctx context.Context, user *User) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	result, err := queries.UpdateAuthUser(ctx, data.UpdateAuthUserParams{
+		Email:      user.Email,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		ExternalID: nullableString(user.ExternalID),
+		ID:         user.ID,
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = queries.UpdateUser(ctx, data.UpdateUserParams{
+		Email:        user.Email,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		MobileNumber: nullableString(user.MobileNumber),
+		ID:           user.ID,
+	})
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.IsActive() {
+		err = queries.ReactivateAuthUser(ctx, user.ID)
+	} else {
+		err = deactivateAuthUser(ctx, queries, user.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
 	return nil
 }
+
+// deactivateAuthUser revokes the user's sessions and refresh tokens the first
+// time the account is deactivated.
+func deactivateAuthUser(ctx context.Context, queries *data.Queries, userID uuid.UUID) error {
+	result, err := queries.DeactivateAuthUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	err = queries.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return queries.RevokeUserRefreshTokens(ctx, userID)
+}
+
+// ListGroups returns one page of groups, oldest first, with their members,
+// along with the number of groups matching the filter.
+func (repository *UserSqlRepository) ListGroups(ctx context.Context, filter GroupFilter, offset int, limit int) ([]*Group, int, error) {
+	totalCount, err := repository.queries.CountGroups(ctx, data.CountGroupsParams{
+		DisplayName: filter.DisplayName,
+		ExternalID:  filter.ExternalID,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repository.queries.ListGroups(ctx, data.ListGroupsParams{
+		DisplayName: filter.DisplayName,
+		ExternalID:  filter.ExternalID,
+		PageLimit:   int32(limit),
+		PageOffset:  int32(offset),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groups := make([]*Group, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, groupFromRow(row))
+	}
+	err = repository.loadGroupMembers(ctx, groups)
+	if err != nil {
+		return nil, 0, err
+	}
+	return groups, int(totalCount), nil
+}
+
+// FindGroupByID returns nil when the group does not exist.
+func (repository *UserSqlRepository) FindGroupByID(ctx context.Context, id uuid.UUID) (*Group, error) {
+	row, err := repository.queries.FindGroupByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	group := groupFromRow(row)
+	err = repository.loadGroupMembers(ctx, []*Group{group})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (repository *UserSqlRepository) CreateGroup(ctx context.Context, group *Group) (uuid.UUID, error) {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	created, err := queries.CreateGroup(ctx, data.CreateGroupParams{
+		DisplayName: group.DisplayName,
+		ExternalID:  nullableString(group.ExternalID),
+	})
+	if err != nil {
+		return uuid.Nil, groupWriteError(err)
+	}
+
+	for _, userID := range group.MemberIDs() {
+		err = queries.AddGroupMember(ctx, data.AddGroupMemberParams{GroupID: created.ID, UserID: userID})
+		if err != nil {
+			return uuid.Nil, groupWriteError(err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	group.CreatedAt = created.CreatedAt.Time
+	group.UpdatedAt = created.UpdatedAt.Time
+	return created.ID, nil
+}
+
+// UpdateGroup saves the group and makes its stored members match group.Members.
+func (repository *UserSqlRepository) UpdateGroup(ctx context.Context, group *Group) error {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	queries := repository.queries.WithTx(tx)
+
+	result, err := queries.UpdateGroup(ctx, data.UpdateGroupParams{
+		DisplayName: group.DisplayName,
+		ExternalID:  nullableString(group.ExternalID),
+		ID:          group.ID,
+	})
+	if err != nil {
+		return groupWriteError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	memberIDs := group.MemberIDs()
+	err = queries.RemoveGroupMembersExcept(ctx, data.RemoveGroupMembersExceptParams{GroupID: group.ID, UserIds: memberIDs})
+	if err != nil {
+		return err
+	}
+	for _, userID := range memberIDs {
+		err = queries.AddGroupMember(ctx, data.AddGroupMemberParams{GroupID: group.ID, UserID: userID})
+		if err != nil {
+			return groupWriteError(err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	group.UpdatedAt = time.Now()
+	return nil
+}
+
+func (repository *UserSqlRepository) DeleteGroup(ctx context.Context, id uuid.UUID) error {
+	result, err := repository.queries.DeleteGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// loadGroupMembers fills in the members of every group with a single query.
+func (repository *UserSqlRepository) loadGroupMembers(ctx context.Context, groups []*Group) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	groupsByID := make(map[uuid.UUID]*Group, len(groups))
+	groupIDs := make([]uuid.UUID, 0, len(groups))
+	for _, group := range groups {
+		groupsByID[group.ID] = group
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	rows, err := repository.queries.ListGroupMembers(ctx, groupIDs)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		group := groupsByID[row.GroupID]
+		group.Members = append(group.Members, GroupMember{
+			UserID:  row.UserID,
+			Display: strings.TrimSpace(row.FirstName + " " + row.LastName),
+		})
+	}
+	return nil
+}
+
+func groupFromRow(row data.Group) *Group {
+	return &Group{
+		ID:          row.ID,
+		DisplayName: row.DisplayName,
+		ExternalID:  stringValue(row.ExternalID),
+		Members:     []GroupMember{},
+		CreatedAt:   row.CreatedAt.Time,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+}
+
+func groupWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolationCode:
+			return ErrGroupNameTaken
+		case foreignKeyViolationCode:
+			return ErrGroupMemberNotFound
+		}
+	}
+	return err
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// nullableString stores an empty string as NULL.
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func timePointer(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+func nullableTime(value *time.Time) pgtype.Timestamptz {
+	if value == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *value, Valid: true}
+}
+
+func uuidValue(value pgtype.UUID) uuid.UUID {
+	if !value.Valid {
+		return uuid.Nil
+	}
+	return value.Bytes
+}
+
+// nullableUUID stores uuid.Nil as NULL.
+func nullableUUID(value uuid.UUID) pgtype.UUID {
+	if value == uuid.Nil {
+		return pgtype.UUID{}
+	}
+	return pgtype.UUID{Bytes: value, Valid: true}
+}
@@ -0,0 +1,176 @@
+package user
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/utilities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// assignableRoles are the roles admins grant and revoke. Every user holds
+// authentication.RoleUser, which is not taken away.
+var assignableRoles = []string{authentication.RoleAdmin}
+
+type UserRolesApiDto struct {
+	Roles []string `json:"roles"`
+}
+
+// UserRoleHandler lets admins grant and revoke roles. Roles are read when
+// tokens are issued, so a granted role applies from the user's next refresh;
+// revoking one also revokes their access tokens.
+type UserRoleHandler struct {
+	repository UserRepository
+	policy     *UserPolicy
+	logger     *log.Logger
+}
+
+func NewUserRoleHandler(repository UserRepository, policy *UserPolicy, logger *log.Logger) *UserRoleHandler {
+	return &UserRoleHandler{
+		repository: repository,
+		policy:     policy,
+		logger:     logger,
+	}
+}
+
+// @Summary List a user's roles
+// @Description Returns the roles a user holds. Admins only.
+// @Tags users
+// @Param id path string true "User ID"
+// @Produce json
+// @Success 200 {object} UserRolesApiDto "Roles"
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/{id}/roles [get]
+func (handler UserRoleHandler) ListRoles(ctx *gin.Context) {
+	user, ok := handler.findUser(ctx)
+	if !ok {
+		return
+	}
+
+	handler.respondRoles(ctx, user.ID)
+}
+
+// @Summary Grant a role to a user
+// @Description Grants a role to a user. The role is added to their tokens from their next refresh. Granting a role the user holds does nothing. Admins only.
+// @Tags users
+// @Param id path string true "User ID"
+// @Param role path string true "Role" Enums(admin)
+// @Produce json
+// @Success 200 {object} UserRolesApiDto "Roles after the grant"
+// @Failure 400 {object} map[string]string "Invalid ID or role"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/{id}/roles/{role} [put]
+func (handler UserRoleHandler) GrantRole(ctx *gin.Context) {
+	user, role, ok := handler.findUserAndRole(ctx)
+	if !ok {
+		return
+	}
+
+	err := handler.repository.GrantUserRole(ctx.Request.Context(), user.ID, role)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryGrantUserRole: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	handler.respondRoles(ctx, user.ID)
+}
+
+// @Summary Revoke a role from a user
+// @Description Revokes a role from a user and revokes their access tokens, so the role's scopes stop working right away. The last admin cannot lose the admin role. Admins only.
+// @Tags users
+// @Param id path string true "User ID"
+// @Param role path string true "Role" Enums(admin)
+// @Produce json
+// @Success 200 {object} UserRolesApiDto "Roles after the revocation"
+// @Failure 400 {object} map[string]string "Invalid ID or role"
+// @Failure 403 {object} map[string]string "Not an admin"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 409 {object} map[string]string "User is the last admin"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/{id}/roles/{role} [delete]
+func (handler UserRoleHandler) RevokeRole(ctx *gin.Context) {
+	user, role, ok := handler.findUserAndRole(ctx)
+	if !ok {
+		return
+	}
+
+	err := handler.repository.RevokeUserRole(ctx.Request.Context(), user.ID, role)
+	if errors.Is(err, ErrLastAdmin) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "User Is The Last Admin"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryRevokeUserRole: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	handler.respondRoles(ctx, user.ID)
+}
+
+func (handler UserRoleHandler) findUserAndRole(ctx *gin.Context) (*User, string, bool) {
+	user, ok := handler.findUser(ctx)
+	if !ok {
+		return nil, "", false
+	}
+
+	role := ctx.Param("role")
+	if !slices.Contains(assignableRoles, role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Role"})
+		return nil, "", false
+	}
+	return user, role, true
+}
+
+func (handler UserRoleHandler) findUser(ctx *gin.Context) (*User, bool) {
+	userID, err := utilities.ReadIDParam(ctx)
+	if err != nil {
+		handler.logger.Printf("ERROR: readIDParam: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid User ID"})
+		return nil, false
+	}
+
+	user, err := handler.repository.FindUserByID(ctx.Request.Context(), userID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryGetUserByID: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return nil, false
+	}
+	if user == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return nil, false
+	}
+
+	err = handler.policy.CanManageRoles(authentication.GetAuthUser(ctx), user)
+	if err != nil {
+		handler.logger.Printf("ERROR: policyCanManageRoles: %v", err)
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return nil, false
+	}
+	return user, true
+}
+
+func (handler UserRoleHandler) respondRoles(ctx *gin.Context, userID uuid.UUID) {
+	roles, err := handler.repository.ListUserRoles(ctx.Request.Context(), userID)
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryListUserRoles: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+
+	ctx.JSON(http.StatusOK, UserRolesApiDto{Roles: roles})
+}
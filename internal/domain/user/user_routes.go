@@ -3,26 +3,69 @@ package user
 import (
 	"log"
 
+	"catalyst.api/config"
 	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/common/pagination"
 	"catalyst.api/internal/domain/user/data"
+	"catalyst.api/internal/mailer"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func RegisterRoutes(router *gin.Engine, db *pgxpool.Pool, repo UserRepository, authMiddleware authentication.AuthenticationMiddleware, logger *log.Logger) {
+func RegisterRoutes(router *gin.Engine, db *pgxpool.Pool, repo UserRepository, authMiddleware authentication.AuthenticationMiddleware, mailer mailer.Mailer, frontendURL string, exportWorker *DataExportWorker, cursors *pagination.CursorCodec, userConfig config.UserConfig, logger *log.Logger) {
 	queries := data.New(db)
+	policy := NewUserPolicy()
 	// Set up handlers
 	detailHandler := NewUserDetailHandler(queries, logger)
-	updateHandler := NewUserUpdateHandler(repo, logger)
-	deleteHandler := NewUserDeleteHandler(repo, logger)
+	listHandler := NewUserListHandler(queries, cursors, logger)
+	updateHandler := NewUserUpdateHandler(repo, policy, mailer, frontendURL, logger)
+	deleteHandler := NewUserDeleteHandler(repo, policy, userConfig.DeletionGracePeriod, logger)
+	roleHandler := NewUserRoleHandler(repo, policy, logger)
+	meHandler := NewUserMeHandler(queries, updateHandler, deleteHandler, logger)
+	exportHandler := NewDataExportHandler(repo, exportWorker, logger)
+	scimHandler := NewSCIMHandler(repo, logger)
 
 	// Set up routes
 	userRoutes := router.Group("/user")
 	userRoutes.Use(authMiddleware.RequireAuthUser())
 	{
-		userRoutes.GET("/:id", detailHandler.GetUserByID)
-		userRoutes.PUT("/:id", updateHandler.UpdateUser)
-		userRoutes.DELETE("/:id", deleteHandler.DeleteUser)
+		userRoutes.GET("", authMiddleware.RequireScopes(authentication.AdminScope), listHandler.ListUsers)
+		userRoutes.GET("/me", authMiddleware.RequireScopes(authentication.UsersReadScope), meHandler.GetMe)
+		userRoutes.PUT("/me", authMiddleware.RejectImpersonation(), authMiddleware.RequireScopes(authentication.UsersWriteScope), meHandler.UpdateMe)
+		userRoutes.DELETE("/me", authMiddleware.RejectImpersonation(), authMiddleware.RequireScopes(authentication.UsersWriteScope), meHandler.DeleteMe)
+		userRoutes.POST("/me/exports", authMiddleware.RejectImpersonation(), authMiddleware.RequireScopes(authentication.UsersReadScope), exportHandler.RequestExport)
+		userRoutes.GET("/me/exports/:id", authMiddleware.RequireScopes(authentication.UsersReadScope), exportHandler.GetExport)
+		userRoutes.GET("/me/exports/:id/download", authMiddleware.RejectImpersonation(), authMiddleware.RequireScopes(authentication.UsersReadScope), exportHandler.DownloadExport)
+		userRoutes.GET("/:id", authMiddleware.RequireScopes(authentication.UsersReadScope), detailHandler.GetUserByID)
+		userRoutes.PUT("/:id", authMiddleware.RejectImpersonation(), authMiddleware.RequireScopes(authentication.UsersWriteScope), updateHandler.UpdateUser)
+		userRoutes.DELETE("/:id", authMiddleware.RejectImpersonation(), authMiddleware.RequireScopes(authentication.UsersWriteScope), deleteHandler.DeleteUser)
+		userRoutes.POST("/:id/restore", authMiddleware.RequireScopes(authentication.AdminScope), deleteHandler.RestoreUser)
+		userRoutes.GET("/:id/deletion-receipt", authMiddleware.RequireScopes(authentication.AdminScope), deleteHandler.GetDeletionReceipt)
+		userRoutes.GET("/:id/roles", authMiddleware.RequireScopes(authentication.AdminScope), roleHandler.ListRoles)
+		userRoutes.PUT("/:id/roles/:role", authMiddleware.RequireScopes(authentication.AdminScope), roleHandler.GrantRole)
+		userRoutes.DELETE("/:id/roles/:role", authMiddleware.RequireScopes(authentication.AdminScope), roleHandler.RevokeRole)
+	}
+
+	// the link mailed to a new email proves it is the user's, signed in or not
+	router.POST("/user/email/confirm", updateHandler.ConfirmEmailChange)
+
+	// identity providers provision users and groups here with a SCIM token
+	scimRoutes := router.Group("/scim/v2")
+	scimRoutes.Use(authMiddleware.RequireSCIMToken())
+	{
+		scimRoutes.GET("/ServiceProviderConfig", scimHandler.GetServiceProviderConfig)
+		scimRoutes.GET("/Users", scimHandler.ListUsers)
+		scimRoutes.POST("/Users", scimHandler.CreateUser)
+		scimRoutes.GET("/Users/:id", scimHandler.GetUser)
+		scimRoutes.PUT("/Users/:id", scimHandler.ReplaceUser)
+		scimRoutes.PATCH("/Users/:id", scimHandler.PatchUser)
+		scimRoutes.DELETE("/Users/:id", scimHandler.DeleteUser)
+		scimRoutes.GET("/Groups", scimHandler.ListGroups)
+		scimRoutes.POST("/Groups", scimHandler.CreateGroup)
+		scimRoutes.GET("/Groups/:id", scimHandler.GetGroup)
+		scimRoutes.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+		scimRoutes.PATCH("/Groups/:id", scimHandler.PatchGroup)
+		scimRoutes.DELETE("/Groups/:id", scimHandler.DeleteGroup)
 	}
 }
@@ -1,10 +1,18 @@
 package user
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/mailer"
 	"catalyst.api/internal/utilities"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +20,10 @@ import (
 	"github.com/google/uuid"
 )
 
+// emailChangeMailTimeout bounds how long the email change mails may take to
+// send after the request has been answered.
+const emailChangeMailTimeout = 30 * time.Second
+
 type UserUpdateCommand struct {
 	ID           uuid.UUID
 	Email        string
@@ -32,20 +44,35 @@ func (dto *UserUpdateApiDto) ValidateApiDto() error {
 	return validate.Struct(dto)
 }
 
+type ConfirmEmailChangeApiDto struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (dto *ConfirmEmailChangeApiDto) ValidateApiDto() error {
+	validate := validator.New()
+	return validate.Struct(dto)
+}
+
 type UserUpdateHandler struct {
-	repository UserRepository
-	logger     *log.Logger
+	repository  UserRepository
+	policy      *UserPolicy
+	mailer      mailer.Mailer
+	frontendURL string
+	logger      *log.Logger
 }
 
-func NewUserUpdateHandler(repository UserRepository, logger *log.Logger) *UserUpdateHandler {
+func NewUserUpdateHandler(repository UserRepository, policy *UserPolicy, mailer mailer.Mailer, frontendURL string, logger *log.Logger) *UserUpdateHandler {
 	return &UserUpdateHandler{
-		repository: repository,
-		logger:     logger,
+		repository:  repository,
+		policy:      policy,
+		mailer:      mailer,
+		frontendURL: frontendURL,
+		logger:      logger,
 	}
 }
 
 // @Summary Update user information by ID
-// @Description Updates the email, name, and mobile number for a given user.
+// @Description Updates the name and mobile number for a given user. A new email is not applied straight away: a confirmation link is mailed to it, and pendingEmail holds it until the link is followed. Changing the email with a personal access token needs the auth scope.
 // @Tags users
 // @Param id path string true "User ID"
 // @Accept json
@@ -53,7 +80,9 @@ func NewUserUpdateHandler(repository UserRepository, logger *log.Logger) *UserUp
 // @Param user body UserUpdateApiDto true "User update payload"
 // @Success 200 {object} map[string]interface{} "Updated user object"
 // @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 403 {object} map[string]string "Not allowed to update this user, impersonating, or changing the email without the auth scope"
 // @Failure 404 {object} map[string]string "User not found"
+// @Failure 409 {object} map[string]string "Email already in use"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /users/{id} [put]
 func (handler UserUpdateHandler) UpdateUser(ctx *gin.Context) {
@@ -64,13 +93,29 @@ func (handler UserUpdateHandler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	user, pendingEmail, ok := handler.updateUser(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if pendingEmail != "" {
+		ctx.JSON(http.StatusOK, gin.H{"User": user, "pendingEmail": pendingEmail})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"User": user})
+}
+
+// updateUser applies the update in the request body to the user with the
+// given ID. A new email is only requested, and returned as pendingEmail. On
+// failure it writes the error response and returns false.
+func (handler UserUpdateHandler) updateUser(ctx *gin.Context, userID uuid.UUID) (*User, string, bool) {
 	// build command
 	var userUpdateApiDto UserUpdateApiDto
-	err = json.NewDecoder(ctx.Request.Body).Decode(&userUpdateApiDto)
+	err := json.NewDecoder(ctx.Request.Body).Decode(&userUpdateApiDto)
 	if err != nil {
 		handler.logger.Printf("Error: decodeUserUpdateApiDto: %v", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
-		return
+		return nil, "", false
 	}
 
 	// validate
@@ -78,12 +123,12 @@ func (handler UserUpdateHandler) UpdateUser(ctx *gin.Context) {
 	if err != nil {
 		handler.logger.Printf("ERROR: validateUserUpdateApiDto: %v", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
-		return
+		return nil, "", false
 	}
 
 	command := UserUpdateCommand{
 		ID:           userID,
-		Email:        userUpdateApiDto.Email,
+		Email:        strings.TrimSpace(userUpdateApiDto.Email),
 		FirstName:    userUpdateApiDto.FirstName,
 		LastName:     userUpdateApiDto.LastName,
 		MobileNumber: userUpdateApiDto.MobileNumber,
@@ -93,22 +138,155 @@ func (handler UserUpdateHandler) UpdateUser(ctx *gin.Context) {
 	if err != nil {
 		handler.logger.Printf("Error: repositoryGetUserByID: %v", err)
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
-		return
+		return nil, "", false
+	}
+
+	if user == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return nil, "", false
+	}
+
+	err = handler.policy.CanUpdate(authentication.GetAuthUser(ctx), user)
+	if err != nil {
+		handler.logger.Printf("ERROR: policyCanUpdate: %v", err)
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return nil, "", false
+	}
+
+	// the email only changes once the link mailed to the new one is followed
+	pendingEmail := ""
+	if !strings.EqualFold(command.Email, user.Email) {
+		if !handler.canChangeEmail(ctx) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "changing the email needs a token with the auth scope"})
+			return nil, "", false
+		}
+
+		emailChange, plainToken, err := NewEmailChange(user.ID, command.Email)
+		if err != nil {
+			handler.logger.Printf("ERROR: newEmailChange: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return nil, "", false
+		}
+		err = handler.repository.RequestEmailChange(ctx.Request.Context(), emailChange)
+		if errors.Is(err, ErrEmailTaken) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Email Already In Use"})
+			return nil, "", false
+		}
+		if err != nil {
+			handler.logger.Printf("ERROR: repositoryRequestEmailChange: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return nil, "", false
+		}
+
+		go handler.sendEmailChangeMails(user.Email, emailChange.NewEmail, plainToken)
+		pendingEmail = emailChange.NewEmail
+		command.Email = user.Email
 	}
 
 	user, err = user.Update(command.Email, command.FirstName, command.LastName, command.MobileNumber)
 	if err != nil {
 		handler.logger.Printf("Error: modelUserUpdate: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-		return
+		return nil, "", false
 	}
 
 	user, err = handler.repository.UpdateUser(ctx.Request.Context(), user)
+	if errors.Is(err, ErrEmailTaken) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email Already In Use"})
+		return nil, "", false
+	}
 	if err != nil {
 		handler.logger.Printf("Error: repositoryUpdateUser: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return nil, "", false
+	}
+
+	return user, pendingEmail, true
+}
+
+// canChangeEmail reports whether the request may change an email. The email
+// is what password resets and sign-in links go to, so a personal access token
+// needs the auth scope for it, as it does to change the password.
+func (handler UserUpdateHandler) canChangeEmail(ctx *gin.Context) bool {
+	if authentication.GetPersonalAccessToken(ctx) == nil {
+		return true
+	}
+	return authentication.GetAccessTokenClaims(ctx).HasScopes(authentication.AuthScope)
+}
+
+// sendEmailChangeMails mails the confirmation link to the new email, and lets
+// the current email know a change was asked for.
+func (handler UserUpdateHandler) sendEmailChangeMails(currentEmail string, newEmail string, plainToken string) {
+	ctx, cancel := context.WithTimeout(context.Background(), emailChangeMailTimeout)
+	defer cancel()
+
+	confirmURL := fmt.Sprintf("%s/confirm-email?token=%s", handler.frontendURL, url.QueryEscape(plainToken))
+	messages := []mailer.Message{
+		{
+			To:      newEmail,
+			Subject: "Confirm your new catalyst email",
+			Body: fmt.Sprintf("Someone asked to use this email for their catalyst account.\n\n"+
+				"Use this link within %s to confirm it:\n\n%s\n\n"+
+				"If this wasn't you, you can ignore this email.\n", EmailChangeTimeToLive, confirmURL),
+		},
+		{
+			To:      currentEmail,
+			Subject: "Your catalyst email is being changed",
+			Body: fmt.Sprintf("Someone asked to change the email of your catalyst account to %s.\n\n"+
+				"The change happens once the link mailed to that address is followed. "+
+				"If this wasn't you, change your password and contact an administrator.\n", newEmail),
+		},
+	}
+
+	for _, message := range messages {
+		err := handler.mailer.Send(ctx, message)
+		if err != nil {
+			handler.logger.Printf("ERROR: mailerSend: %v", err)
+		}
+	}
+}
+
+// @Summary Confirm an email change
+// @Description Moves the account to the new email using the token from the link mailed to it. The new email is also the one used to sign in. Each link works once.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param confirmation body ConfirmEmailChangeApiDto true "Token from the confirmation link"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string "Invalid, used or expired link"
+// @Failure 409 {object} map[string]string "Email already in use"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/email/confirm [post]
+func (handler UserUpdateHandler) ConfirmEmailChange(ctx *gin.Context) {
+	var confirmApiDto ConfirmEmailChangeApiDto
+	err := json.NewDecoder(ctx.Request.Body).Decode(&confirmApiDto)
+	if err != nil {
+		handler.logger.Printf("ERROR: decodeConfirmEmailChangeApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = confirmApiDto.ValidateApiDto()
+	if err != nil {
+		handler.logger.Printf("ERROR: validateConfirmEmailChangeApiDto: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Request Sent"})
+		return
+	}
+
+	err = handler.repository.ConfirmEmailChange(ctx.Request.Context(), HashEmailChangeToken(confirmApiDto.Token))
+	if errors.Is(err, ErrInvalidEmailChange) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrEmailTaken) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email Already In Use"})
+		return
+	}
+	if err != nil {
+		handler.logger.Printf("ERROR: repositoryConfirmEmailChange: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"User": user})
+	ctx.Writer.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,181 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"catalyst.api/config"
+)
+
+// KeySize is the size of keyring keys and data keys, for AES-256.
+const KeySize = 32
+
+var ErrUnknownKey = errors.New("encryption key not found")
+
+// Keyring holds the key new data keys are wrapped with and every key that
+// older data keys may still be unwrapped with.
+type Keyring struct {
+	currentKeyID string
+	keys         map[string]cipher.AEAD
+}
+
+// NewKeyring loads current and previous keys from config. Previous keys are
+// only used to unwrap data keys written before a rotation.
+func NewKeyring(current config.EncryptionKeyConfig, previous []config.EncryptionKeyConfig) (*Keyring, error) {
+	if current.KeyID == "" {
+		return nil, errors.New("encryption key id must be set")
+	}
+
+	keyring := &Keyring{
+		currentKeyID: current.KeyID,
+		keys:         make(map[string]cipher.AEAD),
+	}
+	for _, keyConfig := range append([]config.EncryptionKeyConfig{current}, previous...) {
+		if _, exists := keyring.keys[keyConfig.KeyID]; exists {
+			return nil, fmt.Errorf("duplicate encryption key id %q", keyConfig.KeyID)
+		}
+		key, err := base64.StdEncoding.DecodeString(keyConfig.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", keyConfig.KeyID, err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", keyConfig.KeyID, err)
+		}
+		keyring.keys[keyConfig.KeyID] = aead
+	}
+	return keyring, nil
+}
+
+// NewEphemeralKeyring returns a keyring with a random key that only lives as
+// long as the process. Data wrapped with it cannot be read after a restart.
+func NewEphemeralKeyring() (*Keyring, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	keyID := make([]byte, 8)
+	if _, err := rand.Read(keyID); err != nil {
+		return nil, err
+	}
+	return NewKeyring(config.EncryptionKeyConfig{
+		KeyID: "ephemeral-" + base64.RawURLEncoding.EncodeToString(keyID),
+		Key:   base64.StdEncoding.EncodeToString(key),
+	}, nil)
+}
+
+func (keyring *Keyring) CurrentKeyID() string {
+	return keyring.currentKeyID
+}
+
+// DataKey encrypts a single record. It is stored next to the record, wrapped by
+// the keyring key named by KeyID, so rotating the keyring only means wrapping
+// data keys again rather than re-encrypting every record.
+type DataKey struct {
+	KeyID      string
+	WrappedKey []byte
+	plainKey   []byte
+	aead       cipher.AEAD
+}
+
+// NewDataKey generates a data key wrapped with the current keyring key.
+// associatedData binds the wrapped key to the record it belongs to.
+func (keyring *Keyring) NewDataKey(associatedData []byte) (*DataKey, error) {
+	plainKey := make([]byte, KeySize)
+	if _, err := rand.Read(plainKey); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(plainKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := &DataKey{plainKey: plainKey, aead: aead}
+	err = keyring.Rewrap(dataKey, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return dataKey, nil
+}
+
+// OpenDataKey unwraps a stored data key. ErrUnknownKey is returned if keyID is
+// no longer in the keyring.
+func (keyring *Keyring) OpenDataKey(keyID string, wrappedKey []byte, associatedData []byte) (*DataKey, error) {
+	keyAEAD, exists := keyring.keys[keyID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	plainKey, err := open(keyAEAD, wrappedKey, wrapAssociatedData(keyID, associatedData))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(plainKey)
+	if err != nil {
+		return nil, err
+	}
+	return &DataKey{KeyID: keyID, WrappedKey: wrappedKey, plainKey: plainKey, aead: aead}, nil
+}
+
+// IsCurrent reports whether dataKey is wrapped with the current keyring key.
+func (keyring *Keyring) IsCurrent(dataKey *DataKey) bool {
+	return dataKey.KeyID == keyring.currentKeyID
+}
+
+// Rewrap wraps dataKey with the current keyring key. Records encrypted with it
+// stay as they are.
+func (keyring *Keyring) Rewrap(dataKey *DataKey, associatedData []byte) error {
+	wrappedKey, err := seal(keyring.keys[keyring.currentKeyID], dataKey.plainKey, wrapAssociatedData(keyring.currentKeyID, associatedData))
+	if err != nil {
+		return err
+	}
+	dataKey.KeyID = keyring.currentKeyID
+	dataKey.WrappedKey = wrappedKey
+	return nil
+}
+
+func (dataKey *DataKey) Seal(plaintext []byte, associatedData []byte) ([]byte, error) {
+	return seal(dataKey.aead, plaintext, associatedData)
+}
+
+func (dataKey *DataKey) Open(ciphertext []byte, associatedData []byte) ([]byte, error) {
+	return open(dataKey.aead, ciphertext, associatedData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal prepends a random nonce to the ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte, associatedData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, associatedData)
+}
+
+// wrapAssociatedData binds a wrapped data key to the keyring key that wrapped
+// it as well as to its record.
+func wrapAssociatedData(keyID string, associatedData []byte) []byte {
+	return append([]byte(keyID+"\x00"), associatedData...)
+}
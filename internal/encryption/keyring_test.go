@@ -0,0 +1,157 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"catalyst.api/config"
+)
+
+func newTestKey(t *testing.T, keyID string) config.EncryptionKeyConfig {
+	t.Helper()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return config.EncryptionKeyConfig{KeyID: keyID, Key: base64.StdEncoding.EncodeToString(key)}
+}
+
+func newTestKeyring(t *testing.T, current config.EncryptionKeyConfig, previous ...config.EncryptionKeyConfig) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(current, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestNewKeyringRejectsInvalidKeys(t *testing.T) {
+	valid := newTestKey(t, "valid")
+
+	tests := []struct {
+		name     string
+		current  config.EncryptionKeyConfig
+		previous []config.EncryptionKeyConfig
+	}{
+		{"missing key id", config.EncryptionKeyConfig{Key: valid.Key}, nil},
+		{"not base64", config.EncryptionKeyConfig{KeyID: "k1", Key: "not base64!"}, nil},
+		{"wrong size", config.EncryptionKeyConfig{KeyID: "k1", Key: base64.StdEncoding.EncodeToString([]byte("too short"))}, nil},
+		{"duplicate key id", valid, []config.EncryptionKeyConfig{valid}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewKeyring(test.current, test.previous); err == nil {
+				t.Error("NewKeyring succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDataKeySealsAndOpens(t *testing.T) {
+	keyring := newTestKeyring(t, newTestKey(t, "k1"))
+	record := []byte("record-1")
+
+	dataKey, err := keyring.NewDataKey(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := dataKey.Seal([]byte("secret"), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := keyring.OpenDataKey(dataKey.KeyID, dataKey.WrappedKey, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := opened.Open(ciphertext, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("Open = %q, want %q", plaintext, "secret")
+	}
+
+	if _, err := opened.Open(ciphertext, []byte("record-2")); err == nil {
+		t.Error("Open succeeded with another record's associated data")
+	}
+}
+
+func TestOpenDataKeyIsBoundToItsRecord(t *testing.T) {
+	keyring := newTestKeyring(t, newTestKey(t, "k1"))
+
+	dataKey, err := keyring.NewDataKey([]byte("record-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.OpenDataKey(dataKey.KeyID, dataKey.WrappedKey, []byte("record-2")); err == nil {
+		t.Error("OpenDataKey succeeded with another record's associated data")
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := newTestKey(t, "old")
+	newKey := newTestKey(t, "new")
+	record := []byte("record-1")
+
+	oldKeyring := newTestKeyring(t, oldKey)
+	dataKey, err := oldKeyring.NewDataKey(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := dataKey.Seal([]byte("secret"), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedKeyring := newTestKeyring(t, newKey, oldKey)
+	opened, err := rotatedKeyring.OpenDataKey(dataKey.KeyID, dataKey.WrappedKey, record)
+	if err != nil {
+		t.Fatalf("OpenDataKey with the previous key: %v", err)
+	}
+	if rotatedKeyring.IsCurrent(opened) {
+		t.Error("IsCurrent = true for a data key wrapped with the previous key")
+	}
+
+	err = rotatedKeyring.Rewrap(opened, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.KeyID != "new" || !rotatedKeyring.IsCurrent(opened) {
+		t.Errorf("rewrapped data key has key id %q, want %q", opened.KeyID, "new")
+	}
+
+	// once the old key is dropped, the rewrapped key still opens the record
+	// it was sealed for without re-encrypting it
+	newKeyring := newTestKeyring(t, newKey)
+	reopened, err := newKeyring.OpenDataKey(opened.KeyID, opened.WrappedKey, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := reopened.Open(ciphertext, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, []byte("secret")) {
+		t.Errorf("Open = %q, want %q", plaintext, "secret")
+	}
+
+	_, err = newKeyring.OpenDataKey(dataKey.KeyID, dataKey.WrappedKey, record)
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("OpenDataKey with a dropped key = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestOpenRejectsTruncatedCiphertext(t *testing.T) {
+	keyring := newTestKeyring(t, newTestKey(t, "k1"))
+	dataKey, err := keyring.NewDataKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dataKey.Open([]byte("short"), nil); err == nil {
+		t.Error("Open succeeded on a ciphertext shorter than the nonce")
+	}
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"catalyst.api/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer returns the mailer chosen by MailerConfig.Transport.
+func NewMailer(cfg config.MailerConfig) (Mailer, error) {
+	switch cfg.Transport {
+	case "", "smtp":
+		smtpMailer, err := NewSMTPMailer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return smtpMailer, nil
+	case "memory":
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unsupported MAIL_TRANSPORT %q", cfg.Transport)
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryMailer keeps sent messages in memory instead of delivering them, for
+// tests and local runs without a mail server.
+type MemoryMailer struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mailer *MemoryMailer) Send(ctx context.Context, message Message) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	mailer.messages = append(mailer.messages, message)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (mailer *MemoryMailer) Messages() []Message {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	return slices.Clone(mailer.messages)
+}
+
+// LastMessageTo returns the most recent message sent to the address.
+func (mailer *MemoryMailer) LastMessageTo(to string) (Message, bool) {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	for i := len(mailer.messages) - 1; i >= 0; i-- {
+		if mailer.messages[i].To == to {
+			return mailer.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+func (mailer *MemoryMailer) Reset() {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	mailer.messages = nil
+}
@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"catalyst.api/config"
+)
+
+// SMTPMailer delivers mail through an SMTP server. STARTTLS is used when the
+// server offers it.
+type SMTPMailer struct {
+	address string
+	from    *mail.Address
+	auth    smtp.Auth
+}
+
+func NewSMTPMailer(cfg config.MailerConfig) (*SMTPMailer, error) {
+	from, err := mail.ParseAddress(cfg.FromAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
+	}
+
+	return &SMTPMailer{
+		address: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from:    from,
+		auth:    auth,
+	}, nil
+}
+
+func (mailer *SMTPMailer) Send(ctx context.Context, message Message) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, so run it aside and stop waiting on cancel
+	result := make(chan error, 1)
+	go func() {
+		result <- smtp.SendMail(mailer.address, mailer.auth, mailer.from.Address, []string{to.Address}, mailer.buildMessage(to, message))
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (mailer *SMTPMailer) buildMessage(to *mail.Address, message Message) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + mailer.from.String() + "\r\n")
+	builder.WriteString("To: " + to.String() + "\r\n")
+	builder.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
@@ -9,8 +9,13 @@ type Middlewares struct {
 	AuthenticationMiddleware authentication.AuthenticationMiddleware
 }
 
-func RegisterMiddlewares(repositories *domain.Repositories) *Middlewares {
-	authenticationMiddleware := authentication.AuthenticationMiddleware{AuthenticationRepository: repositories.AuthenticationRepository}
+func RegisterMiddlewares(repositories *domain.Repositories, auth *authentication.Authentication) *Middlewares {
+	revocationStore := authentication.NewCachedRevocationStore(repositories.AuthenticationRepository, authentication.RevocationCacheTimeToLive)
+	authenticationMiddleware := authentication.AuthenticationMiddleware{
+		AuthenticationRepository: repositories.AuthenticationRepository,
+		RevocationStore:          revocationStore,
+		KeySet:                   auth.KeySet,
+	}
 
 	middlewares := &Middlewares{
 		AuthenticationMiddleware: authenticationMiddleware,
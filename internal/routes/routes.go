@@ -4,9 +4,12 @@ import (
 	"log"
 
 	"catalyst.api/cmd/docs"
+	"catalyst.api/config"
 	"catalyst.api/internal/authentication"
+	"catalyst.api/internal/common/pagination"
 	"catalyst.api/internal/domain"
 	"catalyst.api/internal/domain/user"
+	"catalyst.api/internal/mailer"
 	"catalyst.api/internal/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -15,13 +18,13 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRoutes(engine *gin.Engine, db *pgxpool.Pool, repos *domain.Repositories, middlewares *middleware.Middlewares, logger *log.Logger) {
+func SetupRoutes(engine *gin.Engine, db *pgxpool.Pool, repos *domain.Repositories, auth *authentication.Authentication, middlewares *middleware.Middlewares, mailer mailer.Mailer, exportWorker *user.DataExportWorker, cursors *pagination.CursorCodec, userConfig config.UserConfig, logger *log.Logger) {
 	router := engine
 	docs.SwaggerInfo.BasePath = "/"
 	router.Use(middlewares.AuthenticationMiddleware.Authenticate())
 	{
-		authentication.RegisterRoutes(router, repos.AuthenticationRepository, logger)
-		user.RegisterRoutes(router, db, repos.UserRepository, middlewares.AuthenticationMiddleware, logger)
+		authentication.RegisterRoutes(router, auth, repos.AuthenticationRepository, middlewares.AuthenticationMiddleware, mailer, logger)
+		user.RegisterRoutes(router, db, repos.UserRepository, middlewares.AuthenticationMiddleware, mailer, auth.FrontendURL, exportWorker, cursors, userConfig, logger)
 	}
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
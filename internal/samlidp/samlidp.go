@@ -0,0 +1,135 @@
+// Package samlidp is a stand-in SAML identity provider for trying SAML sign-in
+// locally and for testing it. It signs in whoever fills in its form, with no
+// password, so it must never be exposed beyond a developer's machine.
+package samlidp
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/xml"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+)
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html>
+<head><title>Stand-in identity provider</title></head>
+<body>
+<h1>Stand-in identity provider</h1>
+<p>Signing in to {{.ServiceProvider}}. Anyone can sign in as anyone here.</p>
+<form method="get" action="{{.Action}}">
+{{range $name, $values := .Query}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">
+{{end}}{{end}}<p><label>Email <input name="email" type="email" required></label></p>
+<p><label>First name <input name="firstName"></label></p>
+<p><label>Last name <input name="lastName"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>`))
+
+// formSessionProvider asks for the user to sign in as, and uses the email as
+// a persistent NameID so the same email is the same user every time.
+type formSessionProvider struct{}
+
+func (formSessionProvider) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) *saml.Session {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	if email == "" {
+		query := r.URL.Query()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := loginPage.Execute(w, map[string]interface{}{
+			"ServiceProvider": req.ServiceProviderMetadata.EntityID,
+			"Action":          r.URL.Path,
+			"Query":           query,
+		})
+		if err != nil {
+			log.Printf("ERROR: loginPageExecute: %v", err)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	return &saml.Session{
+		ID:           email,
+		CreateTime:   now,
+		ExpireTime:   now.Add(time.Hour),
+		Index:        email,
+		NameID:       email,
+		NameIDFormat: string(saml.PersistentNameIDFormat),
+		CustomAttributes: []saml.Attribute{
+			newAttribute("email", email),
+			newAttribute("firstName", r.URL.Query().Get("firstName")),
+			newAttribute("lastName", r.URL.Query().Get("lastName")),
+		},
+	}
+}
+
+// metadataServiceProviderProvider trusts any service provider, reading its
+// metadata from the URL it names as its entity ID, or from metadataURL.
+type metadataServiceProviderProvider struct {
+	metadataURL string
+}
+
+func (provider metadataServiceProviderProvider) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	metadataURL, err := url.Parse(provider.metadataURL)
+	if provider.metadataURL == "" {
+		metadataURL, err = url.Parse(serviceProviderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return samlsp.FetchMetadata(r.Context(), http.DefaultClient, *metadataURL)
+}
+
+func newAttribute(name string, value string) saml.Attribute {
+	return saml.Attribute{
+		Name:       name,
+		NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic",
+		Values:     []saml.AttributeValue{{Type: "xs:string", Value: value}},
+	}
+}
+
+// New returns the identity provider reached by the browser at baseURL. It
+// serves its metadata at /metadata and single sign-on at /sso. The email,
+// firstName and lastName attributes it sends match the default attribute
+// mapping. Service provider metadata is read from serviceProviderMetadataURL,
+// or from the service provider's entity ID if that is empty.
+func New(baseURL string, key crypto.PrivateKey, certificate *x509.Certificate, serviceProviderMetadataURL string, logger *log.Logger) (http.Handler, error) {
+	metadataURL, err := url.Parse(baseURL + "/metadata")
+	if err != nil {
+		return nil, err
+	}
+	ssoURL, err := url.Parse(baseURL + "/sso")
+	if err != nil {
+		return nil, err
+	}
+
+	identityProvider := &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             certificate,
+		Logger:                  logger,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		ServiceProviderProvider: metadataServiceProviderProvider{metadataURL: serviceProviderMetadataURL},
+		SessionProvider:         formSessionProvider{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		metadata, err := xml.MarshalIndent(identityProvider.Metadata(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/samlmetadata+xml")
+		w.Write(metadata)
+	})
+	mux.HandleFunc("/sso", identityProvider.ServeSSO)
+	return mux, nil
+}
@@ -2,7 +2,11 @@ package utilities
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+
+	"catalyst.api/internal/common/dtos"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -22,3 +26,24 @@ func ReadIDParam(ctx *gin.Context) (uuid.UUID, error) {
 	}
 	return id, nil
 }
+
+// ReadPageParams reads the 1-based page and the pageSize query parameters,
+// falling back to the first page of dtos.DefaultPageSize items.
+func ReadPageParams(ctx *gin.Context) (page int, pageSize int, err error) {
+	page = 1
+	pageSize = dtos.DefaultPageSize
+
+	if pageParam := ctx.Query("page"); pageParam != "" {
+		page, err = strconv.Atoi(pageParam)
+		if err != nil || page < 1 {
+			return 0, 0, errors.New("page must be a positive integer")
+		}
+	}
+	if pageSizeParam := ctx.Query("pageSize"); pageSizeParam != "" {
+		pageSize, err = strconv.Atoi(pageSizeParam)
+		if err != nil || pageSize < 1 || pageSize > dtos.MaxPageSize {
+			return 0, 0, fmt.Errorf("pageSize must be between 1 and %d", dtos.MaxPageSize)
+		}
+	}
+	return page, pageSize, nil
+}
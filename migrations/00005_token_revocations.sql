@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES auth_users(id),
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  revoked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS tokens_revoked_at TIMESTAMP WITH TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE auth_users DROP COLUMN IF EXISTS tokens_revoked_at;
+DROP TABLE revoked_tokens;
+-- +goose StatementEnd
\ No newline at end of file
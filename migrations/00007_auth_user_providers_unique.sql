@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE auth_user_providers
+  ADD CONSTRAINT auth_user_providers_provider_identity_key UNIQUE (provider, provider_user_id);
+
+CREATE INDEX IF NOT EXISTS auth_user_providers_user_id_idx ON auth_user_providers (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS auth_user_providers_user_id_idx;
+ALTER TABLE auth_user_providers DROP CONSTRAINT IF EXISTS auth_user_providers_provider_identity_key;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS device_authorizations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  device_code_hash VARCHAR(64) UNIQUE NOT NULL,
+  user_code VARCHAR(8) UNIQUE NOT NULL,
+  client_id VARCHAR(255) NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  user_id UUID REFERENCES auth_users(id),
+  interval_seconds INTEGER NOT NULL,
+  last_polled_at TIMESTAMP WITH TIME ZONE,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE device_authorizations;
+-- +goose StatementEnd
\ No newline at end of file
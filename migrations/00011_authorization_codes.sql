@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS authorization_codes (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  user_id UUID NOT NULL REFERENCES auth_users(id),
+  code_hash VARCHAR(64) UNIQUE NOT NULL,
+  code_challenge VARCHAR(128) NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE authorization_codes;
+-- +goose StatementEnd
\ No newline at end of file
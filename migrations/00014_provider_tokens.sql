@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS provider_tokens (
+  auth_user_provider_id UUID PRIMARY KEY REFERENCES auth_user_providers(id) ON DELETE CASCADE,
+  key_id VARCHAR(64) NOT NULL,
+  wrapped_key BYTEA NOT NULL,
+  access_token BYTEA NOT NULL,
+  refresh_token BYTEA,
+  expires_at TIMESTAMP WITH TIME ZONE,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE provider_tokens;
+-- +goose StatementEnd
\ No newline at end of file
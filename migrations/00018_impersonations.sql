@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS impersonations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  actor_id UUID NOT NULL REFERENCES auth_users(id),
+  user_id UUID NOT NULL REFERENCES auth_users(id),
+  reason TEXT NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  ended_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS impersonation_requests (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  impersonation_id UUID NOT NULL REFERENCES impersonations(id) ON DELETE CASCADE,
+  method VARCHAR(10) NOT NULL,
+  path VARCHAR(2048) NOT NULL,
+  status_code INTEGER,
+  ip_address VARCHAR(45) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS impersonations_created_at_idx ON impersonations (created_at);
+CREATE INDEX IF NOT EXISTS impersonation_requests_impersonation_id_idx ON impersonation_requests (impersonation_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE impersonation_requests;
+DROP TABLE impersonations;
+-- +goose StatementEnd
\ No newline at end of file
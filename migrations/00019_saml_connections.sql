@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS saml_connections (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  workspace VARCHAR(63) NOT NULL UNIQUE,
+  entity_id VARCHAR(1024) NOT NULL,
+  idp_entity_id VARCHAR(1024) NOT NULL,
+  idp_metadata TEXT NOT NULL,
+  email_attribute VARCHAR(255) NOT NULL,
+  first_name_attribute VARCHAR(255) NOT NULL,
+  last_name_attribute VARCHAR(255) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS saml_requests (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  connection_id UUID NOT NULL REFERENCES saml_connections(id) ON DELETE CASCADE,
+  request_id VARCHAR(255) NOT NULL,
+  code_challenge VARCHAR(128) NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE saml_requests;
+DROP TABLE saml_connections;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS provisioned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS auth_users_external_id_idx ON auth_users (external_id);
+
+CREATE TABLE IF NOT EXISTS scim_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  name VARCHAR(255) NOT NULL,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  token_hint VARCHAR(32) NOT NULL,
+  created_by UUID REFERENCES auth_users(id) ON DELETE SET NULL,
+  last_used_at TIMESTAMP WITH TIME ZONE,
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  display_name VARCHAR(255) NOT NULL,
+  external_id VARCHAR(255),
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS groups_display_name_idx ON groups (lower(display_name));
+
+CREATE TABLE IF NOT EXISTS group_members (
+  group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES auth_users(id),
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS group_members_user_id_idx ON group_members (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE group_members;
+DROP TABLE groups;
+DROP TABLE scim_tokens;
+DROP INDEX IF EXISTS auth_users_external_id_idx;
+ALTER TABLE auth_users DROP COLUMN IF EXISTS deactivated_at;
+ALTER TABLE auth_users DROP COLUMN IF EXISTS provisioned;
+ALTER TABLE auth_users DROP COLUMN IF EXISTS external_id;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS deletion_requested_by UUID;
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS auth_users_deletion_scheduled_at_idx ON auth_users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS deletion_receipts (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
+  user_id UUID UNIQUE NOT NULL,
+  requested_by UUID,
+  requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  purged_rows JSONB NOT NULL,
+  purged_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE deletion_receipts;
+DROP INDEX IF EXISTS auth_users_deletion_scheduled_at_idx;
+ALTER TABLE auth_users DROP COLUMN IF EXISTS deletion_scheduled_at;
+ALTER TABLE auth_users DROP COLUMN IF EXISTS deletion_requested_by;
+ALTER TABLE auth_users DROP COLUMN IF EXISTS deletion_requested_at;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE impersonations ADD COLUMN IF NOT EXISTS actor_email VARCHAR(255);
+UPDATE impersonations
+SET actor_email = auth_users.email
+FROM auth_users
+WHERE auth_users.id = impersonations.actor_id;
+ALTER TABLE impersonations ALTER COLUMN actor_email SET NOT NULL;
+
+ALTER TABLE impersonations ALTER COLUMN actor_id DROP NOT NULL;
+ALTER TABLE impersonations DROP CONSTRAINT IF EXISTS impersonations_actor_id_fkey;
+ALTER TABLE impersonations ADD CONSTRAINT impersonations_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES auth_users(id) ON DELETE SET NULL;
+
+ALTER TABLE deletion_receipts ADD COLUMN IF NOT EXISTS pseudonymised_rows JSONB NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE deletion_receipts DROP COLUMN IF EXISTS pseudonymised_rows;
+
+DELETE FROM impersonations WHERE actor_id IS NULL;
+ALTER TABLE impersonations DROP CONSTRAINT IF EXISTS impersonations_actor_id_fkey;
+ALTER TABLE impersonations ADD CONSTRAINT impersonations_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES auth_users(id);
+ALTER TABLE impersonations ALTER COLUMN actor_id SET NOT NULL;
+ALTER TABLE impersonations DROP COLUMN IF EXISTS actor_email;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE data_exports
+SET status = 'failed', error = 'superseded', completed_at = CURRENT_TIMESTAMP
+WHERE status IN ('pending', 'running') AND id NOT IN (
+  SELECT DISTINCT ON (user_id) id
+  FROM data_exports
+  WHERE status IN ('pending', 'running')
+  ORDER BY user_id, created_at DESC
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS data_exports_user_id_active_key ON data_exports (user_id) WHERE status IN ('pending', 'running');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX data_exports_user_id_active_key;
+-- +goose StatementEnd